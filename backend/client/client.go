@@ -0,0 +1,303 @@
+// Package client is a typed Go client for the orchestrator's REST API:
+//
+//    c := client.New("http://localhost:8080")
+//    t, err := c.CreateTask(ctx, client.TaskRequest{Query: "summarize https://example.com"})
+//    if err != nil { ... }
+//    if _, err := c.StartTask(ctx, t.ID, 0); err != nil { ... }
+//    t, err = c.WaitForCompletion(ctx, t.ID, 5*time.Minute)
+//
+// Requests and responses use the server's own types (aliased below). Non-2xx responses
+// are returned as *Error. Events streams /v1/tasks/{id}/events with reconnects and typed
+// payloads; see events.go.
+package client
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/scheduler"
+    "github.com/example/agent-orchestrator/internal/workflows"
+)
+
+type (
+    Task        = models.Task
+    Plan        = models.Plan
+    Step        = models.Step
+    PlanVersion = models.PlanVersion
+    Run         = models.Run
+    StepRun     = models.StepRun
+    Result      = models.Result
+    Status      = models.Status
+    QueueStats  = orchestrator.QueueStats
+    QueuedJob   = orchestrator.QueuedJob
+    Schedule    = scheduler.Schedule
+    Workflow    = workflows.Workflow
+    Param       = workflows.Param
+)
+
+// Client calls one server. The zero value is not usable; use New.
+type Client struct {
+    BaseURL string
+    // HTTP sends the requests; it should have no overall timeout, since event
+    // streams stay open. Use contexts to bound calls.
+    HTTP *http.Client
+    // ClientID is sent as X-Client-ID for fair queueing (default: the remote host).
+    ClientID string
+    // Author is sent as X-Author and attributes plan edits, workflows and step
+    // decisions.
+    Author string
+    // MaxRetries is how many consecutive failed reconnects an event stream tolerates
+    // (default 5).
+    MaxRetries int
+    // RetryWait is how long an event stream waits before reconnecting, times the
+    // number of consecutive failures (default 1s).
+    RetryWait time.Duration
+}
+
+func New(baseURL string) *Client {
+    return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTP: &http.Client{}}
+}
+
+// Error is a non-2xx response. Code is the server's stable error code, e.g.
+// "task_running" or "step_not_found"; Problems lists validation problems of a 400 or
+// 422 one by one, which Message already sums up.
+type Error struct {
+    StatusCode int
+    Code       string
+    Message    string
+    Problems   []string
+}
+
+func (e *Error) Error() string {
+    msg := http.StatusText(e.StatusCode)
+    if e.Message != "" { msg += ": " + e.Message }
+    return msg
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool { return statusOf(err) == http.StatusNotFound }
+
+// IsConflict reports whether err is a 409 response, e.g. starting a running task;
+// Error.Code names the conflict.
+func IsConflict(err error) bool { return statusOf(err) == http.StatusConflict }
+
+func statusOf(err error) int {
+    var e *Error
+    if errors.As(err, &e) { return e.StatusCode }
+    return 0
+}
+
+func responseError(resp *http.Response) error {
+    b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+    e := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(b))}
+    // errors are {"error", "code", "problems"?}; anything else (a proxy's error page)
+    // is kept as text
+    var body struct {
+        Error    string   `json:"error"`
+        Code     string   `json:"code"`
+        Problems []string `json:"problems"`
+    }
+    if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") && json.Unmarshal(b, &body) == nil && body.Error != "" {
+        e.Message, e.Code, e.Problems = body.Error, body.Code, body.Problems
+    }
+    return e
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+    req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+    if err != nil { return nil, err }
+    if c.ClientID != "" { req.Header.Set("X-Client-ID", c.ClientID) }
+    if c.Author != "" { req.Header.Set("X-Author", c.Author) }
+    return req, nil
+}
+
+// do sends body as JSON and decodes the response into out (when not nil).
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+    var rd io.Reader
+    if body != nil {
+        b, err := json.Marshal(body)
+        if err != nil { return err }
+        rd = bytes.NewReader(b)
+    }
+    req, err := c.newRequest(ctx, method, path, rd)
+    if err != nil { return err }
+    if body != nil { req.Header.Set("Content-Type", "application/json") }
+    return c.send(req, out)
+}
+
+func (c *Client) send(req *http.Request, out any) error {
+    resp, err := c.HTTP.Do(req)
+    if err != nil { return err }
+    defer resp.Body.Close()
+    if resp.StatusCode >= 300 { return responseError(resp) }
+    if out == nil { return nil }
+    if b, ok := out.(*[]byte); ok {
+        *b, err = io.ReadAll(resp.Body)
+        return err
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func esc(s string) string { return url.PathEscape(s) }
+
+// Health calls GET /health.
+func (c *Client) Health(ctx context.Context) error {
+    return c.do(ctx, http.MethodGet, "/health", nil, nil)
+}
+
+// LLMStatus is the answer of GET /debug/llm.
+type LLMStatus struct {
+    Provider string `json:"provider"`
+    Model    string `json:"model"`
+    OK       bool   `json:"ok"`
+    Error    string `json:"error,omitempty"`
+}
+
+// CheckLLM asks the server to ping its LLM provider.
+func (c *Client) CheckLLM(ctx context.Context) (*LLMStatus, error) {
+    var st LLMStatus
+    if err := c.do(ctx, http.MethodGet, "/debug/llm", nil, &st); err != nil { return nil, err }
+    return &st, nil
+}
+
+// Queue returns the worker pool and the queued jobs in their expected order.
+func (c *Client) Queue(ctx context.Context) (*QueueStats, error) {
+    var st QueueStats
+    if err := c.do(ctx, http.MethodGet, "/v1/queue", nil, &st); err != nil { return nil, err }
+    return &st, nil
+}
+
+// TaskRequest is the body of POST /v1/tasks.
+type TaskRequest struct {
+    Query    string         `json:"query"`
+    Context  map[string]any `json:"context,omitempty"`
+    Priority int            `json:"priority,omitempty"`
+}
+
+func (c *Client) CreateTask(ctx context.Context, req TaskRequest) (*Task, error) {
+    var t Task
+    if err := c.do(ctx, http.MethodPost, "/v1/tasks", req, &t); err != nil { return nil, err }
+    return &t, nil
+}
+
+func (c *Client) ListTasks(ctx context.Context) ([]*Task, error) {
+    var tasks []*Task
+    err := c.do(ctx, http.MethodGet, "/v1/tasks", nil, &tasks)
+    return tasks, err
+}
+
+func (c *Client) GetTask(ctx context.Context, id string) (*Task, error) {
+    var t Task
+    if err := c.do(ctx, http.MethodGet, "/v1/tasks/"+esc(id), nil, &t); err != nil { return nil, err }
+    return &t, nil
+}
+
+// DeleteTask removes a finished or idle task with its runs and plan history; queued
+// and active tasks are a conflict until they are cancelled.
+func (c *Client) DeleteTask(ctx context.Context, id string) error {
+    return c.do(ctx, http.MethodDelete, "/v1/tasks/"+esc(id), nil, nil)
+}
+
+// PlanTask computes the task's plan without running it.
+func (c *Client) PlanTask(ctx context.Context, id string) (*Plan, error) {
+    var p Plan
+    if err := c.do(ctx, http.MethodPost, "/v1/tasks/"+esc(id)+":plan", nil, &p); err != nil { return nil, err }
+    return &p, nil
+}
+
+// StartTask queues a plan-and-run; priority 0 keeps the task's own.
+func (c *Client) StartTask(ctx context.Context, id string, priority int) (*QueuedJob, error) {
+    return c.enqueue(ctx, "/v1/tasks/"+esc(id)+":start", priority)
+}
+
+// ExecuteTask queues a run of the task's current plan; priority 0 keeps the task's own.
+func (c *Client) ExecuteTask(ctx context.Context, id string, priority int) (*QueuedJob, error) {
+    return c.enqueue(ctx, "/v1/tasks/"+esc(id)+":execute", priority)
+}
+
+// ResumeTask queues a paused task to continue where it stopped.
+func (c *Client) ResumeTask(ctx context.Context, id string) (*QueuedJob, error) {
+    return c.enqueue(ctx, "/v1/tasks/"+esc(id)+":resume", 0)
+}
+
+func (c *Client) enqueue(ctx context.Context, path string, priority int) (*QueuedJob, error) {
+    if priority != 0 { path += "?priority=" + strconv.Itoa(priority) }
+    var job QueuedJob
+    if err := c.do(ctx, http.MethodPost, path, nil, &job); err != nil { return nil, err }
+    return &job, nil
+}
+
+// PauseTask asks a running task to stop at the next step boundary.
+func (c *Client) PauseTask(ctx context.Context, id string) error {
+    return c.do(ctx, http.MethodPost, "/v1/tasks/"+esc(id)+":pause", nil, nil)
+}
+
+// CancelTask cancels a queued, running or paused task.
+func (c *Client) CancelTask(ctx context.Context, id string) error {
+    return c.do(ctx, http.MethodPost, "/v1/tasks/"+esc(id)+":cancel", nil, nil)
+}
+
+// Decision is the answer to a step approval, rejection or answer.
+type Decision struct {
+    TaskID   string `json:"task_id"`
+    StepID   string `json:"step_id"`
+    Decision string `json:"decision"`
+    By       string `json:"by"`
+}
+
+// ApproveStep lets a step waiting for approval run. by names who approves; empty, it
+// defaults to Author, and the server refuses a decision without either.
+func (c *Client) ApproveStep(ctx context.Context, id, stepID, by, comment string) (*Decision, error) {
+    if by == "" { by = c.Author }
+    return c.stepAction(ctx, id, stepID, "approve", map[string]string{"by": by, "comment": comment})
+}
+
+// RejectStep fails a step waiting for approval. by is as for ApproveStep.
+func (c *Client) RejectStep(ctx context.Context, id, stepID, by, comment string) (*Decision, error) {
+    if by == "" { by = c.Author }
+    return c.stepAction(ctx, id, stepID, "reject", map[string]string{"by": by, "comment": comment})
+}
+
+// AnswerStep replies to an ask_user question; stepID may name a map sub-step as
+// "STEP[i].SUB".
+func (c *Client) AnswerStep(ctx context.Context, id, stepID, answer, by string) (*Decision, error) {
+    return c.stepAction(ctx, id, stepID, "answer", map[string]string{"answer": answer, "by": by})
+}
+
+func (c *Client) stepAction(ctx context.Context, id, stepID, action string, body map[string]string) (*Decision, error) {
+    var d Decision
+    if err := c.do(ctx, http.MethodPost, "/v1/tasks/"+esc(id)+"/steps/"+esc(stepID)+":"+action, body, &d); err != nil { return nil, err }
+    return &d, nil
+}
+
+// Runs lists the task's runs, oldest first.
+func (c *Client) Runs(ctx context.Context, id string) ([]*Run, error) {
+    var runs []*Run
+    err := c.do(ctx, http.MethodGet, "/v1/tasks/"+esc(id)+"/runs", nil, &runs)
+    return runs, err
+}
+
+func (c *Client) GetRun(ctx context.Context, id, runID string) (*Run, error) {
+    var run Run
+    if err := c.do(ctx, http.MethodGet, "/v1/tasks/"+esc(id)+"/runs/"+esc(runID), nil, &run); err != nil { return nil, err }
+    return &run, nil
+}
+
+// exportPath adds the format to an export URL.
+func exportPath(path, format string, version int) string {
+    q := url.Values{}
+    if format != "" { q.Set("format", format) }
+    if version > 0 { q.Set("version", strconv.Itoa(version)) }
+    if len(q) == 0 { return path }
+    return path + "?" + q.Encode()
+}
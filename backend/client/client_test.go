@@ -0,0 +1,162 @@
+package client
+
+import (
+    "context"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/api"
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// newTestClient returns a client talking to an in-process server, served like
+// cmd/server.
+func newTestClient(t *testing.T) *Client {
+    t.Helper()
+    mux := http.NewServeMux()
+    api.RegisterRoutes(mux)
+    srv := httptest.NewServer(api.ValidateRequests(mux))
+    t.Cleanup(srv.Close)
+    c := New(srv.URL + "/")
+    c.RetryWait = 10 * time.Millisecond
+    return c
+}
+
+// plannedTask creates a task and gives it steps, so no planner runs.
+func plannedTask(t *testing.T, c *Client, steps ...*Step) *Task {
+    t.Helper()
+    ctx := context.Background()
+    task, err := c.CreateTask(ctx, TaskRequest{Query: "client test", Context: map[string]any{"k": "v"}})
+    if err != nil { t.Fatal(err) }
+    if _, err := c.ReplacePlan(ctx, task.ID, &Plan{Steps: steps}); err != nil { t.Fatal(err) }
+    return task
+}
+
+func echo(id, text string, deps ...string) *Step {
+    return &Step{ID: id, Tool: "echo", Deps: deps, Inputs: map[string]any{"text": text}}
+}
+
+func TestTaskLifecycle(t *testing.T) {
+    c := newTestClient(t)
+    ctx := context.Background()
+    if err := c.Health(ctx); err != nil { t.Fatal(err) }
+    task := plannedTask(t, c, echo("a", "hi {{context.k}}"))
+
+    // plan edits come back as versions
+    if _, err := c.InsertStep(ctx, task.ID, echo("b", "{{step:a.output}}", "a"), InsertAt{After: "a"}); err != nil { t.Fatal(err) }
+    v, err := c.PatchStep(ctx, task.ID, "b", map[string]any{"inputs": map[string]any{"text": "got {{step:a.output}}"}})
+    if err != nil || v.Action != "update_step" { t.Fatalf("patch: %+v, %v", v, err) }
+    versions, err := c.PlanVersions(ctx, task.ID)
+    if err != nil || len(versions) != 3 || versions[2].Version != v.Version { t.Fatalf("versions %d, %v", len(versions), err) }
+    if yaml, err := c.ExportPlan(ctx, task.ID, "yaml"); err != nil || !strings.Contains(string(yaml), "tool: echo") { t.Fatalf("export: %s, %v", yaml, err) }
+
+    job, err := c.ExecuteTask(ctx, task.ID, 0)
+    if err != nil || job.TaskID != task.ID { t.Fatalf("execute: %+v, %v", job, err) }
+    done, err := c.WaitForCompletion(ctx, task.ID, 5*time.Second)
+    if err != nil || done.Status != models.StatusSuccess { t.Fatalf("wait: %v, %+v", err, done) }
+    if out := done.Results[1].Output; out != "echo: got echo: hi v" { t.Fatalf("output %v", out) }
+    runs, err := c.Runs(ctx, task.ID)
+    if err != nil || len(runs) != 1 { t.Fatalf("runs %d, %v", len(runs), err) }
+    if run, err := c.GetRun(ctx, task.ID, runs[0].ID); err != nil || run.Status != models.StatusSuccess { t.Fatalf("run %+v, %v", run, err) }
+
+    if err := c.DeleteTask(ctx, task.ID); err != nil { t.Fatal(err) }
+    if _, err := c.GetTask(ctx, task.ID); !IsNotFound(err) { t.Fatalf("deleted task: %v", err) }
+}
+
+func TestErrors(t *testing.T) {
+    c := newTestClient(t)
+    ctx := context.Background()
+    _, err := c.GetTask(ctx, "nope")
+    e, ok := err.(*Error)
+    if !ok || e.StatusCode != http.StatusNotFound || e.Code != "task_not_found" || !IsNotFound(err) || IsConflict(err) { t.Fatalf("missing task: %#v", err) }
+
+    task := plannedTask(t, c, echo("a", "x"))
+    _, err = c.ReplacePlan(ctx, task.ID, &Plan{Steps: []*Step{{ID: "a", Tool: "nope"}, echo("b", "x", "zz")}})
+    e, ok = err.(*Error)
+    if !ok || e.StatusCode != http.StatusUnprocessableEntity || e.Code != "invalid_plan" || len(e.Problems) != 2 { t.Fatalf("invalid plan: %#v", err) }
+    if !strings.Contains(e.Error(), "Unprocessable Entity: ") { t.Fatalf("message %q", e.Error()) }
+
+    // a body that is not a JSON error is kept as text
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { http.Error(w, "bad gateway page", http.StatusBadGateway) }))
+    defer srv.Close()
+    err = New(srv.URL).Health(ctx)
+    if e, ok := err.(*Error); !ok || e.StatusCode != http.StatusBadGateway || e.Message != "bad gateway page" || e.Code != "" { t.Fatalf("proxy error: %#v", err) }
+}
+
+func TestApprovalsAndQuestions(t *testing.T) {
+    c := newTestClient(t)
+    ctx := context.Background()
+    target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{"ok": true}`)) }))
+    defer target.Close()
+    // http_post_json is high risk and waits for approval; ask_user waits for an answer
+    task := plannedTask(t, c,
+        &Step{ID: "post", Tool: "http_post_json", Inputs: map[string]any{"url": target.URL, "body": map[string]any{"a": 1}}},
+        &Step{ID: "ask", Tool: "ask_user", Deps: []string{"post"}, Inputs: map[string]any{"question": "Which?", "choices": []any{"x", "y"}}},
+    )
+    var asked []string
+    err := c.Events(ctx, task.ID, 0, func(ev Event) error {
+        switch p := ev.Payload.(type) {
+        case *Task:
+            // execute once the stream is open, so no request is missed
+            if _, err := c.ExecuteTask(ctx, task.ID, 0); err != nil { return err }
+        case *ApprovalRequest:
+            asked = append(asked, p.StepID)
+            if _, err := c.ApproveStep(ctx, task.ID, p.StepID, "ann", "fine"); err != nil { return err }
+        case *QuestionAsked:
+            asked = append(asked, p.StepID)
+            if _, err := c.AnswerStep(ctx, task.ID, p.StepID, "z", "ann"); err == nil || asError(err).Code == "" { t.Errorf("answer not among the choices: %v", err) }
+            if d, err := c.AnswerStep(ctx, task.ID, p.StepID, "y", "ann"); err != nil || d.Decision == "" { t.Errorf("answer: %+v, %v", d, err) }
+        case *TaskStatus:
+            if Finished(p.Status) { return ErrStop }
+        }
+        return nil
+    })
+    if err != nil || strings.Join(asked, ",") != "post,ask" { t.Fatalf("events: %v, asked %q", err, asked) }
+    done, err := c.GetTask(ctx, task.ID)
+    if err != nil || done.Status != models.StatusSuccess || done.Results[1].Output != "y" { t.Fatalf("task %+v, %v", done, err) }
+    if _, err := c.ApproveStep(ctx, task.ID, "post", "ann", ""); !IsConflict(err) { t.Fatalf("second approval: %v", err) }
+}
+
+// asError returns err as an *Error, or an empty one.
+func asError(err error) *Error {
+    if e, ok := err.(*Error); ok { return e }
+    return &Error{}
+}
+
+func TestWaitForCompletionTimeout(t *testing.T) {
+    c := newTestClient(t)
+    ctx := context.Background()
+    task := plannedTask(t, c, &Step{ID: "ask", Tool: "ask_user", Inputs: map[string]any{"question": "ok?"}})
+    if _, err := c.ExecuteTask(ctx, task.ID, 0); err != nil { t.Fatal(err) }
+    // a task waiting for input is not finished
+    last, err := c.WaitForCompletion(ctx, task.ID, 200*time.Millisecond)
+    if err == nil || !strings.Contains(err.Error(), "not finished after 200ms") || last == nil || last.ID != task.ID { t.Fatalf("wait: %v, %+v", err, last) }
+    if _, err := c.AnswerStep(ctx, task.ID, "ask", "yes", "ann"); err != nil { t.Fatal(err) }
+    if done, err := c.WaitForCompletion(ctx, task.ID, 5*time.Second); err != nil || done.Status != models.StatusSuccess { t.Fatalf("after answer: %v, %+v", err, done) }
+}
+
+func TestSchedulesAndWorkflows(t *testing.T) {
+    c := newTestClient(t)
+    ctx := context.Background()
+    sc, err := c.CreateSchedule(ctx, &Schedule{Cron: "0 0 1 1 *", Query: "yearly", Plan: &Plan{Steps: []*Step{echo("a", "new year")}}})
+    if err != nil || sc.ID == "" || sc.NextRunAt == nil { t.Fatalf("create schedule: %+v, %v", sc, err) }
+    defer c.DeleteSchedule(ctx, sc.ID)
+    task, err := c.RunSchedule(ctx, sc.ID)
+    if err != nil { t.Fatal(err) }
+    if done, err := c.WaitForCompletion(ctx, task.ID, 5*time.Second); err != nil || done.Status != models.StatusSuccess { t.Fatalf("scheduled task: %v, %+v", err, done) }
+    if tasks, err := c.ScheduleTasks(ctx, sc.ID); err != nil || len(tasks) != 1 || tasks[0].ID != task.ID { t.Fatalf("schedule tasks: %v, %v", tasks, err) }
+    if _, err := c.CreateSchedule(ctx, &Schedule{Cron: "not cron", Query: "q"}); err == nil || asError(err).StatusCode/100 != 4 { t.Fatalf("invalid cron: %v", err) }
+
+    name := "client-test-greet"
+    wf, err := c.SaveWorkflow(ctx, &Workflow{Name: name, Params: []Param{{Name: "who", Type: "string", Required: true}}, Plan: &Plan{Steps: []*Step{echo("a", "hi {{params.who}}")}}})
+    if err != nil || wf.Version != 1 { t.Fatalf("save workflow: %+v, %v", wf, err) }
+    defer c.DeleteWorkflow(ctx, name)
+    if _, err := c.RunWorkflow(ctx, name, WorkflowRunRequest{}); asError(err).StatusCode != http.StatusUnprocessableEntity || len(asError(err).Problems) == 0 { t.Fatalf("missing param: %v", err) }
+    run, err := c.RunWorkflow(ctx, name, WorkflowRunRequest{Params: map[string]any{"who": "ann"}})
+    if err != nil || run.Job == nil { t.Fatalf("run workflow: %+v, %v", run, err) }
+    if done, err := c.WaitForCompletion(ctx, run.Task.ID, 5*time.Second); err != nil || done.Results[0].Output != "echo: hi ann" { t.Fatalf("workflow task: %v, %+v", err, done) }
+    if yaml, err := c.ExportWorkflow(ctx, name, "yaml", 0); err != nil || !strings.Contains(string(yaml), "name: "+name) { t.Fatalf("export: %s, %v", yaml, err) }
+}
@@ -0,0 +1,345 @@
+package client
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+type (
+    // Event is one orchestrator event. DecodeEvent and the streams set Payload to
+    // the type listed for its name:
+    //
+    //    task_status        *TaskStatus
+    //    run_status         *RunStatus
+    //    queue_position     *QueuePosition
+    //    plan               *Plan
+    //    plan_version       *PlanEdited
+    //    step_status        *StepRun
+    //    result             *Result
+    //    token              *Token
+    //    map_item           *MapItem
+    //    approval_required  *ApprovalRequest
+    //    approval_decided   *ApprovalDecision
+    //    question           *QuestionAsked
+    //    question_answered  *QuestionAnswered
+    //    snapshot           *Task (first message of a task stream)
+    //    gap                *Gap (events were lost; a snapshot follows on task streams)
+    //
+    // Other events keep a generic map[string]any.
+    Event = orchestrator.Event
+    Gap   = orchestrator.Gap
+)
+
+type TaskStatus struct {
+    Status   Status `json:"status"`
+    RunID    string `json:"run_id,omitempty"`
+    Query    string `json:"query,omitempty"`
+    Error    string `json:"error,omitempty"`
+    // Position and Depth are set when the task is queued.
+    Position int    `json:"position,omitempty"`
+    Depth    int    `json:"depth,omitempty"`
+}
+
+type RunStatus struct {
+    RunID      string `json:"run_id"`
+    Number     int    `json:"number"`
+    Status     Status `json:"status"`
+    Error      string `json:"error,omitempty"`
+    DurationMs int64  `json:"duration_ms,omitempty"`
+}
+
+type QueuePosition struct {
+    Position int `json:"position"`
+    Depth    int `json:"depth"`
+}
+
+type PlanEdited struct {
+    Version int                 `json:"version"`
+    Author  string              `json:"author"`
+    Action  string              `json:"action"`
+    Diff    []models.PlanChange `json:"diff"`
+}
+
+// Token is a chunk of streamed LLM output; StepID may name a map sub-step.
+type Token struct {
+    StepID string `json:"step_id"`
+    Chunk  string `json:"chunk"`
+}
+
+type MapItem struct {
+    StepID string `json:"step_id"`
+    Index  int    `json:"index"`
+    Status Status `json:"status"`
+    Output any    `json:"output,omitempty"`
+    Error  string `json:"error,omitempty"`
+}
+
+type ApprovalRequest struct {
+    RunID     string         `json:"run_id"`
+    StepID    string         `json:"step_id"`
+    Tool      string         `json:"tool"`
+    Risk      string         `json:"risk"`
+    Inputs    map[string]any `json:"inputs"`
+    ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+}
+
+type ApprovalDecision struct {
+    RunID    string `json:"run_id"`
+    StepID   string `json:"step_id"`
+    Decision string `json:"decision"`
+    By       string `json:"by"`
+    Comment  string `json:"comment"`
+}
+
+type QuestionAsked struct {
+    RunID    string   `json:"run_id"`
+    StepID   string   `json:"step_id"`
+    Question string   `json:"question"`
+    Choices  []string `json:"choices,omitempty"`
+}
+
+type QuestionAnswered struct {
+    RunID  string `json:"run_id"`
+    StepID string `json:"step_id"`
+    Answer string `json:"answer"`
+    By     string `json:"by"`
+}
+
+func newPayload(event string) any {
+    switch event {
+    case "task_status":
+        return &TaskStatus{}
+    case "run_status":
+        return &RunStatus{}
+    case "queue_position":
+        return &QueuePosition{}
+    case "plan":
+        return &Plan{}
+    case "plan_version":
+        return &PlanEdited{}
+    case "step_status":
+        return &StepRun{}
+    case "result":
+        return &Result{}
+    case "token":
+        return &Token{}
+    case "map_item":
+        return &MapItem{}
+    case "approval_required":
+        return &ApprovalRequest{}
+    case "approval_decided":
+        return &ApprovalDecision{}
+    case "question":
+        return &QuestionAsked{}
+    case "question_answered":
+        return &QuestionAnswered{}
+    }
+    return nil
+}
+
+// DecodeEvent decodes an encoded event (an "update" message's data, or the Data of
+// an orchestrator Record) with a typed payload.
+func DecodeEvent(data []byte) (Event, error) {
+    var raw struct {
+        Event
+        Payload json.RawMessage `json:"payload"`
+    }
+    if err := json.Unmarshal(data, &raw); err != nil { return Event{}, err }
+    ev := raw.Event
+    if len(raw.Payload) == 0 { return ev, nil }
+    p := newPayload(ev.Event)
+    if p == nil {
+        var m map[string]any
+        if err := json.Unmarshal(raw.Payload, &m); err != nil { return ev, fmt.Errorf("%s payload: %v", ev.Event, err) }
+        ev.Payload = m
+        return ev, nil
+    }
+    if err := json.Unmarshal(raw.Payload, p); err != nil { return ev, fmt.Errorf("%s payload: %v", ev.Event, err) }
+    ev.Payload = p
+    return ev, nil
+}
+
+// ErrStop ends a stream from its callback without an error.
+var ErrStop = errors.New("stop")
+
+// Events streams a task's events to fn until fn returns an error (ErrStop for a
+// clean stop, which returns nil), ctx ends, or reconnecting fails MaxRetries times in
+// a row. With lastEventID 0 the stream starts with a "snapshot" of the task;
+// otherwise it resumes after that event. Dropped connections resume from the last
+// event received. After a "gap" (e.g. the server restarted and its event IDs began
+// again) the stream resumes from the snapshot that follows it.
+func (c *Client) Events(ctx context.Context, taskID string, lastEventID uint64, fn func(Event) error) error {
+    return c.stream(ctx, "/v1/tasks/"+esc(taskID)+"/events", lastEventID, fn)
+}
+
+// EventFilter selects firehose events; empty fields match everything.
+type EventFilter struct {
+    Types []string
+    // Statuses match the status the task had when the event was published.
+    Statuses []string
+    // Tools match events about steps (step_status, result, token, map_item) using
+    // one of them.
+    Tools []string
+    // TaskPrefix matches task IDs.
+    TaskPrefix string
+}
+
+// Firehose streams all tasks' events (GET /v1/events) like Events, without snapshots.
+func (c *Client) Firehose(ctx context.Context, f EventFilter, lastEventID uint64, fn func(Event) error) error {
+    q := url.Values{}
+    set := func(key string, vals []string) {
+        if len(vals) > 0 { q.Set(key, strings.Join(vals, ",")) }
+    }
+    set("type", f.Types)
+    set("status", f.Statuses)
+    set("tool", f.Tools)
+    if f.TaskPrefix != "" { q.Set("task", f.TaskPrefix) }
+    path := "/v1/events"
+    if len(q) > 0 { path += "?" + q.Encode() }
+    return c.stream(ctx, path, lastEventID, fn)
+}
+
+func (c *Client) stream(ctx context.Context, path string, lastID uint64, fn func(Event) error) error {
+    retries := c.MaxRetries
+    if retries <= 0 { retries = 5 }
+    wait := c.RetryWait
+    if wait <= 0 { wait = time.Second }
+    failures := 0
+    for {
+        progressed, err := c.streamOnce(ctx, path, &lastID, fn)
+        var cb callbackError
+        if errors.As(err, &cb) {
+            if errors.Is(cb.err, ErrStop) { return nil }
+            return cb.err
+        }
+        if ctx.Err() != nil { return ctx.Err() }
+        var e *Error
+        if errors.As(err, &e) { return err }
+        if progressed { failures = 0 }
+        if failures++; failures > retries { return fmt.Errorf("event stream: %v", err) }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(time.Duration(failures) * wait):
+        }
+    }
+}
+
+// callbackError is an error returned by a stream's callback, which ends the stream
+// instead of reconnecting.
+type callbackError struct{ err error }
+
+func (e callbackError) Error() string { return e.err.Error() }
+
+// streamOnce reads one connection; progressed reports whether any message arrived.
+func (c *Client) streamOnce(ctx context.Context, path string, lastID *uint64, fn func(Event) error) (progressed bool, err error) {
+    req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+    if err != nil { return false, err }
+    req.Header.Set("Accept", "text/event-stream")
+    if *lastID > 0 { req.Header.Set("Last-Event-ID", strconv.FormatUint(*lastID, 10)) }
+    resp, err := c.HTTP.Do(req)
+    if err != nil { return false, err }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK { return false, responseError(resp) }
+    sc := bufio.NewScanner(resp.Body)
+    sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+    var id uint64
+    name, data := "", []byte(nil)
+    for sc.Scan() {
+        line := sc.Text()
+        switch {
+        case line == "":
+            if id > 0 { *lastID = id }
+            if data != nil {
+                progressed = true
+                ev, err := sseEvent(name, id, data)
+                if err != nil { return progressed, err }
+                switch p := ev.Payload.(type) {
+                case *Task:
+                    // a snapshot is the new starting point; without an ID (the
+                    // server has no events yet) a reconnect asks for a new one
+                    *lastID = id
+                case *Gap:
+                    // the cursor may be from before a server restart; sending it
+                    // again would only bring the gap back
+                    *lastID = 0
+                    if p.FirstAvailableID > 0 { *lastID = p.FirstAvailableID - 1 }
+                }
+                if err := fn(ev); err != nil { return progressed, callbackError{err} }
+            }
+            id, name, data = 0, "", nil
+        case strings.HasPrefix(line, "id:"):
+            id, _ = strconv.ParseUint(strings.TrimSpace(line[3:]), 10, 64)
+        case strings.HasPrefix(line, "event:"):
+            name = strings.TrimSpace(line[6:])
+        case strings.HasPrefix(line, "data:"):
+            data = append(data, strings.TrimPrefix(line[5:], " ")...)
+        }
+    }
+    if err := sc.Err(); err != nil { return progressed, err }
+    return progressed, io.ErrUnexpectedEOF
+}
+
+// sseEvent turns an SSE message into an Event.
+func sseEvent(name string, id uint64, data []byte) (Event, error) {
+    switch name {
+    case "snapshot":
+        var t Task
+        if err := json.Unmarshal(data, &t); err != nil { return Event{}, err }
+        return Event{ID: id, Event: "snapshot", TaskID: t.ID, Payload: &t}, nil
+    case "gap":
+        var g Gap
+        if err := json.Unmarshal(data, &g); err != nil { return Event{}, err }
+        return Event{Event: "gap", TaskID: g.TaskID, Payload: &g}, nil
+    }
+    return DecodeEvent(data)
+}
+
+// Finished reports whether a task in status s is done: succeeded, failed or
+// cancelled.
+func Finished(s Status) bool {
+    return s == models.StatusSuccess || s == models.StatusFailed || s == models.StatusCancelled
+}
+
+// WaitForCompletion follows a task's events until it is Finished and returns it. A
+// timeout > 0 bounds the wait; when it (or ctx) ends first, the task is returned as
+// last seen together with an error wrapping the context error. Paused tasks and
+// tasks waiting for approval or input are not finished.
+func (c *Client) WaitForCompletion(ctx context.Context, id string, timeout time.Duration) (*Task, error) {
+    waitCtx := ctx
+    if timeout > 0 {
+        var cancel context.CancelFunc
+        waitCtx, cancel = context.WithTimeout(ctx, timeout)
+        defer cancel()
+    }
+    var last *Task
+    err := c.Events(waitCtx, id, 0, func(ev Event) error {
+        switch p := ev.Payload.(type) {
+        case *Task:
+            last = p
+            if Finished(p.Status) { return ErrStop }
+        case *TaskStatus:
+            if Finished(p.Status) { return ErrStop }
+        }
+        return nil
+    })
+    if err != nil && waitCtx.Err() != nil && ctx.Err() == nil {
+        // timed out: report how far the task got
+        if t, gerr := c.GetTask(ctx, id); gerr == nil { last = t }
+        return last, fmt.Errorf("task %s not finished after %s: %w", id, timeout, err)
+    }
+    if err != nil { return last, err }
+    return c.GetTask(ctx, id)
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// scriptedServer answers the n-th event stream connection (from 0) with script[n],
+// and GET /v1/tasks/t with task. It records each connection's Last-Event-ID.
+type scriptedServer struct {
+    mu      sync.Mutex
+    cursors []string
+}
+
+func (s *scriptedServer) start(t *testing.T, task string, script ...string) *Client {
+    t.Helper()
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !strings.HasSuffix(r.URL.Path, "/events") {
+            w.Header().Set("Content-Type", "application/json")
+            fmt.Fprint(w, task)
+            return
+        }
+        s.mu.Lock()
+        n := len(s.cursors)
+        s.cursors = append(s.cursors, r.Header.Get("Last-Event-ID"))
+        s.mu.Unlock()
+        if n >= len(script) {
+            http.Error(w, "gone", http.StatusServiceUnavailable)
+            return
+        }
+        w.Header().Set("Content-Type", "text/event-stream")
+        // the connection ends with the script, as when the server goes away
+        fmt.Fprint(w, script[n])
+    }))
+    t.Cleanup(srv.Close)
+    c := New(srv.URL)
+    c.RetryWait = time.Millisecond
+    c.MaxRetries = 2
+    return c
+}
+
+func (s *scriptedServer) seen() []string {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]string(nil), s.cursors...)
+}
+
+const runningSnapshot = `{"id":"t","query":"q","status":"RUNNING"}`
+
+func TestEventsResumeAfterRestart(t *testing.T) {
+    s := &scriptedServer{}
+    c := s.start(t, `{"id":"t","status":"SUCCESS"}`,
+        "id: 40\nevent: snapshot\ndata: "+runningSnapshot+"\n\n"+
+            "id: 41\nevent: update\ndata: {\"id\":41,\"event\":\"step_status\",\"task_id\":\"t\",\"payload\":{\"id\":\"a\",\"status\":\"RUNNING\"}}\n\n",
+        // the restarted server has only events 3 and later: a gap, then the
+        // connection drops before the snapshot
+        "event: gap\ndata: {\"task_id\":\"t\",\"last_event_id\":41,\"first_available_id\":3}\n\n",
+        "id: 7\nevent: snapshot\ndata: {\"id\":\"t\",\"status\":\"SUCCESS\"}\n\n",
+    )
+    var got []string
+    err := c.Events(context.Background(), "t", 0, func(ev Event) error {
+        got = append(got, fmt.Sprintf("%s:%d", ev.Event, ev.ID))
+        if task, ok := ev.Payload.(*Task); ok && Finished(task.Status) { return ErrStop }
+        return nil
+    })
+    if err != nil { t.Fatal(err) }
+    if strings.Join(got, " ") != "snapshot:40 step_status:41 gap:0 snapshot:7" { t.Fatalf("events %q", got) }
+    // the stale cursor 41 is sent once and dropped after the gap
+    if cursors := strings.Join(s.seen(), ","); cursors != ",41,2" { t.Fatalf("Last-Event-ID %q", cursors) }
+}
+
+func TestWaitForCompletionAfterRestart(t *testing.T) {
+    s := &scriptedServer{}
+    c := s.start(t, `{"id":"t","status":"SUCCESS"}`,
+        "id: 50\nevent: snapshot\ndata: "+runningSnapshot+"\n\n",
+        // a gap without a first available ID, then a snapshot of a server with no
+        // events yet: the next connection asks for a fresh snapshot
+        "event: gap\ndata: {\"task_id\":\"t\",\"last_event_id\":50}\n\nevent: snapshot\ndata: "+runningSnapshot+"\n\n",
+        "id: 1\nevent: snapshot\ndata: {\"id\":\"t\",\"status\":\"SUCCESS\"}\n\n",
+    )
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    task, err := c.WaitForCompletion(ctx, "t", 0)
+    if err != nil || task.Status != "SUCCESS" { t.Fatalf("wait: %v, %+v", err, task) }
+    if cursors := strings.Join(s.seen(), ","); cursors != ",50," { t.Fatalf("Last-Event-ID %q", cursors) }
+}
+
+func TestEventsStop(t *testing.T) {
+    stream := "id: 1\nevent: snapshot\ndata: " + runningSnapshot + "\n\n"
+    s := &scriptedServer{}
+    c := s.start(t, "", stream)
+    if err := c.Events(context.Background(), "t", 0, func(Event) error { return ErrStop }); err != nil { t.Fatalf("ErrStop: %v", err) }
+
+    // other callback errors end the stream and come back as they are
+    boom := errors.New("boom")
+    s = &scriptedServer{}
+    c = s.start(t, "", stream, stream)
+    if err := c.Events(context.Background(), "t", 0, func(Event) error { return boom }); err != boom || len(s.seen()) != 1 { t.Fatalf("callback error: %v after %d connections", err, len(s.seen())) }
+
+    // connections that end without a message are given up on after MaxRetries
+    // reconnects in a row
+    s = &scriptedServer{}
+    c = s.start(t, "", stream, "", "", stream)
+    err := c.Events(context.Background(), "t", 0, func(Event) error { return nil })
+    if err == nil || !strings.HasPrefix(err.Error(), "event stream: ") || len(s.seen()) != 3 { t.Fatalf("dropping server: %v after %d connections", err, len(s.seen())) }
+
+    // error responses are not retried
+    s = &scriptedServer{}
+    c = s.start(t, "")
+    err = c.Events(context.Background(), "t", 0, func(Event) error { return nil })
+    if asError(err).StatusCode != http.StatusServiceUnavailable || len(s.seen()) != 1 { t.Fatalf("failing server: %v after %d connections", err, len(s.seen())) }
+}
+
+func TestEventsNotFound(t *testing.T) {
+    c := newTestClient(t)
+    err := c.Events(context.Background(), "nope", 0, func(Event) error { return nil })
+    if !IsNotFound(err) || asError(err).Code != "task_not_found" { t.Fatalf("unknown task: %v", err) }
+}
+
+func TestFirehoseStaleCursor(t *testing.T) {
+    c := newTestClient(t)
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+    task := plannedTask(t, c, echo("a", "x"))
+    // a cursor from before a restart gets a gap, then the events that follow it
+    var got []string
+    err := c.Firehose(ctx, EventFilter{TaskPrefix: task.ID}, 999999999, func(ev Event) error {
+        got = append(got, ev.Event)
+        if ev.Event == "gap" {
+            if _, err := c.ExecuteTask(ctx, task.ID, 0); err != nil { return err }
+        }
+        if p, ok := ev.Payload.(*TaskStatus); ok && Finished(p.Status) { return ErrStop }
+        return nil
+    })
+    if err != nil || len(got) < 2 || got[0] != "gap" { t.Fatalf("firehose: %v, %q", err, got) }
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+    "context"
+    "net/http"
+    "strconv"
+)
+
+// TaskPlan is a task's current plan and its version (GET /v1/tasks/{id}/plan).
+type TaskPlan struct {
+    Version int   `json:"version"`
+    Plan    *Plan `json:"plan"`
+}
+
+func (c *Client) GetPlan(ctx context.Context, id string) (*TaskPlan, error) {
+    var p TaskPlan
+    if err := c.do(ctx, http.MethodGet, "/v1/tasks/"+esc(id)+"/plan", nil, &p); err != nil { return nil, err }
+    return &p, nil
+}
+
+// ReplacePlan sets the task's plan; the server validates it (422 with problems).
+func (c *Client) ReplacePlan(ctx context.Context, id string, plan *Plan) (*PlanVersion, error) {
+    return c.planEdit(ctx, http.MethodPut, "/v1/tasks/"+esc(id)+"/plan", plan)
+}
+
+// InsertAt places an inserted step after or before another one; the zero value
+// appends it.
+type InsertAt struct {
+    After  string `json:"after,omitempty"`
+    Before string `json:"before,omitempty"`
+}
+
+func (c *Client) InsertStep(ctx context.Context, id string, step *Step, at InsertAt) (*PlanVersion, error) {
+    body := struct {
+        Step *Step `json:"step"`
+        InsertAt
+    }{step, at}
+    return c.planEdit(ctx, http.MethodPost, "/v1/tasks/"+esc(id)+"/plan/steps", body)
+}
+
+// PatchStep applies a JSON merge patch to one step; null removes a field.
+func (c *Client) PatchStep(ctx context.Context, id, stepID string, patch map[string]any) (*PlanVersion, error) {
+    return c.planEdit(ctx, http.MethodPatch, "/v1/tasks/"+esc(id)+"/plan/steps/"+esc(stepID), patch)
+}
+
+func (c *Client) DeleteStep(ctx context.Context, id, stepID string) (*PlanVersion, error) {
+    return c.planEdit(ctx, http.MethodDelete, "/v1/tasks/"+esc(id)+"/plan/steps/"+esc(stepID), nil)
+}
+
+func (c *Client) planEdit(ctx context.Context, method, path string, body any) (*PlanVersion, error) {
+    var v PlanVersion
+    if err := c.do(ctx, method, path, body, &v); err != nil { return nil, err }
+    return &v, nil
+}
+
+// PlanVersions lists every version of the task's plan, oldest first.
+func (c *Client) PlanVersions(ctx context.Context, id string) ([]*PlanVersion, error) {
+    var versions []*PlanVersion
+    err := c.do(ctx, http.MethodGet, "/v1/tasks/"+esc(id)+"/plan/versions", nil, &versions)
+    return versions, err
+}
+
+func (c *Client) GetPlanVersion(ctx context.Context, id string, n int) (*PlanVersion, error) {
+    var v PlanVersion
+    if err := c.do(ctx, http.MethodGet, "/v1/tasks/"+esc(id)+"/plan/versions/"+strconv.Itoa(n), nil, &v); err != nil { return nil, err }
+    return &v, nil
+}
+
+// ExportPlan returns the task's plan as a workflow file; format is "yaml" (default)
+// or "json".
+func (c *Client) ExportPlan(ctx context.Context, id, format string) ([]byte, error) {
+    var b []byte
+    err := c.do(ctx, http.MethodGet, exportPath("/v1/tasks/"+esc(id)+"/plan:export", format, 0), nil, &b)
+    return b, err
+}
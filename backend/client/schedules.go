@@ -0,0 +1,50 @@
+package client
+
+import (
+    "context"
+    "net/http"
+)
+
+func (c *Client) ListSchedules(ctx context.Context) ([]*Schedule, error) {
+    var list []*Schedule
+    err := c.do(ctx, http.MethodGet, "/v1/schedules", nil, &list)
+    return list, err
+}
+
+// CreateSchedule saves a new schedule. A nil Enabled means enabled.
+func (c *Client) CreateSchedule(ctx context.Context, sc *Schedule) (*Schedule, error) {
+    return c.schedule(ctx, http.MethodPost, "/v1/schedules", sc)
+}
+
+func (c *Client) GetSchedule(ctx context.Context, id string) (*Schedule, error) {
+    return c.schedule(ctx, http.MethodGet, "/v1/schedules/"+esc(id), nil)
+}
+
+// UpdateSchedule replaces a schedule's definition.
+func (c *Client) UpdateSchedule(ctx context.Context, id string, sc *Schedule) (*Schedule, error) {
+    return c.schedule(ctx, http.MethodPut, "/v1/schedules/"+esc(id), sc)
+}
+
+func (c *Client) schedule(ctx context.Context, method, path string, body any) (*Schedule, error) {
+    var sc Schedule
+    if err := c.do(ctx, method, path, body, &sc); err != nil { return nil, err }
+    return &sc, nil
+}
+
+func (c *Client) DeleteSchedule(ctx context.Context, id string) error {
+    return c.do(ctx, http.MethodDelete, "/v1/schedules/"+esc(id), nil, nil)
+}
+
+// RunSchedule fires a schedule now and returns the task it created and queued.
+func (c *Client) RunSchedule(ctx context.Context, id string) (*Task, error) {
+    var t Task
+    if err := c.do(ctx, http.MethodPost, "/v1/schedules/"+esc(id)+":run", nil, &t); err != nil { return nil, err }
+    return &t, nil
+}
+
+// ScheduleTasks lists the tasks a schedule created.
+func (c *Client) ScheduleTasks(ctx context.Context, id string) ([]*Task, error) {
+    var tasks []*Task
+    err := c.do(ctx, http.MethodGet, "/v1/schedules/"+esc(id)+"/tasks", nil, &tasks)
+    return tasks, err
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+    "bytes"
+    "context"
+    "net/http"
+    "strconv"
+)
+
+func (c *Client) ListWorkflows(ctx context.Context) ([]*Workflow, error) {
+    var list []*Workflow
+    err := c.do(ctx, http.MethodGet, "/v1/workflows", nil, &list)
+    return list, err
+}
+
+// SaveWorkflow stores a new version of wf; the server numbers it.
+func (c *Client) SaveWorkflow(ctx context.Context, wf *Workflow) (*Workflow, error) {
+    return c.workflow(ctx, http.MethodPost, "/v1/workflows", wf)
+}
+
+// SaveWorkflowFromTask stores a new version of wf with the current plan of a task.
+func (c *Client) SaveWorkflowFromTask(ctx context.Context, wf *Workflow, taskID string) (*Workflow, error) {
+    body := struct {
+        *Workflow
+        TaskID string `json:"task_id"`
+    }{wf, taskID}
+    return c.workflow(ctx, http.MethodPost, "/v1/workflows", body)
+}
+
+// SaveWorkflowFile stores a workflow file (YAML or JSON, see ExportWorkflow).
+func (c *Client) SaveWorkflowFile(ctx context.Context, data []byte) (*Workflow, error) {
+    req, err := c.newRequest(ctx, http.MethodPost, "/v1/workflows", bytes.NewReader(data))
+    if err != nil { return nil, err }
+    req.Header.Set("Content-Type", "application/yaml")
+    var wf Workflow
+    if err := c.send(req, &wf); err != nil { return nil, err }
+    return &wf, nil
+}
+
+// GetWorkflow returns the latest version of a workflow.
+func (c *Client) GetWorkflow(ctx context.Context, name string) (*Workflow, error) {
+    return c.workflow(ctx, http.MethodGet, "/v1/workflows/"+esc(name), nil)
+}
+
+func (c *Client) GetWorkflowVersion(ctx context.Context, name string, version int) (*Workflow, error) {
+    return c.workflow(ctx, http.MethodGet, "/v1/workflows/"+esc(name)+"/versions/"+strconv.Itoa(version), nil)
+}
+
+func (c *Client) workflow(ctx context.Context, method, path string, body any) (*Workflow, error) {
+    var wf Workflow
+    if err := c.do(ctx, method, path, body, &wf); err != nil { return nil, err }
+    return &wf, nil
+}
+
+// WorkflowVersions lists every version of a workflow, oldest first.
+func (c *Client) WorkflowVersions(ctx context.Context, name string) ([]*Workflow, error) {
+    var list []*Workflow
+    err := c.do(ctx, http.MethodGet, "/v1/workflows/"+esc(name)+"/versions", nil, &list)
+    return list, err
+}
+
+// DeleteWorkflow removes all versions of a workflow.
+func (c *Client) DeleteWorkflow(ctx context.Context, name string) error {
+    return c.do(ctx, http.MethodDelete, "/v1/workflows/"+esc(name), nil, nil)
+}
+
+// ExportWorkflow returns a workflow as a file; format is "yaml" (default) or "json",
+// version 0 is the latest.
+func (c *Client) ExportWorkflow(ctx context.Context, name, format string, version int) ([]byte, error) {
+    var b []byte
+    err := c.do(ctx, http.MethodGet, exportPath("/v1/workflows/"+esc(name)+":export", format, version), nil, &b)
+    return b, err
+}
+
+// WorkflowRunRequest is the body of POST /v1/workflows/{name}:run; Version 0 runs the
+// latest.
+type WorkflowRunRequest struct {
+    Version  int            `json:"version,omitempty"`
+    Params   map[string]any `json:"params,omitempty"`
+    Context  map[string]any `json:"context,omitempty"`
+    Priority int            `json:"priority,omitempty"`
+}
+
+// WorkflowRun is the task created by RunWorkflow and its queue entry.
+type WorkflowRun struct {
+    Task *Task      `json:"task"`
+    Job  *QueuedJob `json:"job"`
+}
+
+// RunWorkflow creates a task from a workflow and queues it. Invalid parameters come
+// back as a 422 *Error with Problems.
+func (c *Client) RunWorkflow(ctx context.Context, name string, req WorkflowRunRequest) (*WorkflowRun, error) {
+    var run WorkflowRun
+    if err := c.do(ctx, http.MethodPost, "/v1/workflows/"+esc(name)+":run", req, &run); err != nil { return nil, err }
+    return &run, nil
+}
+
+// WorkflowTasks lists the tasks created from a workflow.
+func (c *Client) WorkflowTasks(ctx context.Context, name string) ([]*Task, error) {
+    var tasks []*Task
+    err := c.do(ctx, http.MethodGet, "/v1/workflows/"+esc(name)+"/tasks", nil, &tasks)
+    return tasks, err
+}
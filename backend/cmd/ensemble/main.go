@@ -0,0 +1,310 @@
+// Command ensemble drives an orchestrator server from the command line:
+//
+//    ensemble [-server URL] [-json] <command> [flags] [task-id]
+//
+//    create  -query Q [-context FILE|JSON] [-priority N]   create a task
+//    plan    ID                                           compute the plan
+//    execute ID [-follow] [-timeout D]                    run the current plan
+//    start   ID|-query Q [-follow] [-timeout D]           plan and run
+//    list    [-status S]                                  list tasks
+//    show    ID                                           task details
+//    follow  ID [-timeout D]                              tail the task's events
+//    cancel  ID                                           cancel a queued, running or paused task
+//    delete  ID                                           delete a finished or idle task
+//    run     -query Q | -plan FILE [...]                   run in-process, without a server (see runOffline)
+//
+// The server defaults to $ENSEMBLE_SERVER, else http://localhost:8080. With -json every
+// command prints JSON; follow prints one event per line and the final task last.
+//
+// Exit codes: 0 success, 1 task failed, 2 usage error, 3 request error or timeout,
+// 4 task cancelled, 5 task paused.
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+    "strings"
+    "syscall"
+    "text/tabwriter"
+    "time"
+
+    "github.com/example/agent-orchestrator/client"
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+const (
+    exitOK        = 0
+    exitFailed    = 1
+    exitUsage     = 2
+    exitError     = 3
+    exitCancelled = 4
+    exitPaused    = 5
+)
+
+// errUsage marks errors caused by how the command was invoked.
+var errUsage = errors.New("usage")
+
+type cli struct {
+    api     *client.Client
+    jsonOut bool
+}
+
+func main() {
+    server := os.Getenv("ENSEMBLE_SERVER")
+    if server == "" { server = "http://localhost:8080" }
+    fs := flag.NewFlagSet("ensemble", flag.ContinueOnError)
+    fs.StringVar(&server, "server", server, "orchestrator base URL")
+    jsonOut := fs.Bool("json", false, "print JSON for scripting")
+    fs.Usage = usage
+    if err := fs.Parse(os.Args[1:]); err != nil { os.Exit(exitUsage) }
+    if fs.NArg() == 0 { usage(); os.Exit(exitUsage) }
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    c := &cli{api: client.New(server), jsonOut: *jsonOut}
+    code, err := c.run(ctx, fs.Arg(0), fs.Args()[1:])
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "ensemble:", err)
+        if errors.Is(err, errUsage) { code = exitUsage } else if code == exitOK { code = exitError }
+    }
+    os.Exit(code)
+}
+
+func usage() {
+    fmt.Fprint(os.Stderr, `usage: ensemble [-server URL] [-json] <command> [flags] [task-id]
+
+commands:
+  create  -query Q [-context FILE|JSON] [-priority N]
+  plan    ID
+  execute ID [-follow] [-timeout D]
+  start   ID | -query Q [-context ...] [-priority N] [-follow] [-timeout D]
+  list    [-status S]
+  show    ID
+  follow  ID [-timeout D]
+  cancel  ID
+  delete  ID
+  run     -query Q | -plan FILE [-param k=v]... [-context ...] [-out task.json] [-timeout D]
+          [-auto-approve] [-answer step=text]... [-stdin]
+          runs in this process with the server's tools, planner and verifier; no server needed
+
+exit codes: 0 success, 1 task failed, 2 usage, 3 request error or timeout, 4 cancelled, 5 paused
+`)
+}
+
+func (c *cli) run(ctx context.Context, cmd string, args []string) (int, error) {
+    if cmd == "run" { return c.runOffline(ctx, args) }
+    fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+    query := fs.String("query", "", "task query")
+    ctxArg := fs.String("context", "", "task context: a JSON file, - for stdin, or inline JSON")
+    priority := fs.Int("priority", 0, "queue priority (higher runs first)")
+    follow := fs.Bool("follow", false, "tail events until the task finishes")
+    timeout := fs.Duration("timeout", 0, "give up following after this long (0 = no limit)")
+    status := fs.String("status", "", "only tasks with this status")
+    // flags may come before or after the task id
+    var positional []string
+    for {
+        if err := fs.Parse(args); err != nil { return exitUsage, errUsage }
+        if fs.NArg() == 0 { break }
+        positional = append(positional, fs.Arg(0))
+        args = fs.Args()[1:]
+    }
+    if len(positional) > 1 { return exitUsage, fmt.Errorf("%w: unexpected arguments %q", errUsage, positional[1:]) }
+    id := ""
+    if len(positional) == 1 { id = positional[0] }
+    needID := func() error {
+        if id == "" { return fmt.Errorf("%w: %s needs a task id", errUsage, cmd) }
+        return nil
+    }
+    switch cmd {
+    case "create":
+        t, err := c.create(ctx, *query, *ctxArg, *priority)
+        if err != nil { return errExit(err), err }
+        if c.jsonOut { return exitOK, printJSON(t) }
+        fmt.Println(t.ID)
+        return exitOK, nil
+    case "plan":
+        if err := needID(); err != nil { return exitUsage, err }
+        plan, err := c.api.PlanTask(ctx, id)
+        if err != nil { return exitError, err }
+        if c.jsonOut { return exitOK, printJSON(plan) }
+        printPlan(plan, nil)
+        return exitOK, nil
+    case "execute", "start":
+        if cmd == "start" && id == "" && *query != "" {
+            t, err := c.create(ctx, *query, *ctxArg, *priority)
+            if err != nil { return errExit(err), err }
+            id = t.ID
+        }
+        if err := needID(); err != nil { return exitUsage, err }
+        enqueue := c.api.StartTask
+        if cmd == "execute" { enqueue = c.api.ExecuteTask }
+        job, err := enqueue(ctx, id, *priority)
+        if err != nil { return exitError, err }
+        if !*follow {
+            if c.jsonOut { return exitOK, printJSON(job) }
+            fmt.Printf("%s queued at position %d\n", id, job.Position)
+            return exitOK, nil
+        }
+        return c.follow(ctx, id, *timeout)
+    case "follow":
+        if err := needID(); err != nil { return exitUsage, err }
+        return c.follow(ctx, id, *timeout)
+    case "list":
+        tasks, err := c.api.ListTasks(ctx)
+        if err != nil { return exitError, err }
+        var out []*models.Task
+        for _, t := range tasks {
+            if *status == "" || strings.EqualFold(string(t.Status), *status) { out = append(out, t) }
+        }
+        if c.jsonOut {
+            if out == nil { out = []*models.Task{} }
+            return exitOK, printJSON(out)
+        }
+        tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+        fmt.Fprintln(tw, "ID\tSTATUS\tUPDATED\tQUERY")
+        for _, t := range out { fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.ID, t.Status, t.UpdatedAt.Local().Format(time.DateTime), truncate(t.Query, 60)) }
+        return exitOK, tw.Flush()
+    case "show":
+        if err := needID(); err != nil { return exitUsage, err }
+        t, err := c.api.GetTask(ctx, id)
+        if err != nil { return exitError, err }
+        if c.jsonOut {
+            if err := printJSON(t); err != nil { return exitError, err }
+        } else {
+            printTask(t)
+        }
+        return statusExit(t.Status), nil
+    case "cancel":
+        if err := needID(); err != nil { return exitUsage, err }
+        if err := c.api.CancelTask(ctx, id); err != nil { return exitError, err }
+        if c.jsonOut { return exitOK, printJSON(map[string]any{"task_id": id, "cancelled": true}) }
+        fmt.Println(id, "cancelled")
+        return exitOK, nil
+    case "delete":
+        if err := needID(); err != nil { return exitUsage, err }
+        if err := c.api.DeleteTask(ctx, id); err != nil { return exitError, err }
+        if c.jsonOut { return exitOK, printJSON(map[string]any{"task_id": id, "deleted": true}) }
+        fmt.Println(id, "deleted")
+        return exitOK, nil
+    }
+    return exitUsage, fmt.Errorf("%w: unknown command %q", errUsage, cmd)
+}
+
+// errExit is the exit code for a command that failed with err.
+func errExit(err error) int {
+    if errors.Is(err, errUsage) { return exitUsage }
+    return exitError
+}
+
+func (c *cli) create(ctx context.Context, query, ctxArg string, priority int) (*models.Task, error) {
+    if query == "" { return nil, fmt.Errorf("%w: -query is required", errUsage) }
+    taskCtx, err := readContext(ctxArg)
+    if err != nil { return nil, err }
+    return c.api.CreateTask(ctx, client.TaskRequest{Query: query, Context: taskCtx, Priority: priority})
+}
+
+// readContext reads -context: inline JSON, "-" for stdin, or a file.
+func readContext(arg string) (map[string]any, error) {
+    if arg == "" { return nil, nil }
+    var data []byte
+    var err error
+    switch {
+    case strings.HasPrefix(strings.TrimSpace(arg), "{"):
+        data = []byte(arg)
+    case arg == "-":
+        data, err = io.ReadAll(os.Stdin)
+    default:
+        data, err = os.ReadFile(arg)
+    }
+    if err != nil { return nil, err }
+    var m map[string]any
+    if err := json.Unmarshal(data, &m); err != nil { return nil, fmt.Errorf("context: %v", err) }
+    return m, nil
+}
+
+// follow tails a task's events until it succeeds, fails, is cancelled or paused, and
+// returns the matching exit code.
+func (c *cli) follow(ctx context.Context, id string, timeout time.Duration) (int, error) {
+    if timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+        defer cancel()
+    }
+    r := &renderer{json: c.jsonOut}
+    err := c.api.Events(ctx, id, 0, r.handle)
+    r.endLine()
+    if errors.Is(err, context.DeadlineExceeded) { return exitError, fmt.Errorf("timed out after %s waiting for %s", timeout, id) }
+    if err != nil { return exitError, err }
+    // the stream ends on a final status; report the task as it is now
+    t, err := c.api.GetTask(context.Background(), id)
+    if err != nil { return exitError, err }
+    if c.jsonOut {
+        // one line, like the events before it
+        if err := json.NewEncoder(os.Stdout).Encode(t); err != nil { return exitError, err }
+    } else {
+        fmt.Printf("%s %s\n", t.ID, t.Status)
+        if t.LastRun != nil && t.LastRun.Error != "" { fmt.Println("error:", t.LastRun.Error) }
+    }
+    return statusExit(t.Status), nil
+}
+
+// final reports whether following a task in this status should stop.
+func final(s models.Status) bool {
+    return s == models.StatusSuccess || s == models.StatusFailed || s == models.StatusCancelled || s == models.StatusPaused
+}
+
+func statusExit(s models.Status) int {
+    switch s {
+    case models.StatusFailed:
+        return exitFailed
+    case models.StatusCancelled:
+        return exitCancelled
+    case models.StatusPaused:
+        return exitPaused
+    }
+    return exitOK
+}
+
+func printJSON(v any) error {
+    enc := json.NewEncoder(os.Stdout)
+    enc.SetIndent("", "  ")
+    return enc.Encode(v)
+}
+
+func printTask(t *models.Task) {
+    fmt.Printf("id:      %s\nstatus:  %s\nquery:   %s\n", t.ID, t.Status, t.Query)
+    if t.Workflow != "" { fmt.Printf("workflow: %s\n", t.Workflow) }
+    fmt.Printf("created: %s\nupdated: %s\n", t.CreatedAt.Local().Format(time.DateTime), t.UpdatedAt.Local().Format(time.DateTime))
+    if t.Plan == nil { return }
+    fmt.Printf("plan (version %d):\n", t.PlanVersion)
+    printPlan(t.Plan, t.LastRun)
+    if t.LastRun != nil && t.LastRun.Error != "" { fmt.Println("error:", t.LastRun.Error) }
+}
+
+// printPlan lists the steps, with their state in run when there is one.
+func printPlan(p *models.Plan, run *models.Run) {
+    for i, s := range p.Steps {
+        line := fmt.Sprintf("  %d. %-12s %-14s %s", i+1, s.ID, s.Tool, s.Description)
+        if run != nil {
+            if sr := run.Step(s.ID); sr != nil {
+                line += "  [" + string(sr.Status) + "]"
+                if sr.Reason != "" { line += " " + sr.Reason }
+            }
+            for _, res := range run.Results {
+                if res.StepID == s.ID && res.Error != "" { line += " " + res.Error }
+            }
+        }
+        fmt.Println(strings.TrimRight(line, " "))
+    }
+}
+
+func truncate(s string, n int) string {
+    s = strings.Join(strings.Fields(s), " ")
+    if len([]rune(s)) <= n { return s }
+    return string([]rune(s)[:n-1]) + "…"
+}
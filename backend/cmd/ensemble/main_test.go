@@ -0,0 +1,175 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/client"
+    "github.com/example/agent-orchestrator/internal/api"
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// newTestCLI returns a cli talking to an in-process server, served like cmd/server.
+func newTestCLI(t *testing.T, jsonOut bool) *cli {
+    t.Helper()
+    mux := http.NewServeMux()
+    api.RegisterRoutes(mux)
+    srv := httptest.NewServer(api.ValidateRequests(mux))
+    t.Cleanup(srv.Close)
+    return &cli{api: client.New(srv.URL), jsonOut: jsonOut}
+}
+
+// capture runs fn with os.Stdout redirected and returns what it printed.
+func capture(t *testing.T, fn func()) string {
+    t.Helper()
+    r, w, err := os.Pipe()
+    if err != nil { t.Fatal(err) }
+    stdout := os.Stdout
+    os.Stdout = w
+    out := make(chan string)
+    go func() { b, _ := io.ReadAll(r); out <- string(b) }()
+    defer func() { os.Stdout = stdout }()
+    fn()
+    w.Close()
+    return <-out
+}
+
+// exec runs one command and returns its exit code, error and output.
+func (c *cli) exec(t *testing.T, args ...string) (int, error, string) {
+    t.Helper()
+    var code int
+    var err error
+    out := capture(t, func() { code, err = c.run(context.Background(), args[0], args[1:]) })
+    return code, err, out
+}
+
+// plannedTask creates a task on the server and gives it steps, so no planner runs.
+func plannedTask(t *testing.T, c *cli, steps ...*models.Step) string {
+    t.Helper()
+    ctx := context.Background()
+    task, err := c.api.CreateTask(ctx, client.TaskRequest{Query: "cli test"})
+    if err != nil { t.Fatal(err) }
+    if _, err := c.api.ReplacePlan(ctx, task.ID, &models.Plan{Steps: steps}); err != nil { t.Fatal(err) }
+    return task.ID
+}
+
+func echoStep(text string) *models.Step {
+    return &models.Step{ID: "say", Tool: "echo", Inputs: map[string]any{"text": text}}
+}
+
+func TestUsageErrors(t *testing.T) {
+    c := newTestCLI(t, false)
+    for _, args := range [][]string{
+        {"bogus"},
+        {"plan"},
+        {"show", "a", "b"},
+        {"create"},
+        {"list", "-nope"},
+        {"run"},
+    } {
+        code, err, _ := c.exec(t, args...)
+        if code != exitUsage || !errors.Is(err, errUsage) { t.Errorf("%q: exit %d, %v", args, code, err) }
+    }
+}
+
+func TestCreateShowList(t *testing.T) {
+    c := newTestCLI(t, false)
+    code, err, out := c.exec(t, "create", "-query", "find things", "-context", `{"k": "v"}`, "-priority", "3")
+    if code != exitOK || err != nil { t.Fatalf("create: %d %v", code, err) }
+    id := strings.TrimSpace(out)
+    task, err := c.api.GetTask(context.Background(), id)
+    if err != nil || task.Query != "find things" || task.Context["k"] != "v" || task.Priority != 3 { t.Fatalf("created %+v, %v", task, err) }
+
+    // flags may follow the task id
+    if code, _, out := c.exec(t, "show", id); code != exitOK || !strings.Contains(out, "status:  PENDING") { t.Fatalf("show: %d %s", code, out) }
+    if _, _, out := c.exec(t, "list", "-status", "pending"); !strings.Contains(out, id) { t.Fatalf("list: %s", out) }
+    if _, _, out := c.exec(t, "list", "-status", "SUCCESS"); strings.Contains(out, id) { t.Fatalf("list -status SUCCESS: %s", out) }
+
+    c.jsonOut = true
+    if _, _, out := c.exec(t, "list", "-status", "no-such-status"); strings.TrimSpace(out) != "[]" { t.Fatalf("empty JSON list: %q", out) }
+    if _, _, out := c.exec(t, "show", id); !strings.Contains(out, `"id": "`+id+`"`) { t.Fatalf("show -json: %s", out) }
+    if code, err, _ := c.exec(t, "show", "no-such-task"); code != exitError || !client.IsNotFound(err) { t.Fatalf("missing task: %d %v", code, err) }
+}
+
+func TestExecuteFollow(t *testing.T) {
+    c := newTestCLI(t, false)
+    id := plannedTask(t, c, echoStep("hi"))
+    code, err, out := c.exec(t, "execute", id, "-follow", "-timeout", "5s")
+    if code != exitOK || err != nil { t.Fatalf("execute: %d %v\n%s", code, err, out) }
+    // the stream starts with a snapshot, and the final task is printed last
+    if !strings.HasPrefix(out, id+" ") || !strings.HasSuffix(out, id+" SUCCESS\n") { t.Fatalf("output:\n%s", out) }
+
+    // the exit code reflects the task's status
+    id = plannedTask(t, c, &models.Step{ID: "get", Tool: "http_get", Inputs: map[string]any{"url": "http://127.0.0.1:1/"}})
+    c.jsonOut = true
+    code, _, out = c.exec(t, "execute", id, "-follow", "-timeout", "5s")
+    lines := strings.Split(strings.TrimSpace(out), "\n")
+    if code != exitFailed || !strings.Contains(lines[len(lines)-1], `"status":"FAILED"`) { t.Fatalf("failed task: exit %d\n%s", code, out) }
+}
+
+func TestExecuteWithoutFollow(t *testing.T) {
+    c := newTestCLI(t, false)
+    id := plannedTask(t, c, echoStep("hi"))
+    if code, _, out := c.exec(t, "execute", id); code != exitOK || !strings.HasPrefix(out, id+" queued at position") { t.Fatalf("execute: %d %s", code, out) }
+    if code, _, out := c.exec(t, "follow", id, "-timeout", "5s"); code != exitOK || !strings.HasSuffix(out, id+" SUCCESS\n") { t.Fatalf("follow: %d %s", code, out) }
+}
+
+func TestFollowTimeout(t *testing.T) {
+    c := newTestCLI(t, false)
+    id := plannedTask(t, c, echoStep("hi"))
+    code, err, _ := c.exec(t, "follow", id, "-timeout", "50ms")
+    if code != exitError || err == nil || !strings.Contains(err.Error(), "timed out after 50ms") { t.Fatalf("exit %d, %v", code, err) }
+}
+
+func TestCancelDelete(t *testing.T) {
+    c := newTestCLI(t, false)
+    id := plannedTask(t, c, echoStep("hi"))
+    if code, _, out := c.exec(t, "delete", id); code != exitOK || out != id+" deleted\n" { t.Fatalf("delete: %d %q", code, out) }
+    if code, err, _ := c.exec(t, "cancel", id); code != exitError || !client.IsNotFound(err) { t.Fatalf("cancel deleted task: %d %v", code, err) }
+}
+
+func TestReadContext(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "ctx.json")
+    os.WriteFile(path, []byte(`{"from": "file"}`), 0o644)
+    for arg, want := range map[string]any{` {"from": "inline"}`: "inline", path: "file", "": nil} {
+        m, err := readContext(arg)
+        if err != nil || m["from"] != want { t.Errorf("%q: %v, %v", arg, m, err) }
+    }
+    if _, err := readContext(`{"broken"`); err == nil || !strings.HasPrefix(err.Error(), "context:") { t.Fatalf("invalid JSON: %v", err) }
+    if _, err := readContext(filepath.Join(t.TempDir(), "missing.json")); err == nil { t.Fatal("missing file accepted") }
+}
+
+func TestRenderer(t *testing.T) {
+    r := &renderer{}
+    var stop error
+    out := capture(t, func() {
+        r.handle(client.Event{Event: "token", Payload: &client.Token{StepID: "a", Chunk: "hel"}})
+        r.handle(client.Event{Event: "token", Payload: &client.Token{StepID: "a", Chunk: "lo"}})
+        r.handle(client.Event{Event: "step_status", Payload: &models.StepRun{ID: "a", Status: models.StatusSuccess}})
+        stop = r.handle(client.Event{Event: "task_status", Payload: &client.TaskStatus{Status: models.StatusPaused}})
+    })
+    // tokens share a line, which ends before the next status line
+    if out != "hello\n  a SUCCESS\nstatus PAUSED\n" { t.Fatalf("output %q", out) }
+    if stop != client.ErrStop { t.Fatalf("final status returned %v", stop) }
+    capture(t, func() { stop = r.handle(client.Event{Event: "task_status", Payload: &client.TaskStatus{Status: models.StatusRunning}}) })
+    if stop != nil { t.Fatalf("running status returned %v", stop) }
+}
+
+func TestStatusExit(t *testing.T) {
+    for s, want := range map[models.Status]int{
+        models.StatusSuccess:   exitOK,
+        models.StatusFailed:    exitFailed,
+        models.StatusCancelled: exitCancelled,
+        models.StatusPaused:    exitPaused,
+        models.StatusPending:   exitOK,
+    } {
+        if got := statusExit(s); got != want { t.Errorf("%s: %d, want %d", s, got, want) }
+    }
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+
+    "github.com/example/agent-orchestrator/client"
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// renderer prints a task's event stream: readable lines with LLM tokens written as
+// they arrive, or one JSON event per line.
+type renderer struct {
+    json bool
+    // midLine is set while tokens are being written on the current line
+    midLine bool
+}
+
+func (r *renderer) handle(ev client.Event) error {
+    if r.json {
+        b, err := json.Marshal(ev)
+        if err != nil { return err }
+        os.Stdout.Write(append(b, '\n'))
+    } else {
+        r.render(ev)
+    }
+    switch p := ev.Payload.(type) {
+    case *models.Task:
+        if final(p.Status) { return client.ErrStop }
+    case *client.TaskStatus:
+        if final(p.Status) { return client.ErrStop }
+    }
+    // after a gap the server follows up with a fresh snapshot
+    return nil
+}
+
+func (r *renderer) render(ev client.Event) {
+    switch p := ev.Payload.(type) {
+    case *models.Task:
+        r.line("%s %s", p.ID, p.Status)
+    case *client.Token:
+        fmt.Print(p.Chunk)
+        r.midLine = true
+    case *client.TaskStatus:
+        msg := "status " + string(p.Status)
+        if p.Position > 0 { msg += fmt.Sprintf(" (position %d of %d)", p.Position, p.Depth) }
+        if p.Error != "" { msg += ": " + p.Error }
+        r.line("%s", msg)
+    case *client.QueuePosition:
+        r.line("queue position %d of %d", p.Position, p.Depth)
+    case *models.Plan:
+        r.line("plan with %d steps", len(p.Steps))
+    case *models.StepRun:
+        msg := fmt.Sprintf("  %s %s", p.ID, p.Status)
+        if p.Reason != "" { msg += " (" + p.Reason + ")" }
+        r.line("%s", msg)
+    case *models.Result:
+        if p.Error != "" { r.line("  %s error: %s", p.StepID, p.Error) }
+    case *client.ApprovalRequest:
+        r.line("  %s awaits approval (%s risk): POST /v1/tasks/{id}/steps/%s:approve or :reject", p.StepID, p.Risk, p.StepID)
+    case *client.QuestionAsked:
+        msg := fmt.Sprintf("  %s asks: %s", p.StepID, p.Question)
+        if len(p.Choices) > 0 { msg += fmt.Sprintf(" %v", p.Choices) }
+        r.line("%s", msg)
+    case *client.MapItem:
+        if p.Error != "" { r.line("  %s[%d] error: %s", p.StepID, p.Index, p.Error) }
+    }
+}
+
+// line prints one line of status output, ending any token output first.
+func (r *renderer) line(format string, args ...any) {
+    r.endLine()
+    fmt.Printf(format+"\n", args...)
+}
+
+func (r *renderer) endLine() {
+    if r.midLine { fmt.Println() }
+    r.midLine = false
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/example/agent-orchestrator/client"
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/wiring"
+    "github.com/example/agent-orchestrator/internal/workflows"
+)
+
+// paramFlags collects repeated -param name=value flags; values are JSON when they
+// parse as JSON, else strings.
+type paramFlags map[string]any
+
+func (p paramFlags) String() string { return "" }
+
+func (p paramFlags) Set(s string) error {
+    name, value, ok := strings.Cut(s, "=")
+    if !ok || name == "" { return fmt.Errorf("want name=value, got %q", s) }
+    var v any
+    if json.Unmarshal([]byte(value), &v) != nil { v = value }
+    p[name] = v
+    return nil
+}
+
+// answerFlags collects repeated -answer step=text flags for ask_user steps.
+type answerFlags map[string]string
+
+func (a answerFlags) String() string { return "" }
+
+func (a answerFlags) Set(s string) error {
+    step, text, ok := strings.Cut(s, "=")
+    if !ok || step == "" { return fmt.Errorf("want step=answer, got %q", s) }
+    a[step] = text
+    return nil
+}
+
+// lookup finds the answer for a question's stream ID; sub-steps of a map
+// ("STEP[i].SUB") also take the answer given for "SUB".
+func (a answerFlags) lookup(stepID string) (string, bool) {
+    if text, ok := a[stepID]; ok { return text, true }
+    if i := strings.LastIndex(stepID, "]."); i >= 0 {
+        text, ok := a[stepID[i+2:]]
+        return text, ok
+    }
+    return "", false
+}
+
+// readLines sends stdin's lines until it ends.
+func readLines(r io.Reader) <-chan string {
+    ch := make(chan string)
+    go func() {
+        defer close(ch)
+        sc := bufio.NewScanner(r)
+        for sc.Scan() { ch <- strings.TrimSpace(sc.Text()) }
+    }()
+    return ch
+}
+
+// prompt asks on stderr and returns the next line of input; false when the input
+// ended or ctx did.
+func prompt(ctx context.Context, lines <-chan string, format string, args ...any) (string, bool) {
+    fmt.Fprintf(os.Stderr, format, args...)
+    select {
+    case line, ok := <-lines:
+        return line, ok
+    case <-ctx.Done():
+        return "", false
+    }
+}
+
+func isTerminal(f *os.File) bool {
+    fi, err := f.Stat()
+    return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// runOffline runs planner, executor and verifier in this process, wired like the
+// server (wiring.FromEnv) but without HTTP server or storage:
+//
+//    ensemble run -query Q [-context FILE|-|JSON] [-plan plan.yaml [-param k=v ...]]
+//                 [-out task.json] [-timeout D] [-auto-approve] [-answer step=text ...] [-stdin]
+//
+// Events go to stdout as they happen (JSON lines with -json). With -plan the planner
+// is skipped and the file's steps run as they are. Nobody can answer over HTTP, so
+// ask_user steps take -answer and approval gates need -auto-approve; with -stdin (the
+// default when stdin is a terminal) the rest are prompted for. A question or approval
+// left without an answer cancels the run and fails the command.
+func (c *cli) runOffline(ctx context.Context, args []string) (int, error) {
+    fs := flag.NewFlagSet("run", flag.ContinueOnError)
+    query := fs.String("query", "", "task query")
+    ctxArg := fs.String("context", "", "task context: a JSON file, - for stdin, or inline JSON")
+    planFile := fs.String("plan", "", "plan or workflow file (YAML or JSON) to run instead of planning")
+    params := paramFlags{}
+    fs.Var(params, "param", "workflow parameter name=value (repeatable)")
+    out := fs.String("out", "", "write the final task JSON to this file")
+    timeout := fs.Duration("timeout", 0, "cancel the run after this long (0 = no limit)")
+    autoApprove := fs.Bool("auto-approve", false, "run high-risk tools without waiting for approval")
+    answers := answerFlags{}
+    fs.Var(answers, "answer", "answer for an ask_user step, step=text (repeatable)")
+    useStdin := fs.Bool("stdin", isTerminal(os.Stdin), "prompt on stderr and read answers and approvals from stdin")
+    if err := fs.Parse(args); err != nil { return exitUsage, errUsage }
+    if fs.NArg() > 0 { return exitUsage, fmt.Errorf("%w: unexpected arguments %q", errUsage, fs.Args()) }
+    if *query == "" && *planFile == "" { return exitUsage, fmt.Errorf("%w: run needs -query or -plan", errUsage) }
+    if *useStdin && *ctxArg == "-" { return exitUsage, fmt.Errorf("%w: -context - and -stdin both read stdin", errUsage) }
+    taskCtx, err := readContext(*ctxArg)
+    if err != nil { return exitError, err }
+
+    cfg := wiring.FromEnv()
+    cfg.Workers = 1
+    if *autoApprove { cfg.ApprovalRisk = "" }
+    eng, err := wiring.Build(cfg)
+    if err != nil { return exitError, err }
+    defer eng.Close()
+    o := eng.Orch
+    var wf *workflows.Workflow
+    var bound map[string]any
+    if *planFile != "" {
+        data, err := os.ReadFile(*planFile)
+        if err != nil { return exitError, err }
+        lib, _ := workflows.New(o, "")
+        if wf, err = lib.ParseFile(*planFile, data); err != nil { return exitError, err }
+        if *query == "" { *query = wf.Query }
+        if *query == "" { *query = wf.Name }
+        if bound, err = wf.BindParams(params); err != nil { return exitError, err }
+    }
+    if timeout := *timeout; timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+        defer cancel()
+    }
+
+    id := "run-" + time.Now().Format("20060102150405")
+    sub := o.Subscribe(id, 0)
+    defer sub.Close()
+    t := o.CreateTask(id, *query, taskCtx)
+    done := make(chan error, 1)
+    go func() {
+        if wf == nil { done <- o.Start(ctx, id); return }
+        t.Params, t.Workflow = bound, wf.Name
+        plan := wf.Plan.Clone()
+        if _, err := o.EditPlan(id, "file:"+filepath.Base(*planFile), "plan_file", func(p *models.Plan) error { p.Steps = plan.Steps; return nil }); err != nil { done <- err; return }
+        done <- o.ExecutePlan(ctx, id)
+    }()
+
+    // stuck is why the run was cancelled: a question or approval nobody can answer
+    var stuck error
+    var lines <-chan string
+    if *useStdin { lines = readLines(os.Stdin) }
+    giveUp := func(format string, args ...any) {
+        stuck = fmt.Errorf(format, args...)
+        o.Cancel(id)
+    }
+    respond := func(ev client.Event) {
+        if stuck != nil { return }
+        switch p := ev.Payload.(type) {
+        case *client.QuestionAsked:
+            if text, ok := answers.lookup(p.StepID); ok {
+                if err := o.AnswerQuestion(id, p.StepID, text, "cli"); err != nil { giveUp("answer for %s: %v", p.StepID, err) }
+                return
+            }
+            if lines == nil { giveUp("step %s asks %q: pass -answer %s=TEXT or -stdin", p.StepID, p.Question, p.StepID); return }
+            for {
+                choices := ""
+                if len(p.Choices) > 0 { choices = " " + strings.Join(p.Choices, "/") }
+                text, ok := prompt(ctx, lines, "%s asks: %s%s\n> ", p.StepID, p.Question, choices)
+                if !ok { giveUp("step %s asks %q: no answer on stdin", p.StepID, p.Question); return }
+                err := o.AnswerQuestion(id, p.StepID, text, "cli")
+                if err == nil { return }
+                if !errors.Is(err, orchestrator.ErrInvalidAnswer) { giveUp("answer for %s: %v", p.StepID, err); return }
+                fmt.Fprintln(os.Stderr, err)
+            }
+        case *client.ApprovalRequest:
+            if lines == nil { giveUp("step %s (%s, %s risk) needs approval: pass -auto-approve or -stdin", p.StepID, p.Tool, p.Risk); return }
+            text, ok := prompt(ctx, lines, "approve %s (%s, %s risk)? [y/N] ", p.StepID, p.Tool, p.Risk)
+            if !ok { giveUp("step %s needs approval: no answer on stdin", p.StepID); return }
+            approved := strings.EqualFold(text, "y") || strings.EqualFold(text, "yes")
+            if err := o.DecideApproval(id, p.StepID, approved, "cli", ""); err != nil { giveUp("approval for %s: %v", p.StepID, err) }
+        }
+    }
+
+    r := &renderer{json: c.jsonOut}
+    drain := func() {
+        recs, _ := sub.Next()
+        for _, rec := range recs {
+            if ev, err := client.DecodeEvent(rec.Data); err == nil { r.handle(ev); respond(ev) }
+        }
+    }
+    var runErr error
+    for waiting := true; waiting; {
+        select {
+        case <-sub.C():
+            drain()
+        case runErr = <-done:
+            drain()
+            waiting = false
+        }
+    }
+    r.endLine()
+    if runErr != nil && stuck == nil && t.Status != models.StatusFailed && t.Status != models.StatusCancelled {
+        // rejected before the run began, e.g. an invalid plan or parameters
+        return exitError, runErr
+    }
+    if ctx.Err() != nil && stuck == nil && t.Status != models.StatusSuccess { fmt.Fprintln(os.Stderr, "ensemble: run stopped:", ctx.Err()) }
+    if *out != "" {
+        b, err := json.MarshalIndent(t, "", "  ")
+        if err != nil { return exitError, err }
+        if err := os.WriteFile(*out, append(b, '\n'), 0o644); err != nil { return exitError, err }
+    }
+    if stuck != nil { return exitError, stuck }
+    if c.jsonOut {
+        if err := json.NewEncoder(os.Stdout).Encode(t); err != nil { return exitError, err }
+    } else {
+        fmt.Printf("%s %s\n", t.ID, t.Status)
+        if t.LastRun != nil && t.LastRun.Error != "" { fmt.Println("error:", t.LastRun.Error) }
+        if res := lastOutput(t); res != nil { fmt.Println(res) }
+    }
+    return statusExit(t.Status), nil
+}
+
+// lastOutput is the output of the task's final successful step, for a quick look.
+func lastOutput(t *models.Task) any {
+    for i := len(t.Results) - 1; i >= 0; i-- {
+        if r := t.Results[i]; r.Error == "" && r.Output != nil {
+            if s, ok := r.Output.(string); ok { return s }
+            b, _ := json.MarshalIndent(r.Output, "", "  ")
+            return string(b)
+        }
+    }
+    return nil
+}
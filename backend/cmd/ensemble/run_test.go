@@ -0,0 +1,122 @@
+package main
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+const askPlan = `name: pick
+steps:
+  - id: ask
+    tool: ask_user
+    inputs: {question: "Which color?", choices: [red, blue]}
+  - id: say
+    tool: echo
+    deps: [ask]
+    inputs: {text: "{{step:ask.output}}"}
+`
+
+// planFile writes a plan file for -plan.
+func planFile(t *testing.T, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), "plan.yaml")
+    if err := os.WriteFile(path, []byte(content), 0o644); err != nil { t.Fatal(err) }
+    return path
+}
+
+// withStdin runs fn with input on os.Stdin.
+func withStdin(t *testing.T, input string, fn func()) {
+    t.Helper()
+    r, w, err := os.Pipe()
+    if err != nil { t.Fatal(err) }
+    go func() { w.WriteString(input); w.Close() }()
+    stdin := os.Stdin
+    os.Stdin = r
+    defer func() { os.Stdin = stdin; r.Close() }()
+    fn()
+}
+
+func readTask(t *testing.T, path string) *models.Task {
+    t.Helper()
+    b, err := os.ReadFile(path)
+    if err != nil { t.Fatal(err) }
+    var task models.Task
+    if err := json.Unmarshal(b, &task); err != nil { t.Fatal(err) }
+    return &task
+}
+
+func TestRunPlanFile(t *testing.T) {
+    c := &cli{}
+    out := filepath.Join(t.TempDir(), "task.json")
+    plan := planFile(t, "name: hello\nparams: [{name: who, type: string}]\nsteps:\n  - {id: say, tool: echo, inputs: {text: \"hi {{params.who}}\"}}\n")
+    code, err, stdout := c.exec(t, "run", "-plan", plan, "-param", "who=ann", "-out", out, "-stdin=false")
+    if code != exitOK || err != nil { t.Fatalf("run: %d %v\n%s", code, err, stdout) }
+    if !strings.HasSuffix(stdout, "echo: hi ann\n") { t.Fatalf("output:\n%s", stdout) }
+    if task := readTask(t, out); task.Status != models.StatusSuccess || task.Workflow != "hello" { t.Fatalf("task %s %q", task.Status, task.Workflow) }
+}
+
+func TestRunWithoutAnswerFailsFast(t *testing.T) {
+    c := &cli{}
+    out := filepath.Join(t.TempDir(), "task.json")
+    start := time.Now()
+    code, err, _ := c.exec(t, "run", "-plan", planFile(t, askPlan), "-out", out, "-stdin=false", "-timeout", "10s")
+    if code != exitError || err == nil || !strings.Contains(err.Error(), `step ask asks "Which color?": pass -answer ask=TEXT or -stdin`) { t.Fatalf("exit %d, %v", code, err) }
+    if time.Since(start) > 5*time.Second { t.Fatalf("took %s", time.Since(start)) }
+    if task := readTask(t, out); task.Status != models.StatusCancelled { t.Fatalf("task %s", task.Status) }
+
+    // an answer that is not among the choices fails too
+    code, err, _ = c.exec(t, "run", "-plan", planFile(t, askPlan), "-answer", "ask=green", "-stdin=false", "-timeout", "10s")
+    if code != exitError || err == nil || !strings.Contains(err.Error(), "answer for ask: invalid answer") { t.Fatalf("exit %d, %v", code, err) }
+}
+
+func TestRunAnswerFlag(t *testing.T) {
+    c := &cli{}
+    code, err, stdout := c.exec(t, "run", "-plan", planFile(t, askPlan), "-answer", "ask=blue", "-stdin=false", "-timeout", "10s")
+    if code != exitOK || err != nil || !strings.HasSuffix(stdout, "echo: blue\n") { t.Fatalf("exit %d, %v\n%s", code, err, stdout) }
+
+    // map sub-steps take the answer given for the sub-step ID
+    mapPlan := "name: each\nsteps:\n  - id: each\n    map:\n      over: \"[1, 2]\"\n      as: item\n      steps:\n        - {id: ask, tool: ask_user, inputs: {question: \"ok?\"}}\n"
+    code, err, _ = c.exec(t, "run", "-plan", planFile(t, mapPlan), "-answer", "ask=yes", "-stdin=false", "-timeout", "10s")
+    if code != exitOK || err != nil { t.Fatalf("map: exit %d, %v", code, err) }
+}
+
+func TestRunStdinAnswers(t *testing.T) {
+    c := &cli{}
+    var code int
+    var err error
+    var stdout string
+    // the first answer is not a choice and is asked again
+    withStdin(t, "green\nred\n", func() { code, err, stdout = c.exec(t, "run", "-plan", planFile(t, askPlan), "-stdin", "-timeout", "10s") })
+    if code != exitOK || err != nil || !strings.HasSuffix(stdout, "echo: red\n") { t.Fatalf("exit %d, %v\n%s", code, err, stdout) }
+
+    // stdin ending before the answer fails the run
+    withStdin(t, "", func() { code, err, _ = c.exec(t, "run", "-plan", planFile(t, askPlan), "-stdin", "-timeout", "10s") })
+    if code != exitError || err == nil || !strings.Contains(err.Error(), "no answer on stdin") { t.Fatalf("exit %d, %v", code, err) }
+
+    if code, err, _ := c.exec(t, "run", "-query", "q", "-context", "-", "-stdin"); code != exitUsage { t.Fatalf("-context - with -stdin: %d %v", code, err) }
+}
+
+func TestRunApprovals(t *testing.T) {
+    t.Setenv("TOOL_RISK", "echo=high")
+    t.Setenv("APPROVAL_RISK", "high")
+    c := &cli{}
+    plan := planFile(t, "name: risky\nsteps:\n  - {id: say, tool: echo, inputs: {text: hi}}\n")
+    code, err, _ := c.exec(t, "run", "-plan", plan, "-stdin=false", "-timeout", "10s")
+    if code != exitError || err == nil || !strings.Contains(err.Error(), "step say (echo, high risk) needs approval: pass -auto-approve or -stdin") { t.Fatalf("exit %d, %v", code, err) }
+
+    if code, err, _ := c.exec(t, "run", "-plan", plan, "-stdin=false", "-auto-approve", "-timeout", "10s"); code != exitOK { t.Fatalf("-auto-approve: exit %d, %v", code, err) }
+
+    out := filepath.Join(t.TempDir(), "task.json")
+    withStdin(t, "y\n", func() { code, err, _ = c.exec(t, "run", "-plan", plan, "-stdin", "-out", out, "-timeout", "10s") })
+    if code != exitOK || err != nil { t.Fatalf("approved on stdin: exit %d, %v", code, err) }
+    if a := readTask(t, out).LastRun.Step("say").Approval; a == nil || a.Decision != "approved" || a.By != "cli" { t.Fatalf("approval %+v", a) }
+
+    withStdin(t, "n\n", func() { code, err, _ = c.exec(t, "run", "-plan", plan, "-stdin", "-timeout", "10s") })
+    if code != exitFailed || err != nil { t.Fatalf("rejected on stdin: exit %d, %v", code, err) }
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+    "context"
+    "errors"
     "log"
     "net/http"
     "os"
+    "os/signal"
+    "syscall"
+    "time"
 
     "github.com/example/agent-orchestrator/internal/api"
     "github.com/joho/godotenv"
@@ -19,8 +24,22 @@ func main() {
     mux := http.NewServeMux()
     api.RegisterRoutes(mux)
 
+    // requests are checked against the OpenAPI document (GET /openapi.json)
+    srv := &http.Server{Addr: addr, Handler: cors(api.ValidateRequests(mux))}
+    // end event streams (SSE and WebSocket) so shutdown does not wait on them
+    srv.RegisterOnShutdown(api.CloseStreams)
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+    go func() {
+        <-ctx.Done()
+        log.Printf("shutting down")
+        shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+        defer cancel()
+        srv.Shutdown(shutdownCtx)
+    }()
+
     log.Printf("server listening on %s", addr)
-    if err := http.ListenAndServe(addr, cors(mux)); err != nil {
+    if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
         log.Fatal(err)
     }
 }
@@ -29,8 +48,8 @@ func main() {
 func cors(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         w.Header().Set("Access-Control-Allow-Origin", "*")
-        w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-        w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+        w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+        w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Author, X-Client-ID")
         if r.Method == http.MethodOptions {
             w.WriteHeader(http.StatusNoContent)
             return
@@ -0,0 +1,215 @@
+package ensemble
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "log"
+    "sync/atomic"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/tools"
+    "github.com/example/agent-orchestrator/internal/wiring"
+)
+
+// Builder configures an Engine. Methods return the builder for chaining; problems
+// such as an unusable store directory are reported by Build.
+type Builder struct {
+    cfg   wiring.Config
+    tools []Tool
+    risks map[string]Risk
+}
+
+// New starts a builder with bare defaults: no tools, the mock planner, the simple
+// verifier, a mock LLM, no approval gates, in-memory tasks and 4 queue workers.
+func New() *Builder {
+    return &Builder{cfg: wiring.Config{Workers: 4}, risks: map[string]Risk{}}
+}
+
+// FromEnv starts a builder wired like the server: built-in tools, the LLM client
+// from LLM_PROVIDER and API key variables, USE_LLM_PLANNER / USE_LLM_VERIFIER,
+// TOOL_RISK=name=level,..., and approval gates from APPROVAL_RISK (default high, "off"
+// disables) and APPROVAL_TIMEOUT. Invalid values are logged and ignored. Storage,
+// workers and sinks stay at New's defaults.
+func FromEnv() *Builder {
+    return &Builder{cfg: *wiring.FromEnv(), risks: map[string]Risk{}}
+}
+
+// WithLLM sets the client used by built-in LLM tools (and nothing else: pass it to
+// LLMPlanner / LLMVerifier as well to plan or verify with it).
+func (b *Builder) WithLLM(c LLMClient) *Builder { b.cfg.LLM = c; return b }
+
+// WithBuiltinTools registers BuiltinTools; tools added with WithTool replace
+// built-ins of the same name.
+func (b *Builder) WithBuiltinTools() *Builder { b.cfg.Builtins = true; return b }
+
+// WithTool registers custom tools.
+func (b *Builder) WithTool(t ...Tool) *Builder { b.tools = append(b.tools, t...); return b }
+
+// WithToolRisk overrides a tool's risk level.
+func (b *Builder) WithToolRisk(name string, risk Risk) *Builder { b.risks[name] = risk; return b }
+
+func (b *Builder) WithPlanner(p Planner) *Builder { b.cfg.Planner = p; return b }
+
+// WithExecutor replaces the default executor, which calls each step's tool.
+func (b *Builder) WithExecutor(e Executor) *Builder { b.cfg.Executor = e; return b }
+
+func (b *Builder) WithVerifier(v Verifier) *Builder { b.cfg.Verifier = v; return b }
+
+// WithStore persists tasks; Build restores the saved ones (see Engine.Restored) and
+// brings interrupted runs back as PAUSED.
+func (b *Builder) WithStore(s Store) *Builder { b.cfg.Store = s; return b }
+
+// WithStoreDir is WithStore with a NewFileStore for dir.
+func (b *Builder) WithStoreDir(dir string) *Builder { b.cfg.StoreDir = dir; return b }
+
+// WithSink delivers every event to s.
+func (b *Builder) WithSink(s ...EventSink) *Builder {
+    for _, sink := range s { b.cfg.Sinks = append(b.cfg.Sinks, sink) }
+    return b
+}
+
+// OnEvent calls fn for every event, in order, from a goroutine of its own. The
+// payload is decoded generically (maps and slices).
+func (b *Builder) OnEvent(fn func(Event)) *Builder {
+    return b.WithSink(&funcSink{name: fmt.Sprintf("subscriber-%d", len(b.cfg.Sinks)+1), fn: fn})
+}
+
+// WithWorkers sizes the queue used by Engine.Enqueue: n runs at once, at most
+// maxPerClient (0 = no limit) per client.
+func (b *Builder) WithWorkers(n, maxPerClient int) *Builder { b.cfg.Workers, b.cfg.MaxPerClient = n, maxPerClient; return b }
+
+// WithApproval pauses steps whose tool risk is at least risk until someone calls
+// Engine.DecideApproval, rejecting them after timeout (0 = wait forever). An empty
+// risk disables approval gates.
+func (b *Builder) WithApproval(risk Risk, timeout time.Duration) *Builder {
+    b.cfg.ApprovalRisk, b.cfg.ApprovalTimeout = tools.Risk(risk), timeout
+    return b
+}
+
+// Build wires the components and starts the queue workers and event sinks.
+func (b *Builder) Build() (*Engine, error) {
+    cfg := b.cfg
+    // a tool's own risk, then TOOL_RISK, then WithToolRisk
+    cfg.Risks = map[string]tools.Risk{}
+    for _, t := range b.tools {
+        if t == nil { continue }
+        if rc, ok := t.(RiskClassifier); ok { cfg.Risks[t.Name()] = tools.Risk(rc.Risk()) }
+    }
+    for name, risk := range b.cfg.Risks { cfg.Risks[name] = risk }
+    for name, risk := range b.risks { cfg.Risks[name] = tools.Risk(risk) }
+    cfg.Tools = nil
+    for _, t := range b.tools { cfg.Tools = append(cfg.Tools, t) }
+    w, err := wiring.Build(&cfg)
+    if err != nil { return nil, fmt.Errorf("ensemble: %v", err) }
+    return &Engine{Restored: w.Restored, w: w, o: w.Orch}, nil
+}
+
+// Engine is a running orchestrator. Run and RunPlan cover the common case of running
+// one task to completion; the other methods are the task API the HTTP server offers.
+type Engine struct {
+    // Restored is the number of tasks loaded from the store by Build.
+    Restored int
+
+    w *wiring.Engine
+    o *orchestrator.Orchestrator
+}
+
+var taskSeq atomic.Uint64
+
+func newTaskID() string {
+    return fmt.Sprintf("task-%s-%d", time.Now().Format("20060102150405"), taskSeq.Add(1))
+}
+
+// Run creates a task, plans and executes it, and returns it once it has finished.
+// A task whose steps failed is returned with a nil error; check its Status. The
+// error is for tasks that never ran: the planner failed (the error wraps the
+// planner's) or ctx ended while planning.
+func (e *Engine) Run(ctx context.Context, query string, taskCtx map[string]any) (*Task, error) {
+    t := e.o.CreateTask(newTaskID(), query, taskCtx)
+    if err := e.o.Start(ctx, t.ID); err != nil { return t, fmt.Errorf("ensemble: plan %s: %w", t.ID, err) }
+    return t, nil
+}
+
+// RunPlan is Run with a given plan instead of the planner's. An invalid plan is an
+// error.
+func (e *Engine) RunPlan(ctx context.Context, query string, taskCtx map[string]any, plan *Plan) (*Task, error) {
+    t := e.o.CreateTask(newTaskID(), query, taskCtx)
+    steps := plan.Clone().Steps
+    if _, err := e.o.EditPlan(t.ID, "ensemble", "run_plan", func(p *Plan) error { p.Steps = steps; return nil }); err != nil { return t, fmt.Errorf("ensemble: %w", err) }
+    if err := e.o.ExecutePlan(ctx, t.ID); err != nil { return t, fmt.Errorf("ensemble: %w", err) }
+    return t, nil
+}
+
+// CreateTask adds a PENDING task.
+func (e *Engine) CreateTask(id, query string, taskCtx map[string]any) *Task { return e.o.CreateTask(id, query, taskCtx) }
+
+// Task returns a task. Its fields change while it runs; read them once it finished.
+func (e *Engine) Task(id string) (*Task, bool) { return e.o.GetTask(id) }
+
+// Tasks lists all tasks.
+func (e *Engine) Tasks() []*Task { return e.o.ListTasks() }
+
+// Start plans and runs a task in the calling goroutine.
+func (e *Engine) Start(ctx context.Context, id string) error { return e.o.Start(ctx, id) }
+
+// PlanOnly plans a task without running it.
+func (e *Engine) PlanOnly(ctx context.Context, id string) (*Plan, error) { return e.o.PlanOnly(ctx, id) }
+
+// ExecutePlan runs a task's current plan in the calling goroutine.
+func (e *Engine) ExecutePlan(ctx context.Context, id string) error { return e.o.ExecutePlan(ctx, id) }
+
+// EditPlan changes a task's plan through edit; the result is validated and recorded
+// as a new version by author. action names the edit in the plan history.
+func (e *Engine) EditPlan(id, author, action string, edit func(p *Plan) error) (*PlanVersion, error) {
+    return e.o.EditPlan(id, author, action, edit)
+}
+
+// Enqueue queues a JobStart, JobExecute or JobResume for the workers.
+func (e *Engine) Enqueue(id, kind string) (*QueuedJob, error) { return e.o.Enqueue(id, kind) }
+
+// Pause stops a running task at the next step boundary; Resume continues it.
+func (e *Engine) Pause(id string) error { return e.o.Pause(id) }
+
+func (e *Engine) Resume(ctx context.Context, id string) error { return e.o.Resume(ctx, id) }
+
+// Cancel stops a queued, running or paused task.
+func (e *Engine) Cancel(id string) error { return e.o.Cancel(id) }
+
+// DeleteTask removes a task that is not queued or running.
+func (e *Engine) DeleteTask(id string) error { return e.o.DeleteTask(id) }
+
+// DecideApproval approves or rejects a step waiting for approval.
+func (e *Engine) DecideApproval(id, stepID string, approved bool, by, comment string) error {
+    return e.o.DecideApproval(id, stepID, approved, by, comment)
+}
+
+// AnswerQuestion answers the question of a waiting ask_user step.
+func (e *Engine) AnswerQuestion(id, stepID, text, by string) error { return e.o.AnswerQuestion(id, stepID, text, by) }
+
+// Subscribe follows a task's events (AllTasks for every task) from lastEventID, or
+// from now on with 0.
+func (e *Engine) Subscribe(id string, lastEventID uint64) Subscription { return e.o.Subscribe(id, lastEventID) }
+
+// Close stops the queue workers (a running task finishes its run first) and event
+// delivery to sinks and OnEvent subscribers.
+func (e *Engine) Close() { e.w.Close() }
+
+// funcSink delivers events to an OnEvent callback.
+type funcSink struct {
+    name string
+    fn   func(Event)
+}
+
+func (s *funcSink) Name() string { return s.name }
+
+func (s *funcSink) Deliver(rec Record) error {
+    var ev Event
+    if err := json.Unmarshal(rec.Data, &ev); err != nil { return err }
+    defer func() {
+        if r := recover(); r != nil { log.Printf("event subscriber %s: panic: %v", s.name, r) }
+    }()
+    s.fn(ev)
+    return nil
+}
@@ -0,0 +1,230 @@
+// Package ensemble embeds the orchestrator in other Go programs. Build an Engine with
+// New (bare defaults) or FromEnv (the server's wiring), add tools, planners,
+// verifiers, storage and event subscribers, and run tasks in-process:
+//
+//    eng, err := ensemble.New().
+//        WithBuiltinTools().
+//        WithTool(ensemble.NewTool("lookup", lookup)).
+//        OnEvent(func(ev ensemble.Event) { log.Println(ev.Event) }).
+//        Build()
+//    if err != nil { ... }
+//    defer eng.Close()
+//    task, err := eng.Run(ctx, "summarize https://example.com", nil)
+//
+// Data types are the orchestrator's own, under stable names. Extension points and the
+// Engine are interfaces and methods of this package; the HTTP server in cmd/server is
+// built from the same parts. See examples/ for complete programs.
+package ensemble
+
+import (
+    "context"
+
+    "github.com/example/agent-orchestrator/internal/agents"
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/providers/llm"
+    "github.com/example/agent-orchestrator/internal/store"
+    "github.com/example/agent-orchestrator/internal/tools"
+    "github.com/example/agent-orchestrator/internal/wiring"
+)
+
+// Data model.
+type (
+    Task        = models.Task
+    Plan        = models.Plan
+    Step        = models.Step
+    MapSpec     = models.MapSpec
+    Result      = models.Result
+    Run         = models.Run
+    StepRun     = models.StepRun
+    Status      = models.Status
+    PlanVersion = models.PlanVersion
+    // TaskState is a task with its runs and plan history, as a Store keeps it.
+    TaskState   = models.TaskState
+    QueuedJob   = orchestrator.QueuedJob
+    Event       = orchestrator.Event
+    // Record is an event as delivered to an EventSink, still encoded.
+    Record      = orchestrator.Record
+    // Gap reports events a Subscription missed.
+    Gap         = orchestrator.Gap
+)
+
+// Tool is a named action a plan step can call. Tools with side effects should also
+// implement RiskClassifier.
+type Tool interface {
+    Name() string
+    // Execute returns the step output and a short log line.
+    Execute(ctx context.Context, inputs map[string]any) (output any, logs string, err error)
+}
+
+// Risk is how much damage a tool call can do: RiskLow, RiskMedium or RiskHigh.
+type Risk string
+
+// RiskClassifier is implemented by tools with side effects; other tools are low risk.
+type RiskClassifier interface {
+    Risk() Risk
+}
+
+// Planner turns a task into a plan.
+type Planner interface {
+    Plan(ctx context.Context, task *Task) (*Plan, error)
+}
+
+// Executor runs one step, normally by calling its tool.
+type Executor interface {
+    Execute(ctx context.Context, step *Step) (*Result, error)
+}
+
+// Verifier judges a step result; false fails the step with the returned reason.
+type Verifier interface {
+    Verify(ctx context.Context, task *Task, step *Step, res *Result) (bool, string)
+}
+
+// LLMClient is what built-in LLM tools, planner and verifier talk to.
+type LLMClient interface {
+    GeneratePlan(ctx context.Context, prompt string) (string, error)
+    Verify(ctx context.Context, prompt string, output string) (bool, string, error)
+    GenerateText(ctx context.Context, prompt string) (string, error)
+    // GenerateTextStream sends chunks to onDelta as they arrive; clients that cannot
+    // stream may send the whole text as one chunk.
+    GenerateTextStream(ctx context.Context, prompt string, onDelta func(chunk string) error) error
+}
+
+// Store persists tasks so they survive restarts. Stores that also have
+// DeleteTask(id string) error forget deleted tasks.
+type Store interface {
+    SaveTask(st *TaskState) error
+    LoadTasks() ([]*TaskState, error)
+}
+
+// EventSink receives every event, in order, from its own goroutine. Sinks may also
+// have Accept(Record) bool, to skip events before they are queued, and
+// DeadLetter(Record, error) error, for events they will never receive.
+type EventSink interface {
+    Name() string
+    Deliver(rec Record) error
+}
+
+// Subscription follows one task's events (or AllTasks'). C signals new events;
+// Next returns them, with a Gap when some were evicted before they were read.
+type Subscription interface {
+    C() <-chan struct{}
+    Next() ([]Record, *Gap)
+    Close()
+}
+
+const (
+    StatusPending          = models.StatusPending
+    StatusPlanned          = models.StatusPlanned
+    StatusQueued           = models.StatusQueued
+    StatusRunning          = models.StatusRunning
+    StatusAwaitingApproval = models.StatusAwaitingApproval
+    StatusAwaitingInput    = models.StatusAwaitingInput
+    StatusPaused           = models.StatusPaused
+    StatusSuccess          = models.StatusSuccess
+    StatusFailed           = models.StatusFailed
+    StatusCancelled        = models.StatusCancelled
+    StatusSkipped          = models.StatusSkipped
+
+    RiskLow    Risk = "low"
+    RiskMedium Risk = "medium"
+    RiskHigh   Risk = "high"
+
+    // Job kinds for Engine.Enqueue.
+    JobStart   = orchestrator.JobStart
+    JobExecute = orchestrator.JobExecute
+    JobResume  = orchestrator.JobResume
+)
+
+// AllTasks subscribes to every task's events (Engine.Subscribe).
+const AllTasks = orchestrator.AllTasks
+
+// Errors returned by Engine methods; test for them with errors.Is.
+var (
+    ErrTaskNotFound  = orchestrator.ErrTaskNotFound
+    ErrTaskRunning   = orchestrator.ErrTaskRunning
+    ErrAlreadyQueued = orchestrator.ErrAlreadyQueued
+    ErrTaskPaused    = orchestrator.ErrTaskPaused
+    ErrNoPlan        = orchestrator.ErrNoPlan
+    ErrNotPaused     = orchestrator.ErrNotPaused
+    ErrNotAwaiting   = orchestrator.ErrNotAwaiting
+    ErrNoApprover    = orchestrator.ErrNoApprover
+    ErrInvalidAnswer = orchestrator.ErrInvalidAnswer
+)
+
+// ParseRisk reads "low", "medium" or "high".
+func ParseRisk(s string) (Risk, error) {
+    r, err := tools.ParseRisk(s)
+    return Risk(r), err
+}
+
+// funcTool adapts a function to Tool.
+type funcTool struct {
+    name string
+    fn   func(ctx context.Context, inputs map[string]any) (any, error)
+    risk Risk
+}
+
+func (t *funcTool) Name() string { return t.name }
+
+func (t *funcTool) Execute(ctx context.Context, inputs map[string]any) (any, string, error) {
+    out, err := t.fn(ctx, inputs)
+    return out, "", err
+}
+
+func (t *funcTool) Risk() Risk { return t.risk }
+
+// NewTool makes a low-risk Tool from a function.
+func NewTool(name string, fn func(ctx context.Context, inputs map[string]any) (any, error)) Tool {
+    return &funcTool{name: name, fn: fn, risk: RiskLow}
+}
+
+// NewRiskyTool is NewTool for a tool with side effects; see Builder.WithApproval.
+func NewRiskyTool(name string, risk Risk, fn func(ctx context.Context, inputs map[string]any) (any, error)) Tool {
+    return &funcTool{name: name, fn: fn, risk: risk}
+}
+
+// PlannerFunc adapts a function to Planner.
+type PlannerFunc func(ctx context.Context, task *Task) (*Plan, error)
+
+func (f PlannerFunc) Plan(ctx context.Context, task *Task) (*Plan, error) { return f(ctx, task) }
+
+// VerifierFunc adapts a function to Verifier.
+type VerifierFunc func(ctx context.Context, task *Task, step *Step, res *Result) (bool, string)
+
+func (f VerifierFunc) Verify(ctx context.Context, task *Task, step *Step, res *Result) (bool, string) {
+    return f(ctx, task, step, res)
+}
+
+// MockPlanner is the rule-based planner the server uses without USE_LLM_PLANNER.
+func MockPlanner() Planner { return &agents.MockPlanner{} }
+
+// LLMPlanner plans with an LLM; the built-in tools are described in its prompt.
+func LLMPlanner(c LLMClient) Planner { return &agents.LLMPlanner{Client: c} }
+
+// SimpleVerifier accepts any result without an error.
+func SimpleVerifier() Verifier { return &agents.SimpleVerifier{} }
+
+// LLMVerifier asks an LLM whether a result satisfies its step.
+func LLMVerifier(c LLMClient) Verifier { return &agents.LLMVerifier{Client: c} }
+
+// LLMFromEnv returns the client selected by LLM_PROVIDER and the provider API key
+// variables, or a mock client when none is configured.
+func LLMFromEnv() LLMClient { return llm.NewFromEnv() }
+
+// MockLLM answers every prompt with canned text; useful in tests.
+func MockLLM() LLMClient { return &llm.MockClient{} }
+
+// NewFileStore keeps each task as a JSON file in dir.
+func NewFileStore(dir string) (Store, error) {
+    fs, err := store.NewFileStore(dir)
+    if err != nil { return nil, err }
+    return fs, nil
+}
+
+// BuiltinTools returns the server's tools, with LLM-backed ones using c.
+func BuiltinTools(c LLMClient) []Tool {
+    var out []Tool
+    for _, t := range wiring.BuiltinTools(c) { out = append(out, t) }
+    return out
+}
@@ -0,0 +1,165 @@
+package ensemble
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "runtime"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+// waitFor polls cond until it holds or two seconds have passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(2 * time.Second)
+    for !cond() {
+        if time.Now().After(deadline) { t.Fatalf("timed out waiting for %s", what) }
+        time.Sleep(2 * time.Millisecond)
+    }
+}
+
+// waitEvent reads sub until an event contains every part.
+func waitEvent(t *testing.T, sub Subscription, parts ...string) Event {
+    t.Helper()
+    timeout := time.After(2 * time.Second)
+    for {
+        recs, _ := sub.Next()
+        for _, r := range recs {
+            match := true
+            for _, p := range parts { match = match && strings.Contains(string(r.Data), p) }
+            if match {
+                var ev Event
+                json.Unmarshal(r.Data, &ev)
+                return ev
+            }
+        }
+        select {
+        case <-sub.C():
+        case <-timeout:
+            t.Fatalf("no event with %q", parts)
+        }
+    }
+}
+
+func shout() Tool {
+    return NewTool("shout", func(ctx context.Context, inputs map[string]any) (any, error) {
+        return strings.ToUpper(inputs["text"].(string)) + "!", nil
+    })
+}
+
+func TestRunPlanWithCustomTool(t *testing.T) {
+    var mu sync.Mutex
+    var seen []string
+    eng, err := New().WithBuiltinTools().WithTool(shout()).OnEvent(func(ev Event) {
+        mu.Lock()
+        defer mu.Unlock()
+        seen = append(seen, ev.Event)
+    }).Build()
+    if err != nil { t.Fatal(err) }
+    defer eng.Close()
+    task, err := eng.RunPlan(context.Background(), "q", map[string]any{"name": "gopher"}, &Plan{Steps: []*Step{
+        {ID: "greet", Tool: "echo", Inputs: map[string]any{"text": "hi {{context.name}}"}},
+        {ID: "loud", Tool: "shout", Deps: []string{"greet"}, Inputs: map[string]any{"text": "{{step:greet.output}}"}},
+    }})
+    if err != nil || task.Status != StatusSuccess { t.Fatalf("run: %v, %+v", err, task) }
+    if out := task.Results[1].Output; out != "ECHO: HI GOPHER!" { t.Fatalf("output %v", out) }
+    waitFor(t, "events", func() bool { mu.Lock(); defer mu.Unlock(); return len(seen) > 0 && seen[len(seen)-1] == "task_status" })
+
+    // an invalid plan is an error, not a failed task
+    if _, err := eng.RunPlan(context.Background(), "q", nil, &Plan{Steps: []*Step{{ID: "x", Tool: "nope"}}}); err == nil { t.Fatal("unknown tool accepted") }
+}
+
+func TestRunReturnsPlannerErrors(t *testing.T) {
+    boom := errors.New("no plan today")
+    eng, err := New().WithPlanner(PlannerFunc(func(ctx context.Context, task *Task) (*Plan, error) { return nil, boom })).Build()
+    if err != nil { t.Fatal(err) }
+    defer eng.Close()
+    task, err := eng.Run(context.Background(), "q", nil)
+    if !errors.Is(err, boom) || task.Status != StatusFailed { t.Fatalf("run: %v, %s", err, task.Status) }
+
+    // a step that fails is reported by the task's status
+    eng, err = New().WithBuiltinTools().WithPlanner(PlannerFunc(func(ctx context.Context, task *Task) (*Plan, error) {
+        return &Plan{Steps: []*Step{{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "x"}}}}, nil
+    })).WithVerifier(VerifierFunc(func(ctx context.Context, task *Task, step *Step, res *Result) (bool, string) { return false, "never" })).Build()
+    if err != nil { t.Fatal(err) }
+    defer eng.Close()
+    if task, err := eng.Run(context.Background(), "q", nil); err != nil || task.Status != StatusFailed { t.Fatalf("run: %v, %s", err, task.Status) }
+}
+
+func TestRiskyTools(t *testing.T) {
+    rm := NewRiskyTool("rm", RiskHigh, func(ctx context.Context, inputs map[string]any) (any, error) { return "removed", nil })
+    eng, err := New().WithTool(rm).WithApproval(RiskHigh, 0).Build()
+    if err != nil { t.Fatal(err) }
+    defer eng.Close()
+    task := eng.CreateTask("t1", "q", nil)
+    if _, err := eng.EditPlan("t1", "test", "replace_plan", func(p *Plan) error { p.Steps = []*Step{{ID: "rm", Tool: "rm"}}; return nil }); err != nil { t.Fatal(err) }
+    sub := eng.Subscribe("t1", 1)
+    defer sub.Close()
+    if _, err := eng.Enqueue("t1", JobExecute); err != nil { t.Fatal(err) }
+    // the tool's own risk level gates it
+    waitEvent(t, sub, `"approval_required"`, `"risk":"high"`)
+    if err := eng.DecideApproval("t1", "rm", true, "ann", ""); err != nil { t.Fatal(err) }
+    waitEvent(t, sub, `"task_status"`, `"SUCCESS"`)
+    if err := eng.DecideApproval("t1", "rm", true, "ann", ""); !errors.Is(err, ErrNotAwaiting) { t.Fatalf("second decision: %v", err) }
+    if task.Results[0].Output != "removed" { t.Fatalf("output %v", task.Results[0].Output) }
+
+    // WithToolRisk overrides it
+    eng, err = New().WithTool(rm).WithApproval(RiskHigh, 0).WithToolRisk("rm", RiskLow).Build()
+    if err != nil { t.Fatal(err) }
+    defer eng.Close()
+    if task, err := eng.RunPlan(context.Background(), "q", nil, &Plan{Steps: []*Step{{ID: "rm", Tool: "rm"}}}); err != nil || task.Status != StatusSuccess { t.Fatalf("run: %v, %s", err, task.Status) }
+}
+
+// memStore keeps tasks in memory.
+type memStore struct {
+    mu    sync.Mutex
+    saved map[string][]byte
+}
+
+func (s *memStore) SaveTask(st *TaskState) error {
+    b, err := json.Marshal(st)
+    if err != nil { return err }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.saved[st.Task.ID] = b
+    return nil
+}
+
+func (s *memStore) LoadTasks() ([]*TaskState, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var out []*TaskState
+    for _, b := range s.saved {
+        var st TaskState
+        if err := json.Unmarshal(b, &st); err != nil { return nil, err }
+        out = append(out, &st)
+    }
+    return out, nil
+}
+
+func TestStoreRestores(t *testing.T) {
+    st := &memStore{saved: map[string][]byte{}}
+    eng, err := New().WithBuiltinTools().WithStore(st).Build()
+    if err != nil { t.Fatal(err) }
+    if _, err := eng.RunPlan(context.Background(), "q", nil, &Plan{Steps: []*Step{{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "x"}}}}); err != nil { t.Fatal(err) }
+    eng.Close()
+
+    eng, err = New().WithBuiltinTools().WithStore(st).Build()
+    if err != nil { t.Fatal(err) }
+    defer eng.Close()
+    if tasks := eng.Tasks(); eng.Restored != 1 || len(tasks) != 1 || tasks[0].Status != StatusSuccess { t.Fatalf("restored %d: %+v", eng.Restored, tasks) }
+    if _, err := New().WithStoreDir("/dev/null/tasks").Build(); err == nil { t.Fatal("unusable store directory accepted") }
+}
+
+func TestCloseStopsWorkers(t *testing.T) {
+    before := runtime.NumGoroutine()
+    eng, err := New().WithWorkers(8, 0).OnEvent(func(Event) {}).Build()
+    if err != nil { t.Fatal(err) }
+    if n := runtime.NumGoroutine(); n < before+8 { t.Fatalf("%d goroutines after Build, %d before", n, before) }
+    eng.Close()
+    eng.Close()
+    waitFor(t, "workers and sinks to stop", func() bool { return runtime.NumGoroutine() <= before })
+}
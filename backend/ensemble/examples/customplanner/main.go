@@ -0,0 +1,37 @@
+// Command customplanner swaps in a planner and a verifier of its own: every query is
+// answered by a two-step plan, and answers that are too short are rejected.
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+
+    "github.com/example/agent-orchestrator/ensemble"
+)
+
+func main() {
+    planner := ensemble.PlannerFunc(func(ctx context.Context, t *ensemble.Task) (*ensemble.Plan, error) {
+        return &ensemble.Plan{Steps: []*ensemble.Step{
+            {ID: "answer", Tool: "llm_answer", Inputs: map[string]any{"text": "{{task.query}}"}},
+            {ID: "report", Tool: "echo", Deps: []string{"answer"}, Inputs: map[string]any{"text": "Q: {{task.query}}\nA: {{step:answer.output}}"}},
+        }}, nil
+    })
+    verifier := ensemble.VerifierFunc(func(ctx context.Context, t *ensemble.Task, s *ensemble.Step, res *ensemble.Result) (bool, string) {
+        if res.Error != "" { return false, res.Error }
+        if text, ok := res.Output.(string); ok && len(text) < 5 { return false, "answer too short" }
+        return true, ""
+    })
+    // FromEnv picks the LLM provider from the environment (a mock without API keys)
+    eng, err := ensemble.FromEnv().
+        WithPlanner(planner).
+        WithVerifier(verifier).
+        Build()
+    if err != nil { log.Fatal(err) }
+    defer eng.Close()
+
+    task, err := eng.Run(context.Background(), "What is a goroutine?", nil)
+    if err != nil { log.Fatal(err) }
+    fmt.Println(task.Status)
+    if n := len(task.Results); n > 0 { fmt.Println(task.Results[n-1].Output) }
+}
@@ -0,0 +1,38 @@
+// Command customtool embeds the orchestrator with a custom tool and runs a fixed plan
+// that uses it, printing events as they happen.
+package main
+
+import (
+    "context"
+    "fmt"
+    "log"
+    "strings"
+
+    "github.com/example/agent-orchestrator/ensemble"
+)
+
+func main() {
+    shout := ensemble.NewTool("shout", func(ctx context.Context, inputs map[string]any) (any, error) {
+        text, _ := inputs["text"].(string)
+        if text == "" { return nil, fmt.Errorf("missing text") }
+        return strings.ToUpper(text) + "!", nil
+    })
+    eng, err := ensemble.New().
+        WithBuiltinTools().
+        WithTool(shout).
+        OnEvent(func(ev ensemble.Event) {
+            if ev.Event == "step_status" { fmt.Printf("event: %s %v\n", ev.Event, ev.Payload) }
+        }).
+        Build()
+    if err != nil { log.Fatal(err) }
+    defer eng.Close()
+
+    plan := &ensemble.Plan{Steps: []*ensemble.Step{
+        {ID: "greet", Tool: "echo", Inputs: map[string]any{"text": "hello {{context.name}}"}},
+        {ID: "loud", Tool: "shout", Deps: []string{"greet"}, Inputs: map[string]any{"text": "{{step:greet.output}}"}},
+    }}
+    task, err := eng.RunPlan(context.Background(), "greet loudly", map[string]any{"name": "gopher"}, plan)
+    if err != nil { log.Fatal(err) }
+    fmt.Println(task.Status)
+    for _, r := range task.Results { fmt.Printf("%s: %v %s\n", r.StepID, r.Output, r.Error) }
+}
@@ -10,6 +10,7 @@ require (
 	github.com/ledongthuc/pdf v0.0.0-20250511090121-5959a4027728
 	golang.org/x/net v0.43.0
 	google.golang.org/api v0.248.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
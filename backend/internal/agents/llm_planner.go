@@ -21,6 +21,8 @@ type llmStep struct {
     Tool        string                 `json:"tool"`
     Inputs      map[string]any         `json:"inputs,omitempty"`
     Deps        []string               `json:"deps,omitempty"`
+    Condition   string                 `json:"if,omitempty"`
+    Map         *models.MapSpec        `json:"map,omitempty"`
 }
 
 func (p *LLMPlanner) Plan(ctx context.Context, task *models.Task) (*models.Plan, error) {
@@ -70,6 +72,8 @@ func (p *LLMPlanner) Plan(ctx context.Context, task *models.Task) (*models.Plan,
             Inputs:      s.Inputs,
             Deps:        s.Deps,
             Status:      models.StatusPending,
+            Condition:   s.Condition,
+            Map:         s.Map,
         })
     }
     return &models.Plan{Steps: out}, nil
@@ -86,14 +90,23 @@ Tools (you MUST stick to these):
 - summarize: inputs {"text": string}
 - llm_answer: inputs {"text": string}
  - http_post_json: inputs {"url": string, "json": any}
+- llm_extract: inputs {"text": string, "schema": JSON Schema object, "instructions"?: string} (returns a JSON object matching the schema)
+- crawl: inputs {"url": string, "max_depth"?: number, "max_pages"?: number} (follows same-site links; returns [{url,title,depth,text}])
+- ask_user: inputs {"question": string, "choices"?: [string]} (pauses until the user answers; returns the answer text)
 
 Rules:
 - Produce 1–3 ordered steps. Prefer 2 steps when helpful.
 - Use "deps" to express order (e.g., step2 depends on step1).
 - To pass the output of a previous step to a later step, set a string input to the exact template: {{step:ID.output}}
+- Fields of structured outputs can be referenced directly, e.g. {{step:step2.output.price}} or {{step:step1.output[0].url}}; {{task.query}} and {{context.KEY}} are also available.
 - If the query contains or implies a URL, plan: (1) http_get(url) -> (2) html_to_text(html="{{step:step1.output}}") -> (3) summarize(text="{{step:step2.output}}").
 - If the query starts with "summarize:" or "summarise:", use a single summarize step with {"text": "<rest of query>"}.
+- If the query asks to summarize a whole site or documentation (not a single page), plan: (1) crawl(url) -> (2) summarize(text="{{step:step1.output}}").
+- If the query asks to extract specific fields (e.g. name, price, date) as JSON, end with an llm_extract step whose schema lists those fields.
+- To repeat steps for every element of a list output, use a step with "tool": "map" and "map": {"over": "{{step:step1.output.urls}}", "steps": [ ...sub-steps... ]}. Sub-steps read the element as {{item}} (or {{item.field}}) and each other as {{step:SUBID.output}}; the map step's output is the array of each element's last sub-step output.
+- A step may carry "if": a condition evaluated just before it runs; when false the step is SKIPPED (and so are steps whose deps were all skipped). Example: "if": "len({{step:step2.output}}) >= 500" or "if": "contains({{step:step1.logs}}, \"application/pdf\")". Only use conditions when the branch genuinely depends on an earlier output.
 - If the query suggests calling a JSON API (mentions POST/JSON/payload) and includes a URL and a simple JSON object, use a single http_post_json step with that URL and JSON.
+- If the query is ambiguous in a way that changes the plan (e.g. several documents, URLs or options could be meant), start with an ask_user step and use {{step:ID.output}} in later steps. Offer "choices" when the options are known. Do not ask when a reasonable reading exists.
 - If there is no URL and it is a direct question, use a single llm_answer step with {"text": "<the query>"}.
 
 Special context:
@@ -101,7 +114,7 @@ Special context:
   - If the query mentions "summarize"/"summarise": (1) pdf_extract(data_base64 from context) -> (2) summarize(text from step1).
   - Otherwise: (1) pdf_extract(data_base64 from context) -> (2) llm_answer(text="<the query>", instructions="Use the following PDF content as context.\n\nContext:\n{{step:step1.output}}" ).
 
-Schema for each step: {"id": "stepN", "description": "...", "tool": "echo"|"http_get"|"html_to_text"|"summarize"|"llm_answer"|"http_post_json"|"pdf_extract", "inputs": { ... }, "deps": ["stepK"]}
+Schema for each step: {"id": "stepN", "description": "...", "tool": "echo"|"http_get"|"html_to_text"|"summarize"|"llm_answer"|"http_post_json"|"pdf_extract"|"crawl"|"llm_extract"|"ask_user", "inputs": { ... }, "deps": ["stepK"]}
 
 User query: %s
 Context: %v`, task.Query, task.Context)
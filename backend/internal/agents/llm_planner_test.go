@@ -0,0 +1,15 @@
+package agents
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func TestPlanPromptOffersAskUser(t *testing.T) {
+    p := buildPlanPrompt(&models.Task{Query: "summarize the report"})
+    for _, want := range []string{`- ask_user: inputs {"question": string, "choices"?: [string]}`, "If the query is ambiguous", `"ask_user"`} {
+        if !strings.Contains(p, want) { t.Errorf("prompt lacks %q", want) }
+    }
+}
@@ -10,6 +10,7 @@ import (
 
     "github.com/example/agent-orchestrator/internal/models"
     "github.com/example/agent-orchestrator/internal/providers/llm"
+    "github.com/example/agent-orchestrator/internal/templating"
 )
 
 // LLMVerifier asks an LLM to judge if a result meets the step intent.
@@ -17,7 +18,7 @@ type LLMVerifier struct { Client llm.Client }
 
 func (v *LLMVerifier) Verify(ctx context.Context, task *models.Task, step *models.Step, res *models.Result) (bool, string) {
     if res.Error != "" { return false, "execution error" }
-    outStr := stringify(res.Output)
+    outStr := templating.Stringify(res.Output)
     ok, reason, err := v.Client.Verify(ctx, buildVerifyPrompt(task, step), outStr)
     if err != nil {
         if os.Getenv("LLM_DEBUG") == "1" {
@@ -43,13 +44,3 @@ func buildVerifyPrompt(task *models.Task, step *models.Step) string {
 Respond with JSON: {"ok": true|false, "reason": "..."}.
 Task and step: %s`, string(b))
 }
-
-func stringify(v any) string {
-    switch t := v.(type) {
-    case string:
-        return t
-    default:
-        b, _ := json.Marshal(t)
-        return string(b)
-    }
-}
@@ -2,7 +2,6 @@ package agents
 
 import (
     "context"
-    "strings"
 
     "github.com/example/agent-orchestrator/internal/models"
 )
@@ -11,86 +10,82 @@ type Planner interface {
     Plan(ctx context.Context, task *models.Task) (*models.Plan, error)
 }
 
-// MockPlanner is a simple rule-based planner for MVP.
+// MockPlanner is a simple rule-based planner for MVP. It returns one declarative plan
+// covering every branch; step conditions decide at run time which branch executes.
 type MockPlanner struct{}
 
+const (
+    condHasPDF   = `{{context.pdf_data_base64}}`
+    condSummary  = `contains(lower({{task.query}}), "summarize") || contains(lower({{task.query}}), "summarise")`
+    condHasURL   = `!{{context.pdf_data_base64}} && contains(lower({{task.query}}), "http")`
+    condQuestion = `!{{context.pdf_data_base64}} && !contains(lower({{task.query}}), "http")`
+)
+
 func (m *MockPlanner) Plan(ctx context.Context, task *models.Task) (*models.Plan, error) {
-    q := strings.ToLower(task.Query)
-    if b64, ok := task.Context["pdf_data_base64"].(string); ok && b64 != "" {
-        // If the query mentions summarize, summarize the PDF content; otherwise answer the query using the PDF as context.
-        if strings.Contains(q, "summarize") || strings.Contains(q, "summarise") {
-            return &models.Plan{Steps: []*models.Step{
-                {
-                    ID:          "step1",
-                    Description: "Extract text from PDF",
-                    Tool:        "pdf_extract",
-                    Inputs:      map[string]any{"data_base64": b64},
-                    Status:      models.StatusPending,
-                },
-                {
-                    ID:          "step2",
-                    Description: "Summarize PDF content",
-                    Tool:        "summarize",
-                    Inputs:      map[string]any{"text": "{{step:step1.output}}"},
-                    Deps:        []string{"step1"},
-                    Status:      models.StatusPending,
-                },
-            }}, nil
-        }
-        return &models.Plan{Steps: []*models.Step{
-            {
-                ID:          "step1",
-                Description: "Extract text from PDF",
-                Tool:        "pdf_extract",
-                Inputs:      map[string]any{"data_base64": b64},
-                Status:      models.StatusPending,
-            },
-            {
-                ID:          "step2",
-                Description: "Answer question using PDF context",
-                Tool:        "llm_answer",
-                Inputs:      map[string]any{
-                    "text": task.Query,
-                    "instructions": "Use the following PDF content as context to answer.\n\nContext:\n{{step:step1.output}}",
-                },
-                Deps:        []string{"step1"},
-                Status:      models.StatusPending,
-            },
-        }}, nil
-    }
-    // Prefer richer defaults: URL -> http_get -> html_to_text -> summarize, else llm_answer
-    if strings.Contains(q, "http") || strings.HasPrefix(q, "http://") || strings.HasPrefix(q, "https://") {
-        return &models.Plan{Steps: []*models.Step{
-            {
-                ID:          "step1",
-                Description: "HTTP GET a URL",
-                Tool:        "http_get",
-                Inputs:      map[string]any{"url": task.Query},
-                Status:      models.StatusPending,
-            },
-            {
-                ID:          "step2",
-                Description: "Convert HTML to text",
-                Tool:        "html_to_text",
-                Inputs:      map[string]any{"html": "{{step:step1.output}}"},
-                Deps:        []string{"step1"},
-                Status:      models.StatusPending,
-            },
-            {
-                ID:          "step3",
-                Description: "Summarize content",
-                Tool:        "summarize",
-                Inputs:      map[string]any{"text": "{{step:step2.output}}"},
-                Deps:        []string{"step2"},
-                Status:      models.StatusPending,
+    return &models.Plan{Steps: []*models.Step{
+        // PDF in context: summarize it, or answer the query using it as context
+        {
+            ID:          "extract_pdf",
+            Description: "Extract text from PDF",
+            Tool:        "pdf_extract",
+            Inputs:      map[string]any{"data_base64": "{{context.pdf_data_base64}}"},
+            Condition:   condHasPDF,
+            Status:      models.StatusPending,
+        },
+        {
+            ID:          "summarize_pdf",
+            Description: "Summarize PDF content",
+            Tool:        "summarize",
+            Inputs:      map[string]any{"text": "{{step:extract_pdf.output}}"},
+            Deps:        []string{"extract_pdf"},
+            Condition:   condSummary,
+            Status:      models.StatusPending,
+        },
+        {
+            ID:          "answer_pdf",
+            Description: "Answer question using PDF context",
+            Tool:        "llm_answer",
+            Inputs:      map[string]any{
+                "text": "{{task.query}}",
+                "instructions": "Use the following PDF content as context to answer.\n\nContext:\n{{step:extract_pdf.output}}",
             },
-        }}, nil
-    }
-    return &models.Plan{Steps: []*models.Step{{
-        ID:          "step1",
-        Description: "Answer with LLM",
-        Tool:        "llm_answer",
-        Inputs:      map[string]any{"text": task.Query},
-        Status:      models.StatusPending,
-    }}}, nil
+            Deps:        []string{"extract_pdf"},
+            Condition:   "!(" + condSummary + ")",
+            Status:      models.StatusPending,
+        },
+        // URL in query: http_get -> html_to_text -> summarize
+        {
+            ID:          "fetch",
+            Description: "HTTP GET a URL",
+            Tool:        "http_get",
+            Inputs:      map[string]any{"url": "{{task.query}}"},
+            Condition:   condHasURL,
+            Status:      models.StatusPending,
+        },
+        {
+            ID:          "to_text",
+            Description: "Convert HTML to text",
+            Tool:        "html_to_text",
+            Inputs:      map[string]any{"html": "{{step:fetch.output}}"},
+            Deps:        []string{"fetch"},
+            Status:      models.StatusPending,
+        },
+        {
+            ID:          "summarize",
+            Description: "Summarize content",
+            Tool:        "summarize",
+            Inputs:      map[string]any{"text": "{{step:to_text.output}}"},
+            Deps:        []string{"to_text"},
+            Status:      models.StatusPending,
+        },
+        // anything else: answer directly
+        {
+            ID:          "answer",
+            Description: "Answer with LLM",
+            Tool:        "llm_answer",
+            Inputs:      map[string]any{"text": "{{task.query}}"},
+            Condition:   condQuestion,
+            Status:      models.StatusPending,
+        },
+    }}, nil
 }
@@ -0,0 +1,26 @@
+package agents
+
+import (
+    "context"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func TestSimpleVerifier(t *testing.T) {
+    echo := &models.Step{ID: "e", Tool: "echo", Inputs: map[string]any{"text": "hi"}}
+    other := &models.Step{ID: "o", Tool: "http_get"}
+    for _, c := range []struct {
+        step *models.Step
+        res  *models.Result
+        want bool
+    }{
+        {echo, &models.Result{Output: "hi there"}, true},
+        {echo, &models.Result{Output: "bye"}, false},
+        {other, &models.Result{Output: "body"}, true},
+        {other, &models.Result{}, false},
+        {other, &models.Result{Output: "body", Error: "boom"}, false},
+    } {
+        if got, _ := (&SimpleVerifier{}).Verify(context.Background(), &models.Task{}, c.step, c.res); got != c.want { t.Errorf("%s %+v: %v, want %v", c.step.ID, c.res, got, c.want) }
+    }
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// newTestServer serves the API the way cmd/server does, against the package's
+// orchestrator (built from an empty environment: mock LLM, no storage).
+func newTestServer(t *testing.T) *httptest.Server {
+    t.Helper()
+    mux := http.NewServeMux()
+    RegisterRoutes(mux)
+    srv := httptest.NewServer(ValidateRequests(mux))
+    t.Cleanup(srv.Close)
+    return srv
+}
+
+// call sends a request with an optional JSON body and decodes a JSON response into out
+// (when non-nil). It returns the response with its body consumed.
+func call(t *testing.T, method, url string, body any, out any) *http.Response {
+    t.Helper()
+    var rd io.Reader
+    if body != nil {
+        b, _ := json.Marshal(body)
+        rd = bytes.NewReader(b)
+    }
+    req, _ := http.NewRequest(method, url, rd)
+    if body != nil { req.Header.Set("Content-Type", "application/json") }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    defer resp.Body.Close()
+    b, _ := io.ReadAll(resp.Body)
+    if out != nil && len(b) > 0 {
+        if err := json.Unmarshal(b, out); err != nil { t.Fatalf("%s %s: %v: %s", method, url, err, b) }
+    }
+    return resp
+}
+
+// apiError is the error envelope.
+type apiError struct {
+    Error    string   `json:"error"`
+    Code     string   `json:"code"`
+    Problems []string `json:"problems"`
+}
+
+// sseEvent is one message of an event stream.
+type sseEvent struct {
+    ID    string
+    Event string
+    Data  string
+}
+
+// openStream starts an SSE request and returns its messages as they arrive. The stream
+// is closed with the test.
+func openStream(t *testing.T, url string, header http.Header) <-chan sseEvent {
+    t.Helper()
+    req, _ := http.NewRequest(http.MethodGet, url, nil)
+    for k, v := range header { req.Header[k] = v }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil { t.Fatal(err) }
+    if resp.StatusCode != http.StatusOK { b, _ := io.ReadAll(resp.Body); t.Fatalf("GET %s: %d %s", url, resp.StatusCode, b) }
+    t.Cleanup(func() { resp.Body.Close() })
+    ch := make(chan sseEvent, 256)
+    go func() {
+        defer close(ch)
+        sc := bufio.NewScanner(resp.Body)
+        var ev sseEvent
+        for sc.Scan() {
+            line := sc.Text()
+            switch {
+            case line == "":
+                if ev.Event != "" { ch <- ev }
+                ev = sseEvent{}
+            case strings.HasPrefix(line, "id: "):
+                ev.ID = line[4:]
+            case strings.HasPrefix(line, "event: "):
+                ev.Event = line[7:]
+            case strings.HasPrefix(line, "data: "):
+                ev.Data = line[6:]
+            }
+        }
+    }()
+    return ch
+}
+
+// next returns the next stream message, failing after a timeout.
+func next(t *testing.T, ch <-chan sseEvent) sseEvent {
+    t.Helper()
+    select {
+    case ev, ok := <-ch:
+        if !ok { t.Fatal("stream ended") }
+        return ev
+    case <-time.After(2 * time.Second):
+        t.Fatal("no event within 2s")
+    }
+    return sseEvent{}
+}
+
+var taskSeq atomic.Int64
+
+// newTask creates a task with a one-step echo plan, directly on the orchestrator.
+func newTask(t *testing.T, prefix string) *models.Task {
+    t.Helper()
+    return newTaskWith(t, prefix, &models.Step{ID: "say", Tool: "echo", Inputs: map[string]any{"text": "hello"}})
+}
+
+// newTaskWith creates a task planned with steps.
+func newTaskWith(t *testing.T, prefix string, steps ...*models.Step) *models.Task {
+    t.Helper()
+    id := fmt.Sprintf("%s-%d", prefix, taskSeq.Add(1))
+    task := orch.CreateTask(id, "query", nil)
+    _, err := orch.EditPlan(id, "test", "replace_plan", func(p *models.Plan) error { p.Steps = steps; return nil })
+    if err != nil { t.Fatal(err) }
+    return task
+}
+
+// waitEvent waits for an event of the task that contains every part, and returns its
+// JSON. It reads the task's log from the start, so earlier waits don't consume events.
+// Tests watch events rather than the task, which its run changes without locks.
+func waitEvent(t *testing.T, taskID string, parts ...string) string {
+    t.Helper()
+    sub := orch.Subscribe(taskID, 1) // 0 would mean "from now on"
+    defer sub.Close()
+    timeout := time.After(2 * time.Second)
+    for {
+        recs, _ := sub.Next()
+        for _, r := range recs {
+            match := true
+            for _, p := range parts { match = match && strings.Contains(string(r.Data), p) }
+            if match { return string(r.Data) }
+        }
+        select {
+        case <-sub.C():
+        case <-timeout:
+            t.Fatalf("no event with %q within 2s", parts)
+        }
+    }
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+    "net/http"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+// handleTaskAction serves the custom methods of a task:
+//
+//    POST /v1/tasks/{id}:plan       compute the plan without running it (200)
+//    POST /v1/tasks/{id}:start      queue plan + execute, ?priority=N (202)
+//    POST /v1/tasks/{id}:execute    queue a run of the current plan, ?priority=N (202)
+//    POST /v1/tasks/{id}:pause      stop at the next step boundary (202)
+//    POST /v1/tasks/{id}:resume     queue a paused task to continue (202)
+//    POST /v1/tasks/{id}:cancel     abort a queued, running or paused task (202)
+//
+// Pause takes effect at the next step boundary and the others are queued, so they
+// answer 202; the outcome arrives as task_status events. Transitions the task's state
+// does not allow answer 409 with a code naming the state.
+func handleTaskAction(w http.ResponseWriter, r *http.Request) {
+    id, action := customMethod(r.PathValue("id"))
+    var err error
+    switch action {
+    case "plan":
+        plan, err := orch.PlanOnly(r.Context(), id)
+        if err != nil { respondErr(w, err, http.StatusUnprocessableEntity, codePlanningFailed); return }
+        respondJSON(w, plan)
+        return
+    case "start":
+        enqueue(w, r, id, orchestrator.JobStart)
+        return
+    case "execute":
+        enqueue(w, r, id, orchestrator.JobExecute)
+        return
+    case "resume":
+        // resumed runs wait for a worker like any other
+        enqueue(w, r, id, orchestrator.JobResume)
+        return
+    case "pause":
+        err = orch.Pause(id)
+    case "cancel":
+        err = orch.Cancel(id)
+    default:
+        unknownMethod(w, action)
+        return
+    }
+    if err != nil { respondErr(w, err, http.StatusConflict, codeConflict); return }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusAccepted)
+    respondJSON(w, map[string]any{"task_id": id, "action": action})
+}
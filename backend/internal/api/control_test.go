@@ -0,0 +1,32 @@
+package api
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func TestPauseResumeEndpoints(t *testing.T) {
+    srv := newTestServer(t)
+    // the ask_user step holds the run until the test answers it
+    task := newTaskWith(t, "pause",
+        &models.Step{ID: "hold", Tool: "ask_user", Inputs: map[string]any{"question": "go on?"}},
+        &models.Step{ID: "say", Tool: "echo", Inputs: map[string]any{"text": "after {{step:hold.output}}"}},
+    )
+    base := srv.URL + "/v1/tasks/" + task.ID
+    var e apiError
+    if resp := call(t, http.MethodPost, base+":pause", nil, &e); resp.StatusCode != http.StatusConflict { t.Fatalf("pause idle task: %d %+v", resp.StatusCode, e) }
+    if resp := call(t, http.MethodPost, base+":resume", nil, &e); resp.StatusCode != http.StatusConflict { t.Fatalf("resume unpaused task: %d %+v", resp.StatusCode, e) }
+
+    call(t, http.MethodPost, base+":execute", nil, nil)
+    waitEvent(t, task.ID, `"question"`)
+    if resp := call(t, http.MethodPost, base+":pause", nil, nil); resp.StatusCode != http.StatusAccepted { t.Fatalf("pause: %d", resp.StatusCode) }
+    call(t, http.MethodPost, base+"/steps/hold:answer", map[string]any{"answer": "yes"}, nil)
+    waitEvent(t, task.ID, `"task_status"`, `"PAUSED"`)
+
+    if resp := call(t, http.MethodPost, base+":resume", nil, nil); resp.StatusCode != http.StatusAccepted { t.Fatalf("resume: %d", resp.StatusCode) }
+    if ev := waitEvent(t, task.ID, `"result"`, `"say"`); !strings.Contains(ev, "after yes") { t.Fatalf("result %s", ev) }
+    waitEvent(t, task.ID, `"task_status"`, `"SUCCESS"`)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/scheduler"
+    "github.com/example/agent-orchestrator/internal/workflows"
+)
+
+// Every error response is the same JSON envelope:
+//
+//    {"error": "task is running", "code": "task_running", "problems": ["..."]}
+//
+// error is meant for people and may change; code is stable and is what clients should
+// test. problems lists validation problems one by one (400 and 422 only).
+const (
+    codeInvalidRequest   = "invalid_request"    // 400: malformed body or parameters
+    codeNotFound         = "not_found"          // 404: no such endpoint or action
+    codeTaskNotFound     = "task_not_found"     // 404
+    codeStepNotFound     = "step_not_found"     // 404: not a step of the task's plan
+    codeRunNotFound      = "run_not_found"      // 404
+    codeVersionNotFound  = "version_not_found"  // 404: plan or workflow version
+    codeScheduleNotFound = "schedule_not_found" // 404
+    codeWorkflowNotFound = "workflow_not_found" // 404
+    codeMethodNotAllowed = "method_not_allowed" // 405
+    codeTaskRunning      = "task_running"       // 409: planning, running or waiting for a decision
+    codeTaskQueued       = "task_queued"        // 409: waiting for a worker
+    codeTaskPaused       = "task_paused"        // 409: plan edit of a paused task
+    codeTaskNotRunning   = "task_not_running"   // 409: pause or cancel of an idle task
+    codeTaskNotPaused    = "task_not_paused"    // 409: resume of a task that is not paused
+    codeNoPlan           = "no_plan"            // 409: execute or export without a plan
+    codeStepNotAwaiting  = "step_not_awaiting"  // 409: no pending approval or question
+    codeConflict         = "conflict"           // 409: any other state conflict
+    codeInvalidPlan      = "invalid_plan"       // 422, with problems
+    codeInvalidWorkflow  = "invalid_workflow"   // 422, with problems
+    codeInvalidAnswer    = "invalid_answer"     // 422: not one of the question's choices
+    codePlanningFailed   = "planning_failed"    // 422: the planner gave up
+    codeInternal         = "internal"           // 500
+)
+
+// errorCodes gives the errors of the orchestrator, scheduler and workflow library
+// their status and code.
+var errorCodes = []struct {
+    err    error
+    status int
+    code   string
+}{
+    {orchestrator.ErrNoApprover, http.StatusBadRequest, codeInvalidRequest},
+    {orchestrator.ErrTaskNotFound, http.StatusNotFound, codeTaskNotFound},
+    {orchestrator.ErrStepNotFound, http.StatusNotFound, codeStepNotFound},
+    {scheduler.ErrNotFound, http.StatusNotFound, codeScheduleNotFound},
+    {workflows.ErrNotFound, http.StatusNotFound, codeWorkflowNotFound},
+    {orchestrator.ErrTaskRunning, http.StatusConflict, codeTaskRunning},
+    {orchestrator.ErrAlreadyQueued, http.StatusConflict, codeTaskQueued},
+    {orchestrator.ErrTaskPaused, http.StatusConflict, codeTaskPaused},
+    {orchestrator.ErrNotActive, http.StatusConflict, codeTaskNotRunning},
+    {orchestrator.ErrNotPaused, http.StatusConflict, codeTaskNotPaused},
+    {orchestrator.ErrNoPlan, http.StatusConflict, codeNoPlan},
+    {orchestrator.ErrNotAwaiting, http.StatusConflict, codeStepNotAwaiting},
+    {orchestrator.ErrInvalidAnswer, http.StatusUnprocessableEntity, codeInvalidAnswer},
+}
+
+// classify returns the status, code and problems for err; errors it does not know
+// get status and code.
+func classify(err error, status int, code string) (int, string, []string) {
+    var pe *orchestrator.PlanError
+    var we *workflows.Error
+    switch {
+    case errors.As(err, &pe):
+        return http.StatusUnprocessableEntity, codeInvalidPlan, pe.Problems
+    case errors.As(err, &we):
+        return http.StatusUnprocessableEntity, codeInvalidWorkflow, we.Problems
+    }
+    for _, c := range errorCodes {
+        if errors.Is(err, c.err) { return c.status, c.code, nil }
+    }
+    return status, code, nil
+}
+
+// respondErr writes err as an error envelope; see classify.
+func respondErr(w http.ResponseWriter, err error, status int, code string) {
+    status, code, problems := classify(err, status, code)
+    respondError(w, status, code, err.Error(), problems)
+}
+
+func respondError(w http.ResponseWriter, status int, code, msg string, problems []string) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    body := map[string]any{"error": msg, "code": code}
+    if len(problems) > 0 { body["problems"] = problems }
+    json.NewEncoder(w).Encode(body)
+}
+
+// decodeBody decodes a JSON request body into v, answering 400 when it cannot.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+    if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+        respondError(w, http.StatusBadRequest, codeInvalidRequest, "invalid body: "+err.Error(), nil)
+        return false
+    }
+    return true
+}
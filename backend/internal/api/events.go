@@ -0,0 +1,209 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+var (
+    shuttingDown = make(chan struct{})
+    shutdownOnce sync.Once
+)
+
+// CloseStreams ends open SSE and WebSocket streams so http.Server.Shutdown can
+// complete; register it with Server.RegisterOnShutdown. WebSocket clients receive a
+// "shutdown" event before the connection closes.
+func CloseStreams() { shutdownOnce.Do(func() { close(shuttingDown) }) }
+
+// startSSE sets the stream headers; it fails when the writer cannot flush.
+func startSSE(w http.ResponseWriter) (http.Flusher, bool) {
+    flusher, ok := w.(http.Flusher)
+    if !ok { respondError(w, http.StatusInternalServerError, codeInternal, "stream unsupported", nil); return nil, false }
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.Header().Set("Connection", "keep-alive")
+    return flusher, true
+}
+
+// lastEventID reads the resume cursor: EventSource sends Last-Event-ID when it
+// reconnects; the query parameter covers clients that cannot set headers.
+func lastEventID(r *http.Request) uint64 {
+    id, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+    if v := r.URL.Query().Get("last_event_id"); v != "" { id, _ = strconv.ParseUint(v, 10, 64) }
+    return id
+}
+
+// streamEvents writes sub's events as "update" messages until the client goes away.
+// keep (optional) filters records. After a gap event, resync (optional) lets the
+// caller bring the client up to date instead of the records that follow the gap;
+// without it they are sent.
+func streamEvents(w http.ResponseWriter, r *http.Request, flusher http.Flusher, sub *orchestrator.Subscription, keep func(orchestrator.Record) bool, resync func()) {
+    ticker := time.NewTicker(20 * time.Second)
+    defer ticker.Stop()
+    sent := sub.Cursor()
+    for {
+        select {
+        case <-r.Context().Done():
+            return
+        case <-shuttingDown:
+            return
+        case <-sub.C():
+            recs, gap := sub.Next()
+            if gap != nil {
+                // the client fell further behind than the replay log reaches
+                b, _ := json.Marshal(gap)
+                writeSSE(w, 0, "gap", b)
+                if resync != nil {
+                    resync()
+                    flusher.Flush()
+                    continue
+                }
+            }
+            for _, rec := range recs {
+                if keep != nil && !keep(rec) { continue }
+                writeSSE(w, rec.ID, "update", rec.Data)
+                sent = rec.ID
+            }
+            flusher.Flush()
+        case <-ticker.C:
+            // an id-only message moves the client's Last-Event-ID past filtered
+            // events, so a reconnect does not replay (or miss) them
+            if c := sub.Cursor(); c > sent {
+                w.Write([]byte("id: " + strconv.FormatUint(c, 10) + "\n\n"))
+                sent = c
+            }
+            w.Write([]byte(": ping\n\n"))
+            flusher.Flush()
+        }
+    }
+}
+
+// eventFilter selects firehose events. Empty sets match everything.
+type eventFilter struct {
+    types    map[string]bool
+    statuses map[string]bool
+    tools    map[string]bool
+    prefix   string
+}
+
+func parseEventFilter(r *http.Request) *eventFilter {
+    q := r.URL.Query()
+    set := func(key string) map[string]bool {
+        var m map[string]bool
+        for _, v := range strings.Split(q.Get(key), ",") {
+            if v = strings.TrimSpace(v); v == "" { continue }
+            if m == nil { m = map[string]bool{} }
+            m[v] = true
+        }
+        return m
+    }
+    f := &eventFilter{types: set("type"), statuses: set("status"), tools: set("tool"), prefix: q.Get("task")}
+    for s := range f.statuses {
+        // statuses are matched case-insensitively
+        delete(f.statuses, s)
+        f.statuses[strings.ToUpper(s)] = true
+    }
+    return f
+}
+
+// match reports whether rec passes the filter. Status compares against the task's
+// status when the event was published, so replayed events match as they did live;
+// tool only matches events about a step (step_status, result, token, map_item) whose
+// tool, or map sub-step tool, is listed.
+func (f *eventFilter) match(rec orchestrator.Record) bool {
+    if !strings.HasPrefix(rec.TaskID, f.prefix) { return false }
+    if f.types != nil && !f.types[rec.Event] { return false }
+    if f.statuses != nil && !f.statuses[rec.Status] { return false }
+    if f.tools != nil {
+        t, ok := orch.GetTask(rec.TaskID)
+        if !ok { return false }
+        stepID := eventStepID(rec.Data)
+        if stepID == "" { return false }
+        for _, tool := range stepTools(t, stepID) {
+            if f.tools[tool] { return true }
+        }
+        return false
+    }
+    return true
+}
+
+// eventStepID extracts the step an event is about, if any.
+func eventStepID(data []byte) string {
+    var ev struct {
+        Event   string `json:"event"`
+        Payload struct {
+            ID     string `json:"id"`
+            StepID string `json:"step_id"`
+        } `json:"payload"`
+    }
+    if json.Unmarshal(data, &ev) != nil { return "" }
+    if ev.Payload.StepID != "" { return ev.Payload.StepID }
+    if ev.Event == "step_status" { return ev.Payload.ID }
+    return ""
+}
+
+// stepTools returns the tools involved in stepID, which may name a map sub-step as
+// "STEP[i].SUB".
+func stepTools(t *models.Task, stepID string) []string {
+    if t.Plan == nil { return nil }
+    base, sub := stepID, ""
+    if i := strings.IndexByte(stepID, '['); i != -1 {
+        base = stepID[:i]
+        if j := strings.Index(stepID, "]."); j != -1 { sub = stepID[j+2:] }
+    }
+    for _, s := range t.Plan.Steps {
+        if s.ID != base { continue }
+        tools := []string{s.Tool}
+        if s.Map != nil && sub != "" {
+            for _, ss := range s.Map.Steps {
+                if ss.ID == sub { tools = append(tools, ss.Tool) }
+            }
+        }
+        return tools
+    }
+    return nil
+}
+
+// handleTaskEvents streams one task's events: GET /v1/tasks/{id}/events. A new client
+// starts with a "snapshot" of the task; a resuming one (Last-Event-ID) replays from its
+// cursor.
+func handleTaskEvents(w http.ResponseWriter, r *http.Request) {
+    id := r.PathValue("id")
+    if _, ok := taskOr404(w, r); !ok { return }
+    flusher, ok := startSSE(w)
+    if !ok { return }
+    lastID := lastEventID(r)
+    sub := orch.Subscribe(id, lastID)
+    defer sub.Close()
+    snapshot := func() {
+        if t, ok := orch.GetTask(id); ok {
+            b, _ := json.Marshal(t)
+            writeSSE(w, sub.Cursor(), "snapshot", b)
+        }
+    }
+    if lastID == 0 {
+        snapshot()
+        flusher.Flush()
+    }
+    streamEvents(w, r, flusher, sub, nil, snapshot)
+}
+
+// handleFirehose streams events for all tasks: GET /v1/events?type=&status=&tool=&task=
+func handleFirehose(w http.ResponseWriter, r *http.Request) {
+    flusher, ok := startSSE(w)
+    if !ok { return }
+    f := parseEventFilter(r)
+    sub := orch.Subscribe(orchestrator.AllTasks, lastEventID(r))
+    defer sub.Close()
+    // tell the client the stream is live before the first event arrives
+    w.Write([]byte(": connected\n\n"))
+    flusher.Flush()
+    streamEvents(w, r, flusher, sub, f.match, nil)
+}
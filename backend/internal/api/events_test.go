@@ -0,0 +1,96 @@
+package api
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "strconv"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+// eventsOf reads n stream messages and returns "task_id:event[:status]" for each.
+func eventsOf(t *testing.T, ch <-chan sseEvent, n int) []string {
+    t.Helper()
+    var out []string
+    for len(out) < n {
+        ev := next(t, ch)
+        var e struct {
+            Event   string `json:"event"`
+            TaskID  string `json:"task_id"`
+            Payload struct {
+                Status string `json:"status"`
+            } `json:"payload"`
+        }
+        json.Unmarshal([]byte(ev.Data), &e)
+        s := e.TaskID + ":" + e.Event
+        if e.Payload.Status != "" { s += ":" + e.Payload.Status }
+        out = append(out, s)
+    }
+    return out
+}
+
+func TestFirehoseFilters(t *testing.T) {
+    srv := newTestServer(t)
+    byType := openStream(t, srv.URL+"/v1/events?type=task_status&task=fh-a", nil)
+    byTool := openStream(t, srv.URL+"/v1/events?tool=echo&type=step_status&task=fh-", nil)
+    byStatus := openStream(t, srv.URL+"/v1/events?status=success&task=fh-", nil)
+
+    a := newTask(t, "fh-a")
+    b := newTask(t, "fh-b")
+    sub := orch.Subscribe(orchestrator.AllTasks, 0)
+    before := sub.Cursor()
+    sub.Close()
+    orch.ExecutePlan(context.Background(), b.ID)
+    orch.ExecutePlan(context.Background(), a.ID)
+
+    // only task_status events, only of fh-a*
+    got := eventsOf(t, byType, 4)
+    want := []string{a.ID + ":task_status:PENDING", a.ID + ":task_status:PLANNED", a.ID + ":task_status:RUNNING", a.ID + ":task_status:SUCCESS"}
+    if strings.Join(got, " ") != strings.Join(want, " ") { t.Fatalf("type filter: %v", got) }
+
+    // step_status of echo steps for both tasks
+    got = eventsOf(t, byTool, 4)
+    if strings.Join(got, " ") != b.ID+":step_status:RUNNING "+b.ID+":step_status:SUCCESS "+a.ID+":step_status:RUNNING "+a.ID+":step_status:SUCCESS" { t.Fatalf("tool filter: %v", got) }
+
+    // the status filter looks at the task's status when each event was published:
+    // only the final task_status events were published while the tasks had succeeded
+    got = eventsOf(t, byStatus, 2)
+    if strings.Join(got, " ") != b.ID+":task_status:SUCCESS "+a.ID+":task_status:SUCCESS" { t.Fatalf("status filter: %v", got) }
+
+    // replayed events match as they did live, although the task has finished since
+    running := openStream(t, srv.URL+"/v1/events?status=running&task="+a.ID+"&last_event_id="+strconv.FormatUint(before, 10), nil)
+    got = eventsOf(t, running, 6)
+    want = []string{a.ID + ":task_status:RUNNING", a.ID + ":run_status:RUNNING", a.ID + ":step_status:RUNNING", a.ID + ":result", a.ID + ":step_status:SUCCESS", a.ID + ":run_status:SUCCESS"}
+    if strings.Join(got, " ") != strings.Join(want, " ") { t.Fatalf("replayed status filter: %v", got) }
+}
+
+func TestTaskEvents(t *testing.T) {
+    srv := newTestServer(t)
+    task := newTask(t, "ev")
+    stream := openStream(t, srv.URL+"/v1/tasks/"+task.ID+"/events", nil)
+    snap := next(t, stream)
+    if snap.Event != "snapshot" || !strings.Contains(snap.Data, `"id":"`+task.ID+`"`) { t.Fatalf("first message %+v", snap) }
+    orch.ExecutePlan(context.Background(), task.ID)
+    var last sseEvent
+    for {
+        last = next(t, stream)
+        if strings.Contains(last.Data, `"status":"SUCCESS"`) && strings.Contains(last.Data, "task_status") { break }
+    }
+
+    // resuming from the snapshot's cursor replays the run without a new snapshot
+    resumed := openStream(t, srv.URL+"/v1/tasks/"+task.ID+"/events", http.Header{"Last-Event-Id": {snap.ID}})
+    first := next(t, resumed)
+    if first.Event != "update" || first.ID == "" { t.Fatalf("resume started with %+v", first) }
+    for first.ID != last.ID { first = next(t, resumed) }
+
+    // a cursor from before a restart (IDs start over) gets a gap and a fresh snapshot
+    stale := openStream(t, srv.URL+"/v1/tasks/"+task.ID+"/events", http.Header{"Last-Event-Id": {"999999999"}})
+    if gap := next(t, stale); gap.Event != "gap" || !strings.Contains(gap.Data, `"last_event_id":999999999`) { t.Fatalf("stale cursor: %+v", gap) }
+    if snap := next(t, stale); snap.Event != "snapshot" || !strings.Contains(snap.Data, `"status":"SUCCESS"`) { t.Fatalf("after gap: %+v", snap) }
+
+    resp := call(t, http.MethodGet, srv.URL+"/v1/tasks/nope/events", nil, nil)
+    if resp.StatusCode != http.StatusNotFound { t.Fatalf("unknown task: %d", resp.StatusCode) }
+}
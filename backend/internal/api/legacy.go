@@ -0,0 +1,79 @@
+package api
+
+import (
+    "net/http"
+    "net/url"
+    "strings"
+)
+
+// legacyPrefixes are the mux patterns of the routes from before /v1. They serve the
+// same handlers, with Deprecation (RFC 9745) and Link: <...>; rel="successor-version"
+// headers pointing at the /v1 route.
+var legacyPrefixes = []string{"/tasks", "/tasks/", "/schedules", "/schedules/", "/workflows", "/workflows/", "/queue", "/events", "/ws"}
+
+// deprecatedSince is the Deprecation header of the old routes: 2026-10-18.
+const deprecatedSince = "@1792281600"
+
+// legacyPath maps a route from before /v1 to its /v1 path. Verbs in the path become
+// custom methods and everything else gains the prefix:
+//
+//    /tasks/start/{id}                     /v1/tasks/{id}:start (also plan, execute)
+//    /tasks/{id}/pause                     /v1/tasks/{id}:pause (also resume, cancel)
+//    /tasks/{id}/steps/{stepID}/approve    /v1/tasks/{id}/steps/{stepID}:approve (also reject, answer)
+//    /tasks/{id}/plan/export               /v1/tasks/{id}/plan:export
+//    /schedules/{id}/run                   /v1/schedules/{id}:run
+//    /workflows/{name}/run                 /v1/workflows/{name}:run (also export)
+//
+// It reports false for paths that are not old routes.
+func legacyPath(path string) (string, bool) {
+    p := strings.Split(strings.Trim(path, "/"), "/")
+    switch p[0] {
+    case "tasks", "schedules", "workflows", "queue", "events", "ws":
+    default:
+        return "", false
+    }
+    switch {
+    case len(p) == 2 && p[0] == "tasks" && (p[1] == "start" || p[1] == "plan" || p[1] == "execute") && strings.HasSuffix(path, "/"):
+        // /tasks/start/ without a task ID
+        return "", false
+    case len(p) == 3 && p[0] == "tasks" && (p[1] == "start" || p[1] == "plan" || p[1] == "execute"):
+        return "/v1/tasks/" + p[2] + ":" + p[1], true
+    case len(p) == 3 && p[0] == "tasks" && (p[2] == "pause" || p[2] == "resume" || p[2] == "cancel"):
+        return "/v1/tasks/" + p[1] + ":" + p[2], true
+    case len(p) == 5 && p[0] == "tasks" && p[2] == "steps":
+        return "/v1/tasks/" + p[1] + "/steps/" + p[3] + ":" + p[4], true
+    case len(p) == 4 && p[0] == "tasks" && p[2] == "plan" && p[3] == "export":
+        return "/v1/tasks/" + p[1] + "/plan:export", true
+    case len(p) == 3 && p[0] == "schedules" && p[2] == "run",
+        len(p) == 3 && p[0] == "workflows" && (p[2] == "run" || p[2] == "export"):
+        return "/v1/" + p[0] + "/" + p[1] + ":" + p[2], true
+    }
+    return "/v1/" + strings.Join(p, "/"), true
+}
+
+// legacyAlias serves an old route with the handler of its /v1 route. Creating a task
+// answers 200 as it did before /v1, not 201.
+func legacyAlias(mux *http.ServeMux) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        path, ok := legacyPath(r.URL.Path)
+        if !ok { respondError(w, http.StatusNotFound, codeNotFound, "no such endpoint: "+r.URL.Path, nil); return }
+        w.Header().Set("Deprecation", deprecatedSince)
+        w.Header().Set("Link", "<"+path+`>; rel="successor-version"`)
+        // like http.StripPrefix: a shallow copy with its own URL
+        r2 := new(http.Request)
+        *r2 = *r
+        r2.URL = new(url.URL)
+        *r2.URL = *r.URL
+        r2.URL.Path, r2.URL.RawPath = path, ""
+        if r.Method == http.MethodPost && path == "/v1/tasks" { w = createdAsOK{w} }
+        mux.ServeHTTP(w, r2)
+    })
+}
+
+// createdAsOK turns a 201 into a 200.
+type createdAsOK struct{ http.ResponseWriter }
+
+func (w createdAsOK) WriteHeader(code int) {
+    if code == http.StatusCreated { code = http.StatusOK }
+    w.ResponseWriter.WriteHeader(code)
+}
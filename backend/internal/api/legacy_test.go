@@ -0,0 +1,70 @@
+package api
+
+import (
+    "net/http"
+    "testing"
+)
+
+func TestLegacyPath(t *testing.T) {
+    for path, want := range map[string]string{
+        "/tasks":                        "/v1/tasks",
+        "/tasks/t1":                     "/v1/tasks/t1",
+        "/tasks/start/t1":               "/v1/tasks/t1:start",
+        "/tasks/t1/cancel":              "/v1/tasks/t1:cancel",
+        "/tasks/t1/steps/a/approve":     "/v1/tasks/t1/steps/a:approve",
+        "/tasks/t1/plan/export":         "/v1/tasks/t1/plan:export",
+        "/schedules/s1/run":             "/v1/schedules/s1:run",
+        "/workflows/w/export":           "/v1/workflows/w:export",
+        "/tasks/start/":                 "",
+        "/health":                       "",
+    } {
+        got, ok := legacyPath(path)
+        if got != want || ok != (want != "") { t.Errorf("%s: %q %v, want %q", path, got, ok, want) }
+    }
+}
+
+func TestLegacyRoutes(t *testing.T) {
+    srv := newTestServer(t)
+    // creating a task keeps answering 200 on the old route
+    var task struct{ ID string `json:"id"` }
+    resp := call(t, http.MethodPost, srv.URL+"/tasks", map[string]any{"query": "old client"}, &task)
+    if resp.StatusCode != http.StatusOK || task.ID == "" { t.Fatalf("POST /tasks: %d %+v", resp.StatusCode, task) }
+    if resp.Header.Get("Deprecation") != deprecatedSince || resp.Header.Get("Link") != `</v1/tasks>; rel="successor-version"` { t.Fatalf("headers %v", resp.Header) }
+    if resp := call(t, http.MethodPost, srv.URL+"/v1/tasks", map[string]any{"query": "new client"}, nil); resp.StatusCode != http.StatusCreated || resp.Header.Get("Deprecation") != "" { t.Fatalf("POST /v1/tasks: %d", resp.StatusCode) }
+    if resp := call(t, http.MethodGet, srv.URL+"/tasks/"+task.ID, nil, nil); resp.StatusCode != http.StatusOK || resp.Header.Get("Link") != "</v1/tasks/"+task.ID+`>; rel="successor-version"` { t.Fatalf("GET /tasks/{id}: %d %v", resp.StatusCode, resp.Header) }
+
+    id := newTask(t, "legacy").ID
+    if resp := call(t, http.MethodPost, srv.URL+"/tasks/execute/"+id, nil, nil); resp.StatusCode != http.StatusAccepted { t.Fatalf("POST /tasks/execute/{id}: %d", resp.StatusCode) }
+    waitEvent(t, id, `"task_status"`, `"SUCCESS"`)
+
+    // without a task ID there is no such endpoint
+    var e apiError
+    if resp := call(t, http.MethodPost, srv.URL+"/tasks/start/", nil, &e); resp.StatusCode != http.StatusNotFound || e.Code != codeNotFound { t.Fatalf("POST /tasks/start/: %d %+v", resp.StatusCode, e) }
+}
+
+func TestUnknownRoutes(t *testing.T) {
+    srv := newTestServer(t)
+    id := newTask(t, "unknown").ID
+    // unknown custom methods are no endpoint, whether or not the resource exists
+    for _, path := range []string{
+        "/v1/tasks/" + id + ":bogus",
+        "/v1/tasks/nope:bogus",
+        "/v1/schedules/nope:bogus",
+        "/v1/workflows/nope:bogus",
+        "/v1/tasks/" + id + "/steps/say:bogus",
+        "/tasks/" + id + "/bogus",
+    } {
+        var e apiError
+        if resp := call(t, http.MethodPost, srv.URL+path, nil, &e); resp.StatusCode != http.StatusNotFound || e.Code != codeNotFound { t.Errorf("POST %s: %d %+v", path, resp.StatusCode, e) }
+    }
+
+    // a known path or custom method with the wrong HTTP method is 405
+    for path, allow := range map[string]string{
+        "/v1/tasks/" + id:            "DELETE, GET",
+        "/v1/tasks/" + id + ":start": "POST",
+    } {
+        var e apiError
+        resp := call(t, http.MethodPut, srv.URL+path, nil, &e)
+        if resp.StatusCode != http.StatusMethodNotAllowed || e.Code != codeMethodNotAllowed || resp.Header.Get("Allow") != allow { t.Errorf("PUT %s: %d %+v, Allow %q", path, resp.StatusCode, e, resp.Header.Get("Allow")) }
+    }
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+    "bytes"
+    _ "embed"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/example/agent-orchestrator/internal/jsonschema"
+)
+
+// openapiJSON documents every endpoint and event payload. It is served at
+// /openapi.json, enforced by ValidateRequests and checked against the handlers by
+// openapi_test.go; change it together with the handlers.
+//
+//go:embed openapi.json
+var openapiJSON []byte
+
+// Operation is one documented method and path, e.g. POST /v1/tasks/{taskID}:pause.
+type Operation struct {
+    ID     string
+    Method string
+    Path   string
+    // Spec is the operation object of the document.
+    Spec map[string]any
+
+    segments []string
+    params   []map[string]any
+}
+
+// Spec returns the OpenAPI document as parsed JSON; callers must not modify it.
+func Spec() map[string]any { return spec }
+
+// Operations lists the documented operations sorted by path and method.
+func Operations() []*Operation { return operations }
+
+var (
+    spec       map[string]any
+    operations []*Operation
+)
+
+func init() {
+    if err := json.Unmarshal(openapiJSON, &spec); err != nil { log.Fatalf("openapi.json: %v", err) }
+    paths, _ := spec["paths"].(map[string]any)
+    for path, item := range paths {
+        item, _ := item.(map[string]any)
+        for method, o := range item {
+            o, ok := o.(map[string]any)
+            if !ok { continue }
+            op := &Operation{Method: strings.ToUpper(method), Path: path, Spec: o, segments: strings.Split(strings.TrimPrefix(path, "/"), "/")}
+            op.ID, _ = o["operationId"].(string)
+            params, _ := o["parameters"].([]any)
+            for _, p := range params {
+                if pm, ok := p.(map[string]any); ok { op.params = append(op.params, pm) }
+            }
+            operations = append(operations, op)
+        }
+    }
+    sort.Slice(operations, func(i, j int) bool {
+        if operations[i].Path != operations[j].Path { return operations[i].Path < operations[j].Path }
+        return operations[i].Method < operations[j].Method
+    })
+}
+
+// match reports whether path fits the operation's template, returning the path
+// parameters and the number of literal segments (more literals = better match). A
+// parameter may be followed by a custom method, as in {taskID}:start, which counts as
+// a literal. A trailing slash is a segment of its own, so /v1/tasks/ is not /v1/tasks.
+func (op *Operation) match(path string) (map[string]string, int, bool) {
+    segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+    if len(segs) != len(op.segments) { return nil, 0, false }
+    vars := map[string]string{}
+    literals := 0
+    for i, s := range op.segments {
+        if strings.HasPrefix(s, "{") {
+            end := strings.Index(s, "}")
+            v, ok := strings.CutSuffix(segs[i], s[end+1:])
+            if !ok || v == "" { return nil, 0, false }
+            vars[s[1:end]] = v
+            if end+1 < len(s) { literals++ }
+            continue
+        }
+        if s != segs[i] { return nil, 0, false }
+        literals++
+    }
+    return vars, literals, true
+}
+
+// findOperation returns the operation for method and path, or the methods allowed on
+// path when only the method is wrong (those of the templates that match it best).
+func findOperation(method, path string) (*Operation, map[string]string, []string) {
+    var best *Operation
+    var bestVars map[string]string
+    bestLiterals, allowedLiterals := -1, -1
+    var allowed []string
+    for _, op := range operations {
+        vars, literals, ok := op.match(path)
+        if !ok { continue }
+        if op.Method != method {
+            if literals > allowedLiterals { allowed, allowedLiterals = nil, literals }
+            if literals == allowedLiterals { allowed = append(allowed, op.Method) }
+            continue
+        }
+        if literals > bestLiterals { best, bestVars, bestLiterals = op, vars, literals }
+    }
+    return best, bestVars, allowed
+}
+
+// hasCustomMethod reports whether an operation on path ends with the custom method,
+// as {taskID}:start does for "start".
+func hasCustomMethod(path, method string) bool {
+    for _, op := range operations {
+        if _, _, ok := op.match(path); ok && strings.HasSuffix(op.Path, "}:"+method) { return true }
+    }
+    return false
+}
+
+// maxBodyBytes bounds request bodies read for validation (contexts may carry base64 PDFs).
+const maxBodyBytes = 32 << 20
+
+// ValidateRequests enforces the OpenAPI document: requests for undocumented paths or
+// custom methods (as in :start) get 404 and undocumented methods 405; path and query parameters and JSON bodies that do
+// not match their schemas get 400 with the problems listed. Deprecated routes from
+// before /v1 are checked as the /v1 route they stand for. Valid requests reach next
+// with the body intact.
+func ValidateRequests(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Method == http.MethodOptions { next.ServeHTTP(w, r); return }
+        path := r.URL.Path
+        if v1, ok := legacyPath(path); ok { path = v1 }
+        op, vars, allowed := findOperation(r.Method, path)
+        if op == nil {
+            if _, method := customMethod(path[strings.LastIndex(path, "/")+1:]); method != "" && len(allowed) > 0 && !hasCustomMethod(path, method) {
+                // POST /v1/tasks/{id}:bogus is no endpoint rather than a wrong method
+                unknownMethod(w, method)
+                return
+            }
+            if len(allowed) > 0 {
+                w.Header().Set("Allow", strings.Join(allowed, ", "))
+                respondError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, fmt.Sprintf("%s not allowed on %s", r.Method, r.URL.Path), nil)
+                return
+            }
+            respondError(w, http.StatusNotFound, codeNotFound, "no such endpoint: "+r.URL.Path, nil)
+            return
+        }
+        problems := op.checkParams(r, vars)
+        body, bodyProblems, err := op.checkBody(r)
+        if err != nil { respondError(w, http.StatusBadRequest, codeInvalidRequest, err.Error(), nil); return }
+        problems = append(problems, bodyProblems...)
+        if len(problems) > 0 {
+            respondError(w, http.StatusBadRequest, codeInvalidRequest, "invalid request: "+strings.Join(problems, "; "), problems)
+            return
+        }
+        if body != nil {
+            r.Body = io.NopCloser(bytes.NewReader(body))
+            r.ContentLength = int64(len(body))
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// checkParams validates path and query parameters. Query values are converted to
+// the schema's type first; undocumented query parameters are ignored.
+func (op *Operation) checkParams(r *http.Request, vars map[string]string) []string {
+    var problems []string
+    q := r.URL.Query()
+    for _, p := range op.params {
+        name, _ := p["name"].(string)
+        schema, _ := p["schema"].(map[string]any)
+        var raw string
+        var present bool
+        switch p["in"] {
+        case "path":
+            raw, present = vars[name], true
+        case "query":
+            present = q.Has(name)
+            raw = q.Get(name)
+        default:
+            continue
+        }
+        if !present {
+            if req, _ := p["required"].(bool); req { problems = append(problems, p["in"].(string)+"."+name+": is required") }
+            continue
+        }
+        v, err := paramValue(schema, raw)
+        if err == nil { err = jsonschema.ValidateWithRoot(spec, schema, v) }
+        problems = append(problems, prefixProblems(p["in"].(string)+"."+name, err)...)
+    }
+    return problems
+}
+
+// paramValue converts a parameter to the JSON type its schema expects.
+func paramValue(schema map[string]any, raw string) (any, error) {
+    switch schema["type"] {
+    case "integer", "number":
+        f, err := strconv.ParseFloat(raw, 64)
+        if err != nil { return nil, fmt.Errorf("expected %s, got %q", schema["type"], raw) }
+        return f, nil
+    case "boolean":
+        b, err := strconv.ParseBool(raw)
+        if err != nil { return nil, fmt.Errorf("expected boolean, got %q", raw) }
+        return b, nil
+    }
+    return raw, nil
+}
+
+// checkBody validates a JSON request body against the operation's schema and returns
+// the bytes read. Bodies of other documented types (YAML workflow files) pass as they
+// are; handlers decode JSON whatever the Content-Type says, so undeclared types are
+// checked as JSON.
+func (op *Operation) checkBody(r *http.Request) ([]byte, []string, error) {
+    rb, _ := op.Spec["requestBody"].(map[string]any)
+    if rb == nil { return nil, nil, nil }
+    content, _ := rb["content"].(map[string]any)
+    ctype := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+    if _, ok := content[ctype]; ok && !strings.HasSuffix(ctype, "json") { return nil, nil, nil }
+    media, _ := content["application/json"].(map[string]any)
+    schema, _ := media["schema"].(map[string]any)
+    if schema == nil { return nil, nil, nil }
+    body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+    if err != nil { return nil, nil, err }
+    if len(body) > maxBodyBytes { return nil, nil, errors.New("request body too large") }
+    if len(bytes.TrimSpace(body)) == 0 {
+        if req, _ := rb["required"].(bool); req { return body, []string{"body: is required"}, nil }
+        return body, nil, nil
+    }
+    var v any
+    if err := json.Unmarshal(body, &v); err != nil { return body, []string{"body: invalid JSON: " + err.Error()}, nil }
+    return body, prefixProblems("body", jsonschema.ValidateWithRoot(spec, schema, v)), nil
+}
+
+// prefixProblems renders validation errors with where the value came from, e.g.
+// "body.context: expected object, got string".
+func prefixProblems(prefix string, err error) []string {
+    if err == nil { return nil }
+    var errs jsonschema.Errors
+    if !errors.As(err, &errs) { return []string{prefix + ": " + err.Error()} }
+    out := make([]string, len(errs))
+    for i, e := range errs { out[i] = prefix + strings.TrimPrefix(e.Path, "$") + ": " + e.Message }
+    return out
+}
+
+// serveSpec serves GET /openapi.json.
+func serveSpec(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet { w.WriteHeader(http.StatusMethodNotAllowed); return }
+    w.Header().Set("Content-Type", "application/json")
+    w.Write(openapiJSON)
+}
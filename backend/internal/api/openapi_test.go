@@ -0,0 +1,206 @@
+package api
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/jsonschema"
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func TestOpenAPIDocument(t *testing.T) {
+    seen := map[string]string{}
+    for _, op := range Operations() {
+        where := op.Method + " " + op.Path
+        if op.ID == "" { t.Errorf("%s: no operationId", where) }
+        if prev, dup := seen[op.ID]; dup && op.ID != "" { t.Errorf("%s: operationId %s also used by %s", where, op.ID, prev) }
+        seen[op.ID] = where
+        if r, _ := op.Spec["responses"].(map[string]any); len(r) == 0 { t.Errorf("%s: no responses", where) }
+    }
+}
+
+// TestOpenAPIOperations calls every documented operation against the routes
+// RegisterRoutes serves: with the IDs of real resources, with IDs that do not exist,
+// and through a sample of the deprecated routes from before /v1. Statuses, content
+// types and JSON bodies must be documented, and so must the events the calls cause.
+func TestOpenAPIOperations(t *testing.T) {
+    srv := newTestServer(t)
+    events := openStream(t, srv.URL+"/v1/events", nil)
+    ids := openAPIFixtures(t, srv.URL)
+
+    // unknown IDs first, while nothing has changed; deletions go last so the other
+    // operations still find their resources, and replanning after them since it
+    // replaces the fixture's steps
+    ops := append([]*Operation(nil), Operations()...)
+    for _, op := range ops { callMissing(t, srv.URL, op) }
+    for _, l := range legacyRoutes { callLegacy(t, srv.URL, l.path, l.opID, ids) }
+    rank := func(op *Operation) int {
+        switch {
+        case op.ID == "planTask" || op.ID == "startTask":
+            return 2
+        case op.Method == http.MethodDelete:
+            return 1
+        }
+        return 0
+    }
+    sort.SliceStable(ops, func(i, j int) bool { return rank(ops[i]) < rank(ops[j]) })
+    for _, op := range ops {
+        path := fillPath(op.Path, ids)
+        if op.ID == "deleteTask" {
+            // a task of its own, so the fixture survives for the operations after it
+            path = fillPath(op.Path, map[string]string{"taskID": newTask(t, "openapi-delete").ID})
+        }
+        if strings.Contains(path, "{") { t.Errorf("%s %s: no fixture for a path parameter", op.Method, op.Path); continue }
+        callOperation(t, srv.URL, op, path, false)
+    }
+    checkEvents(t, events)
+}
+
+// openAPIFixtures creates the resources path parameters refer to and returns their
+// IDs.
+func openAPIFixtures(t *testing.T, base string) map[string]string {
+    t.Helper()
+    task := newTaskWith(t, "openapi",
+        &models.Step{ID: "first", Tool: "echo", Inputs: map[string]any{"text": "one"}},
+        &models.Step{ID: "second", Tool: "echo", Inputs: map[string]any{"text": "two"}},
+    )
+    if resp := call(t, http.MethodPost, base+"/v1/tasks/"+task.ID+":execute", nil, nil); resp.StatusCode != http.StatusAccepted { t.Fatalf("execute fixture: %d", resp.StatusCode) }
+    waitEvent(t, task.ID, `"task_status"`, `"SUCCESS"`)
+    var done models.Task
+    call(t, http.MethodGet, base+"/v1/tasks/"+task.ID, nil, &done)
+    var sc struct{ ID string `json:"id"` }
+    if resp := call(t, http.MethodPost, base+"/v1/schedules", map[string]any{"name": "openapi", "cron": "@yearly", "query": "openapi fixture"}, &sc); resp.StatusCode != http.StatusCreated { t.Fatalf("schedule fixture: %d", resp.StatusCode) }
+    var wf struct{ Name string `json:"name"` }
+    if resp := call(t, http.MethodPost, base+"/v1/workflows", map[string]any{"name": "openapi-fixture", "task_id": task.ID}, &wf); resp.StatusCode != http.StatusCreated { t.Fatalf("workflow fixture: %d", resp.StatusCode) }
+    return map[string]string{
+        "taskID":     task.ID,
+        "stepID":     "second",
+        "runID":      done.LastRun.ID,
+        "version":    "1",
+        "scheduleID": sc.ID,
+        "name":       wf.Name,
+    }
+}
+
+// legacyRoutes samples the deprecated routes and the operation each stands for.
+var legacyRoutes = []struct{ path, opID string }{
+    {"/tasks", "listTasks"},
+    {"/tasks/{taskID}", "getTask"},
+    {"/tasks/{taskID}/runs", "listRuns"},
+    {"/tasks/{taskID}/plan/export", "exportPlan"},
+    {"/tasks/{taskID}/pause", "pauseTask"},
+    {"/tasks/{taskID}/steps/{stepID}/approve", "approveStep"},
+    {"/queue", "getQueue"},
+    {"/schedules/{scheduleID}/tasks", "listScheduleTasks"},
+    {"/workflows/{name}", "getWorkflow"},
+    {"/workflows/{name}/export", "exportWorkflow"},
+}
+
+func fillPath(template string, ids map[string]string) string {
+    for name, id := range ids { template = strings.ReplaceAll(template, "{"+name+"}", id) }
+    return template
+}
+
+// callMissing calls op with IDs that do not exist, which must answer a documented 404.
+func callMissing(t *testing.T, base string, op *Operation) {
+    t.Helper()
+    if !strings.Contains(op.Path, "{") { return }
+    path := op.Path
+    for _, p := range []string{"taskID", "stepID", "runID", "scheduleID", "name"} { path = strings.ReplaceAll(path, "{"+p+"}", "openapi-missing") }
+    path = strings.ReplaceAll(path, "{version}", "999")
+    if status := callOperation(t, base, op, path, false); status != http.StatusNotFound && status != 0 {
+        t.Errorf("%s %s: unknown IDs answered %d, not 404", op.Method, op.Path, status)
+    }
+}
+
+// callLegacy calls a deprecated route for the operation with ID opID.
+func callLegacy(t *testing.T, base, template, opID string, ids map[string]string) {
+    t.Helper()
+    for _, op := range Operations() {
+        if op.ID == opID { callOperation(t, base, op, fillPath(template, ids), true); return }
+    }
+    t.Errorf("%s: no operation %s", template, opID)
+}
+
+// callOperation makes one request for op, with an example body built from its
+// request schema, and checks the response against the document. It returns the
+// status, or 0 when the request was not served.
+func callOperation(t *testing.T, base string, op *Operation, path string, deprecated bool) int {
+    t.Helper()
+    where := op.Method + " " + op.Path
+    if deprecated { where = op.Method + " " + path + " (deprecated " + op.ID + ")" }
+    var body io.Reader
+    if schema := requestSchema(op); schema != nil {
+        b, _ := json.Marshal(jsonschema.Example(map[string]any{"allOf": []any{schema}, "components": Spec()["components"]}))
+        body = bytes.NewReader(b)
+    }
+    req, _ := http.NewRequest(op.Method, base+path, body)
+    if body != nil { req.Header.Set("Content-Type", "application/json") }
+    req.Header.Set("X-Author", "openapi-test")
+    resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+    if err != nil { t.Errorf("%s: %v", where, err); return 0 }
+    defer resp.Body.Close()
+    ctype := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+    if deprecated && resp.Header.Get("Deprecation") == "" { t.Errorf("%s: no Deprecation header", where) }
+    // streams stay open, so only other responses are read
+    var b []byte
+    if ctype != "text/event-stream" { b, _ = io.ReadAll(io.LimitReader(resp.Body, 1<<20)) }
+    var e apiError
+    json.Unmarshal(b, &e)
+    // a real route must never reach the router's own 404 or 405
+    if e.Code == "not_found" || e.Code == "method_not_allowed" || (resp.StatusCode == http.StatusNotFound && e.Code == "") {
+        t.Errorf("%s: not served: %d %s", where, resp.StatusCode, bytes.TrimSpace(b))
+        return 0
+    }
+    responses, _ := op.Spec["responses"].(map[string]any)
+    doc, ok := responses[strconv.Itoa(resp.StatusCode)].(map[string]any)
+    if !ok { t.Errorf("%s: status %d is not documented: %s", where, resp.StatusCode, bytes.TrimSpace(b)); return resp.StatusCode }
+    content, _ := doc["content"].(map[string]any)
+    if len(content) == 0 { return resp.StatusCode }
+    media, ok := content[ctype].(map[string]any)
+    if !ok { t.Errorf("%s: %d answered %s, not documented", where, resp.StatusCode, ctype); return resp.StatusCode }
+    schema, _ := media["schema"].(map[string]any)
+    if ctype != "application/json" || schema == nil { return resp.StatusCode }
+    var v any
+    if err := json.Unmarshal(b, &v); err != nil { t.Errorf("%s: %d: invalid JSON: %v", where, resp.StatusCode, err); return resp.StatusCode }
+    if err := jsonschema.ValidateWithRoot(Spec(), schema, v); err != nil { t.Errorf("%s: %d response: %v", where, resp.StatusCode, err) }
+    return resp.StatusCode
+}
+
+// requestSchema is the JSON body schema of op, if it takes one.
+func requestSchema(op *Operation) map[string]any {
+    rb, _ := op.Spec["requestBody"].(map[string]any)
+    content, _ := rb["content"].(map[string]any)
+    media, _ := content["application/json"].(map[string]any)
+    schema, _ := media["schema"].(map[string]any)
+    return schema
+}
+
+// checkEvents validates the firehose's events against the Event schema until the
+// stream has been quiet for a while.
+func checkEvents(t *testing.T, events <-chan sseEvent) {
+    t.Helper()
+    schema := map[string]any{"$ref": "#/components/schemas/Event"}
+    n := 0
+    for {
+        select {
+        case ev, ok := <-events:
+            if !ok { t.Fatal("firehose ended") }
+            var v any
+            if err := json.Unmarshal([]byte(ev.Data), &v); err != nil { t.Errorf("event %s: invalid JSON: %v", ev.Event, err); continue }
+            n++
+            name, _ := v.(map[string]any)["event"].(string)
+            if err := jsonschema.ValidateWithRoot(Spec(), schema, v); err != nil { t.Errorf("event %s: %v: %s", name, err, ev.Data) }
+        case <-time.After(500 * time.Millisecond):
+            if n == 0 { t.Error("no events on the firehose") }
+            return
+        }
+    }
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+    "fmt"
+    "net/http"
+    "strconv"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/workflows"
+)
+
+// Plan routes:
+//
+//    GET    /v1/tasks/{id}/plan                    current plan and version
+//    PUT    /v1/tasks/{id}/plan                    replace the plan
+//    GET    /v1/tasks/{id}/plan/versions[/{n}]     plan history
+//    GET    /v1/tasks/{id}/plan:export             as a workflow file, ?format=yaml|json
+//    POST   /v1/tasks/{id}/plan/steps              insert {step, after?|before?}
+//    PATCH  /v1/tasks/{id}/plan/steps/{stepID}     JSON merge patch of one step
+//    DELETE /v1/tasks/{id}/plan/steps/{stepID}
+//
+// Edits are attributed to the X-Author header and answer with the new version; see
+// respondErr for the errors.
+
+func getPlan(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    respondJSON(w, map[string]any{"version": t.PlanVersion, "plan": t.Plan})
+}
+
+func replacePlan(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    var plan models.Plan
+    if !decodeBody(w, r, &plan) { return }
+    v, err := orch.EditPlan(t.ID, author(r), "replace_plan", func(p *models.Plan) error {
+        p.Steps = plan.Steps
+        return nil
+    })
+    respondPlanEdit(w, v, err)
+}
+
+func listPlanVersions(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    respondJSON(w, orch.PlanVersions(t.ID))
+}
+
+func getPlanVersion(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    versions := orch.PlanVersions(t.ID)
+    n, err := strconv.Atoi(r.PathValue("n"))
+    if err != nil || n < 1 || n > len(versions) { respondError(w, http.StatusNotFound, codeVersionNotFound, "plan version not found", nil); return }
+    respondJSON(w, versions[n-1])
+}
+
+func exportPlan(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    if t.Plan == nil { respondErr(w, orchestrator.ErrNoPlan, http.StatusConflict, codeNoPlan); return }
+    respondFile(w, workflows.FileForTask(t), r.URL.Query().Get("format"))
+}
+
+func insertStep(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    var req struct {
+        Step   *models.Step `json:"step"`
+        After  string       `json:"after"`
+        Before string       `json:"before"`
+    }
+    if !decodeBody(w, r, &req) { return }
+    if req.Step == nil { respondError(w, http.StatusBadRequest, codeInvalidRequest, "missing step", nil); return }
+    v, err := orch.EditPlan(t.ID, author(r), "insert_step", func(p *models.Plan) error {
+        at := len(p.Steps)
+        switch {
+        case req.After != "":
+            i := orchestrator.StepIndex(p, req.After)
+            if i == -1 { return fmt.Errorf("after %q: %w", req.After, orchestrator.ErrStepNotFound) }
+            at = i + 1
+        case req.Before != "":
+            i := orchestrator.StepIndex(p, req.Before)
+            if i == -1 { return fmt.Errorf("before %q: %w", req.Before, orchestrator.ErrStepNotFound) }
+            at = i
+        }
+        p.Steps = append(p.Steps[:at], append([]*models.Step{req.Step}, p.Steps[at:]...)...)
+        return nil
+    })
+    respondPlanEdit(w, v, err)
+}
+
+// editStep serves PATCH and DELETE /v1/tasks/{id}/plan/steps/{stepID}.
+func editStep(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    stepID := r.PathValue("stepID")
+    var patch map[string]any
+    if r.Method == http.MethodPatch && !decodeBody(w, r, &patch) { return }
+    action := "update_step"
+    if r.Method == http.MethodDelete { action = "delete_step" }
+    v, err := orch.EditPlan(t.ID, author(r), action, func(p *models.Plan) error {
+        if len(p.Steps) == 0 { return orchestrator.ErrNoPlan }
+        i := orchestrator.StepIndex(p, stepID)
+        if i == -1 { return fmt.Errorf("%q: %w", stepID, orchestrator.ErrStepNotFound) }
+        if patch == nil {
+            p.Steps = append(p.Steps[:i], p.Steps[i+1:]...)
+            return nil
+        }
+        updated, err := orchestrator.PatchStep(p.Steps[i], patch)
+        if err != nil { return err }
+        p.Steps[i] = updated
+        return nil
+    })
+    respondPlanEdit(w, v, err)
+}
+
+// author attributes an edit: the X-Author header, else "anonymous".
+func author(r *http.Request) string {
+    if a := r.Header.Get("X-Author"); a != "" { return a }
+    return "anonymous"
+}
+
+// respondPlanEdit writes the new plan version, or the error: 404 unknown task/step,
+// 409 running task or missing plan, 422 invalid plan.
+func respondPlanEdit(w http.ResponseWriter, v *models.PlanVersion, err error) {
+    if err != nil { respondErr(w, err, http.StatusBadRequest, codeInvalidRequest); return }
+    respondJSON(w, v)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+    "net/http"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func TestPlanEditing(t *testing.T) {
+    srv := newTestServer(t)
+    task := newTask(t, "plans")
+    base := srv.URL + "/v1/tasks/" + task.ID + "/plan"
+
+    var v models.PlanVersion
+    step := map[string]any{"id": "first", "tool": "echo", "inputs": map[string]any{"text": "one"}}
+    if resp := call(t, http.MethodPost, base+"/steps", map[string]any{"step": step, "before": "say"}, &v); resp.StatusCode != http.StatusOK || v.Version != 2 {
+        t.Fatalf("insert: %d %+v", resp.StatusCode, v)
+    }
+    if resp := call(t, http.MethodPatch, base+"/steps/say", map[string]any{"inputs": map[string]any{"text": "{{step:first.output}}"}}, &v); resp.StatusCode != http.StatusOK {
+        t.Fatalf("patch: %d", resp.StatusCode)
+    }
+    if len(v.Diff) != 1 || v.Diff[0].Op != "change" || v.Diff[0].Field != "inputs.text" { t.Fatalf("patch diff %+v", v.Diff) }
+
+    var plan struct {
+        Version int         `json:"version"`
+        Plan    models.Plan `json:"plan"`
+    }
+    call(t, http.MethodGet, base, nil, &plan)
+    if plan.Version != 3 || len(plan.Plan.Steps) != 2 || plan.Plan.Steps[0].ID != "first" { t.Fatalf("plan %+v", plan) }
+
+    // edits that break the plan are refused with the problems
+    var e apiError
+    resp := call(t, http.MethodDelete, base+"/steps/first", nil, &e)
+    if resp.StatusCode != http.StatusUnprocessableEntity || e.Code != "invalid_plan" || len(e.Problems) != 1 { t.Fatalf("delete referenced step: %d %+v", resp.StatusCode, e) }
+    resp = call(t, http.MethodPut, base, map[string]any{"steps": []any{step, map[string]any{"id": "m", "tool": "map", "map": map[string]any{"over": "{{step:first.output}}", "steps": []any{step}}}}}, &e)
+    if resp.StatusCode != http.StatusUnprocessableEntity || e.Problems[0] != `m.step id "first" is already used outside this map` { t.Fatalf("colliding sub-step: %d %+v", resp.StatusCode, e) }
+    if resp := call(t, http.MethodPatch, base+"/steps/nope", map[string]any{"tool": "echo"}, &e); resp.StatusCode != http.StatusNotFound || e.Code != "step_not_found" { t.Fatalf("unknown step: %d %+v", resp.StatusCode, e) }
+
+    // replacing the plan records a version with the step-level diff
+    say := map[string]any{"id": "say", "tool": "echo", "inputs": map[string]any{"text": "hello"}}
+    call(t, http.MethodPut, base, map[string]any{"steps": []any{say, step}}, &v)
+    if v.Version != 4 || len(v.Diff) != 2 || v.Diff[0].Field != "inputs.text" || v.Diff[1].Op != "move" { t.Fatalf("replace: %+v", v) }
+    var versions []models.PlanVersion
+    call(t, http.MethodGet, base+"/versions", nil, &versions)
+    if len(versions) != 4 || versions[3].Author != "anonymous" { t.Fatalf("versions %+v", versions) }
+    if resp := call(t, http.MethodGet, base+"/versions/9", nil, &e); resp.StatusCode != http.StatusNotFound || e.Code != "version_not_found" { t.Fatalf("version 9: %d %+v", resp.StatusCode, e) }
+}
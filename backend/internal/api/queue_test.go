@@ -0,0 +1,28 @@
+package api
+
+import (
+    "net/http"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+func TestQueueEndpoints(t *testing.T) {
+    srv := newTestServer(t)
+    task := newTaskWith(t, "queue", &models.Step{ID: "hold", Tool: "ask_user", Inputs: map[string]any{"question": "go on?"}})
+    base := srv.URL + "/v1/tasks/" + task.ID
+    var e apiError
+    if resp := call(t, http.MethodPost, base+":execute?priority=high", nil, &e); resp.StatusCode != http.StatusBadRequest { t.Fatalf("bad priority: %d %+v", resp.StatusCode, e) }
+    var job orchestrator.QueuedJob
+    if resp := call(t, http.MethodPost, base+":execute?priority=2", nil, &job); resp.StatusCode != http.StatusAccepted || job.TaskID != task.ID || job.Priority != 2 || job.Kind != "execute" {
+        t.Fatalf("execute: %d %+v", resp.StatusCode, job)
+    }
+    waitEvent(t, task.ID, `"question"`)
+    if resp := call(t, http.MethodPost, base+":execute", nil, &e); resp.StatusCode != http.StatusConflict || e.Code != "task_running" { t.Fatalf("execute while running: %d %+v", resp.StatusCode, e) }
+    if resp := call(t, http.MethodDelete, base, nil, &e); resp.StatusCode != http.StatusConflict { t.Fatalf("delete while running: %d %+v", resp.StatusCode, e) }
+    var stats orchestrator.QueueStats
+    if call(t, http.MethodGet, srv.URL+"/v1/queue", nil, &stats); stats.Workers < 1 || stats.Running < 1 { t.Fatalf("queue %+v", stats) }
+    call(t, http.MethodPost, base+"/steps/hold:answer", map[string]any{"answer": "yes"}, nil)
+    waitEvent(t, task.ID, `"task_status"`, `"SUCCESS"`)
+}
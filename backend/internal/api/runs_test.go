@@ -0,0 +1,36 @@
+package api
+
+import (
+    "context"
+    "net/http"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func TestRunHistory(t *testing.T) {
+    srv := newTestServer(t)
+    task := newTask(t, "runs")
+    for i := 0; i < 2; i++ {
+        if err := orch.ExecutePlan(context.Background(), task.ID); err != nil { t.Fatal(err) }
+    }
+    var runs []models.Run
+    if resp := call(t, http.MethodGet, srv.URL+"/v1/tasks/"+task.ID+"/runs", nil, &runs); resp.StatusCode != http.StatusOK { t.Fatalf("list: %d", resp.StatusCode) }
+    if len(runs) != 2 || runs[0].Number != 1 || runs[1].Number != 2 { t.Fatalf("runs %+v", runs) }
+    for _, r := range runs {
+        if r.Status != models.StatusSuccess || len(r.Steps) != 1 || r.Steps[0].Inputs["text"] != "hello" || r.Plan == nil { t.Fatalf("run %+v", r) }
+    }
+
+    var run models.Run
+    call(t, http.MethodGet, srv.URL+"/v1/tasks/"+task.ID+"/runs/"+runs[0].ID, nil, &run)
+    if run.ID != runs[0].ID || len(run.Results) != 1 || run.Results[0].Output != "echo: hello" { t.Fatalf("run %+v", run) }
+
+    for url, code := range map[string]string{
+        "/v1/tasks/" + task.ID + "/runs/nope": "run_not_found",
+        "/v1/tasks/nope/runs":                 "task_not_found",
+        "/v1/tasks/nope/runs/x":               "task_not_found",
+    } {
+        var e apiError
+        if resp := call(t, http.MethodGet, srv.URL+url, nil, &e); resp.StatusCode != http.StatusNotFound || e.Code != code { t.Errorf("%s: %d %+v", url, resp.StatusCode, e) }
+    }
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+    "net/http"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/scheduler"
+)
+
+// decodeSchedule reads the body of POST /v1/schedules and PUT /v1/schedules/{id};
+// enabled defaults to true.
+func decodeSchedule(w http.ResponseWriter, r *http.Request) (*scheduler.Schedule, bool) {
+    var sc scheduler.Schedule
+    if !decodeBody(w, r, &sc) { return nil, false }
+    return &sc, true
+}
+
+// Schedule routes:
+//
+//    GET    /v1/schedules              list
+//    POST   /v1/schedules              create
+//    GET    /v1/schedules/{id}         one schedule
+//    PUT    /v1/schedules/{id}         replace the definition
+//    DELETE /v1/schedules/{id}
+//    POST   /v1/schedules/{id}:run     fire now
+//    GET    /v1/schedules/{id}/tasks   tasks created by the schedule
+
+func createSchedule(w http.ResponseWriter, r *http.Request) {
+    sc, ok := decodeSchedule(w, r)
+    if !ok { return }
+    created, err := sched.Create(sc)
+    if err != nil { respondErr(w, err, http.StatusBadRequest, codeInvalidRequest); return }
+    w.Header().Set("Location", "/v1/schedules/"+created.ID)
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    respondJSON(w, created)
+}
+
+// scheduleOr404 returns the schedule named by the path, or answers 404.
+func scheduleOr404(w http.ResponseWriter, r *http.Request) (*scheduler.Schedule, bool) {
+    sc, ok := sched.Get(r.PathValue("id"))
+    if !ok { respondErr(w, scheduler.ErrNotFound, http.StatusNotFound, codeScheduleNotFound) }
+    return sc, ok
+}
+
+func getSchedule(w http.ResponseWriter, r *http.Request) {
+    sc, ok := scheduleOr404(w, r)
+    if !ok { return }
+    respondJSON(w, sc)
+}
+
+func updateSchedule(w http.ResponseWriter, r *http.Request) {
+    old, ok := scheduleOr404(w, r)
+    if !ok { return }
+    sc, ok := decodeSchedule(w, r)
+    if !ok { return }
+    updated, err := sched.Update(old.ID, sc)
+    if err != nil { respondErr(w, err, http.StatusBadRequest, codeInvalidRequest); return }
+    respondJSON(w, updated)
+}
+
+func deleteSchedule(w http.ResponseWriter, r *http.Request) {
+    if err := sched.Delete(r.PathValue("id")); err != nil { respondErr(w, err, http.StatusNotFound, codeScheduleNotFound); return }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// handleScheduleAction serves POST /v1/schedules/{id}:run, which answers 202 with the
+// task it created.
+func handleScheduleAction(w http.ResponseWriter, r *http.Request) {
+    id, action := customMethod(r.PathValue("id"))
+    if action != "run" { unknownMethod(w, action); return }
+    t, err := sched.RunNow(id)
+    if err != nil { respondErr(w, err, http.StatusConflict, codeConflict); return }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusAccepted)
+    respondJSON(w, t)
+}
+
+func scheduleTasks(w http.ResponseWriter, r *http.Request) {
+    sc, ok := scheduleOr404(w, r)
+    if !ok { return }
+    tasks := []*models.Task{}
+    for _, t := range orch.ListTasks() {
+        if t.ScheduleID == sc.ID { tasks = append(tasks, t) }
+    }
+    respondJSON(w, tasks)
+}
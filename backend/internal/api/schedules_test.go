@@ -0,0 +1,33 @@
+package api
+
+import (
+    "net/http"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/scheduler"
+)
+
+func TestScheduleEndpoints(t *testing.T) {
+    srv := newTestServer(t)
+    // a pinned plan, so runs do not need the planner
+    body := map[string]any{"cron": "@daily", "plan": map[string]any{"steps": []any{map[string]any{"id": "say", "tool": "echo", "inputs": map[string]any{"text": "hi"}}}}}
+    var sc scheduler.Schedule
+    resp := call(t, http.MethodPost, srv.URL+"/v1/schedules", body, &sc)
+    if resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/v1/schedules/"+sc.ID { t.Fatalf("create: %d %s", resp.StatusCode, resp.Header.Get("Location")) }
+    if !sc.IsEnabled() || sc.NextRunAt == nil { t.Fatalf("omitted enabled should mean enabled: %+v", sc) }
+
+    body["enabled"] = false
+    var updated scheduler.Schedule
+    if call(t, http.MethodPut, srv.URL+"/v1/schedules/"+sc.ID, body, &updated); updated.IsEnabled() || updated.NextRunAt != nil { t.Fatalf("disabled: %+v", updated) }
+    var e apiError
+    if resp := call(t, http.MethodPost, srv.URL+"/v1/schedules", map[string]any{"cron": "bogus", "query": "q"}, &e); resp.StatusCode != http.StatusBadRequest { t.Fatalf("bad cron: %d %+v", resp.StatusCode, e) }
+
+    var task models.Task
+    if resp := call(t, http.MethodPost, srv.URL+"/v1/schedules/"+sc.ID+":run", nil, &task); resp.StatusCode != http.StatusAccepted || task.ScheduleID != sc.ID { t.Fatalf("run: %d %+v", resp.StatusCode, task) }
+    waitEvent(t, task.ID, `"task_status"`, `"SUCCESS"`)
+    var tasks []models.Task
+    if call(t, http.MethodGet, srv.URL+"/v1/schedules/"+sc.ID+"/tasks", nil, &tasks); len(tasks) != 1 || tasks[0].ID != task.ID { t.Fatalf("tasks %+v", tasks) }
+    if resp := call(t, http.MethodDelete, srv.URL+"/v1/schedules/"+sc.ID, nil, nil); resp.StatusCode != http.StatusNoContent { t.Fatalf("delete: %d", resp.StatusCode) }
+    if resp := call(t, http.MethodGet, srv.URL+"/v1/schedules/"+sc.ID, nil, &e); resp.StatusCode != http.StatusNotFound || e.Code != "schedule_not_found" { t.Fatalf("get deleted: %d %+v", resp.StatusCode, e) }
+}
@@ -4,43 +4,74 @@ import (
     "context"
     "encoding/json"
     "log"
-    "math/rand"
+    "net"
     "net/http"
     "time"
 
-    "github.com/example/agent-orchestrator/internal/agents"
+    "github.com/example/agent-orchestrator/internal/models"
     "github.com/example/agent-orchestrator/internal/orchestrator"
-    "github.com/example/agent-orchestrator/internal/tools"
+    "github.com/example/agent-orchestrator/internal/scheduler"
+    "github.com/example/agent-orchestrator/internal/workflows"
+    "github.com/example/agent-orchestrator/internal/sinks"
+    "github.com/example/agent-orchestrator/internal/wiring"
     "github.com/example/agent-orchestrator/internal/providers/llm"
     "os"
+    "path/filepath"
     "fmt"
+    "strconv"
     "strings"
+    "sync/atomic"
 )
 
 var orch *orchestrator.Orchestrator
 
+var sched *scheduler.Scheduler
+
+var wfLib *workflows.Library
+
 func init() {
-    // Wire default components for MVP
-    reg := tools.NewRegistry()
-    reg.Register(&tools.EchoTool{})
-    reg.Register(&tools.HTTPGetTool{})
-    // LLM-backed summarize tool available when an LLM is configured (falls back to mock if not)
-    reg.Register(&tools.SummarizeTool{Client: llm.NewFromEnv()})
-    reg.Register(&tools.LLMAnswerTool{Client: llm.NewFromEnv()})
-    reg.Register(&tools.HTMLToTextTool{})
-    reg.Register(&tools.HTTPPostJSONTool{})
-    reg.Register(&tools.PDFExtractTool{})
-    // Planner selection
-    var planner agents.Planner = &agents.MockPlanner{}
-    if os.Getenv("USE_LLM_PLANNER") == "1" {
-        planner = &agents.LLMPlanner{Client: llm.NewFromEnv()}
+    // The server is built from the same parts as the ensemble package: tools, planner,
+    // verifier and approval gates come from the environment
+    cfg := wiring.FromEnv()
+    // Worker pool: WORKERS tasks run at once (default 4), at most QUEUE_MAX_PER_CLIENT
+    // of them (0 = no limit) for one X-Client-ID
+    if n, err := strconv.Atoi(os.Getenv("WORKERS")); err == nil && n > 0 { cfg.Workers = n }
+    if n, err := strconv.Atoi(os.Getenv("QUEUE_MAX_PER_CLIENT")); err == nil && n > 0 { cfg.MaxPerClient = n }
+    // Persistence: with DATA_DIR set, tasks survive restarts and interrupted runs
+    // come back PAUSED
+    cfg.StoreDir = os.Getenv("DATA_DIR")
+    // Event sinks (webhook, audit file, NATS) configured via environment
+    eventSinks, err := sinks.FromEnv()
+    if err != nil { log.Printf("event sinks: %v", err) }
+    cfg.Sinks = eventSinks
+    eng, err := wiring.Build(cfg)
+    if err != nil {
+        log.Printf("%v; starting without storage", err)
+        cfg.StoreDir = ""
+        if eng, err = wiring.Build(cfg); err != nil { log.Fatal(err) }
+    }
+    if cfg.StoreDir != "" { log.Printf("restored %d tasks from %s", eng.Restored, cfg.StoreDir) }
+    orch = eng.Orch
+    // Schedules are kept in DATA_DIR/schedules when DATA_DIR is set
+    schedDir := ""
+    if dir := os.Getenv("DATA_DIR"); dir != "" { schedDir = filepath.Join(dir, "schedules") }
+    if sched, err = scheduler.New(orch, schedDir); err != nil {
+        log.Printf("schedules: %v", err)
+        sched, _ = scheduler.New(orch, "")
     }
-    // Verifier selection
-    var verifier agents.Verifier = &agents.SimpleVerifier{}
-    if os.Getenv("USE_LLM_VERIFIER") == "1" {
-        verifier = &agents.LLMVerifier{Client: llm.NewFromEnv()}
+    sched.Start(context.Background())
+    // Workflow templates are kept in DATA_DIR/workflows when DATA_DIR is set
+    wfDir := ""
+    if dir := os.Getenv("DATA_DIR"); dir != "" { wfDir = filepath.Join(dir, "workflows") }
+    if wfLib, err = workflows.New(orch, wfDir); err != nil {
+        log.Printf("workflows: %v", err)
+        wfLib, _ = workflows.New(orch, "")
+    }
+    // Workflow files kept in git: loaded from WORKFLOWS_DIR and reloaded when they change
+    if dir := os.Getenv("WORKFLOWS_DIR"); dir != "" {
+        if err := wfLib.LoadDir(dir); err != nil { log.Printf("workflow files: %v", err) }
+        wfLib.WatchDir(context.Background(), dir, 2*time.Second)
     }
-    orch = orchestrator.New(planner, &agents.ToolExecutor{Registry: reg}, verifier)
 }
 
 func RegisterRoutes(mux *http.ServeMux) {
@@ -77,102 +108,120 @@ func RegisterRoutes(mux *http.ServeMux) {
         respondJSON(w, resp)
     })
 
-    mux.HandleFunc("/tasks", func(w http.ResponseWriter, r *http.Request) {
-        switch r.Method {
-        case http.MethodGet:
-            tasks := orch.ListTasks()
-            respondJSON(w, tasks)
-        case http.MethodPost:
-            var req struct{
-                Query string `json:"query"`
-                Context map[string]any `json:"context"`
-            }
-            if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-                http.Error(w, err.Error(), http.StatusBadRequest)
-                return
-            }
-            id := genID()
-            t := orch.CreateTask(id, req.Query, req.Context)
-            respondJSON(w, t)
-        default:
-            w.WriteHeader(http.StatusMethodNotAllowed)
-        }
-    })
+    mux.HandleFunc("/openapi.json", serveSpec)
 
-    mux.HandleFunc("/tasks/start/", func(w http.ResponseWriter, r *http.Request) {
-        // path: /tasks/start/{id}
-        if r.Method != http.MethodPost { w.WriteHeader(http.StatusMethodNotAllowed); return }
-        id := r.URL.Path[len("/tasks/start/"):]
-        go func() {
-            if err := orch.Start(context.Background(), id); err != nil {
-                log.Printf("start error: %v", err)
-            }
-        }()
-        w.WriteHeader(http.StatusAccepted)
-    })
+    // Version 1: resources are nouns, and actions on them are custom methods after a
+    // colon, e.g. POST /v1/tasks/{id}:start
+    mux.HandleFunc("GET /v1/queue", func(w http.ResponseWriter, r *http.Request) { respondJSON(w, orch.QueueStats()) })
+    mux.HandleFunc("GET /v1/events", handleFirehose)
+    mux.Handle("GET /v1/ws", wsHandler())
 
-    mux.HandleFunc("/tasks/plan/", func(w http.ResponseWriter, r *http.Request) {
-        // path: /tasks/plan/{id}
-        if r.Method != http.MethodPost { w.WriteHeader(http.StatusMethodNotAllowed); return }
-        id := r.URL.Path[len("/tasks/plan/"):]
-        plan, err := orch.PlanOnly(r.Context(), id)
-        if err != nil { http.Error(w, err.Error(), http.StatusBadRequest); return }
-        respondJSON(w, plan)
-    })
+    mux.HandleFunc("GET /v1/tasks", func(w http.ResponseWriter, r *http.Request) { respondJSON(w, orch.ListTasks()) })
+    mux.HandleFunc("POST /v1/tasks", createTask)
+    mux.HandleFunc("GET /v1/tasks/{id}", getTask)
+    mux.HandleFunc("DELETE /v1/tasks/{id}", deleteTask)
+    mux.HandleFunc("POST /v1/tasks/{id}", handleTaskAction)
+    mux.HandleFunc("GET /v1/tasks/{id}/events", handleTaskEvents)
+    mux.HandleFunc("GET /v1/tasks/{id}/runs", listRuns)
+    mux.HandleFunc("GET /v1/tasks/{id}/runs/{runID}", getRun)
+    mux.HandleFunc("POST /v1/tasks/{id}/steps/{stepID}", handleStepAction)
 
-    mux.HandleFunc("/tasks/execute/", func(w http.ResponseWriter, r *http.Request) {
-        // path: /tasks/execute/{id}
-        if r.Method != http.MethodPost { w.WriteHeader(http.StatusMethodNotAllowed); return }
-        id := r.URL.Path[len("/tasks/execute/"):]
-        go func() {
-            if err := orch.ExecutePlan(context.Background(), id); err != nil {
-                log.Printf("execute error: %v", err)
-            }
-        }()
-        w.WriteHeader(http.StatusAccepted)
-    })
+    mux.HandleFunc("GET /v1/tasks/{id}/plan", getPlan)
+    mux.HandleFunc("PUT /v1/tasks/{id}/plan", replacePlan)
+    mux.HandleFunc("GET /v1/tasks/{id}/plan:export", exportPlan)
+    mux.HandleFunc("GET /v1/tasks/{id}/plan/versions", listPlanVersions)
+    mux.HandleFunc("GET /v1/tasks/{id}/plan/versions/{n}", getPlanVersion)
+    mux.HandleFunc("POST /v1/tasks/{id}/plan/steps", insertStep)
+    mux.HandleFunc("PATCH /v1/tasks/{id}/plan/steps/{stepID}", editStep)
+    mux.HandleFunc("DELETE /v1/tasks/{id}/plan/steps/{stepID}", editStep)
 
-    mux.HandleFunc("/tasks/", func(w http.ResponseWriter, r *http.Request) {
-        // Handle both JSON task fetch and SSE stream under /tasks/{id} and /tasks/{id}/events
-        if r.Method != http.MethodGet { w.WriteHeader(http.StatusMethodNotAllowed); return }
-        if strings.HasSuffix(r.URL.Path, "/events") {
-            id := strings.TrimSuffix(r.URL.Path[len("/tasks/"):], "/events")
-            // set SSE headers
-            w.Header().Set("Content-Type", "text/event-stream")
-            w.Header().Set("Cache-Control", "no-cache")
-            w.Header().Set("Connection", "keep-alive")
-            flusher, ok := w.(http.Flusher)
-            if !ok { http.Error(w, "stream unsupported", http.StatusInternalServerError); return }
-            ch, unsub := orch.Subscribe(id)
-            defer unsub()
-            if t, ok := orch.GetTask(id); ok {
-                b, _ := json.Marshal(t)
-                writeSSE(w, "snapshot", b)
-                flusher.Flush()
-            }
-            ticker := time.NewTicker(20 * time.Second)
-            defer ticker.Stop()
-            notify := r.Context().Done()
-            for {
-                select {
-                case <-notify:
-                    return
-                case msg, ok := <-ch:
-                    if !ok { return }
-                    writeSSE(w, "update", msg)
-                    flusher.Flush()
-                case <-ticker.C:
-                    w.Write([]byte(": ping\n\n"))
-                    flusher.Flush()
-                }
-            }
-        }
-        // JSON task fetch: /tasks/{id}
-        id := r.URL.Path[len("/tasks/"):]
-        t, ok := orch.GetTask(id)
-        if !ok { http.NotFound(w, r); return }
-        respondJSON(w, t)
+    mux.HandleFunc("GET /v1/schedules", func(w http.ResponseWriter, r *http.Request) { respondJSON(w, sched.List()) })
+    mux.HandleFunc("POST /v1/schedules", createSchedule)
+    mux.HandleFunc("GET /v1/schedules/{id}", getSchedule)
+    mux.HandleFunc("PUT /v1/schedules/{id}", updateSchedule)
+    mux.HandleFunc("DELETE /v1/schedules/{id}", deleteSchedule)
+    mux.HandleFunc("POST /v1/schedules/{id}", handleScheduleAction)
+    mux.HandleFunc("GET /v1/schedules/{id}/tasks", scheduleTasks)
+
+    mux.HandleFunc("GET /v1/workflows", func(w http.ResponseWriter, r *http.Request) { respondJSON(w, wfLib.List()) })
+    mux.HandleFunc("POST /v1/workflows", saveWorkflow)
+    mux.HandleFunc("GET /v1/workflows/{name}", getWorkflow)
+    mux.HandleFunc("DELETE /v1/workflows/{name}", deleteWorkflow)
+    mux.HandleFunc("POST /v1/workflows/{name}", handleWorkflowAction)
+    mux.HandleFunc("GET /v1/workflows/{name}/versions", listWorkflowVersions)
+    mux.HandleFunc("GET /v1/workflows/{name}/versions/{n}", getWorkflowVersion)
+    mux.HandleFunc("GET /v1/workflows/{name}/tasks", workflowTasks)
+
+    mux.HandleFunc("/v1/", func(w http.ResponseWriter, r *http.Request) {
+        respondError(w, http.StatusNotFound, codeNotFound, "no such endpoint: "+r.URL.Path, nil)
     })
+
+    // the routes from before /v1 still work, marked deprecated (see legacy.go)
+    for _, prefix := range legacyPrefixes { mux.Handle(prefix, legacyAlias(mux)) }
+}
+
+func createTask(w http.ResponseWriter, r *http.Request) {
+    var req struct{
+        Query string `json:"query"`
+        Context map[string]any `json:"context"`
+        Priority int `json:"priority"`
+    }
+    if !decodeBody(w, r, &req) { return }
+    id := genID()
+    t := orch.AddTask(&models.Task{ID: id, Query: req.Query, Context: req.Context, Priority: req.Priority, ClientID: clientID(r)})
+    w.Header().Set("Location", "/v1/tasks/"+id)
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    respondJSON(w, t)
+}
+
+// taskOr404 returns the task named by the path, or answers 404.
+func taskOr404(w http.ResponseWriter, r *http.Request) (*models.Task, bool) {
+    t, ok := orch.GetTask(r.PathValue("id"))
+    if !ok { respondError(w, http.StatusNotFound, codeTaskNotFound, "task not found", nil) }
+    return t, ok
+}
+
+func getTask(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    respondJSON(w, t)
+}
+
+// deleteTask serves DELETE /v1/tasks/{id}; queued and active tasks answer 409 until
+// they are cancelled or finish.
+func deleteTask(w http.ResponseWriter, r *http.Request) {
+    if err := orch.DeleteTask(r.PathValue("id")); err != nil { respondErr(w, err, http.StatusConflict, codeConflict); return }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func listRuns(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    respondJSON(w, orch.Runs(t.ID))
+}
+
+func getRun(w http.ResponseWriter, r *http.Request) {
+    t, ok := taskOr404(w, r)
+    if !ok { return }
+    run, ok := orch.GetRun(t.ID, r.PathValue("runID"))
+    if !ok { respondError(w, http.StatusNotFound, codeRunNotFound, "run not found", nil); return }
+    respondJSON(w, run)
+}
+
+// customMethod splits a path segment like "T1:start" into the resource name and the
+// method; the method is empty when there is no colon.
+func customMethod(segment string) (name, method string) {
+    i := strings.LastIndex(segment, ":")
+    if i < 0 { return segment, "" }
+    return segment[:i], segment[i+1:]
+}
+
+// unknownMethod answers a POST to a resource without a known custom method.
+func unknownMethod(w http.ResponseWriter, method string) {
+    msg := "no such action"
+    if method != "" { msg = fmt.Sprintf("no such action %q", method) }
+    respondError(w, http.StatusNotFound, codeNotFound, msg, nil)
 }
 
 func respondJSON(w http.ResponseWriter, v any) {
@@ -182,7 +231,9 @@ func respondJSON(w http.ResponseWriter, v any) {
     enc.Encode(v)
 }
 
-func writeSSE(w http.ResponseWriter, event string, data []byte) {
+// writeSSE writes one SSE message; a non-zero id becomes the client's Last-Event-ID.
+func writeSSE(w http.ResponseWriter, id uint64, event string, data []byte) {
+    if id > 0 { w.Write([]byte("id: "+strconv.FormatUint(id, 10)+"\n")) }
     w.Write([]byte("event: "+event+"\n"))
     // ensure single-line data chunks by JSON encoding beforehand
     w.Write([]byte("data: "))
@@ -190,7 +241,41 @@ func writeSSE(w http.ResponseWriter, event string, data []byte) {
     w.Write([]byte("\n\n"))
 }
 
+// idSeq counts the task IDs handed out since start.
+var idSeq atomic.Uint64
+
+// genID returns a task ID: the time plus the count in letters (a, b, ..., z, ba, ...).
+// The count keeps IDs created in the same second apart, also when requests race.
 func genID() string {
-    rand.Seed(time.Now().UnixNano())
-    return time.Now().Format("20060102150405") + "-" + string('a'+rune(rand.Intn(26)))
+    n := idSeq.Add(1) - 1
+    suffix := ""
+    for {
+        suffix = string('a'+rune(n%26)) + suffix
+        if n /= 26; n == 0 { break }
+    }
+    return time.Now().Format("20060102150405") + "-" + suffix
+}
+
+// clientID identifies the caller for fair queueing: the X-Client-ID header, else the
+// remote host.
+func clientID(r *http.Request) string {
+    if id := strings.TrimSpace(r.Header.Get("X-Client-ID")); id != "" { return id }
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil { return r.RemoteAddr }
+    return host
+}
+
+// enqueue queues a task run and answers 202 with its queue entry. An optional
+// ?priority=N overrides the task's priority.
+func enqueue(w http.ResponseWriter, r *http.Request, id, kind string) {
+    if v := r.URL.Query().Get("priority"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil { respondError(w, http.StatusBadRequest, codeInvalidRequest, "invalid priority", nil); return }
+        if t, ok := orch.GetTask(id); ok { t.Priority = n }
+    }
+    job, err := orch.Enqueue(id, kind)
+    if err != nil { respondErr(w, err, http.StatusConflict, codeConflict); return }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusAccepted)
+    respondJSON(w, job)
 }
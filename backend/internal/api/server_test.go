@@ -0,0 +1,30 @@
+package api
+
+import (
+    "regexp"
+    "sync"
+    "testing"
+)
+
+func TestGenID(t *testing.T) {
+    var mu sync.Mutex
+    seen := map[string]bool{}
+    var wg sync.WaitGroup
+    for i := 0; i < 8; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for j := 0; j < 100; j++ {
+                id := genID()
+                mu.Lock()
+                if seen[id] { t.Errorf("%s handed out twice", id) }
+                seen[id] = true
+                mu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+    for id := range seen {
+        if !regexp.MustCompile(`^\d{14}-[a-z]+$`).MatchString(id) { t.Fatalf("ID %q", id) }
+    }
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+    "net/http"
+)
+
+// handleStepAction serves POST /v1/tasks/{id}/steps/{stepID}:approve and :reject
+// with a body {"by", "comment"?}, and POST .../{stepID}:answer with {"answer", "by"?}.
+// Approvals must name who decides in by; an answer's by defaults to the X-Author
+// header. Steps that are not in the plan answer 404, steps with nothing pending 409.
+func handleStepAction(w http.ResponseWriter, r *http.Request) {
+    id := r.PathValue("id")
+    stepID, action := customMethod(r.PathValue("stepID"))
+    var req struct {
+        By      string `json:"by"`
+        Comment string `json:"comment"`
+        Answer  string `json:"answer"`
+    }
+    if r.ContentLength != 0 && !decodeBody(w, r, &req) { return }
+    var err error
+    var decision string
+    switch action {
+    case "approve":
+        decision = "approved"
+        err = orch.DecideApproval(id, stepID, true, req.By, req.Comment)
+    case "reject":
+        decision = "rejected"
+        err = orch.DecideApproval(id, stepID, false, req.By, req.Comment)
+    case "answer":
+        decision = "answered"
+        if req.By == "" { req.By = author(r) }
+        err = orch.AnswerQuestion(id, stepID, req.Answer, req.By)
+    default:
+        unknownMethod(w, action)
+        return
+    }
+    if err != nil { respondErr(w, err, http.StatusBadRequest, codeInvalidRequest); return }
+    respondJSON(w, map[string]any{"task_id": id, "step_id": stepID, "decision": decision, "by": req.By})
+}
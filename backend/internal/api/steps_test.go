@@ -0,0 +1,79 @@
+package api
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync/atomic"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// The server gates high-risk tools (APPROVAL_RISK defaults to high); http_post_json is
+// one.
+func TestApprovalEndpoints(t *testing.T) {
+    srv := newTestServer(t)
+    var posts atomic.Int32
+    target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { posts.Add(1) }))
+    defer target.Close()
+    post := func() *models.Step {
+        return &models.Step{ID: "post", Tool: "http_post_json", Inputs: map[string]any{"url": target.URL, "json": map[string]any{"a": 1}}}
+    }
+
+    approved := newTaskWith(t, "appr", post())
+    steps := srv.URL + "/v1/tasks/" + approved.ID + "/steps/"
+    var e apiError
+    alice := map[string]any{"by": "alice"}
+    if resp := call(t, http.MethodPost, steps+"post:approve", alice, &e); resp.StatusCode != http.StatusConflict || e.Code != "step_not_awaiting" { t.Fatalf("approve idle step: %d %+v", resp.StatusCode, e) }
+    call(t, http.MethodPost, srv.URL+"/v1/tasks/"+approved.ID+":execute", nil, nil)
+    waitEvent(t, approved.ID, `"approval_required"`, `"risk":"high"`)
+    if posts.Load() != 0 { t.Fatal("posted before approval") }
+    if resp := call(t, http.MethodPost, steps+"nope:approve", alice, &e); resp.StatusCode != http.StatusNotFound || e.Code != "step_not_found" { t.Fatalf("unknown step: %d %+v", resp.StatusCode, e) }
+    if resp := call(t, http.MethodPost, steps+"post:bogus", nil, &e); resp.StatusCode != http.StatusNotFound { t.Fatalf("unknown action: %d %+v", resp.StatusCode, e) }
+    // a decision must say who made it
+    for _, body := range []any{nil, map[string]any{"comment": "ok"}, map[string]any{"by": ""}, map[string]any{"by": "  "}} {
+        if resp := call(t, http.MethodPost, steps+"post:approve", body, &e); resp.StatusCode != http.StatusBadRequest || e.Code != codeInvalidRequest { t.Fatalf("approve with %v: %d %+v", body, resp.StatusCode, e) }
+    }
+    var decision map[string]any
+    if resp := call(t, http.MethodPost, steps+"post:approve", map[string]any{"by": "alice", "comment": "ok"}, &decision); resp.StatusCode != http.StatusOK || decision["decision"] != "approved" {
+        t.Fatalf("approve: %d %v", resp.StatusCode, decision)
+    }
+    step := waitEvent(t, approved.ID, `"step_status"`, `"SUCCESS"`)
+    if !strings.Contains(step, `"by":"alice"`) || !strings.Contains(step, `"decision":"approved"`) { t.Fatalf("step %s", step) }
+    waitEvent(t, approved.ID, `"task_status"`, `"SUCCESS"`)
+    if posts.Load() != 1 { t.Fatalf("%d posts", posts.Load()) }
+
+    rejected := newTaskWith(t, "appr", post())
+    call(t, http.MethodPost, srv.URL+"/v1/tasks/"+rejected.ID+":execute", nil, nil)
+    waitEvent(t, rejected.ID, `"approval_required"`)
+    // X-Author alone does not name the reviewer
+    req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/tasks/"+rejected.ID+"/steps/post:reject", nil)
+    req.Header.Set("X-Author", "bob")
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil || resp.StatusCode != http.StatusBadRequest { t.Fatalf("reject without by: %v %v", resp, err) }
+    resp.Body.Close()
+    if resp := call(t, http.MethodPost, srv.URL+"/v1/tasks/"+rejected.ID+"/steps/post:reject", map[string]any{"by": "bob"}, nil); resp.StatusCode != http.StatusOK { t.Fatalf("reject: %d", resp.StatusCode) }
+    if ev := waitEvent(t, rejected.ID, `"run_status"`, `"FAILED"`); !strings.Contains(ev, "rejected by bob") { t.Fatalf("run %s", ev) }
+    if posts.Load() != 1 { t.Fatal("rejected step posted") }
+}
+
+func TestAnswerEndpoint(t *testing.T) {
+    srv := newTestServer(t)
+    task := newTaskWith(t, "ask",
+        &models.Step{ID: "which", Tool: "ask_user", Inputs: map[string]any{"question": "Which?", "choices": []any{"a", "b"}}},
+        &models.Step{ID: "say", Tool: "echo", Inputs: map[string]any{"text": "picked {{step:which.output}}"}},
+    )
+    url := srv.URL + "/v1/tasks/" + task.ID + "/steps/which:answer"
+    var e apiError
+    if resp := call(t, http.MethodPost, url, map[string]any{"answer": "a"}, &e); resp.StatusCode != http.StatusConflict || e.Code != "step_not_awaiting" { t.Fatalf("idle: %d %+v", resp.StatusCode, e) }
+    call(t, http.MethodPost, srv.URL+"/v1/tasks/"+task.ID+":execute", nil, nil)
+    waitEvent(t, task.ID, `"question"`, `"Which?"`)
+    if resp := call(t, http.MethodPost, url, map[string]any{"answer": "c"}, &e); resp.StatusCode != http.StatusUnprocessableEntity || e.Code != "invalid_answer" { t.Fatalf("bad choice: %d %+v", resp.StatusCode, e) }
+    var decision map[string]any
+    if resp := call(t, http.MethodPost, url, map[string]any{"answer": "b", "by": "carol"}, &decision); resp.StatusCode != http.StatusOK || decision["decision"] != "answered" {
+        t.Fatalf("answer: %d %v", resp.StatusCode, decision)
+    }
+    if ev := waitEvent(t, task.ID, `"question_answered"`); !strings.Contains(ev, `"by":"carol"`) { t.Fatalf("event %s", ev) }
+    if ev := waitEvent(t, task.ID, `"result"`, `"say"`); !strings.Contains(ev, "picked b") { t.Fatalf("result %s", ev) }
+}
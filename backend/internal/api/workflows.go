@@ -0,0 +1,145 @@
+package api
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/workflows"
+)
+
+// workflowRequest is the body of POST /v1/workflows. Without a plan, task_id copies the
+// current plan of an existing task.
+type workflowRequest struct {
+    workflows.Workflow
+    TaskID string `json:"task_id,omitempty"`
+}
+
+// Workflow routes:
+//
+//    GET    /v1/workflows                      latest version of each workflow
+//    POST   /v1/workflows                      save a new version {name, params, plan | task_id},
+//                                              or a workflow file (Content-Type: application/yaml)
+//    GET    /v1/workflows/{name}               latest version
+//    DELETE /v1/workflows/{name}               all versions
+//    GET    /v1/workflows/{name}/versions[/{n}]
+//    GET    /v1/workflows/{name}:export        as a workflow file, ?format=yaml|json&version=
+//    POST   /v1/workflows/{name}:run           {params, context?, priority?, version?}
+//    GET    /v1/workflows/{name}/tasks         tasks created from the workflow
+
+func saveWorkflow(w http.ResponseWriter, r *http.Request) {
+    var wf *workflows.Workflow
+    if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+        data, err := io.ReadAll(r.Body)
+        if err != nil { respondError(w, http.StatusBadRequest, codeInvalidRequest, err.Error(), nil); return }
+        if wf, err = wfLib.ParseFile("request", data); err != nil { respondWorkflowError(w, err); return }
+        wf.Source = ""
+    } else {
+        var req workflowRequest
+        if !decodeBody(w, r, &req) { return }
+        wf = &req.Workflow
+        if wf.Plan == nil && req.TaskID != "" {
+            t, ok := orch.GetTask(req.TaskID)
+            if !ok { respondError(w, http.StatusNotFound, codeTaskNotFound, "task not found", nil); return }
+            wf.Plan = t.Plan
+        }
+    }
+    wf.Author = author(r)
+    saved, err := wfLib.Save(wf)
+    if err != nil { respondWorkflowError(w, err); return }
+    w.Header().Set("Location", "/v1/workflows/"+saved.Name)
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    respondJSON(w, saved)
+}
+
+// workflowOr404 returns a version of the workflow named by the path (0 = latest), or
+// answers 404.
+func workflowOr404(w http.ResponseWriter, name string, version int) (*workflows.Workflow, bool) {
+    wf, ok := wfLib.Get(name, version)
+    if !ok { respondWorkflowError(w, workflows.ErrNotFound) }
+    return wf, ok
+}
+
+// getWorkflow serves GET /v1/workflows/{name} and {name}:export.
+func getWorkflow(w http.ResponseWriter, r *http.Request) {
+    name, action := customMethod(r.PathValue("name"))
+    switch action {
+    case "":
+        if wf, ok := workflowOr404(w, name, 0); ok { respondJSON(w, wf) }
+    case "export":
+        version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+        if _, ok := workflowOr404(w, name, 0); !ok { return }
+        wf, ok := wfLib.Get(name, version)
+        if !ok { respondError(w, http.StatusNotFound, codeVersionNotFound, "workflow version not found", nil); return }
+        respondFile(w, workflows.FileFor(wf), r.URL.Query().Get("format"))
+    default:
+        unknownMethod(w, action)
+    }
+}
+
+func deleteWorkflow(w http.ResponseWriter, r *http.Request) {
+    if err := wfLib.Delete(r.PathValue("name")); err != nil { respondWorkflowError(w, workflows.ErrNotFound); return }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func listWorkflowVersions(w http.ResponseWriter, r *http.Request) {
+    versions := wfLib.Versions(r.PathValue("name"))
+    if len(versions) == 0 { respondWorkflowError(w, workflows.ErrNotFound); return }
+    respondJSON(w, versions)
+}
+
+func getWorkflowVersion(w http.ResponseWriter, r *http.Request) {
+    name := r.PathValue("name")
+    if _, ok := workflowOr404(w, name, 0); !ok { return }
+    n, err := strconv.Atoi(r.PathValue("n"))
+    wf, ok := wfLib.Get(name, n)
+    if err != nil || n < 1 || !ok { respondError(w, http.StatusNotFound, codeVersionNotFound, "workflow version not found", nil); return }
+    respondJSON(w, wf)
+}
+
+// handleWorkflowAction serves POST /v1/workflows/{name}:run, which answers 202 with
+// the task it created and its queue entry.
+func handleWorkflowAction(w http.ResponseWriter, r *http.Request) {
+    name, action := customMethod(r.PathValue("name"))
+    if action != "run" { unknownMethod(w, action); return }
+    var opts workflows.RunOptions
+    if !decodeBody(w, r, &opts) { return }
+    opts.ClientID = clientID(r)
+    t, job, err := wfLib.Run(genID(), name, opts)
+    if err != nil { respondWorkflowError(w, err); return }
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusAccepted)
+    respondJSON(w, map[string]any{"task": t, "job": job})
+}
+
+func workflowTasks(w http.ResponseWriter, r *http.Request) {
+    name := r.PathValue("name")
+    if _, ok := workflowOr404(w, name, 0); !ok { return }
+    tasks := []*models.Task{}
+    for _, t := range orch.ListTasks() {
+        if wfName, _, _ := strings.Cut(t.Workflow, "@"); t.Workflow != "" && wfName == name { tasks = append(tasks, t) }
+    }
+    respondJSON(w, tasks)
+}
+
+// respondFile writes a workflow file as a download named after it.
+func respondFile(w http.ResponseWriter, f *workflows.File, format string) {
+    b, err := f.Marshal(format)
+    if err != nil { respondError(w, http.StatusBadRequest, codeInvalidRequest, err.Error(), nil); return }
+    ext, ctype := "yaml", "application/yaml"
+    if format == "json" { ext, ctype = "json", "application/json" }
+    w.Header().Set("Content-Type", ctype)
+    w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.Name+"."+ext))
+    w.Write(b)
+}
+
+// respondWorkflowError maps library errors: 404 unknown workflow, 422 with the list
+// of problems for an invalid definition or parameters, 409 when the task cannot be
+// queued.
+func respondWorkflowError(w http.ResponseWriter, err error) {
+    respondErr(w, err, http.StatusConflict, codeConflict)
+}
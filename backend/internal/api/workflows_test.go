@@ -0,0 +1,95 @@
+package api
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/workflows"
+)
+
+// greetWorkflow is a workflow body with a required and a defaulted parameter.
+func greetWorkflow(name string) map[string]any {
+    return map[string]any{
+        "name":   name,
+        "params": []any{map[string]any{"name": "who", "type": "string", "required": true}, map[string]any{"name": "lang", "type": "string", "default": "en"}},
+        "plan":   map[string]any{"steps": []any{map[string]any{"id": "say", "tool": "echo", "inputs": map[string]any{"text": "hi {{params.who}} {{params.lang}}"}}}},
+    }
+}
+
+func TestWorkflowEndpoints(t *testing.T) {
+    srv := newTestServer(t)
+    name := fmt.Sprintf("greet-%d", taskSeq.Add(1))
+    base := srv.URL + "/v1/workflows/" + name
+    var wf workflows.Workflow
+    resp := call(t, http.MethodPost, srv.URL+"/v1/workflows", greetWorkflow(name), &wf)
+    if resp.StatusCode != http.StatusCreated || wf.Version != 1 || resp.Header.Get("Location") != "/v1/workflows/"+name { t.Fatalf("save: %d %+v", resp.StatusCode, wf) }
+    call(t, http.MethodPost, srv.URL+"/v1/workflows", greetWorkflow(name), &wf)
+    var versions []workflows.Workflow
+    if call(t, http.MethodGet, base+"/versions", nil, &versions); len(versions) != 2 { t.Fatalf("%d versions", len(versions)) }
+    if resp := call(t, http.MethodGet, base+"/versions/1", nil, &wf); resp.StatusCode != http.StatusOK || wf.Version != 1 { t.Fatalf("version 1: %d %+v", resp.StatusCode, wf) }
+    var e apiError
+    if resp := call(t, http.MethodGet, base+"/versions/9", nil, &e); resp.StatusCode != http.StatusNotFound || e.Code != "version_not_found" { t.Fatalf("version 9: %d %+v", resp.StatusCode, e) }
+
+    bad := greetWorkflow("bad name")
+    bad["plan"].(map[string]any)["steps"].([]any)[0].(map[string]any)["inputs"] = map[string]any{"text": "{{params.nope}}"}
+    if resp := call(t, http.MethodPost, srv.URL+"/v1/workflows", bad, &e); resp.StatusCode != http.StatusUnprocessableEntity || len(e.Problems) != 2 { t.Fatalf("invalid workflow: %d %+v", resp.StatusCode, e) }
+
+    if resp := call(t, http.MethodPost, base+":run", map[string]any{"params": map[string]any{"who": 3}}, &e); resp.StatusCode != http.StatusUnprocessableEntity || !strings.Contains(strings.Join(e.Problems, " "), "params.who") { t.Fatalf("bad params: %d %+v", resp.StatusCode, e) }
+    var run struct {
+        Task *models.Task `json:"task"`
+        Job  struct {
+            Kind string `json:"kind"`
+        } `json:"job"`
+    }
+    if resp := call(t, http.MethodPost, base+":run", map[string]any{"params": map[string]any{"who": "ann"}, "version": 1}, &run); resp.StatusCode != http.StatusAccepted || run.Task.Workflow != name+"@1" || run.Job.Kind != "execute" {
+        t.Fatalf("run: %d %+v", resp.StatusCode, run)
+    }
+    if ev := waitEvent(t, run.Task.ID, `"result"`, `"say"`); !strings.Contains(ev, "hi ann en") { t.Fatalf("result %s", ev) }
+    var tasks []models.Task
+    if call(t, http.MethodGet, base+"/tasks", nil, &tasks); len(tasks) != 1 || tasks[0].ID != run.Task.ID { t.Fatalf("tasks %+v", tasks) }
+
+    if resp := call(t, http.MethodDelete, base, nil, nil); resp.StatusCode != http.StatusNoContent { t.Fatalf("delete: %d", resp.StatusCode) }
+    if resp := call(t, http.MethodPost, base+":run", map[string]any{}, &e); resp.StatusCode != http.StatusNotFound || e.Code != "workflow_not_found" { t.Fatalf("run deleted: %d %+v", resp.StatusCode, e) }
+}
+
+func TestWorkflowFiles(t *testing.T) {
+    srv := newTestServer(t)
+    name := fmt.Sprintf("file-%d", taskSeq.Add(1))
+    upload := func(body string, out any) *http.Response {
+        resp, err := http.Post(srv.URL+"/v1/workflows", "application/yaml", strings.NewReader(body))
+        if err != nil { t.Fatal(err) }
+        defer resp.Body.Close()
+        json.NewDecoder(resp.Body).Decode(out)
+        return resp
+    }
+    var e apiError
+    if resp := upload("name: "+name+"\nsteps:\n  - id: a\n    tool: nope\n", &e); resp.StatusCode != http.StatusUnprocessableEntity || len(e.Problems) != 1 || !strings.HasPrefix(e.Problems[0], "request:3: step a") {
+        t.Fatalf("invalid file: %d %+v", resp.StatusCode, e)
+    }
+    var wf workflows.Workflow
+    if resp := upload("name: "+name+"\nsteps:\n  - id: a\n    tool: echo\n    inputs: {text: hi}\n", &wf); resp.StatusCode != http.StatusCreated || wf.Source != "" { t.Fatalf("upload: %d %+v", resp.StatusCode, wf) }
+
+    get := func(url string) (*http.Response, string) {
+        resp, err := http.Get(url)
+        if err != nil { t.Fatal(err) }
+        defer resp.Body.Close()
+        b, _ := io.ReadAll(resp.Body)
+        return resp, string(b)
+    }
+    resp, body := get(srv.URL + "/v1/workflows/" + name + ":export")
+    if resp.Header.Get("Content-Type") != "application/yaml" || !strings.Contains(resp.Header.Get("Content-Disposition"), name+".yaml") || !strings.Contains(body, "tool: echo") { t.Fatalf("yaml export: %v\n%s", resp.Header, body) }
+    if resp, body = get(srv.URL + "/v1/workflows/" + name + ":export?format=json"); !strings.Contains(body, `"tool": "echo"`) { t.Fatalf("json export: %s", body) }
+    if resp, _ = get(srv.URL + "/v1/workflows/" + name + ":export?format=toml"); resp.StatusCode != http.StatusBadRequest { t.Fatalf("unknown format: %d", resp.StatusCode) }
+
+    // a task's plan exports in the same format, and uploads again as a workflow
+    task := newTask(t, "export")
+    if resp, body = get(srv.URL + "/v1/tasks/" + task.ID + "/plan:export"); resp.StatusCode != http.StatusOK || !strings.Contains(body, "id: say") { t.Fatalf("plan export: %d %s", resp.StatusCode, body) }
+    if resp := upload(strings.Replace(body, "name: "+task.ID, "name: "+name+"-copy", 1), &wf); resp.StatusCode != http.StatusCreated { t.Fatalf("re-upload: %d %+v", resp.StatusCode, wf) }
+    empty := orch.CreateTask(fmt.Sprintf("noplan-%d", taskSeq.Add(1)), "q", nil)
+    if resp := call(t, http.MethodGet, srv.URL+"/v1/tasks/"+empty.ID+"/plan:export", nil, &e); resp.StatusCode != http.StatusConflict || e.Code != "no_plan" { t.Fatalf("no plan: %d %+v", resp.StatusCode, e) }
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "net/url"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "golang.org/x/net/websocket"
+)
+
+// wsMessage is a control message sent by a WebSocket client.
+//
+//    {"id":"1","type":"create","query":"...","context":{},"subscribe":true}
+//    {"id":"2","type":"subscribe","task_id":"T","last_event_id":0}
+//    {"id":"3","type":"start","task_id":"T"}
+//    {"id":"4","type":"approve","task_id":"T","step_id":"post","by":"alice"}
+//    {"id":"5","type":"answer","task_id":"T","step_id":"which","answer":"b.pdf"}
+//
+// Types: subscribe, unsubscribe, create, plan, execute, start, pause, resume,
+// cancel, approve, reject, answer, ping. task_id "*" subscribes to every task (the /v1/events firehose, without
+// snapshots).
+type wsMessage struct {
+    ID          string         `json:"id,omitempty"`
+    Type        string         `json:"type"`
+    TaskID      string         `json:"task_id,omitempty"`
+    LastEventID uint64         `json:"last_event_id,omitempty"`
+    Query       string         `json:"query,omitempty"`
+    Context     map[string]any `json:"context,omitempty"`
+    Subscribe   bool           `json:"subscribe,omitempty"`
+    StepID      string         `json:"step_id,omitempty"`
+    By          string         `json:"by,omitempty"`
+    Comment     string         `json:"comment,omitempty"`
+    Answer      string         `json:"answer,omitempty"`
+}
+
+// wsConn is one client connection. Everything sent to the client is an
+// orchestrator.Event: task events as published, plus "reply" (answer to a control
+// message, payload {request_id, type, ok, result?, error?, code?}), "snapshot", "gap",
+// "heartbeat" and "shutdown".
+type wsConn struct {
+    ws     *websocket.Conn
+    ctx    context.Context
+    sendMu sync.Mutex
+    subsMu sync.Mutex
+    subs   map[string]context.CancelFunc
+}
+
+// wsHandler serves /v1/ws. Browsers do not apply CORS to WebSocket handshakes, so
+// without a check any page a user visits could open one and drive tasks. Handshakes
+// without Origin (non-browser clients) and from the server's own host are accepted,
+// others only when listed in WS_ALLOWED_ORIGINS (comma separated origins such as
+// http://localhost:5173, or "*" for any).
+func wsHandler() http.Handler {
+    allowed := map[string]bool{}
+    for _, o := range strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",") {
+        if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" { allowed[strings.ToLower(o)] = true }
+    }
+    handshake := func(_ *websocket.Config, r *http.Request) error {
+        origin := r.Header.Get("Origin")
+        if origin == "" || allowed["*"] || allowed[strings.ToLower(origin)] { return nil }
+        if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) { return nil }
+        return fmt.Errorf("websocket: origin %q not allowed", origin)
+    }
+    return websocket.Server{Handshake: handshake, Handler: serveWS}
+}
+
+func serveWS(ws *websocket.Conn) {
+    ctx, cancel := context.WithCancel(ws.Request().Context())
+    c := &wsConn{ws: ws, ctx: ctx, subs: map[string]context.CancelFunc{}}
+    defer func() {
+        cancel()
+        ws.Close()
+    }()
+    go c.heartbeat(cancel)
+    for {
+        var raw string
+        if err := websocket.Message.Receive(ws, &raw); err != nil { return }
+        var msg wsMessage
+        if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+            c.reply(wsMessage{Type: "invalid"}, nil, fmt.Errorf("invalid message: %v", err))
+            continue
+        }
+        c.handle(msg)
+    }
+}
+
+// heartbeat keeps idle connections alive and closes the connection on server
+// shutdown, telling the client first.
+func (c *wsConn) heartbeat(cancel context.CancelFunc) {
+    ticker := time.NewTicker(20 * time.Second)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-c.ctx.Done():
+            return
+        case <-shuttingDown:
+            c.sendEvent(orchestrator.Event{Event: "shutdown"})
+            cancel()
+            c.ws.Close()
+            return
+        case now := <-ticker.C:
+            if err := c.sendEvent(orchestrator.Event{Event: "heartbeat", Payload: map[string]any{"time": now.UTC()}}); err != nil {
+                // a peer that cannot take a heartbeat within the write deadline is gone
+                cancel()
+                c.ws.Close()
+                return
+            }
+        }
+    }
+}
+
+func (c *wsConn) handle(msg wsMessage) {
+    switch msg.Type {
+    case "ping":
+        c.reply(msg, "pong", nil)
+    case "subscribe":
+        if msg.TaskID == "" { c.reply(msg, nil, fmt.Errorf("missing task_id")); return }
+        if msg.TaskID != "*" {
+            if _, ok := orch.GetTask(msg.TaskID); !ok { c.reply(msg, nil, orchestrator.ErrTaskNotFound); return }
+        }
+        c.reply(msg, nil, nil)
+        c.subscribe(msg.TaskID, msg.LastEventID)
+    case "unsubscribe":
+        c.subsMu.Lock()
+        stop, ok := c.subs[msg.TaskID]
+        delete(c.subs, msg.TaskID)
+        c.subsMu.Unlock()
+        if ok { stop() }
+        c.reply(msg, nil, nil)
+    case "create":
+        t := orch.AddTask(&models.Task{ID: genID(), Query: msg.Query, Context: msg.Context, ClientID: clientID(c.ws.Request())})
+        msg.TaskID = t.ID
+        c.reply(msg, t, nil)
+        if msg.Subscribe { c.subscribe(t.ID, 0) }
+    case "plan":
+        if _, ok := orch.GetTask(msg.TaskID); !ok { c.reply(msg, nil, orchestrator.ErrTaskNotFound); return }
+        // planning may call an LLM; keep reading control messages meanwhile
+        go func() {
+            plan, err := orch.PlanOnly(c.ctx, msg.TaskID)
+            c.reply(msg, plan, err)
+        }()
+    case "execute", "start":
+        if _, ok := orch.GetTask(msg.TaskID); !ok { c.reply(msg, nil, orchestrator.ErrTaskNotFound); return }
+        if msg.Subscribe { c.subscribe(msg.TaskID, 0) }
+        // like the HTTP endpoints, the run is queued and outlives the connection
+        job, err := orch.Enqueue(msg.TaskID, msg.Type)
+        c.reply(msg, job, err)
+    case "pause":
+        c.reply(msg, nil, orch.Pause(msg.TaskID))
+    case "cancel":
+        c.reply(msg, nil, orch.Cancel(msg.TaskID))
+    case "resume":
+        job, err := orch.Enqueue(msg.TaskID, orchestrator.JobResume)
+        c.reply(msg, job, err)
+    case "approve", "reject":
+        c.reply(msg, nil, orch.DecideApproval(msg.TaskID, msg.StepID, msg.Type == "approve", msg.By, msg.Comment))
+    case "answer":
+        by := msg.By
+        if by == "" { by = "anonymous" }
+        c.reply(msg, nil, orch.AnswerQuestion(msg.TaskID, msg.StepID, msg.Answer, by))
+    default:
+        c.reply(msg, nil, fmt.Errorf("unknown message type %q", msg.Type))
+    }
+}
+
+// subscribe (re)starts forwarding a task's events; subscribing again replaces the
+// previous subscription.
+func (c *wsConn) subscribe(taskID string, lastID uint64) {
+    key := taskID
+    if taskID == "*" { taskID = orchestrator.AllTasks }
+    ctx, stop := context.WithCancel(c.ctx)
+    c.subsMu.Lock()
+    if old, ok := c.subs[key]; ok { old() }
+    c.subs[key] = stop
+    c.subsMu.Unlock()
+    sub := orch.Subscribe(taskID, lastID)
+    go func() {
+        defer sub.Close()
+        snapshot := func() {
+            if taskID == orchestrator.AllTasks { return }
+            if t, ok := orch.GetTask(taskID); ok {
+                c.sendEvent(orchestrator.Event{ID: sub.Cursor(), Event: "snapshot", TaskID: taskID, Payload: t})
+            }
+        }
+        if lastID == 0 { snapshot() }
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-sub.C():
+            }
+            recs, gap := sub.Next()
+            if gap != nil {
+                c.sendEvent(orchestrator.Event{Event: "gap", TaskID: gap.TaskID, Payload: gap})
+                snapshot()
+                continue
+            }
+            for _, rec := range recs {
+                if c.send(rec.Data) != nil { return }
+            }
+        }
+    }()
+}
+
+func (c *wsConn) reply(msg wsMessage, result any, err error) {
+    payload := map[string]any{"request_id": msg.ID, "type": msg.Type, "ok": err == nil}
+    if result != nil { payload["result"] = result }
+    if err != nil {
+        _, code, _ := classify(err, http.StatusBadRequest, codeInvalidRequest)
+        payload["error"], payload["code"] = err.Error(), code
+    }
+    c.sendEvent(orchestrator.Event{Event: "reply", TaskID: msg.TaskID, Payload: payload})
+}
+
+func (c *wsConn) sendEvent(ev orchestrator.Event) error {
+    b, err := json.Marshal(ev)
+    if err != nil { return err }
+    return c.send(b)
+}
+
+// send writes one text frame; frames from concurrent subscriptions must not interleave.
+func (c *wsConn) send(data []byte) error {
+    c.sendMu.Lock()
+    defer c.sendMu.Unlock()
+    c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
+    return websocket.Message.Send(c.ws, string(data))
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "golang.org/x/net/websocket"
+)
+
+// wsEvent is a server message as decoded by a client.
+type wsEvent struct {
+    ID      uint64          `json:"id"`
+    Event   string          `json:"event"`
+    TaskID  string          `json:"task_id"`
+    Payload json.RawMessage `json:"payload"`
+}
+
+func dialWS(t *testing.T, srv *httptest.Server, origin string, header http.Header) (*websocket.Conn, error) {
+    t.Helper()
+    cfg, err := websocket.NewConfig(strings.Replace(srv.URL, "http", "ws", 1)+"/v1/ws", origin)
+    if err != nil { t.Fatal(err) }
+    if header != nil { cfg.Header = header }
+    ws, err := websocket.DialConfig(cfg)
+    if err == nil { t.Cleanup(func() { ws.Close() }) }
+    return ws, err
+}
+
+// readWS returns the first message matching pred, skipping others.
+func readWS(t *testing.T, ws *websocket.Conn, pred func(wsEvent) bool) wsEvent {
+    t.Helper()
+    ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+    for {
+        var ev wsEvent
+        if err := websocket.JSON.Receive(ws, &ev); err != nil { t.Fatalf("read: %v", err) }
+        if pred(ev) { return ev }
+    }
+}
+
+func isReply(id string) func(wsEvent) bool {
+    return func(ev wsEvent) bool { return ev.Event == "reply" && strings.Contains(string(ev.Payload), `"request_id":"`+id+`"`) }
+}
+
+func TestWSOrigin(t *testing.T) {
+    srv := newTestServer(t)
+    if _, err := dialWS(t, srv, srv.URL, nil); err != nil { t.Fatalf("same origin rejected: %v", err) }
+    if _, err := dialWS(t, srv, "https://evil.example", nil); err == nil { t.Fatal("foreign origin accepted") }
+
+    t.Setenv("WS_ALLOWED_ORIGINS", "http://localhost:5173/, https://ops.example")
+    srv = newTestServer(t)
+    if _, err := dialWS(t, srv, "https://ops.example", nil); err != nil { t.Fatalf("allowed origin rejected: %v", err) }
+    if _, err := dialWS(t, srv, "https://evil.example", nil); err == nil { t.Fatal("foreign origin accepted with an allowlist") }
+    t.Setenv("WS_ALLOWED_ORIGINS", "*")
+    if _, err := dialWS(t, newTestServer(t), "https://evil.example", nil); err != nil { t.Fatalf("* rejected: %v", err) }
+}
+
+func TestWSCreateAndRun(t *testing.T) {
+    srv := newTestServer(t)
+    ws, err := dialWS(t, srv, srv.URL, http.Header{"X-Client-Id": {"ws-client"}})
+    if err != nil { t.Fatal(err) }
+    websocket.JSON.Send(ws, map[string]any{"id": "1", "type": "create", "query": "q", "subscribe": true})
+    reply := readWS(t, ws, isReply("1"))
+    var r struct {
+        OK     bool `json:"ok"`
+        Result struct {
+            ID       string `json:"id"`
+            ClientID string `json:"client_id"`
+        } `json:"result"`
+    }
+    json.Unmarshal(reply.Payload, &r)
+    if !r.OK || r.Result.ClientID != "ws-client" { t.Fatalf("create reply %s", reply.Payload) }
+    // the task was created with its client ID, before it was published or queued
+    if task, _ := orch.GetTask(r.Result.ID); task.ClientID != "ws-client" { t.Fatalf("task client %q", task.ClientID) }
+    if ev := readWS(t, ws, func(ev wsEvent) bool { return ev.Event == "snapshot" }); ev.TaskID != r.Result.ID { t.Fatalf("snapshot for %s", ev.TaskID) }
+
+
+    // run a task with a plan; resuming from an event ID skips the snapshot, which
+    // would read the task while its run changes it
+    task := newTask(t, "ws")
+    websocket.JSON.Send(ws, map[string]any{"id": "2", "type": "subscribe", "task_id": task.ID, "last_event_id": 1})
+    readWS(t, ws, isReply("2"))
+    websocket.JSON.Send(ws, map[string]any{"id": "3", "type": "execute", "task_id": task.ID})
+    if ev := readWS(t, ws, isReply("3")); !strings.Contains(string(ev.Payload), `"ok":true`) { t.Fatalf("execute reply %s", ev.Payload) }
+    readWS(t, ws, func(ev wsEvent) bool {
+        return ev.Event == "task_status" && ev.TaskID == task.ID && strings.Contains(string(ev.Payload), `"SUCCESS"`)
+    })
+}
+
+func TestWSErrors(t *testing.T) {
+    srv := newTestServer(t)
+    ws, err := dialWS(t, srv, srv.URL, nil)
+    if err != nil { t.Fatal(err) }
+    websocket.JSON.Send(ws, map[string]any{"id": "1", "type": "subscribe", "task_id": "no-such-task"})
+    if ev := readWS(t, ws, isReply("1")); !strings.Contains(string(ev.Payload), `"code":"task_not_found"`) { t.Fatalf("reply %s", ev.Payload) }
+    websocket.JSON.Send(ws, map[string]any{"id": "2", "type": "bogus"})
+    if ev := readWS(t, ws, isReply("2")); !strings.Contains(string(ev.Payload), `"ok":false`) { t.Fatalf("reply %s", ev.Payload) }
+    // approvals must name who decides
+    websocket.JSON.Send(ws, map[string]any{"id": "3", "type": "approve", "task_id": newTask(t, "ws").ID, "step_id": "a"})
+    if ev := readWS(t, ws, isReply("3")); !strings.Contains(string(ev.Payload), `"code":"invalid_request"`) { t.Fatalf("reply %s", ev.Payload) }
+    websocket.Message.Send(ws, "not json")
+    if ev := readWS(t, ws, func(ev wsEvent) bool { return ev.Event == "reply" }); !strings.Contains(string(ev.Payload), `"type":"invalid"`) { t.Fatalf("reply %s", ev.Payload) }
+}
@@ -0,0 +1,77 @@
+package jsonschema
+
+// Example builds a placeholder value that satisfies the common constraints of schema:
+// defaults and enums win, objects get their required (or all) properties and arrays
+// get minItems elements. It backs the mock LLM's structured output.
+func Example(schema map[string]any) any {
+    return example(schema, schema, 0)
+}
+
+func example(root, schema map[string]any, depth int) any {
+    if schema == nil || depth > 16 { return nil }
+    if ref, ok := schema["$ref"].(string); ok {
+        vd := &validator{root: root}
+        target, err := vd.resolve(ref)
+        if err != nil { return nil }
+        return example(root, target, depth+1)
+    }
+    if d, ok := schema["default"]; ok { return d }
+    if c, ok := schema["const"]; ok { return c }
+    if enum, ok := list(schema["enum"]); ok && len(enum) > 0 { return enum[0] }
+    for _, key := range []string{"allOf", "anyOf", "oneOf"} {
+        if subs, ok := list(schema[key]); ok && len(subs) > 0 {
+            if sm, ok := subs[0].(map[string]any); ok { return example(root, sm, depth+1) }
+        }
+    }
+    t := schema["type"]
+    if arr, ok := list(t); ok && len(arr) > 0 { t = arr[0] }
+    switch t {
+    case "string":
+        switch schema["format"] {
+        case "date-time":
+            return "1970-01-01T00:00:00Z"
+        case "date":
+            return "1970-01-01"
+        case "email":
+            return "user@example.com"
+        case "uri", "url":
+            return "https://example.com"
+        }
+        if min, ok := number(schema["minLength"]); ok && min > 0 {
+            b := make([]byte, int(min))
+            for i := range b { b[i] = 'x' }
+            return string(b)
+        }
+        return ""
+    case "integer", "number":
+        if min, ok := number(schema["minimum"]); ok { return min }
+        if min, ok := number(schema["exclusiveMinimum"]); ok { return min + 1 }
+        return 0.0
+    case "boolean":
+        return false
+    case "null":
+        return nil
+    case "array":
+        out := []any{}
+        items, _ := schema["items"].(map[string]any)
+        if min, ok := number(schema["minItems"]); ok {
+            for i := 0; i < int(min); i++ { out = append(out, example(root, items, depth+1)) }
+        }
+        return out
+    }
+    // objects, and untyped schemas that declare properties
+    props, _ := schema["properties"].(map[string]any)
+    required := map[string]bool{}
+    if req, ok := list(schema["required"]); ok {
+        for _, r := range req {
+            if s, ok := r.(string); ok { required[s] = true }
+        }
+    }
+    out := map[string]any{}
+    for name, p := range props {
+        if len(required) > 0 && !required[name] { continue }
+        pm, _ := p.(map[string]any)
+        out[name] = example(root, pm, depth+1)
+    }
+    return out
+}
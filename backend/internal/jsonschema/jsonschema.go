@@ -0,0 +1,372 @@
+// Package jsonschema validates decoded JSON values against a practical subset of
+// JSON Schema (draft 2020-12 / OpenAPI 3 flavored). Schemas and values are the
+// generic shapes produced by encoding/json: map[string]any, []any, float64, string,
+// bool and nil.
+package jsonschema
+
+import (
+    "encoding/json"
+    "fmt"
+    "math"
+    "net/mail"
+    "net/url"
+    "reflect"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Error is a single validation failure at a JSON path such as $.items[0].price.
+type Error struct {
+    Path    string
+    Message string
+}
+
+func (e Error) Error() string { return e.Path + ": " + e.Message }
+
+// Errors collects every failure found in one validation pass.
+type Errors []Error
+
+func (es Errors) Error() string {
+    parts := make([]string, len(es))
+    for i, e := range es { parts[i] = e.Error() }
+    return strings.Join(parts, "; ")
+}
+
+// Validate checks v against schema and returns Errors, or nil when v is valid.
+// $ref pointers ("#/...") resolve against schema itself.
+func Validate(schema map[string]any, v any) error {
+    return ValidateWithRoot(schema, schema, v)
+}
+
+// ValidateWithRoot is Validate for a sub-schema whose $ref pointers resolve against a
+// larger document, e.g. an OpenAPI spec with components.
+func ValidateWithRoot(root, schema map[string]any, v any) error {
+    vd := &validator{root: root}
+    vd.validate("$", schema, normalize(v))
+    if len(vd.errs) == 0 { return nil }
+    return vd.errs
+}
+
+// Parse accepts a schema given as a map or as a JSON string.
+func Parse(v any) (map[string]any, error) {
+    switch t := v.(type) {
+    case map[string]any:
+        return t, nil
+    case string:
+        var m map[string]any
+        if err := json.Unmarshal([]byte(t), &m); err != nil { return nil, fmt.Errorf("invalid schema JSON: %w", err) }
+        return m, nil
+    case nil:
+        return nil, fmt.Errorf("missing schema")
+    default:
+        b, err := json.Marshal(t)
+        if err != nil { return nil, err }
+        var m map[string]any
+        if err := json.Unmarshal(b, &m); err != nil { return nil, fmt.Errorf("schema must be an object") }
+        return m, nil
+    }
+}
+
+type validator struct {
+    root map[string]any
+    errs Errors
+    // depth guards against $ref cycles
+    depth int
+}
+
+func (vd *validator) fail(path, format string, args ...any) {
+    vd.errs = append(vd.errs, Error{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (vd *validator) validate(path string, schema map[string]any, v any) {
+    if schema == nil { return }
+    if ref, ok := schema["$ref"].(string); ok {
+        target, err := vd.resolve(ref)
+        if err != nil { vd.fail(path, "%v", err); return }
+        if vd.depth > 64 { vd.fail(path, "$ref nesting too deep at %s", ref); return }
+        vd.depth++
+        vd.validate(path, target, v)
+        vd.depth--
+        return
+    }
+    if v == nil {
+        if n, _ := schema["nullable"].(bool); n { return }
+    }
+    if t, ok := schema["type"]; ok && !matchesType(t, v) {
+        vd.fail(path, "expected %s, got %s", typeString(t), kindOf(v))
+        return
+    }
+    if enum, ok := list(schema["enum"]); ok {
+        found := false
+        for _, e := range enum {
+            if equal(e, v) { found = true; break }
+        }
+        if !found { vd.fail(path, "must be one of %s", compact(enum)) }
+    }
+    if c, ok := schema["const"]; ok && !equal(c, v) {
+        vd.fail(path, "must equal %s", compact(c))
+    }
+
+    switch t := v.(type) {
+    case string:
+        vd.validateString(path, schema, t)
+    case float64:
+        vd.validateNumber(path, schema, t)
+    case []any:
+        vd.validateArray(path, schema, t)
+    case map[string]any:
+        vd.validateObject(path, schema, t)
+    }
+
+    if all, ok := list(schema["allOf"]); ok {
+        for _, s := range all {
+            if sm, ok := s.(map[string]any); ok { vd.validate(path, sm, v) }
+        }
+    }
+    if anyOf, ok := list(schema["anyOf"]); ok {
+        if vd.countMatches(anyOf, v) == 0 { vd.fail(path, "does not match any allowed schema") }
+    }
+    if oneOf, ok := list(schema["oneOf"]); ok {
+        if n := vd.countMatches(oneOf, v); n != 1 { vd.fail(path, "must match exactly one schema, matched %d", n) }
+    }
+    if not, ok := schema["not"].(map[string]any); ok {
+        if vd.matches(not, v) { vd.fail(path, "must not match schema") }
+    }
+}
+
+func (vd *validator) matches(schema map[string]any, v any) bool {
+    sub := &validator{root: vd.root, depth: vd.depth}
+    sub.validate("$", schema, v)
+    return len(sub.errs) == 0
+}
+
+func (vd *validator) countMatches(schemas []any, v any) int {
+    n := 0
+    for _, s := range schemas {
+        if sm, ok := s.(map[string]any); ok && vd.matches(sm, v) { n++ }
+    }
+    return n
+}
+
+func (vd *validator) validateString(path string, schema map[string]any, s string) {
+    n := float64(len([]rune(s)))
+    if min, ok := number(schema["minLength"]); ok && n < min { vd.fail(path, "length must be >= %v", min) }
+    if max, ok := number(schema["maxLength"]); ok && n > max { vd.fail(path, "length must be <= %v", max) }
+    if p, ok := schema["pattern"].(string); ok {
+        re, err := regexp.Compile(p)
+        if err != nil {
+            vd.fail(path, "invalid pattern %q", p)
+        } else if !re.MatchString(s) {
+            vd.fail(path, "must match pattern %q", p)
+        }
+    }
+    if f, ok := schema["format"].(string); ok {
+        if err := checkFormat(f, s); err != nil { vd.fail(path, "invalid %s: %v", f, err) }
+    }
+}
+
+func (vd *validator) validateNumber(path string, schema map[string]any, x float64) {
+    if min, ok := number(schema["minimum"]); ok && x < min { vd.fail(path, "must be >= %v", min) }
+    if max, ok := number(schema["maximum"]); ok && x > max { vd.fail(path, "must be <= %v", max) }
+    if min, ok := number(schema["exclusiveMinimum"]); ok && x <= min { vd.fail(path, "must be > %v", min) }
+    if max, ok := number(schema["exclusiveMaximum"]); ok && x >= max { vd.fail(path, "must be < %v", max) }
+    if m, ok := number(schema["multipleOf"]); ok && m > 0 {
+        if q := x / m; math.Abs(q-math.Round(q)) > 1e-9 { vd.fail(path, "must be a multiple of %v", m) }
+    }
+}
+
+func (vd *validator) validateArray(path string, schema map[string]any, arr []any) {
+    n := float64(len(arr))
+    if min, ok := number(schema["minItems"]); ok && n < min { vd.fail(path, "must have at least %v items", min) }
+    if max, ok := number(schema["maxItems"]); ok && n > max { vd.fail(path, "must have at most %v items", max) }
+    if u, _ := schema["uniqueItems"].(bool); u {
+        for i := range arr {
+            for j := i + 1; j < len(arr); j++ {
+                if equal(arr[i], arr[j]) { vd.fail(path, "items %d and %d are equal", i, j) }
+            }
+        }
+    }
+    if items, ok := schema["items"].(map[string]any); ok {
+        for i, it := range arr { vd.validate(fmt.Sprintf("%s[%d]", path, i), items, it) }
+    }
+}
+
+func (vd *validator) validateObject(path string, schema map[string]any, obj map[string]any) {
+    if req, ok := list(schema["required"]); ok {
+        for _, r := range req {
+            if name, ok := r.(string); ok {
+                if _, present := obj[name]; !present { vd.fail(childPath(path, name), "is required") }
+            }
+        }
+    }
+    n := float64(len(obj))
+    if min, ok := number(schema["minProperties"]); ok && n < min { vd.fail(path, "must have at least %v properties", min) }
+    if max, ok := number(schema["maxProperties"]); ok && n > max { vd.fail(path, "must have at most %v properties", max) }
+    props, _ := schema["properties"].(map[string]any)
+    keys := make([]string, 0, len(obj))
+    for k := range obj { keys = append(keys, k) }
+    sort.Strings(keys)
+    for _, k := range keys {
+        if ps, ok := props[k].(map[string]any); ok {
+            vd.validate(childPath(path, k), ps, obj[k])
+            continue
+        }
+        switch ap := schema["additionalProperties"].(type) {
+        case bool:
+            if !ap { vd.fail(childPath(path, k), "unexpected property") }
+        case map[string]any:
+            vd.validate(childPath(path, k), ap, obj[k])
+        }
+    }
+}
+
+// resolve follows a local JSON pointer such as #/components/schemas/Task.
+func (vd *validator) resolve(ref string) (map[string]any, error) {
+    if !strings.HasPrefix(ref, "#") { return nil, fmt.Errorf("unsupported $ref %q", ref) }
+    var cur any = vd.root
+    for _, tok := range strings.Split(strings.TrimPrefix(strings.TrimPrefix(ref, "#"), "/"), "/") {
+        if tok == "" { continue }
+        tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+        m, ok := cur.(map[string]any)
+        if !ok { return nil, fmt.Errorf("unresolvable $ref %q", ref) }
+        if cur, ok = m[tok]; !ok { return nil, fmt.Errorf("unresolvable $ref %q", ref) }
+    }
+    m, ok := cur.(map[string]any)
+    if !ok { return nil, fmt.Errorf("$ref %q is not a schema", ref) }
+    return m, nil
+}
+
+func childPath(path, key string) string {
+    for _, r := range key {
+        if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+            return path + "[" + strconv.Quote(key) + "]"
+        }
+    }
+    return path + "." + key
+}
+
+func matchesType(t any, v any) bool {
+    if s, ok := t.(string); ok { return matchesOne(s, v) }
+    if arr, ok := list(t); ok {
+        for _, x := range arr {
+            if s, ok := x.(string); ok && matchesOne(s, v) { return true }
+        }
+        return false
+    }
+    return true
+}
+
+func matchesOne(t string, v any) bool {
+    switch t {
+    case "null":
+        return v == nil
+    case "boolean":
+        _, ok := v.(bool)
+        return ok
+    case "string":
+        _, ok := v.(string)
+        return ok
+    case "number":
+        _, ok := v.(float64)
+        return ok
+    case "integer":
+        f, ok := v.(float64)
+        return ok && f == math.Trunc(f)
+    case "array":
+        _, ok := v.([]any)
+        return ok
+    case "object":
+        _, ok := v.(map[string]any)
+        return ok
+    }
+    return false
+}
+
+func typeString(t any) string {
+    if arr, ok := list(t); ok {
+        parts := make([]string, 0, len(arr))
+        for _, x := range arr { parts = append(parts, fmt.Sprint(x)) }
+        return strings.Join(parts, " or ")
+    }
+    return fmt.Sprint(t)
+}
+
+func kindOf(v any) string {
+    switch t := v.(type) {
+    case nil:
+        return "null"
+    case bool:
+        return "boolean"
+    case string:
+        return "string"
+    case float64:
+        if t == math.Trunc(t) { return "integer" }
+        return "number"
+    case []any:
+        return "array"
+    case map[string]any:
+        return "object"
+    }
+    return fmt.Sprintf("%T", v)
+}
+
+func checkFormat(format, s string) error {
+    var err error
+    switch format {
+    case "date-time":
+        _, err = time.Parse(time.RFC3339, s)
+    case "date":
+        _, err = time.Parse("2006-01-02", s)
+    case "email":
+        _, err = mail.ParseAddress(s)
+    case "uri", "url":
+        var u *url.URL
+        if u, err = url.Parse(s); err == nil && u.Scheme == "" { err = fmt.Errorf("missing scheme") }
+    }
+    return err
+}
+
+func number(v any) (float64, bool) {
+    switch t := v.(type) {
+    case float64:
+        return t, true
+    case int:
+        return float64(t), true
+    case int64:
+        return float64(t), true
+    }
+    return 0, false
+}
+
+func equal(a, b any) bool { return reflect.DeepEqual(normalize(a), normalize(b)) }
+
+// normalize converts Go values (ints, typed slices, structs) into the generic JSON
+// shapes the validator understands.
+func normalize(v any) any {
+    switch v.(type) {
+    case nil, bool, string, float64:
+        return v
+    }
+    b, err := json.Marshal(v)
+    if err != nil { return v }
+    var out any
+    if json.Unmarshal(b, &out) != nil { return v }
+    return out
+}
+
+// list returns the array of a schema keyword such as enum or required, also when a
+// schema written in Go gives it as a typed slice like []string.
+func list(v any) ([]any, bool) {
+    if arr, ok := v.([]any); ok { return arr, true }
+    if v == nil || reflect.TypeOf(v).Kind() != reflect.Slice { return nil, false }
+    arr, ok := normalize(v).([]any)
+    return arr, ok
+}
+
+func compact(v any) string {
+    b, _ := json.Marshal(v)
+    return string(b)
+}
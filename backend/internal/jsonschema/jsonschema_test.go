@@ -0,0 +1,101 @@
+package jsonschema
+
+import (
+    "encoding/json"
+    "strings"
+    "testing"
+)
+
+const orderSchema = `{
+  "type": "object",
+  "required": ["id", "items"],
+  "additionalProperties": false,
+  "properties": {
+    "id": {"type": "string", "pattern": "^o-[0-9]+$"},
+    "email": {"type": "string", "format": "email"},
+    "items": {"type": "array", "minItems": 1, "items": {"$ref": "#/$defs/item"}}
+  },
+  "$defs": {
+    "item": {
+      "type": "object",
+      "required": ["sku", "qty"],
+      "properties": {"sku": {"type": "string"}, "qty": {"type": "integer", "minimum": 1}, "kind": {"enum": ["a", "b"]}}
+    }
+  }
+}`
+
+func TestValidate(t *testing.T) {
+    schema, err := Parse(orderSchema)
+    if err != nil { t.Fatal(err) }
+    cases := []struct {
+        doc  string
+        want []string // paths of the expected errors
+    }{
+        {`{"id": "o-1", "items": [{"sku": "x", "qty": 2, "kind": "a"}]}`, nil},
+        {`{"id": "1", "items": []}`, []string{"$.id", "$.items"}},
+        {`{"id": "o-1", "items": [{"sku": "x", "qty": 0.5}], "extra": true}`, []string{"$.extra", "$.items[0].qty"}},
+        {`{"id": "o-1", "email": "nope", "items": [{"sku": 1, "qty": 1, "kind": "c"}]}`, []string{"$.email", "$.items[0].kind", "$.items[0].sku"}},
+    }
+    for _, c := range cases {
+        var v any
+        if err := json.Unmarshal([]byte(c.doc), &v); err != nil { t.Fatal(err) }
+        err := Validate(schema, v)
+        var got []string
+        if errs, ok := err.(Errors); ok {
+            for _, e := range errs { got = append(got, e.Path) }
+        } else if err != nil {
+            t.Fatalf("%s: unexpected error type %T", c.doc, err)
+        }
+        if strings.Join(got, ",") != strings.Join(c.want, ",") { t.Errorf("%s: errors at %v, want %v (%v)", c.doc, got, c.want, err) }
+    }
+}
+
+func TestExampleValidates(t *testing.T) {
+    // Example does not invent strings for patterns
+    schema, err := Parse(strings.Replace(orderSchema, `, "pattern": "^o-[0-9]+$"`, "", 1))
+    if err != nil { t.Fatal(err) }
+    b, _ := json.Marshal(Example(schema))
+    var v any
+    json.Unmarshal(b, &v)
+    if err := Validate(schema, v); err != nil { t.Fatalf("example %s: %v", b, err) }
+}
+
+func TestParseRejectsBadSchema(t *testing.T) {
+    for _, in := range []any{`{`, 42, ""} {
+        if _, err := Parse(in); err == nil { t.Errorf("Parse(%v) accepted", in) }
+    }
+}
+
+func TestGoLiteralSchema(t *testing.T) {
+    // keyword arrays written as typed Go slices count like the decoded []any
+    schema := map[string]any{
+        "type":     "object",
+        "required": []string{"kind", "n"},
+        "properties": map[string]any{
+            "kind": map[string]any{"enum": []string{"a", "b"}},
+            "n":    map[string]any{"type": []string{"integer", "null"}, "minimum": 1},
+            "size": map[string]any{"anyOf": []map[string]any{{"type": "integer", "minimum": 1}, {"type": "null"}}},
+            "tags": map[string]any{"allOf": []map[string]any{{"type": "array"}, {"maxItems": 1}}},
+        },
+    }
+    cases := []struct {
+        doc  string
+        want []string
+    }{
+        {`{"kind": "a", "n": 2}`, nil},
+        {`{"kind": "a", "n": null, "size": null, "tags": ["x"]}`, nil},
+        {`{}`, []string{"$.kind", "$.n"}},
+        {`{"kind": "c", "n": "2"}`, []string{"$.kind", "$.n"}},
+        {`{"kind": "b", "n": 0, "size": 0, "tags": ["x", "y"]}`, []string{"$.n", "$.size", "$.tags"}},
+    }
+    for _, c := range cases {
+        var v any
+        if err := json.Unmarshal([]byte(c.doc), &v); err != nil { t.Fatal(err) }
+        var got []string
+        if errs, ok := Validate(schema, v).(Errors); ok {
+            for _, e := range errs { got = append(got, e.Path) }
+        }
+        if strings.Join(got, ",") != strings.Join(c.want, ",") { t.Errorf("%s: errors at %v, want %v", c.doc, got, c.want) }
+    }
+    if ex, err := json.Marshal(Example(schema)); err != nil || string(ex) != `{"kind":"a","n":1}` { t.Fatalf("example %s, %v", ex, err) }
+}
@@ -12,6 +12,19 @@ const (
     StatusRunning  Status = "RUNNING"
     StatusSuccess  Status = "SUCCESS"
     StatusFailed   Status = "FAILED"
+    // StatusSkipped marks a step whose condition was false or whose deps were all skipped.
+    StatusSkipped  Status = "SKIPPED"
+    // StatusAwaitingApproval marks a step (and its task) paused until a reviewer
+    // approves or rejects a risky tool call.
+    StatusAwaitingApproval Status = "AWAITING_APPROVAL"
+    // StatusAwaitingInput marks an ask_user step (and its task) waiting for an answer.
+    StatusAwaitingInput    Status = "AWAITING_INPUT"
+    // StatusPaused marks a task and run stopped between steps; resuming continues
+    // with the next step.
+    StatusPaused    Status = "PAUSED"
+    // StatusQueued marks a task waiting for a free worker.
+    StatusQueued    Status = "QUEUED"
+    StatusCancelled Status = "CANCELLED"
 )
 
 type Task struct {
@@ -23,6 +36,21 @@ type Task struct {
     Results   []*Result         `json:"results,omitempty"`
     CreatedAt time.Time         `json:"created_at"`
     UpdatedAt time.Time         `json:"updated_at"`
+    // LastRun is the most recent execution; Results mirrors its results. Earlier runs
+    // are kept by the orchestrator (GET /tasks/{id}/runs).
+    LastRun   *Run              `json:"last_run,omitempty"`
+    // PlanVersion is the version number of Plan; every planning or edit adds one.
+    PlanVersion int             `json:"plan_version,omitempty"`
+    // Priority orders queued tasks (higher first); ClientID groups them for fair
+    // scheduling.
+    Priority  int               `json:"priority,omitempty"`
+    ClientID  string            `json:"client_id,omitempty"`
+    // ScheduleID is set on tasks created by a schedule.
+    ScheduleID string           `json:"schedule_id,omitempty"`
+    // Workflow ("name@version") and Params are set on tasks created from a workflow
+    // template; steps read the parameters as {{params.NAME}}.
+    Workflow   string           `json:"workflow,omitempty"`
+    Params     map[string]any   `json:"params,omitempty"`
 }
 
 type Plan struct {
@@ -36,6 +64,26 @@ type Step struct {
     Tool        string         `json:"tool"`
     Inputs      map[string]any `json:"inputs,omitempty"`
     Status      Status         `json:"status"`
+    // Condition is evaluated before the step runs; when false the step is SKIPPED.
+    // See templating.EvalCondition for the syntax.
+    Condition   string         `json:"if,omitempty"`
+    // Map turns the step into a fan-out over a list; Tool is then "map".
+    Map         *MapSpec       `json:"map,omitempty"`
+    // Timeout bounds the step's execution as a Go duration such as "30s"; empty means none.
+    Timeout     string         `json:"timeout,omitempty"`
+}
+
+// MapSpec runs a sub-chain of steps once per element of a list produced earlier in
+// the plan. The step's output is the array of each item's last sub-step output.
+type MapSpec struct {
+    // Over is a reference to an array, e.g. {{step:step1.output.urls}}.
+    Over        string  `json:"over"`
+    // As names the current element for sub-step inputs (default "item"); {{index}} is
+    // the element's position.
+    As          string  `json:"as,omitempty"`
+    Steps       []*Step `json:"steps"`
+    // Parallelism bounds how many items run at once (default 4).
+    Parallelism int     `json:"parallelism,omitempty"`
 }
 
 type Result struct {
@@ -46,3 +94,137 @@ type Result struct {
     Error    string `json:"error,omitempty"`
     Retries  int    `json:"retries"`
 }
+
+// Run is one execution of a task's plan. The plan is snapshotted when the run starts,
+// so step statuses, resolved inputs and results never leak back into the task's plan
+// and runs can be compared with each other.
+type Run struct {
+    ID         string     `json:"id"`
+    TaskID     string     `json:"task_id"`
+    Number     int        `json:"number"`
+    Status     Status     `json:"status"`
+    Plan       *Plan      `json:"plan"`
+    PlanVersion int       `json:"plan_version,omitempty"`
+    Steps      []*StepRun `json:"steps"`
+    Results    []*Result  `json:"results,omitempty"`
+    // Questions asked by ask_user steps, including unanswered ones.
+    Questions  []*Question `json:"questions,omitempty"`
+    Error      string     `json:"error,omitempty"`
+    StartedAt  time.Time  `json:"started_at"`
+    FinishedAt *time.Time `json:"finished_at,omitempty"`
+    DurationMs int64      `json:"duration_ms,omitempty"`
+}
+
+// StepRun is the state of one plan step within a run.
+type StepRun struct {
+    ID         string         `json:"id"`
+    RunID      string         `json:"run_id"`
+    Tool       string         `json:"tool"`
+    Status     Status         `json:"status"`
+    // Inputs are the resolved inputs the tool was called with.
+    Inputs     map[string]any `json:"inputs,omitempty"`
+    // Reason explains a SKIPPED status.
+    Reason     string         `json:"reason,omitempty"`
+    Approval   *Approval      `json:"approval,omitempty"`
+    StartedAt  *time.Time     `json:"started_at,omitempty"`
+    FinishedAt *time.Time     `json:"finished_at,omitempty"`
+    DurationMs int64          `json:"duration_ms,omitempty"`
+}
+
+// Question is asked by an ask_user step; Answer is empty until someone replies.
+type Question struct {
+    StepID     string     `json:"step_id"`
+    Text       string     `json:"question"`
+    Choices    []string   `json:"choices,omitempty"`
+    Answer     string     `json:"answer,omitempty"`
+    AnsweredBy string     `json:"answered_by,omitempty"`
+    AskedAt    time.Time  `json:"asked_at"`
+    AnsweredAt *time.Time `json:"answered_at,omitempty"`
+}
+
+// Approval records the review of a step that needed sign-off before running.
+type Approval struct {
+    Risk        string     `json:"risk"`
+    // Decision is "approved", "rejected" or "timeout"; empty while pending.
+    Decision    string     `json:"decision,omitempty"`
+    By          string     `json:"by,omitempty"`
+    Comment     string     `json:"comment,omitempty"`
+    RequestedAt time.Time  `json:"requested_at"`
+    ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+    DecidedAt   *time.Time `json:"decided_at,omitempty"`
+}
+
+// PlanVersion is one revision of a task's plan: who produced it, how, and what changed
+// relative to the previous version.
+type PlanVersion struct {
+    Version   int          `json:"version"`
+    Author    string       `json:"author"`
+    // Action is "planned", "replace_plan", "update_step", "insert_step" or "delete_step".
+    Action    string       `json:"action"`
+    Diff      []PlanChange `json:"diff"`
+    Plan      *Plan        `json:"plan"`
+    CreatedAt time.Time    `json:"created_at"`
+}
+
+// PlanChange is one difference between two plan versions. Op is "add", "remove",
+// "change" (Field set) or "move" (Before/After are positions).
+type PlanChange struct {
+    Op     string `json:"op"`
+    StepID string `json:"step_id"`
+    Field  string `json:"field,omitempty"`
+    Before any    `json:"before,omitempty"`
+    After  any    `json:"after,omitempty"`
+}
+
+// Step returns the state of step id, or nil.
+func (r *Run) Step(id string) *StepRun {
+    for _, s := range r.Steps {
+        if s.ID == id { return s }
+    }
+    return nil
+}
+
+// Clone returns a deep copy of the plan.
+func (p *Plan) Clone() *Plan {
+    if p == nil { return nil }
+    out := &Plan{Steps: make([]*Step, len(p.Steps))}
+    for i, s := range p.Steps { out.Steps[i] = s.Clone() }
+    return out
+}
+
+// Clone returns a deep copy of the step, including map sub-steps.
+func (s *Step) Clone() *Step {
+    if s == nil { return nil }
+    c := *s
+    c.Deps = append([]string(nil), s.Deps...)
+    if s.Inputs != nil { c.Inputs = cloneValue(s.Inputs).(map[string]any) }
+    if s.Map != nil {
+        m := *s.Map
+        m.Steps = make([]*Step, len(s.Map.Steps))
+        for i, sub := range s.Map.Steps { m.Steps[i] = sub.Clone() }
+        c.Map = &m
+    }
+    return &c
+}
+
+func cloneValue(v any) any {
+    switch t := v.(type) {
+    case map[string]any:
+        out := make(map[string]any, len(t))
+        for k, val := range t { out[k] = cloneValue(val) }
+        return out
+    case []any:
+        out := make([]any, len(t))
+        for i, val := range t { out[i] = cloneValue(val) }
+        return out
+    }
+    return v
+}
+
+// TaskState is everything kept about a task: the task itself, its runs and its plan
+// history. It is the unit of persistence.
+type TaskState struct {
+    Task         *Task          `json:"task"`
+    Runs         []*Run         `json:"runs,omitempty"`
+    PlanVersions []*PlanVersion `json:"plan_versions,omitempty"`
+}
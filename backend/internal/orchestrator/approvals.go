@@ -0,0 +1,161 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/templating"
+    "github.com/example/agent-orchestrator/internal/tools"
+)
+
+// ErrNotAwaiting is returned when a decision or answer arrives for a step that is not waiting
+// for one.
+var ErrNotAwaiting = errors.New("step is not awaiting a decision or answer")
+
+// ErrNoApprover is returned when an approval or rejection does not say who decided.
+var ErrNoApprover = errors.New("approval decisions must name who decides (by)")
+
+// errWaitTimeout is returned by await when nobody resolved the wait in time.
+var errWaitTimeout = errors.New("timed out")
+
+// park registers a wait point that resolveWait can complete. Register before
+// announcing the wait so a fast reply is not lost.
+func (o *Orchestrator) park(key string) chan any {
+    ch := make(chan any, 1)
+    o.waitMu.Lock()
+    o.waiting[key] = ch
+    o.waitMu.Unlock()
+    return ch
+}
+
+// resolveWait delivers v to a parked wait; false if nothing is waiting under key.
+func (o *Orchestrator) resolveWait(key string, v any) bool {
+    o.waitMu.Lock()
+    defer o.waitMu.Unlock()
+    ch, ok := o.waiting[key]
+    if !ok { return false }
+    delete(o.waiting, key)
+    ch <- v
+    return true
+}
+
+// await blocks until the parked wait is resolved, ctx ends or timeout (if > 0)
+// passes, and unregisters it.
+func (o *Orchestrator) await(ctx context.Context, key string, ch chan any, timeout time.Duration) (any, error) {
+    var expired <-chan time.Time
+    if timeout > 0 {
+        timer := time.NewTimer(timeout)
+        defer timer.Stop()
+        expired = timer.C
+    }
+    var err error
+    select {
+    case v := <-ch:
+        return v, nil
+    case <-expired:
+        err = errWaitTimeout
+    case <-ctx.Done():
+        err = ctx.Err()
+    }
+    o.waitMu.Lock()
+    _, pending := o.waiting[key]
+    delete(o.waiting, key)
+    o.waitMu.Unlock()
+    if !pending {
+        // resolved while we were giving up; the value is already buffered
+        return <-ch, nil
+    }
+    return nil, err
+}
+
+func waitKey(taskID, stepID string) string { return taskID + "/" + stepID }
+
+type approvalDecision struct {
+    approved bool
+    by       string
+    comment  string
+}
+
+// stepRisk is the risk of a step's tool, or of the riskiest sub-step of a map step.
+func (o *Orchestrator) stepRisk(step *models.Step) tools.Risk {
+    if step.Map == nil { return o.Tools.Risk(step.Tool) }
+    risk := tools.RiskLow
+    for _, sub := range step.Map.Steps {
+        if r := o.stepRisk(sub); r.AtLeast(risk) { risk = r }
+    }
+    return risk
+}
+
+// awaitApproval pauses a step whose tool is at least ApprovalRisk until a reviewer
+// approves or rejects it, or ApprovalTimeout passes. A nil error means the step may
+// run. Map steps are approved once for all items.
+func (o *Orchestrator) awaitApproval(ctx context.Context, t *models.Task, run *models.Run, step *models.Step, sr *models.StepRun, scope *templating.Scope) error {
+    if o.ApprovalRisk == "" || o.Tools == nil { return nil }
+    risk := o.stepRisk(step)
+    if !risk.AtLeast(o.ApprovalRisk) { return nil }
+    now := time.Now()
+    a := &models.Approval{Risk: string(risk), RequestedAt: now}
+    if o.ApprovalTimeout > 0 {
+        exp := now.Add(o.ApprovalTimeout)
+        a.ExpiresAt = &exp
+    }
+    sr.Approval = a
+    sr.Status = models.StatusAwaitingApproval
+    t.Status = models.StatusAwaitingApproval
+    t.UpdatedAt = now
+    key := waitKey(t.ID, step.ID)
+    ch := o.park(key)
+    // reviewers judge the call that will actually be made, so show resolved inputs
+    inputs, err := templating.ResolveInputs(step.Inputs, scope)
+    if err != nil { inputs = step.Inputs }
+    payload := map[string]any{"run_id": run.ID, "step_id": step.ID, "tool": step.Tool, "risk": risk, "inputs": inputs}
+    if a.ExpiresAt != nil { payload["expires_at"] = a.ExpiresAt }
+    o.hub.Publish(t.ID, Event{Event: "task_status", TaskID: t.ID, Payload: map[string]any{"status": t.Status, "run_id": run.ID}})
+    o.hub.Publish(t.ID, Event{Event: "step_status", TaskID: t.ID, Payload: sr})
+    o.hub.Publish(t.ID, Event{Event: "approval_required", TaskID: t.ID, Payload: payload})
+    o.persist(t)
+
+    v, err := o.await(ctx, key, ch, o.ApprovalTimeout)
+    decided := time.Now()
+    a.DecidedAt = &decided
+    var result error
+    switch {
+    case errors.Is(err, errWaitTimeout):
+        a.Decision = "timeout"
+        result = fmt.Errorf("approval timed out after %s", o.ApprovalTimeout)
+    case err != nil:
+        a.Decision = "rejected"
+        result = fmt.Errorf("approval aborted: %v", err)
+    default:
+        d := v.(approvalDecision)
+        a.By, a.Comment = d.by, d.comment
+        a.Decision = "approved"
+        if !d.approved {
+            a.Decision = "rejected"
+            result = fmt.Errorf("rejected by %s", d.by)
+            if d.comment != "" { result = fmt.Errorf("rejected by %s: %s", d.by, d.comment) }
+        }
+    }
+    o.hub.Publish(t.ID, Event{Event: "approval_decided", TaskID: t.ID, Payload: map[string]any{"run_id": run.ID, "step_id": step.ID, "decision": a.Decision, "by": a.By, "comment": a.Comment}})
+    t.Status = models.StatusRunning
+    t.UpdatedAt = decided
+    o.hub.Publish(t.ID, Event{Event: "task_status", TaskID: t.ID, Payload: map[string]any{"status": t.Status, "run_id": run.ID}})
+    return result
+}
+
+// DecideApproval approves or rejects a step that is awaiting approval. by identifies
+// the reviewer, is required and is recorded on the step's run state.
+func (o *Orchestrator) DecideApproval(taskID, stepID string, approved bool, by, comment string) error {
+    if strings.TrimSpace(by) == "" { return ErrNoApprover }
+    t, ok := o.GetTask(taskID)
+    if !ok { return ErrTaskNotFound }
+    if !hasStep(t, stepID) { return fmt.Errorf("%q: %w", stepID, ErrStepNotFound) }
+    if t.LastRun == nil { return ErrNotAwaiting }
+    if sr := t.LastRun.Step(stepID); sr == nil || sr.Status != models.StatusAwaitingApproval { return ErrNotAwaiting }
+    if !o.resolveWait(waitKey(taskID, stepID), approvalDecision{approved: approved, by: by, comment: comment}) { return ErrNotAwaiting }
+    return nil
+}
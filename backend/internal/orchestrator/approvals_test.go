@@ -0,0 +1,109 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/tools"
+)
+
+// newGatedOrchestrator runs a "read" step and then a high-risk "post" step.
+func newGatedOrchestrator(timeout time.Duration) *Orchestrator {
+    o, _ := newTestOrchestrator(echoExec,
+        &models.Step{ID: "read", Tool: "echo", Inputs: map[string]any{"text": "data"}},
+        &models.Step{ID: "post", Tool: "post", Inputs: map[string]any{"text": "send {{step:read.output}}"}},
+    )
+    o.Tools = tools.NewRegistry()
+    o.Tools.SetRisk("post", tools.RiskHigh)
+    o.ApprovalRisk, o.ApprovalTimeout = tools.RiskHigh, timeout
+    return o
+}
+
+// startGated runs a task in the background and waits until its post step awaits
+// approval. The returned channel is closed when the run ends.
+func startGated(t *testing.T, o *Orchestrator, id string) (*models.Task, <-chan struct{}) {
+    t.Helper()
+    task := o.CreateTask(id, "q", nil)
+    if _, err := o.PlanOnly(context.Background(), id); err != nil { t.Fatal(err) }
+    events := collect(o, id)
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        o.ExecutePlan(context.Background(), id)
+    }()
+    waitFor(t, "approval_required", func() bool {
+        for _, ev := range events() {
+            if ev.Event == "approval_required" { return true }
+        }
+        return false
+    })
+    return task, done
+}
+
+func TestApprovalApproved(t *testing.T) {
+    o := newGatedOrchestrator(0)
+    events := collect(o, "t")
+    task, done := startGated(t, o, "t")
+    if task.Status != models.StatusAwaitingApproval { t.Fatalf("status %s", task.Status) }
+    if err := o.DecideApproval("t", "post", true, "alice", "ok"); err != nil { t.Fatal(err) }
+    <-done
+    a := task.LastRun.Step("post").Approval
+    if task.Status != models.StatusSuccess || a.Decision != "approved" || a.By != "alice" || a.Risk != "high" || a.DecidedAt == nil { t.Fatalf("%s %+v", task.Status, a) }
+    var required map[string]any
+    for _, ev := range events() {
+        if ev.Event == "approval_required" { required = ev.Payload.(map[string]any) }
+    }
+    // reviewers see the inputs the tool will be called with
+    if in := required["inputs"].(map[string]any)["text"]; in != "send data" { t.Fatalf("approval inputs %v", in) }
+    // the read step ran without approval
+    if task.LastRun.Step("read").Approval != nil { t.Fatal("low-risk step waited for approval") }
+}
+
+func TestApprovalRejected(t *testing.T) {
+    o := newGatedOrchestrator(0)
+    task, done := startGated(t, o, "t")
+    if err := o.DecideApproval("t", "post", false, "bob", "wrong target"); err != nil { t.Fatal(err) }
+    <-done
+    if task.Status != models.StatusFailed || task.LastRun.Error != "step post: rejected by bob: wrong target" { t.Fatalf("%s %q", task.Status, task.LastRun.Error) }
+    // a decision only counts once
+    if err := o.DecideApproval("t", "post", true, "bob", ""); !errors.Is(err, ErrNotAwaiting) { t.Fatalf("second decision: %v", err) }
+}
+
+func TestApprovalTimeout(t *testing.T) {
+    o := newGatedOrchestrator(30 * time.Millisecond)
+    task, done := startGated(t, o, "t")
+    if exp := task.LastRun.Step("post").Approval.ExpiresAt; exp == nil { t.Fatal("no expiry recorded") }
+    <-done
+    if task.Status != models.StatusFailed || !strings.Contains(task.LastRun.Error, "approval timed out") { t.Fatalf("%s %q", task.Status, task.LastRun.Error) }
+    if d := task.LastRun.Step("post").Approval.Decision; d != "timeout" { t.Fatalf("decision %q", d) }
+}
+
+func TestDecideApprovalErrors(t *testing.T) {
+    o := newGatedOrchestrator(0)
+    o.CreateTask("fresh", "q", nil)
+    o.PlanOnly(context.Background(), "fresh")
+    for _, c := range []struct {
+        task, step, by string
+        want           error
+    }{
+        {"nope", "post", "x", ErrTaskNotFound},
+        {"fresh", "nope", "x", ErrStepNotFound},
+        {"fresh", "post", "x", ErrNotAwaiting},
+        {"fresh", "post", "", ErrNoApprover},
+        {"fresh", "post", " ", ErrNoApprover},
+    } {
+        if err := o.DecideApproval(c.task, c.step, true, c.by, ""); !errors.Is(err, c.want) { t.Errorf("%s/%s by %q: %v, want %v", c.task, c.step, c.by, err, c.want) }
+    }
+}
+
+func TestApprovalCancelled(t *testing.T) {
+    o := newGatedOrchestrator(0)
+    task, done := startGated(t, o, "t")
+    if err := o.Cancel("t"); err != nil { t.Fatal(err) }
+    <-done
+    if task.Status != models.StatusCancelled || task.LastRun.Step("post").Approval.Decision != "rejected" { t.Fatalf("%s %+v", task.Status, task.LastRun.Step("post").Approval) }
+}
@@ -0,0 +1,26 @@
+package orchestrator
+
+import (
+    "fmt"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/templating"
+)
+
+// skipReason reports why a step should not run: every one of its deps was skipped, or
+// its condition is false. It returns "" when the step should run and an error when
+// the condition cannot be evaluated.
+func skipReason(step *models.Step, scope *templating.Scope, skipped map[string]bool) (string, error) {
+    if len(step.Deps) > 0 {
+        all := true
+        for _, d := range step.Deps {
+            if !skipped[d] { all = false; break }
+        }
+        if all { return "all dependencies were skipped", nil }
+    }
+    if step.Condition == "" { return "", nil }
+    ok, err := templating.EvalCondition(step.Condition, scope)
+    if err != nil { return "", fmt.Errorf("condition %q: %w", step.Condition, err) }
+    if !ok { return fmt.Sprintf("condition %q is false", step.Condition), nil }
+    return "", nil
+}
@@ -0,0 +1,69 @@
+package orchestrator
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/agents"
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// stepStatuses maps each step of the task's last run to its status.
+func stepStatuses(task *models.Task) map[string]models.Status {
+    out := map[string]models.Status{}
+    for _, sr := range task.LastRun.Steps { out[sr.ID] = sr.Status }
+    return out
+}
+
+func TestConditionalSteps(t *testing.T) {
+    o, v := newTestOrchestrator(echoExec,
+        &models.Step{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "pdf"}},
+        &models.Step{ID: "if_pdf", Tool: "echo", Inputs: map[string]any{"text": "yes"}, Condition: `{{step:a.output}} == "pdf"`},
+        &models.Step{ID: "if_html", Tool: "echo", Inputs: map[string]any{"text": "no"}, Condition: `{{step:a.output}} == "html"`},
+        &models.Step{ID: "after_html", Tool: "echo", Inputs: map[string]any{"text": "{{step:if_html.output}}"}, Deps: []string{"if_html"}},
+        &models.Step{ID: "last", Tool: "echo", Inputs: map[string]any{"text": "done"}},
+    )
+    task := runTask(t, o, "c")
+    if task.Status != models.StatusSuccess { t.Fatalf("status %s: %s", task.Status, task.LastRun.Error) }
+    want := map[string]models.Status{"a": "SUCCESS", "if_pdf": "SUCCESS", "if_html": "SKIPPED", "after_html": "SKIPPED", "last": "SUCCESS"}
+    got := stepStatuses(task)
+    for id, s := range want {
+        if got[id] != s { t.Errorf("%s: %s, want %s", id, got[id], s) }
+    }
+    if r := task.LastRun.Step("after_html").Reason; r != "all dependencies were skipped" { t.Errorf("reason %q", r) }
+    if !strings.Contains(task.LastRun.Step("if_html").Reason, "is false") { t.Errorf("reason %q", task.LastRun.Step("if_html").Reason) }
+    // skipped steps are neither executed nor verified
+    if v.calls("if_html") != 0 || v.calls("after_html") != 0 { t.Errorf("verifier calls %v", v.seen) }
+}
+
+func TestConditionError(t *testing.T) {
+    o, _ := newTestOrchestrator(echoExec, &models.Step{ID: "a", Tool: "echo", Condition: "len("})
+    task := runTask(t, o, "c")
+    if task.Status != models.StatusFailed || !strings.Contains(task.LastRun.Error, "condition") { t.Fatalf("status %s: %q", task.Status, task.LastRun.Error) }
+}
+
+// The mock planner returns one plan for every kind of query; conditions pick the branch.
+func TestMockPlannerBranches(t *testing.T) {
+    for _, c := range []struct {
+        query   string
+        context map[string]any
+        ran     string
+    }{
+        {"summarize https://example.com", nil, "fetch,to_text,summarize"},
+        {"what is Go?", nil, "answer"},
+        {"summarize this", map[string]any{"pdf_data_base64": "JVBERi0="}, "extract_pdf,summarize_pdf"},
+        {"who wrote it?", map[string]any{"pdf_data_base64": "JVBERi0="}, "extract_pdf,answer_pdf"},
+    } {
+        o := New(&agents.MockPlanner{}, echoExec, &recordingVerifier{})
+        o.CreateTask("p", c.query, c.context)
+        if _, err := o.PlanOnly(context.Background(), "p"); err != nil { t.Fatal(err) }
+        o.ExecutePlan(context.Background(), "p")
+        task, _ := o.GetTask("p")
+        var ran []string
+        for _, sr := range task.LastRun.Steps {
+            if sr.Status != models.StatusSkipped { ran = append(ran, sr.ID) }
+        }
+        if strings.Join(ran, ",") != c.ran { t.Errorf("%q: ran %v, want %s", c.query, ran, c.ran) }
+    }
+}
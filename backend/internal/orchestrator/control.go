@@ -0,0 +1,241 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "log"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+var (
+    ErrNotActive = errors.New("task is not running")
+    ErrNotPaused = errors.New("task is not paused")
+)
+
+// Store persists task state; see internal/store. Saves happen at step boundaries and
+// on every status change. Stores that also implement TaskDeleter forget deleted
+// tasks; others bring them back on the next Restore.
+type Store interface {
+    SaveTask(st *models.TaskState) error
+    LoadTasks() ([]*models.TaskState, error)
+}
+
+// TaskDeleter is implemented by stores that can remove a task (see DeleteTask).
+type TaskDeleter interface {
+    DeleteTask(id string) error
+}
+
+// runControl lets Pause and Cancel reach a task that is planning or running.
+type runControl struct {
+    cancel    context.CancelFunc
+    pause     bool
+    cancelled bool
+}
+
+// claimKey carries the runControl a queue worker registered for a job (see claim).
+type claimKey struct{}
+
+// track registers a task as active for the duration of a run. It fails if the task
+// is already active, so a task never runs twice at once, unless ctx carries the
+// worker's claim on it.
+func (o *Orchestrator) track(ctx context.Context, taskID string) (context.Context, func(), error) {
+    ctx, cancel := context.WithCancel(ctx)
+    o.ctlMu.Lock()
+    defer o.ctlMu.Unlock()
+    if c, busy := o.controls[taskID]; !busy {
+        o.controls[taskID] = &runControl{cancel: cancel}
+    } else if ctx.Value(claimKey{}) != c {
+        cancel()
+        return nil, nil, ErrTaskRunning
+    }
+    // a claimed task keeps its control, with pause or cancel requests made meanwhile;
+    // cancelling the claim cancels ctx too
+    return ctx, func() {
+        o.ctlMu.Lock()
+        delete(o.controls, taskID)
+        o.ctlMu.Unlock()
+        cancel()
+    }, nil
+}
+
+// claimLocked registers a dequeued task as active before a worker runs its job, so
+// it never looks idle in between. It returns the context to run the job with and a
+// func that releases the claim. The caller holds the queue lock.
+func (o *Orchestrator) claimLocked(taskID string) (context.Context, func(), bool) {
+    o.ctlMu.Lock()
+    defer o.ctlMu.Unlock()
+    if _, busy := o.controls[taskID]; busy { return nil, nil, false }
+    ctx, cancel := context.WithCancel(context.Background())
+    c := &runControl{cancel: cancel}
+    o.controls[taskID] = c
+    return context.WithValue(ctx, claimKey{}, c), func() {
+        o.ctlMu.Lock()
+        if o.controls[taskID] == c { delete(o.controls, taskID) }
+        o.ctlMu.Unlock()
+        cancel()
+    }, true
+}
+
+// requested reports pending pause and cancel requests for an active task.
+func (o *Orchestrator) requested(taskID string) (pause, cancelled bool) {
+    o.ctlMu.Lock()
+    defer o.ctlMu.Unlock()
+    if c, ok := o.controls[taskID]; ok { return c.pause, c.cancelled }
+    return false, false
+}
+
+// Pause asks an active task to stop before its next step. A step that is running (or
+// waiting for approval or an answer) finishes first; the task then becomes PAUSED.
+func (o *Orchestrator) Pause(taskID string) error {
+    if _, ok := o.GetTask(taskID); !ok { return ErrTaskNotFound }
+    o.ctlMu.Lock()
+    defer o.ctlMu.Unlock()
+    c, ok := o.controls[taskID]
+    if !ok { return ErrNotActive }
+    c.pause = true
+    return nil
+}
+
+// Cancel stops an active task right away, aborting the running step, or ends a
+// paused or queued one. The run and task become CANCELLED.
+func (o *Orchestrator) Cancel(taskID string) error {
+    t, ok := o.GetTask(taskID)
+    if !ok { return ErrTaskNotFound }
+    if j, queued := o.dequeue(taskID); queued {
+        if j.prev == models.StatusPaused && t.LastRun != nil {
+            o.finishRun(t, t.LastRun, models.StatusCancelled, "cancelled")
+            return nil
+        }
+        t.Status = models.StatusCancelled
+        t.UpdatedAt = time.Now()
+        o.hub.Publish(taskID, Event{Event: "task_status", TaskID: taskID, Payload: map[string]any{"status": t.Status}})
+        o.hub.Finish(taskID)
+        o.persist(t)
+        return nil
+    }
+    o.ctlMu.Lock()
+    c, active := o.controls[taskID]
+    if active {
+        c.cancelled = true
+        c.cancel()
+    }
+    o.ctlMu.Unlock()
+    if active { return nil }
+    if t.Status != models.StatusPaused || t.LastRun == nil { return ErrNotActive }
+    o.finishRun(t, t.LastRun, models.StatusCancelled, "cancelled")
+    return nil
+}
+
+// DeleteTask removes a task with its runs, plan history and event log, also from the
+// Store. Tasks
+// that are queued, planning, running or waiting for a decision cannot be deleted;
+// cancel them first.
+func (o *Orchestrator) DeleteTask(taskID string) error {
+    t, ok := o.GetTask(taskID)
+    if !ok { return ErrTaskNotFound }
+    o.ctlMu.Lock()
+    defer o.ctlMu.Unlock()
+    if _, active := o.controls[taskID]; active || isActive(t.Status) { return ErrTaskRunning }
+    if t.Status == models.StatusQueued { return ErrAlreadyQueued }
+    o.tasksMu.Lock()
+    delete(o.tasks, taskID)
+    o.tasksMu.Unlock()
+    o.runsMu.Lock()
+    delete(o.runs, taskID)
+    o.runsMu.Unlock()
+    o.plansMu.Lock()
+    delete(o.versions, taskID)
+    o.plansMu.Unlock()
+    o.hub.Drop(taskID)
+    if d, ok := o.Store.(TaskDeleter); ok {
+        o.storeMu.Lock()
+        defer o.storeMu.Unlock()
+        if err := d.DeleteTask(taskID); err != nil { log.Printf("store delete task %s: %v", taskID, err) }
+    }
+    return nil
+}
+
+// Resume continues a paused task's run with its first unfinished step. It blocks
+// until the run ends or is paused again.
+func (o *Orchestrator) Resume(ctx context.Context, taskID string) error {
+    t, ok := o.GetTask(taskID)
+    if !ok { return ErrTaskNotFound }
+    run := t.LastRun
+    if t.Status != models.StatusPaused || run == nil || run.Status != models.StatusPaused { return ErrNotPaused }
+    ctx, release, err := o.track(ctx, taskID)
+    if err != nil { return err }
+    defer release()
+    run.Status = models.StatusRunning
+    t.Status = models.StatusRunning
+    t.UpdatedAt = time.Now()
+    o.hub.Publish(taskID, Event{Event: "run_status", TaskID: taskID, Payload: runStatus(run)})
+    o.hub.Publish(taskID, Event{Event: "task_status", TaskID: taskID, Payload: map[string]any{"status": t.Status, "run_id": run.ID}})
+    o.persist(t)
+    o.runSteps(ctx, t, run)
+    return nil
+}
+
+// pauseRun stops a run at a step boundary.
+func (o *Orchestrator) pauseRun(t *models.Task, run *models.Run) {
+    run.Status = models.StatusPaused
+    t.Status = models.StatusPaused
+    t.UpdatedAt = time.Now()
+    o.hub.Publish(t.ID, Event{Event: "run_status", TaskID: t.ID, Payload: runStatus(run)})
+    o.hub.Publish(t.ID, Event{Event: "task_status", TaskID: t.ID, Payload: map[string]any{"status": t.Status, "run_id": run.ID}})
+    o.persist(t)
+}
+
+// persist saves a task with its runs and plan history; errors are logged, since
+// persistence must not stop a run.
+func (o *Orchestrator) persist(t *models.Task) {
+    if o.Store == nil { return }
+    st := &models.TaskState{Task: t, Runs: o.Runs(t.ID), PlanVersions: o.PlanVersions(t.ID)}
+    o.storeMu.Lock()
+    defer o.storeMu.Unlock()
+    if err := o.Store.SaveTask(st); err != nil { log.Printf("store task %s: %v", t.ID, err) }
+}
+
+// Restore loads the tasks kept in Store. Runs that were in progress when the server
+// stopped become PAUSED: their unfinished steps will run again on Resume, and
+// unanswered questions are asked again. It returns the number of tasks loaded.
+func (o *Orchestrator) Restore() (int, error) {
+    if o.Store == nil { return 0, nil }
+    states, err := o.Store.LoadTasks()
+    if err != nil { return 0, err }
+    for _, st := range states {
+        t := st.Task
+        // the stored task embeds a copy of its last run; share the run history's instead
+        if n := len(st.Runs); n > 0 {
+            t.LastRun = st.Runs[n-1]
+            t.Results = t.LastRun.Results
+        }
+        if run := t.LastRun; run != nil && (isActive(run.Status) || run.Status == models.StatusPaused) {
+            run.Status = models.StatusPaused
+            for _, sr := range run.Steps {
+                if isActive(sr.Status) { sr.Status, sr.StartedAt = models.StatusPending, nil }
+            }
+            var answered []*models.Question
+            for _, q := range run.Questions {
+                if q.AnsweredAt != nil { answered = append(answered, q) }
+            }
+            run.Questions = answered
+            t.Status = models.StatusPaused
+        } else if isActive(t.Status) || t.Status == models.StatusQueued {
+            // stopped while planning or waiting in the queue, which is not kept
+            t.Status = models.StatusPending
+            if t.Plan != nil { t.Status = models.StatusPlanned }
+        }
+        o.tasksMu.Lock()
+        o.tasks[t.ID] = t
+        o.tasksMu.Unlock()
+        o.runsMu.Lock()
+        o.runs[t.ID] = st.Runs
+        o.runsMu.Unlock()
+        o.plansMu.Lock()
+        o.versions[t.ID] = st.PlanVersions
+        o.plansMu.Unlock()
+    }
+    return len(states), nil
+}
@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// gatedExec echoes steps, but step "a" first signals started and waits for release.
+func gatedExec(started, release chan struct{}) execFunc {
+    return func(ctx context.Context, step *models.Step) (any, error) {
+        if step.ID == "a" {
+            close(started)
+            <-release
+        }
+        return step.Inputs["text"], nil
+    }
+}
+
+func twoSteps() []*models.Step {
+    return []*models.Step{
+        {ID: "a", Tool: "echo", Inputs: map[string]any{"text": "A"}},
+        {ID: "b", Tool: "echo", Inputs: map[string]any{"text": "{{step:a.output}}B"}},
+    }
+}
+
+func TestPauseResume(t *testing.T) {
+    started, release := make(chan struct{}), make(chan struct{})
+    o, _ := newTestOrchestrator(gatedExec(started, release), twoSteps()...)
+    store := newMemStore()
+    o.Store = store
+    task := o.CreateTask("t", "q", nil)
+    o.PlanOnly(context.Background(), "t")
+    if err := o.Pause("t"); !errors.Is(err, ErrNotActive) { t.Fatalf("pause idle task: %v", err) }
+    if err := o.Pause("nope"); !errors.Is(err, ErrTaskNotFound) { t.Fatalf("pause unknown task: %v", err) }
+    if err := o.Resume(context.Background(), "t"); !errors.Is(err, ErrNotPaused) { t.Fatalf("resume unpaused task: %v", err) }
+
+    done := make(chan struct{})
+    go func() { defer close(done); o.ExecutePlan(context.Background(), "t") }()
+    <-started
+    if err := o.Pause("t"); err != nil { t.Fatal(err) }
+    close(release)
+    <-done
+    // the running step finished; the next one waits
+    run := task.LastRun
+    if task.Status != models.StatusPaused || run.Status != models.StatusPaused { t.Fatalf("status %s, run %s", task.Status, run.Status) }
+    if run.Step("a").Status != models.StatusSuccess || run.Step("b").Status != models.StatusPending { t.Fatalf("steps %s %s", run.Step("a").Status, run.Step("b").Status) }
+
+    if err := o.Resume(context.Background(), "t"); err != nil { t.Fatal(err) }
+    if task.Status != models.StatusSuccess || run != task.LastRun { t.Fatalf("status %s, new run %v", task.Status, run != task.LastRun) }
+    if out := task.Results[len(task.Results)-1].Output; out != "AB" { t.Fatalf("output %v", out) }
+}
+
+// TestRestorePausesInterruptedRuns restores what a server saved mid-run: the run is
+// PAUSED, the step that was running starts over on Resume.
+func TestRestorePausesInterruptedRuns(t *testing.T) {
+    started, release := make(chan struct{}), make(chan struct{})
+    o, _ := newTestOrchestrator(gatedExec(started, release), twoSteps()...)
+    store := newMemStore()
+    o.Store = store
+    o.CreateTask("t", "q", nil)
+    o.PlanOnly(context.Background(), "t")
+    go o.ExecutePlan(context.Background(), "t")
+    <-started
+    // the saved state has step a running; the "crashed" orchestrator is abandoned
+    store.mu.Lock()
+    saved := map[string][]byte{"t": store.saved["t"]}
+    store.mu.Unlock()
+    defer close(release)
+
+    o2, _ := newTestOrchestrator(echoExec, twoSteps()...)
+    o2.Store = &memStore{saved: saved}
+    if n, err := o2.Restore(); err != nil || n != 1 { t.Fatalf("restored %d: %v", n, err) }
+    task, _ := o2.GetTask("t")
+    if task.Status != models.StatusPaused || task.LastRun.Step("a").Status != models.StatusPending { t.Fatalf("status %s, step a %s", task.Status, task.LastRun.Step("a").Status) }
+    if err := o2.Resume(context.Background(), "t"); err != nil { t.Fatal(err) }
+    if task.Status != models.StatusSuccess || task.Results[len(task.Results)-1].Output != "AB" { t.Fatalf("status %s: %+v", task.Status, task.Results) }
+}
+
+func TestCancelPaused(t *testing.T) {
+    started, release := make(chan struct{}), make(chan struct{})
+    o, _ := newTestOrchestrator(gatedExec(started, release), twoSteps()...)
+    task := o.CreateTask("t", "q", nil)
+    o.PlanOnly(context.Background(), "t")
+    done := make(chan struct{})
+    go func() { defer close(done); o.ExecutePlan(context.Background(), "t") }()
+    <-started
+    o.Pause("t")
+    close(release)
+    <-done
+    if err := o.Cancel("t"); err != nil { t.Fatal(err) }
+    if task.Status != models.StatusCancelled || task.LastRun.Status != models.StatusCancelled { t.Fatalf("status %s, run %s", task.Status, task.LastRun.Status) }
+    if err := o.Cancel("t"); !errors.Is(err, ErrNotActive) { t.Fatalf("second cancel: %v", err) }
+}
@@ -2,52 +2,331 @@ package orchestrator
 
 import (
     "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "sort"
+    "strconv"
     "sync"
+    "time"
 )
 
-// Event is a generic SSE payload wrapper.
+// Event is a generic SSE payload wrapper. ID is assigned by the Hub on publish and
+// increases monotonically across all tasks.
 type Event struct {
+    ID      uint64      `json:"id,omitempty"`
     Event   string      `json:"event"`
     TaskID  string      `json:"task_id"`
     Payload interface{} `json:"payload,omitempty"`
 }
 
-type subscriber chan []byte
+// Record is a published event in encoded form. TaskID and Event are copied out of the
+// encoded data so subscribers can filter without decoding it. Status is the task's
+// status when the event was published, as announced by its latest task_status event
+// (empty before the first).
+type Record struct {
+    ID     uint64
+    TaskID string
+    Event  string
+    Status string
+    Data   []byte
+}
+
+// Gap tells a subscriber that events after LastEventID were evicted from the replay
+// log before it could read them; history resumes at FirstAvailableID.
+type Gap struct {
+    TaskID           string `json:"task_id"`
+    LastEventID      uint64 `json:"last_event_id"`
+    FirstAvailableID uint64 `json:"first_available_id"`
+}
+
+// eventLog is a bounded replay log.
+type eventLog struct {
+    records []Record
+    // evicted is the ID of the newest record dropped to make room
+    evicted uint64
+    // finished is when the task last reached a final status (zero while it may still
+    // publish); finished logs are dropped after the hub's retention
+    finished time.Time
+}
+
+func (l *eventLog) append(r Record, capacity int) {
+    l.records = append(l.records, r)
+    if over := len(l.records) - capacity; over > 0 {
+        l.evicted = l.records[over-1].ID
+        l.records = append([]Record(nil), l.records[over:]...)
+    }
+}
+
+// AllTasks subscribes to the firehose: every event of every task.
+const AllTasks = ""
 
+// Hub fans events out to subscribers. Instead of pushing into per-subscriber buffers
+// (and dropping when they fill), every event is appended to a bounded replay log and
+// subscribers read from it at their own pace; a subscriber only loses events when it
+// falls more than the log capacity behind, and is then told so with a Gap.
 type Hub struct {
-    mu    sync.RWMutex
-    subs  map[string]map[subscriber]struct{} // taskID -> set of subscribers
+    mu        sync.Mutex
+    seq       uint64
+    capacity  int
+    retention time.Duration
+    pruned    time.Time
+    logs      map[string]*eventLog // taskID -> log; AllTasks holds every event
+    status    map[string]string    // taskID -> status of its latest task_status event
+    subs      map[string]map[*Subscription]struct{} // taskID -> set of subscribers
 }
 
-func NewHub() *Hub { return &Hub{subs: map[string]map[subscriber]struct{}{}} }
+// NewHub creates a hub whose per-task replay logs (and the firehose log) hold
+// EVENT_REPLAY_SIZE events (default 1000). The log of a finished task is dropped
+// EVENT_LOG_RETENTION (a Go duration, default 1h) after it finished.
+func NewHub() *Hub {
+    capacity := 1000
+    if v, err := strconv.Atoi(os.Getenv("EVENT_REPLAY_SIZE")); err == nil && v > 0 { capacity = v }
+    retention := time.Hour
+    if d, err := time.ParseDuration(os.Getenv("EVENT_LOG_RETENTION")); err == nil && d > 0 { retention = d }
+    return &Hub{capacity: capacity, retention: retention, pruned: time.Now(), logs: map[string]*eventLog{}, status: map[string]string{}, subs: map[string]map[*Subscription]struct{}{}}
+}
+
+// Subscription reads a task's events in order. Wait on C, then call Next.
+type Subscription struct {
+    hub    *Hub
+    taskID string
+    cursor uint64
+    // stale is a resume cursor from before a restart (when IDs started over); the
+    // first Next reports it as a gap
+    stale  uint64
+    notify chan struct{}
+}
 
-func (h *Hub) Subscribe(taskID string) (subscriber, func()) {
-    ch := make(subscriber, 16)
+// Subscribe starts reading events for taskID (or AllTasks) published after
+// lastEventID. A zero lastEventID means "from now on". An ID the hub has not reached
+// yet was issued before a restart; the subscriber gets a gap and then live events.
+func (h *Hub) Subscribe(taskID string, lastEventID uint64) *Subscription {
     h.mu.Lock()
-    set := h.subs[taskID]
-    if set == nil { set = map[subscriber]struct{}{}; h.subs[taskID] = set }
-    set[ch] = struct{}{}
-    h.mu.Unlock()
-    unsubscribe := func() {
-        h.mu.Lock()
-        if set, ok := h.subs[taskID]; ok {
-            delete(set, ch)
-            if len(set) == 0 { delete(h.subs, taskID) }
-        }
-        close(ch)
-        h.mu.Unlock()
+    defer h.mu.Unlock()
+    s := &Subscription{hub: h, taskID: taskID, cursor: lastEventID, notify: make(chan struct{}, 1)}
+    if lastEventID == 0 {
+        s.cursor = h.seq
+    } else if lastEventID > h.seq {
+        s.cursor, s.stale = h.seq, lastEventID
+        s.notify <- struct{}{}
+    } else {
+        // there may already be something to replay
+        s.notify <- struct{}{}
     }
-    return ch, unsubscribe
+    set := h.subs[taskID]
+    if set == nil { set = map[*Subscription]struct{}{}; h.subs[taskID] = set }
+    set[s] = struct{}{}
+    return s
 }
 
+// Publish assigns the next event ID, records the event and wakes subscribers.
 func (h *Hub) Publish(taskID string, ev Event) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    h.seq++
+    ev.ID = h.seq
+    ev.TaskID = taskID
     b, _ := json.Marshal(ev)
-    h.mu.RLock()
-    set := h.subs[taskID]
-    for ch := range set {
-        // non-blocking send
-        select { case ch <- b: default: }
+    if ev.Event == "task_status" {
+        var st struct{ Payload struct{ Status string `json:"status"` } `json:"payload"` }
+        if json.Unmarshal(b, &st) == nil { h.status[taskID] = st.Payload.Status }
+    }
+    rec := Record{ID: ev.ID, TaskID: taskID, Event: ev.Event, Status: h.status[taskID], Data: b}
+    for _, key := range []string{taskID, AllTasks} {
+        l := h.logs[key]
+        if l == nil { l = &eventLog{}; h.logs[key] = l }
+        l.append(rec, h.capacity)
+        for s := range h.subs[key] {
+            // non-blocking: one pending wakeup is enough, Next drains everything
+            select { case s.notify <- struct{}{}: default: }
+        }
     }
-    h.mu.RUnlock()
+    // a finished task that publishes again (a new run) is live again
+    h.logs[taskID].finished = time.Time{}
+    h.pruneLocked(time.Now())
+}
+
+// Finish marks a task as having reached a final status; its replay log is dropped once
+// the retention has passed without new events.
+func (h *Hub) Finish(taskID string) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if l := h.logs[taskID]; l != nil { l.finished = time.Now() }
+}
+
+// Drop removes a task's replay log, e.g. when the task is deleted. The firehose log
+// keeps its events.
+func (h *Hub) Drop(taskID string) {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if taskID != AllTasks { delete(h.logs, taskID); delete(h.status, taskID) }
 }
 
+// pruneLocked drops the logs of tasks that finished more than the retention ago. It
+// scans at most a few times per retention period.
+func (h *Hub) pruneLocked(now time.Time) {
+    if now.Sub(h.pruned) < h.retention/4 { return }
+    h.pruned = now
+    for id, l := range h.logs {
+        if id != AllTasks && !l.finished.IsZero() && now.Sub(l.finished) >= h.retention { delete(h.logs, id); delete(h.status, id) }
+    }
+}
+
+// LastID returns the ID of the most recent event published for any task.
+func (h *Hub) LastID() uint64 {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    return h.seq
+}
+
+// C is signalled when new events may be available.
+func (s *Subscription) C() <-chan struct{} { return s.notify }
+
+// Cursor is the ID of the last event returned by Next.
+func (s *Subscription) Cursor() uint64 {
+    s.hub.mu.Lock()
+    defer s.hub.mu.Unlock()
+    return s.cursor
+}
+
+// Next returns the events published since the previous call. If some were evicted
+// before being read, gap describes the hole and the returned events start at the
+// oldest one still retained.
+func (s *Subscription) Next() (recs []Record, gap *Gap) {
+    s.hub.mu.Lock()
+    defer s.hub.mu.Unlock()
+    if s.stale != 0 {
+        // nothing the subscriber saw is still known; a snapshot brings it up to date
+        gap = &Gap{TaskID: s.taskID, LastEventID: s.stale, FirstAvailableID: s.cursor + 1}
+        s.stale = 0
+    }
+    l := s.hub.logs[s.taskID]
+    if l == nil {
+        // the task's log was dropped (e.g. pruned after it finished): whatever it held
+        // after the cursor is gone
+        if gap == nil && s.cursor < s.hub.seq {
+            gap = &Gap{TaskID: s.taskID, LastEventID: s.cursor, FirstAvailableID: s.hub.seq + 1}
+            s.cursor = s.hub.seq
+        }
+        return nil, gap
+    }
+    if gap == nil && s.cursor < l.evicted && len(l.records) > 0 {
+        gap = &Gap{TaskID: s.taskID, LastEventID: s.cursor, FirstAvailableID: l.records[0].ID}
+    }
+    for i, r := range l.records {
+        if r.ID > s.cursor {
+            recs = append([]Record(nil), l.records[i:]...)
+            break
+        }
+    }
+    if len(recs) > 0 { s.cursor = recs[len(recs)-1].ID }
+    return recs, gap
+}
+
+// Close unsubscribes.
+func (s *Subscription) Close() {
+    h := s.hub
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    if set, ok := h.subs[s.taskID]; ok {
+        delete(set, s)
+        if len(set) == 0 { delete(h.subs, s.taskID) }
+    }
+}
+
+// EventSink receives every published event, e.g. to forward it to another system.
+// Deliver is called from one goroutine per sink, in event order; it may block or retry
+// (events queue up meanwhile), and errors are logged.
+type EventSink interface {
+    Name() string
+    Deliver(rec Record) error
+}
+
+// SinkFilter is implemented by sinks that only want some events. AddSink skips the
+// others before queueing, so they never take room from the ones the sink wants.
+type SinkFilter interface {
+    Accept(rec Record) bool
+}
+
+// DeadLetterer is implemented by sinks that can keep events they will never receive:
+// ones dropped because the sink's queue was full, and notices of events evicted
+// before the sink read them.
+type DeadLetterer interface {
+    DeadLetter(rec Record, cause error) error
+}
+
+// AddSink starts delivering events published from now on to s. A reader goroutine
+// filters the firehose (see SinkFilter) into a queue of EVENT_REPLAY_SIZE events and
+// a second goroutine delivers from it, so a slow or retrying sink never makes the
+// reader fall behind. Events that do not fit the queue, and events evicted from the
+// firehose log that the task logs no longer hold either, are dead-lettered (see
+// DeadLetterer) or logged. The returned func stops delivery once the queued events
+// are delivered.
+func (h *Hub) AddSink(s EventSink) func() {
+    sub := h.Subscribe(AllTasks, 0)
+    queue := make(chan Record, h.capacity)
+    done := make(chan struct{})
+    go func() {
+        defer close(queue)
+        defer sub.Close()
+        filter, _ := s.(SinkFilter)
+        for {
+            select {
+            case <-done:
+                return
+            case <-sub.C():
+            }
+            recs, gap := sub.Next()
+            if gap != nil { recs = append(h.recoverGap(s, *gap), recs...) }
+            for _, rec := range recs {
+                if filter != nil && !filter.Accept(rec) { continue }
+                select {
+                case queue <- rec:
+                default:
+                    deadLetter(s, rec, errSinkQueueFull)
+                }
+            }
+        }
+    }()
+    go func() {
+        for rec := range queue {
+            if err := s.Deliver(rec); err != nil { log.Printf("event sink %s: event %d: %v", s.Name(), rec.ID, err) }
+        }
+    }()
+    var once sync.Once
+    return func() { once.Do(func() { close(done) }) }
+}
+
+var errSinkQueueFull = errors.New("sink queue full")
+
+// recoverGap returns the events of a firehose gap that are still in the per-task logs,
+// in order. The rest are reported to s as one "events_lost" record carrying the Gap.
+func (h *Hub) recoverGap(s EventSink, gap Gap) []Record {
+    h.mu.Lock()
+    var recs []Record
+    for id, l := range h.logs {
+        if id == AllTasks { continue }
+        for _, r := range l.records {
+            if r.ID > gap.LastEventID && r.ID < gap.FirstAvailableID { recs = append(recs, r) }
+        }
+    }
+    h.mu.Unlock()
+    sort.Slice(recs, func(i, j int) bool { return recs[i].ID < recs[j].ID })
+    if lost := gap.FirstAvailableID - 1 - gap.LastEventID - uint64(len(recs)); lost > 0 {
+        b, _ := json.Marshal(Event{Event: "events_lost", Payload: gap})
+        deadLetter(s, Record{Event: "events_lost", Data: b}, fmt.Errorf("%d events evicted before delivery", lost))
+    }
+    return recs
+}
+
+// deadLetter hands an undeliverable record to the sink's dead letter, or logs it.
+func deadLetter(s EventSink, rec Record, cause error) {
+    if d, ok := s.(DeadLetterer); ok {
+        err := d.DeadLetter(rec, cause)
+        if err == nil { return }
+        cause = err
+    }
+    log.Printf("event sink %s: %s %d lost: %v", s.Name(), rec.Event, rec.ID, cause)
+}
@@ -0,0 +1,134 @@
+package orchestrator
+
+import (
+    "fmt"
+    "testing"
+    "time"
+)
+
+func newTestHub(capacity int) *Hub {
+    h := NewHub()
+    h.capacity = capacity
+    return h
+}
+
+func ids(recs []Record) string {
+    var out []uint64
+    for _, r := range recs { out = append(out, r.ID) }
+    return fmt.Sprint(out)
+}
+
+func TestHubReplay(t *testing.T) {
+    h := newTestHub(10)
+    live := h.Subscribe("a", 0)
+    for i := 0; i < 3; i++ {
+        h.Publish("a", Event{Event: "x"})
+        h.Publish("b", Event{Event: "x"})
+    }
+    if recs, gap := live.Next(); ids(recs) != "[1 3 5]" || gap != nil { t.Fatalf("live: %s %v", ids(recs), gap) }
+    // resuming after event 3 replays the rest of the task's events
+    if recs, _ := h.Subscribe("a", 3).Next(); ids(recs) != "[5]" { t.Fatalf("resume: %s", ids(recs)) }
+    if recs, _ := h.Subscribe(AllTasks, 2).Next(); ids(recs) != "[3 4 5 6]" { t.Fatalf("firehose: %s", ids(recs)) }
+    if recs, _ := live.Next(); len(recs) != 0 { t.Fatalf("read twice: %s", ids(recs)) }
+}
+
+func TestHubRecordsStatus(t *testing.T) {
+    h := newTestHub(10)
+    all := h.Subscribe(AllTasks, 0)
+    h.Publish("a", Event{Event: "x"})
+    h.Publish("a", Event{Event: "task_status", Payload: map[string]any{"status": "RUNNING"}})
+    h.Publish("b", Event{Event: "x"})
+    h.Publish("a", Event{Event: "x"})
+    h.Publish("a", Event{Event: "task_status", Payload: map[string]any{"status": "SUCCESS"}})
+    // each record keeps the status its task had when it was published
+    recs, _ := all.Next()
+    var got []string
+    for _, r := range recs { got = append(got, r.TaskID+":"+r.Status) }
+    if fmt.Sprint(got) != "[a: a:RUNNING b: a:RUNNING a:SUCCESS]" { t.Fatalf("statuses %v", got) }
+    h.Drop("a")
+    h.Publish("a", Event{Event: "x"})
+    if recs, _ := all.Next(); len(recs) != 1 || recs[0].Status != "" { t.Fatalf("status kept after drop: %+v", recs) }
+}
+
+func TestHubGap(t *testing.T) {
+    h := newTestHub(3)
+    slow := h.Subscribe("a", 0)
+    for i := 0; i < 5; i++ { h.Publish("a", Event{Event: "x"}) }
+    recs, gap := slow.Next()
+    if gap == nil || gap.LastEventID != 0 || gap.FirstAvailableID != 3 { t.Fatalf("gap %+v", gap) }
+    if ids(recs) != "[3 4 5]" { t.Fatalf("after gap: %s", ids(recs)) }
+    if _, gap := h.Subscribe("a", 3).Next(); gap != nil { t.Fatalf("no events were lost after 3: %+v", gap) }
+}
+
+func TestHubDropsFinishedLogs(t *testing.T) {
+    h := newTestHub(10)
+    h.retention = time.Hour
+    h.Publish("done", Event{Event: "x"})
+    h.Publish("live", Event{Event: "x"})
+    h.Finish("done")
+    h.Publish("again", Event{Event: "x"})
+    h.Finish("again")
+    h.Publish("again", Event{Event: "x"}) // a new run makes it live again
+
+    h.mu.Lock()
+    h.pruneLocked(time.Now().Add(2 * time.Hour))
+    _, done := h.logs["done"]
+    _, live := h.logs["live"]
+    _, again := h.logs["again"]
+    _, all := h.logs[AllTasks]
+    h.mu.Unlock()
+    if done || !live || !again || !all { t.Fatalf("logs kept: done=%v live=%v again=%v firehose=%v", done, live, again, all) }
+
+    h.Drop("live")
+    if recs, _ := h.Subscribe("live", 1).Next(); len(recs) != 0 { t.Fatalf("dropped log replayed %s", ids(recs)) }
+    if recs, _ := h.Subscribe(AllTasks, 1).Next(); ids(recs) != "[2 3 4]" { t.Fatalf("firehose lost events: %s", ids(recs)) }
+}
+
+func TestHubGapAfterPrune(t *testing.T) {
+    h := newTestHub(10)
+    h.retention = time.Hour
+    h.Publish("a", Event{Event: "x"})
+    h.Publish("a", Event{Event: "x"})
+    h.Finish("a")
+    h.Publish("b", Event{Event: "x"})
+    h.mu.Lock()
+    h.pruneLocked(time.Now().Add(2 * time.Hour))
+    h.mu.Unlock()
+    // a resume within the pruned log cannot be replayed, so it is a gap, reported once
+    s := h.Subscribe("a", 1)
+    recs, gap := s.Next()
+    if gap == nil || gap.LastEventID != 1 || gap.FirstAvailableID != 4 || len(recs) != 0 { t.Fatalf("gap %+v, recs %s", gap, ids(recs)) }
+    if _, gap := s.Next(); gap != nil { t.Fatalf("second gap %+v", gap) }
+    h.Publish("a", Event{Event: "x"})
+    if recs, gap := s.Next(); ids(recs) != "[4]" || gap != nil { t.Fatalf("new run: %s %+v", ids(recs), gap) }
+}
+
+func TestDeleteTaskDropsEvents(t *testing.T) {
+    o, _ := newTestOrchestrator(echoExec)
+    o.CreateTask("gone", "q", nil)
+    if err := o.DeleteTask("gone"); err != nil { t.Fatal(err) }
+    o.hub.mu.Lock()
+    _, kept := o.hub.logs["gone"]
+    o.hub.mu.Unlock()
+    if kept { t.Fatal("event log kept after delete") }
+}
+
+// TestHubStaleCursor resumes with an ID from before a restart, when IDs started over.
+func TestHubStaleCursor(t *testing.T) {
+    h := newTestHub(10)
+    h.Publish("a", Event{Event: "x"})
+    h.Publish("a", Event{Event: "x"})
+    s := h.Subscribe("a", 40)
+    select {
+    case <-s.C():
+    default:
+        t.Fatal("stale subscriber not woken")
+    }
+    recs, gap := s.Next()
+    if gap == nil || gap.LastEventID != 40 || gap.FirstAvailableID != 3 || len(recs) != 0 { t.Fatalf("gap %+v, recs %s", gap, ids(recs)) }
+    // from then on it reads live events
+    h.Publish("a", Event{Event: "x"})
+    if recs, gap := s.Next(); ids(recs) != "[3]" || gap != nil { t.Fatalf("live: %s %+v", ids(recs), gap) }
+    // also for tasks without events yet
+    if _, gap := h.Subscribe("new", 40).Next(); gap == nil { t.Fatal("no gap for a task without a log") }
+}
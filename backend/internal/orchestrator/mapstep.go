@@ -0,0 +1,111 @@
+package orchestrator
+
+import (
+    "context"
+    "fmt"
+    "sync"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/templating"
+)
+
+const defaultMapParallelism = 4
+
+// runMapStep expands a map step over its list and runs the sub-chain for every element,
+// at most Parallelism at a time. Each item sees the parent results plus its own sub-step
+// results, and the element under the name given by As. The combined result is left
+// for the caller to verify.
+func (o *Orchestrator) runMapStep(ctx context.Context, t *models.Task, step *models.Step, scope *templating.Scope) *models.Result {
+    spec := step.Map
+    res := &models.Result{StepID: step.ID}
+    if spec.Over == "" || len(spec.Steps) == 0 {
+        res.Error = "map step needs \"over\" and at least one sub-step"
+        return res
+    }
+    over, err := templating.ResolveString(spec.Over, scope)
+    if err != nil { res.Error = "resolve map over: " + err.Error(); return res }
+    items, ok := templating.List(over)
+    if !ok { res.Error = fmt.Sprintf("map over %s: value is not an array", spec.Over); return res }
+    as := spec.As
+    if as == "" { as = "item" }
+    parallelism := spec.Parallelism
+    if parallelism <= 0 { parallelism = defaultMapParallelism }
+
+    outputs := make([]any, len(items))
+    errs := make([]string, len(items))
+    sem := make(chan struct{}, parallelism)
+    var wg sync.WaitGroup
+    for i, item := range items {
+        wg.Add(1)
+        go func(i int, item any) {
+            defer wg.Done()
+            select {
+            case sem <- struct{}{}:
+            case <-ctx.Done():
+                errs[i] = ctx.Err().Error()
+                return
+            }
+            defer func() { <-sem }()
+            o.publishMapItem(t.ID, step.ID, i, models.StatusRunning, nil, "")
+            out, err := o.runMapItem(ctx, t, step, i, item, as, scope)
+            if err != nil {
+                errs[i] = err.Error()
+                o.publishMapItem(t.ID, step.ID, i, models.StatusFailed, nil, errs[i])
+                return
+            }
+            outputs[i] = out
+            o.publishMapItem(t.ID, step.ID, i, models.StatusSuccess, out, "")
+        }(i, item)
+    }
+    wg.Wait()
+
+    failed, firstErr := 0, ""
+    for i, e := range errs {
+        if e == "" { continue }
+        failed++
+        if firstErr == "" { firstErr = fmt.Sprintf("item %d: %s", i, e) }
+    }
+    res.Output = outputs
+    res.Logs = fmt.Sprintf("items=%d succeeded=%d failed=%d parallelism=%d", len(items), len(items)-failed, failed, parallelism)
+    if failed > 0 {
+        res.Error = fmt.Sprintf("%d of %d map items failed; %s", failed, len(items), firstErr)
+        return res
+    }
+    return res
+}
+
+// runMapItem runs the sub-chain for one element and returns the last executed sub-step's
+// output. Sub-steps may carry conditions like top-level steps.
+func (o *Orchestrator) runMapItem(ctx context.Context, t *models.Task, step *models.Step, index int, item any, as string, parent *templating.Scope) (any, error) {
+    steps := make(map[string]*models.Result, len(parent.Steps)+len(step.Map.Steps))
+    for k, v := range parent.Steps { steps[k] = v }
+    vars := map[string]any{}
+    for k, v := range parent.Vars { vars[k] = v }
+    vars[as] = item
+    vars["index"] = index
+    scope := &templating.Scope{Task: t, Steps: steps, Vars: vars}
+
+    var last any
+    skipped := map[string]bool{}
+    for _, sub := range step.Map.Steps {
+        reason, err := skipReason(sub, scope, skipped)
+        if err != nil { return nil, fmt.Errorf("%s: %v", sub.ID, err) }
+        if reason != "" { skipped[sub.ID] = true; continue }
+        streamID := fmt.Sprintf("%s[%d].%s", step.ID, index, sub.ID)
+        res, _ := o.executeStep(ctx, t, sub, scope, streamID)
+        if !res.Verified || res.Error != "" {
+            if res.Error != "" { return nil, fmt.Errorf("%s: %s", sub.ID, res.Error) }
+            return nil, fmt.Errorf("%s: output not verified", sub.ID)
+        }
+        steps[sub.ID] = res
+        last = res.Output
+    }
+    return last, nil
+}
+
+func (o *Orchestrator) publishMapItem(taskID, stepID string, index int, status models.Status, output any, errMsg string) {
+    payload := map[string]any{"step_id": stepID, "index": index, "status": status}
+    if output != nil { payload["output"] = output }
+    if errMsg != "" { payload["error"] = errMsg }
+    o.hub.Publish(taskID, Event{Event: "map_item", TaskID: taskID, Payload: payload})
+}
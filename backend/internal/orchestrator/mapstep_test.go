@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+    "context"
+    "fmt"
+    "reflect"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func mapPlan(parallelism int) []*models.Step {
+    return []*models.Step{
+        {ID: "list", Tool: "echo", Inputs: map[string]any{"text": []any{"a", "b", "c", "d"}}},
+        {ID: "each", Tool: "map", Deps: []string{"list"}, Map: &models.MapSpec{
+            Over:        "{{step:list.output}}",
+            As:          "letter",
+            Parallelism: parallelism,
+            Steps: []*models.Step{
+                {ID: "up", Tool: "echo", Inputs: map[string]any{"text": "{{letter}}{{index}}"}},
+                {ID: "wrap", Tool: "echo", Inputs: map[string]any{"text": "<{{step:up.output}}>"}},
+            },
+        }},
+    }
+}
+
+func TestMapStep(t *testing.T) {
+    var running, peak atomic.Int32
+    exec := execFunc(func(ctx context.Context, step *models.Step) (any, error) {
+        n := running.Add(1)
+        defer running.Add(-1)
+        for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {}
+        time.Sleep(5 * time.Millisecond)
+        return step.Inputs["text"], nil
+    })
+    o, v := newTestOrchestrator(exec, mapPlan(2)...)
+    o.CreateTask("m", "q", nil)
+    events := collect(o, "m")
+    o.PlanOnly(context.Background(), "m")
+    if err := o.ExecutePlan(context.Background(), "m"); err != nil { t.Fatal(err) }
+    task, _ := o.GetTask("m")
+    if task.Status != models.StatusSuccess { t.Fatalf("status %s: %s", task.Status, task.LastRun.Error) }
+    want := []any{"<a0>", "<b1>", "<c2>", "<d3>"}
+    if got := task.Results[1].Output; !reflect.DeepEqual(got, want) { t.Fatalf("output %v", got) }
+    if peak.Load() > 2 { t.Errorf("%d items ran at once, parallelism is 2", peak.Load()) }
+    // every sub-step and the combined result went through the verifier
+    if v.calls("up") != 4 || v.calls("wrap") != 4 || v.calls("each") != 1 { t.Errorf("verifier calls %v", v.seen) }
+
+    items := map[string]int{}
+    for _, ev := range events() {
+        if ev.Event != "map_item" { continue }
+        p := ev.Payload.(map[string]any)
+        items[fmt.Sprint(p["status"])]++
+    }
+    if items["RUNNING"] != 4 || items["SUCCESS"] != 4 { t.Errorf("map_item events %v", items) }
+}
+
+func TestMapStepVerifierRejectsResult(t *testing.T) {
+    o, v := newTestOrchestrator(echoExec, mapPlan(0)...)
+    v.reject["each"] = true
+    task := runTask(t, o, "m")
+    if task.Status != models.StatusFailed || task.LastRun.Error != "step each: output not verified" { t.Fatalf("status %s: %q", task.Status, task.LastRun.Error) }
+}
+
+func TestMapStepItemFailure(t *testing.T) {
+    exec := execFunc(func(ctx context.Context, step *models.Step) (any, error) {
+        if step.Inputs["text"] == "b1" { return nil, fmt.Errorf("boom") }
+        return step.Inputs["text"], nil
+    })
+    o, v := newTestOrchestrator(exec, mapPlan(1)...)
+    task := runTask(t, o, "m")
+    if task.Status != models.StatusFailed { t.Fatalf("status %s", task.Status) }
+    if msg := task.Results[1].Error; msg != "1 of 4 map items failed; item 1: up: boom" { t.Fatalf("error %q", msg) }
+    if v.calls("each") != 0 { t.Errorf("failed map result was verified") }
+}
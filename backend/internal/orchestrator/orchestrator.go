@@ -2,7 +2,6 @@ package orchestrator
 
 import (
     "context"
-    "encoding/json"
     "errors"
     "fmt"
     "sync"
@@ -10,18 +9,43 @@ import (
 
     "github.com/example/agent-orchestrator/internal/agents"
     "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/templating"
     "github.com/example/agent-orchestrator/internal/tools"
-    "regexp"
 )
 
 type Orchestrator struct {
     Planner  agents.Planner
     Executor agents.Executor
     Verifier agents.Verifier
+    // Tools, when set, lets plan edits be checked against the registered tools and
+    // supplies tool risk for approvals.
+    Tools    *tools.Registry
+    // ApprovalRisk is the tool risk from which steps wait for approval ("" disables
+    // approvals); ApprovalTimeout (0 = none) rejects them when nobody decides in time.
+    ApprovalRisk    tools.Risk
+    ApprovalTimeout time.Duration
+    // Store, when set, keeps tasks across restarts (see Restore).
+    Store    Store
 
     tasksMu sync.RWMutex
     tasks   map[string]*models.Task
 
+    runsMu sync.RWMutex
+    runs   map[string][]*models.Run // taskID -> runs, oldest first
+
+    plansMu  sync.Mutex
+    versions map[string][]*models.PlanVersion // taskID -> plan history, oldest first
+
+    waitMu  sync.Mutex
+    waiting map[string]chan any // taskID/stepID -> pending approval or answer; also guards Run.Questions
+
+    ctlMu    sync.Mutex
+    controls map[string]*runControl // taskID -> active (planning or running) task
+
+    storeMu sync.Mutex
+
+    queue *taskQueue
+
     hub *Hub
 }
 
@@ -31,16 +55,31 @@ func New(planner agents.Planner, executor agents.Executor, verifier agents.Verif
         Executor: executor,
         Verifier: verifier,
         tasks:    map[string]*models.Task{},
+        runs:     map[string][]*models.Run{},
+        versions: map[string][]*models.PlanVersion{},
+        waiting:  map[string]chan any{},
+        controls: map[string]*runControl{},
+        queue:    newTaskQueue(),
         hub:      NewHub(),
     }
 }
 
 func (o *Orchestrator) CreateTask(id string, query string, contextMap map[string]any) *models.Task {
-    t := &models.Task{ID: id, Query: query, Context: contextMap, Status: models.StatusPending, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+    return o.AddTask(&models.Task{ID: id, Query: query, Context: contextMap})
+}
+
+// AddTask registers a new PENDING task prepared by the caller, so fields such as
+// Priority and ClientID are already set when its first event is published and it is
+// first persisted.
+func (o *Orchestrator) AddTask(t *models.Task) *models.Task {
+    id := t.ID
+    t.Status, t.CreatedAt, t.UpdatedAt = models.StatusPending, time.Now(), time.Now()
     o.tasksMu.Lock()
     o.tasks[id] = t
     o.tasksMu.Unlock()
-    o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status}})
+    // the query lets firehose subscribers show tasks they have not fetched
+    o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status, "query": t.Query}})
+    o.persist(t)
     return t
 }
 
@@ -64,8 +103,11 @@ func (o *Orchestrator) ListTasks() []*models.Task {
 func (o *Orchestrator) Start(ctx context.Context, id string) error {
     t, ok := o.GetTask(id)
     if !ok {
-        return errors.New("task not found")
+        return ErrTaskNotFound
     }
+    ctx, release, err := o.track(ctx, id)
+    if err != nil { return err }
+    defer release()
     t.Status = models.StatusRunning
     t.UpdatedAt = time.Now()
     o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status}})
@@ -74,70 +116,40 @@ func (o *Orchestrator) Start(ctx context.Context, id string) error {
     plan, err := o.Planner.Plan(ctx, t)
     if err != nil {
         t.Status = models.StatusFailed
+        if _, cancelled := o.requested(id); cancelled { t.Status = models.StatusCancelled }
         t.UpdatedAt = time.Now()
         o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status, "error": err.Error()}})
+        o.hub.Finish(id)
+        o.persist(t)
         return err
     }
-    t.Plan = plan
-    o.hub.Publish(id, Event{Event: "plan", TaskID: id, Payload: plan})
-
-    // Sequential execution MVP
-    resultsByID := map[string]*models.Result{}
-    for _, step := range plan.Steps {
-        step.Status = models.StatusRunning
-        t.UpdatedAt = time.Now()
-        o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: step})
-        // resolve input references from prior step outputs
-        step.Inputs = resolveInputs(step.Inputs, resultsByID)
-        // attach token streaming callback for LLM tools
-        subCtx := context.WithValue(ctx, tools.CtxTokenCallbackKey, tools.TokenCallback(func(chunk string) {
-            o.hub.Publish(id, Event{Event: "token", TaskID: id, Payload: map[string]any{"step_id": step.ID, "chunk": chunk}})
-        }))
-        res, _ := o.Executor.Execute(subCtx, step)
-        verified, _ := o.Verifier.Verify(ctx, t, step, res)
-        res.Verified = verified
-        if !verified || res.Error != "" {
-            step.Status = models.StatusFailed
-            if t.Results == nil { t.Results = []*models.Result{} }
-            t.Results = append(t.Results, res)
-            t.Status = models.StatusFailed
-            t.UpdatedAt = time.Now()
-            o.hub.Publish(id, Event{Event: "result", TaskID: id, Payload: res})
-            o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: step})
-            o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status}})
-            return nil
-        }
-        res.Verified = true
-        resultsByID[step.ID] = res
-        if t.Results == nil { t.Results = []*models.Result{} }
-        t.Results = append(t.Results, res)
-        step.Status = models.StatusSuccess
-        t.UpdatedAt = time.Now()
-        o.hub.Publish(id, Event{Event: "result", TaskID: id, Payload: res})
-        o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: step})
-    }
-
-    t.Status = models.StatusSuccess
-    t.UpdatedAt = time.Now()
-    o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status}})
+    o.recordPlan(t, plan, "planner", "planned")
+    o.runSteps(ctx, t, o.newRun(t))
     return nil
 }
 
-// PlanOnly computes a plan for a task and stores it without executing.
+// PlanOnly computes a plan for a task and stores it without executing. A queued or
+// active task keeps its plan: ErrAlreadyQueued or ErrTaskRunning.
 func (o *Orchestrator) PlanOnly(ctx context.Context, id string) (*models.Plan, error) {
     t, ok := o.GetTask(id)
     if !ok {
-        return nil, errors.New("task not found")
+        return nil, ErrTaskNotFound
     }
+    if t.Status == models.StatusQueued { return nil, ErrAlreadyQueued }
+    o.ctlMu.Lock()
+    _, active := o.controls[id]
+    o.ctlMu.Unlock()
+    if active || isActive(t.Status) { return nil, ErrTaskRunning }
     plan, err := o.Planner.Plan(ctx, t)
     if err != nil {
         t.Status = models.StatusFailed
         t.UpdatedAt = time.Now()
         return nil, err
     }
-    t.Plan = plan
+    o.recordPlan(t, plan, "planner", "planned")
     t.Status = models.StatusPlanned
     t.UpdatedAt = time.Now()
+    o.persist(t)
     return plan, nil
 }
 
@@ -145,91 +157,209 @@ func (o *Orchestrator) PlanOnly(ctx context.Context, id string) (*models.Plan, e
 func (o *Orchestrator) ExecutePlan(ctx context.Context, id string) error {
     t, ok := o.GetTask(id)
     if !ok {
-        return errors.New("task not found")
+        return ErrTaskNotFound
     }
     if t.Plan == nil || len(t.Plan.Steps) == 0 {
-        return errors.New("no plan to execute")
+        return ErrNoPlan
     }
+    ctx, release, err := o.track(ctx, id)
+    if err != nil { return err }
+    defer release()
     t.Status = models.StatusRunning
     t.UpdatedAt = time.Now()
-    // Sequential execution MVP
+    o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status}})
+    o.runSteps(ctx, t, o.newRun(t))
+    return nil
+}
+
+// Runs returns a task's runs, oldest first.
+func (o *Orchestrator) Runs(taskID string) []*models.Run {
+    o.runsMu.RLock()
+    defer o.runsMu.RUnlock()
+    return append([]*models.Run(nil), o.runs[taskID]...)
+}
+
+// GetRun returns one run of a task.
+func (o *Orchestrator) GetRun(taskID, runID string) (*models.Run, bool) {
+    o.runsMu.RLock()
+    defer o.runsMu.RUnlock()
+    for _, r := range o.runs[taskID] {
+        if r.ID == runID { return r, true }
+    }
+    return nil, false
+}
+
+// newRun records a new run of the task's current plan, working on a snapshot of it.
+// A paused run that was never resumed is cancelled.
+func (o *Orchestrator) newRun(t *models.Task) *models.Run {
+    if prev := t.LastRun; prev != nil && prev.Status == models.StatusPaused {
+        now := time.Now()
+        prev.Status, prev.Error, prev.FinishedAt = models.StatusCancelled, "superseded by a new run", &now
+        prev.DurationMs = now.Sub(prev.StartedAt).Milliseconds()
+        o.hub.Publish(t.ID, Event{Event: "run_status", TaskID: t.ID, Payload: runStatus(prev)})
+    }
+    o.runsMu.Lock()
+    n := len(o.runs[t.ID]) + 1
+    run := &models.Run{ID: fmt.Sprintf("%s-run%d", t.ID, n), TaskID: t.ID, Number: n, Status: models.StatusRunning, Plan: t.Plan.Clone(), PlanVersion: t.PlanVersion, StartedAt: time.Now()}
+    for _, step := range run.Plan.Steps {
+        run.Steps = append(run.Steps, &models.StepRun{ID: step.ID, RunID: run.ID, Tool: step.Tool, Status: models.StatusPending})
+    }
+    o.runs[t.ID] = append(o.runs[t.ID], run)
+    o.runsMu.Unlock()
+    t.LastRun = run
+    t.Results = nil
+    o.hub.Publish(t.ID, Event{Event: "run_status", TaskID: t.ID, Payload: runStatus(run)})
+    o.persist(t)
+    return run
+}
+
+func runStatus(run *models.Run) map[string]any {
+    p := map[string]any{"run_id": run.ID, "number": run.Number, "status": run.Status}
+    if run.Error != "" { p["error"] = run.Error }
+    if run.FinishedAt != nil { p["duration_ms"] = run.DurationMs }
+    return p
+}
+
+// runSteps executes the run's plan snapshot sequentially, stopping at the first failed
+// step, and sets the final run and task status. Skipped steps do not count as failures.
+// Steps the run already finished are not repeated, so a paused run continues where it
+// stopped. Pause and cancel requests are honoured between steps.
+func (o *Orchestrator) runSteps(ctx context.Context, t *models.Task, run *models.Run) {
+    id := t.ID
     resultsByID := map[string]*models.Result{}
-    for _, step := range t.Plan.Steps {
-        step.Status = models.StatusRunning
-        t.UpdatedAt = time.Now()
-        o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: step})
-        step.Inputs = resolveInputs(step.Inputs, resultsByID)
-        subCtx := context.WithValue(ctx, tools.CtxTokenCallbackKey, tools.TokenCallback(func(chunk string) {
-            o.hub.Publish(id, Event{Event: "token", TaskID: id, Payload: map[string]any{"step_id": step.ID, "chunk": chunk}})
-        }))
-        res, _ := o.Executor.Execute(subCtx, step)
-        verified, _ := o.Verifier.Verify(ctx, t, step, res)
-        res.Verified = verified
-        if !verified || res.Error != "" {
-            step.Status = models.StatusFailed
-            if t.Results == nil { t.Results = []*models.Result{} }
-            t.Results = append(t.Results, res)
-            t.Status = models.StatusFailed
+    skipped := map[string]bool{}
+    for _, res := range run.Results { resultsByID[res.StepID] = res }
+    for _, step := range run.Plan.Steps {
+        sr := run.Step(step.ID)
+        switch sr.Status {
+        case models.StatusSuccess:
+            continue
+        case models.StatusSkipped:
+            skipped[step.ID] = true
+            continue
+        }
+        pause, cancelled := o.requested(id)
+        if cancelled || ctx.Err() != nil {
+            o.finishRun(t, run, models.StatusCancelled, "cancelled")
+            return
+        }
+        if pause {
+            o.pauseRun(t, run)
+            return
+        }
+        scope := &templating.Scope{Task: t, Steps: resultsByID}
+        reason, err := skipReason(step, scope, skipped)
+        if reason != "" {
+            skipped[step.ID] = true
+            sr.Status = models.StatusSkipped
+            sr.Reason = reason
             t.UpdatedAt = time.Now()
+            o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: sr})
+            o.persist(t)
+            continue
+        }
+        if err == nil { err = o.awaitApproval(ctx, t, run, step, sr, scope) }
+        started := time.Now()
+        sr.Status = models.StatusRunning
+        sr.StartedAt = &started
+        t.UpdatedAt = started
+        o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: sr})
+        var res *models.Result
+        if err != nil {
+            res = &models.Result{StepID: step.ID, Error: err.Error()}
+        } else {
+            res, sr.Inputs = o.executeStep(ctx, t, step, scope, step.ID)
+        }
+        finished := time.Now()
+        sr.FinishedAt = &finished
+        sr.DurationMs = finished.Sub(started).Milliseconds()
+        run.Results = append(run.Results, res)
+        t.Results = run.Results
+        if !res.Verified || res.Error != "" {
+            _, cancelled := o.requested(id)
+            sr.Status = models.StatusFailed
+            if cancelled { sr.Status = models.StatusCancelled }
             o.hub.Publish(id, Event{Event: "result", TaskID: id, Payload: res})
-            o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: step})
-            o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status}})
-            return nil
+            o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: sr})
+            if cancelled {
+                o.finishRun(t, run, models.StatusCancelled, "cancelled")
+                return
+            }
+            msg := res.Error
+            if msg == "" { msg = "output not verified" }
+            o.finishRun(t, run, models.StatusFailed, fmt.Sprintf("step %s: %s", step.ID, msg))
+            return
         }
-        res.Verified = true
         resultsByID[step.ID] = res
-        if t.Results == nil { t.Results = []*models.Result{} }
-        t.Results = append(t.Results, res)
-        step.Status = models.StatusSuccess
+        sr.Status = models.StatusSuccess
         t.UpdatedAt = time.Now()
         o.hub.Publish(id, Event{Event: "result", TaskID: id, Payload: res})
-        o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: step})
+        o.hub.Publish(id, Event{Event: "step_status", TaskID: id, Payload: sr})
+        o.persist(t)
     }
-    t.Status = models.StatusSuccess
-    t.UpdatedAt = time.Now()
-    o.hub.Publish(id, Event{Event: "task_status", TaskID: id, Payload: map[string]any{"status": t.Status}})
-    return nil
+    o.finishRun(t, run, models.StatusSuccess, "")
 }
 
-// Subscribe returns a channel carrying JSON-encoded Event payloads for a specific task.
-// The caller must call the returned unsubscribe func when done.
-func (o *Orchestrator) Subscribe(taskID string) (<-chan []byte, func()) {
-    ch, unsub := o.hub.Subscribe(taskID)
-    return ch, unsub
-}
-
-// resolveInputs replaces any string value exactly matching {{step:ID.output}} with the
-// stringified output of that prior step, if available.
-func resolveInputs(inputs map[string]any, resultsByID map[string]*models.Result) map[string]any {
-    if inputs == nil { return nil }
-    out := make(map[string]any, len(inputs))
-    for k, v := range inputs {
-        if s, ok := v.(string); ok {
-            // Replace all occurrences of {{step:ID.output}} within the string
-            re := regexp.MustCompile(`\{\{step:([a-zA-Z0-9_\-]+)\.output\}\}`)
-            replaced := re.ReplaceAllStringFunc(s, func(m string) string {
-                match := re.FindStringSubmatch(m)
-                if len(match) != 2 { return m }
-                id := match[1]
-                if res, ok := resultsByID[id]; ok && res != nil {
-                    return stringifyOutput(res.Output)
-                }
-                return fmt.Sprintf("(missing output from %s)", id)
-            })
-            out[k] = replaced
-            continue
-        }
-        out[k] = v
-    }
-    return out
+// finishRun records a run's outcome, which becomes the task's status.
+func (o *Orchestrator) finishRun(t *models.Task, run *models.Run, status models.Status, errMsg string) {
+    now := time.Now()
+    run.Status = status
+    run.Error = errMsg
+    run.FinishedAt = &now
+    run.DurationMs = now.Sub(run.StartedAt).Milliseconds()
+    t.Status = status
+    t.UpdatedAt = now
+    o.hub.Publish(t.ID, Event{Event: "run_status", TaskID: t.ID, Payload: runStatus(run)})
+    o.hub.Publish(t.ID, Event{Event: "task_status", TaskID: t.ID, Payload: map[string]any{"status": t.Status, "run_id": run.ID}})
+    o.hub.Finish(t.ID)
+    o.persist(t)
 }
 
-func stringifyOutput(v any) string {
-    switch t := v.(type) {
-    case string:
-        return t
-    default:
-        b, _ := json.Marshal(t)
-        return string(b)
+// executeStep resolves a step's inputs against scope, runs it and verifies the result.
+// The step itself is not modified; the resolved inputs are returned alongside the
+// result. streamID labels token events (sub-steps of a map use "map[i].sub").
+func (o *Orchestrator) executeStep(ctx context.Context, t *models.Task, step *models.Step, scope *templating.Scope, streamID string) (*models.Result, map[string]any) {
+    // the step's timeout covers the tool call (or every item of a map), not verification
+    execCtx := ctx
+    if d, err := time.ParseDuration(step.Timeout); err == nil && d > 0 {
+        var cancel context.CancelFunc
+        execCtx, cancel = context.WithTimeout(ctx, d)
+        defer cancel()
+    }
+    if step.Map != nil {
+        // items were verified one sub-step at a time; the collected outputs still have
+        // to satisfy the map step itself
+        res := o.runMapStep(execCtx, t, step, scope)
+        if res.Error == "" { res.Verified, _ = o.Verifier.Verify(ctx, t, step, res) }
+        return res, nil
+    }
+    // resolve input references from prior step outputs and task context
+    inputs, err := templating.ResolveInputs(step.Inputs, scope)
+    if err != nil {
+        return &models.Result{StepID: step.ID, Error: "resolve inputs: " + err.Error()}, nil
     }
+    exec := *step
+    exec.Inputs = inputs
+    // attach token streaming callback for LLM tools
+    subCtx := context.WithValue(execCtx, tools.CtxTokenCallbackKey, tools.TokenCallback(func(chunk string) {
+        o.hub.Publish(t.ID, Event{Event: "token", TaskID: t.ID, Payload: map[string]any{"step_id": streamID, "chunk": chunk}})
+    }))
+    // ask_user steps reach the user through the same run
+    subCtx = context.WithValue(subCtx, tools.CtxAskKey, tools.AskFunc(func(ctx context.Context, question string, choices []string) (string, error) {
+        return o.askUser(ctx, t, streamID, question, choices)
+    }))
+    res, _ := o.Executor.Execute(subCtx, &exec)
+    if res.Error != "" && errors.Is(execCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil { res.Error = "timed out after " + step.Timeout }
+    verified, _ := o.Verifier.Verify(ctx, t, &exec, res)
+    res.Verified = verified
+    return res, inputs
+}
+
+// AddSink forwards every event published from now on to s; see Hub.AddSink.
+func (o *Orchestrator) AddSink(s EventSink) func() { return o.hub.AddSink(s) }
+
+// Subscribe reads a task's events (every task's for AllTasks) published after
+// lastEventID (0 for live events only). The caller must Close the subscription when done.
+func (o *Orchestrator) Subscribe(taskID string, lastEventID uint64) *Subscription {
+    return o.hub.Subscribe(taskID, lastEventID)
 }
@@ -0,0 +1,164 @@
+package orchestrator
+
+import (
+    "context"
+    "encoding/json"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// planFunc plans every task with a fresh copy of a fixed plan.
+type planFunc func(t *models.Task) *models.Plan
+
+func (f planFunc) Plan(ctx context.Context, t *models.Task) (*models.Plan, error) { return f(t).Clone(), nil }
+
+// execFunc executes steps with a function of their resolved inputs.
+type execFunc func(ctx context.Context, step *models.Step) (any, error)
+
+func (f execFunc) Execute(ctx context.Context, step *models.Step) (*models.Result, error) {
+    out, err := f(ctx, step)
+    res := &models.Result{StepID: step.ID, Output: out}
+    if err != nil { res.Error = err.Error() }
+    return res, nil
+}
+
+// echoExec returns each step's "text" input.
+var echoExec = execFunc(func(ctx context.Context, step *models.Step) (any, error) { return step.Inputs["text"], nil })
+
+// recordingVerifier accepts everything except the step IDs in reject, and records the
+// IDs it was asked about.
+type recordingVerifier struct {
+    mu     sync.Mutex
+    seen   []string
+    reject map[string]bool
+}
+
+func (v *recordingVerifier) Verify(ctx context.Context, t *models.Task, step *models.Step, res *models.Result) (bool, string) {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    v.seen = append(v.seen, step.ID)
+    if v.reject[step.ID] { return false, "rejected" }
+    return true, "ok"
+}
+
+func (v *recordingVerifier) calls(id string) int {
+    v.mu.Lock()
+    defer v.mu.Unlock()
+    n := 0
+    for _, s := range v.seen {
+        if s == id { n++ }
+    }
+    return n
+}
+
+// newTestOrchestrator returns an orchestrator that plans steps and runs them with exec.
+func newTestOrchestrator(exec execFunc, steps ...*models.Step) (*Orchestrator, *recordingVerifier) {
+    v := &recordingVerifier{reject: map[string]bool{}}
+    o := New(planFunc(func(*models.Task) *models.Plan { return &models.Plan{Steps: steps} }), exec, v)
+    return o, v
+}
+
+// runTask creates a task, plans it and executes the plan synchronously.
+func runTask(t *testing.T, o *Orchestrator, id string) *models.Task {
+    t.Helper()
+    task := o.CreateTask(id, "query "+id, nil)
+    if _, err := o.PlanOnly(context.Background(), id); err != nil { t.Fatalf("plan: %v", err) }
+    if err := o.ExecutePlan(context.Background(), id); err != nil { t.Fatalf("execute: %v", err) }
+    return task
+}
+
+// collect subscribes to a task's events and returns a func that decodes everything
+// published so far.
+func collect(o *Orchestrator, taskID string) func() []Event {
+    sub := o.Subscribe(taskID, 0)
+    var evs []Event
+    return func() []Event {
+        recs, _ := sub.Next()
+        for _, r := range recs {
+            var ev Event
+            json.Unmarshal(r.Data, &ev)
+            evs = append(evs, ev)
+        }
+        return evs
+    }
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(time.Second)
+    for !cond() {
+        if time.Now().After(deadline) { t.Fatalf("timed out waiting for %s", what) }
+        time.Sleep(2 * time.Millisecond)
+    }
+}
+
+func TestRunTask(t *testing.T) {
+    o, v := newTestOrchestrator(echoExec,
+        &models.Step{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "{{task.query}}"}},
+        &models.Step{ID: "b", Tool: "echo", Inputs: map[string]any{"text": "got {{step:a.output}}"}},
+    )
+    task := runTask(t, o, "t1")
+    if task.Status != models.StatusSuccess { t.Fatalf("status %s: %+v", task.Status, task.LastRun) }
+    if out := task.Results[1].Output; out != "got query t1" { t.Fatalf("output %v", out) }
+    if v.calls("a") != 1 || v.calls("b") != 1 { t.Fatalf("verifier calls %v", v.seen) }
+
+    v.reject["b"] = true
+    task = runTask(t, o, "t2")
+    if task.Status != models.StatusFailed || task.LastRun.Error != "step b: output not verified" { t.Fatalf("status %s: %q", task.Status, task.LastRun.Error) }
+}
+
+// memStore keeps JSON copies of saved tasks, as a file store would.
+type memStore struct {
+    mu    sync.Mutex
+    saved map[string][]byte
+    saves []string
+}
+
+func newMemStore() *memStore { return &memStore{saved: map[string][]byte{}} }
+
+func (s *memStore) SaveTask(st *models.TaskState) error {
+    b, err := json.Marshal(st)
+    if err != nil { return err }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.saved[st.Task.ID] = b
+    s.saves = append(s.saves, string(b))
+    return nil
+}
+
+func (s *memStore) LoadTasks() ([]*models.TaskState, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var out []*models.TaskState
+    for _, b := range s.saved {
+        var st models.TaskState
+        if err := json.Unmarshal(b, &st); err != nil { return nil, err }
+        out = append(out, &st)
+    }
+    return out, nil
+}
+
+func (s *memStore) DeleteTask(id string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.saved, id)
+    return nil
+}
+
+func TestAddTask(t *testing.T) {
+    o, _ := newTestOrchestrator(echoExec)
+    store := newMemStore()
+    o.Store = store
+    events := collect(o, AllTasks)
+    task := o.AddTask(&models.Task{ID: "t1", Query: "q", Priority: 3, ClientID: "alice"})
+    if task.Status != models.StatusPending || task.CreatedAt.IsZero() { t.Fatalf("task %+v", task) }
+    // the first save already carries the caller's fields
+    var first models.TaskState
+    json.Unmarshal([]byte(store.saves[0]), &first)
+    if first.Task.ClientID != "alice" || first.Task.Priority != 3 { t.Fatalf("first save %s", store.saves[0]) }
+    if evs := events(); len(evs) != 1 || evs[0].Event != "task_status" { t.Fatalf("events %+v", evs) }
+}
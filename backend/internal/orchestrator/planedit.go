@@ -0,0 +1,326 @@
+package orchestrator
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "reflect"
+    "sort"
+    "strings"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/templating"
+)
+
+var (
+    ErrTaskNotFound = errors.New("task not found")
+    // ErrTaskRunning is returned for changes that are not allowed while a run is active.
+    ErrTaskRunning  = errors.New("task is running")
+    // ErrTaskPaused is returned for plan edits of a paused task, whose run resumes
+    // with the plan it has.
+    ErrTaskPaused   = errors.New("task is paused; resume or cancel it first")
+    ErrNoPlan       = errors.New("task has no plan")
+    ErrStepNotFound = errors.New("step not found")
+)
+
+// isActive reports whether a run is in progress, including one paused for a decision
+// or an answer.
+func isActive(s models.Status) bool {
+    return s == models.StatusRunning || s == models.StatusAwaitingApproval || s == models.StatusAwaitingInput
+}
+
+// PlanError lists everything wrong with a plan.
+type PlanError struct {
+    Problems []string `json:"problems"`
+}
+
+func (e *PlanError) Error() string { return "invalid plan: " + strings.Join(e.Problems, "; ") }
+
+// ValidatePlan checks a plan before it can run: step IDs are present and unique, tools
+// exist (hasTool may be nil to skip that check), map steps are well formed, and deps and
+// {{step:ID}} references only point at earlier steps, which also rules out cycles since
+// steps run in order. Map sub-steps may reference earlier sub-steps and any top-level
+// step before the map; their IDs must not repeat those of enclosing steps, which
+// references, results and approvals would otherwise confuse.
+func ValidatePlan(p *models.Plan, hasTool func(string) bool) error {
+    if p == nil || len(p.Steps) == 0 { return &PlanError{Problems: []string{"plan has no steps"}} }
+    var problems []string
+    validateSteps(p.Steps, nil, nil, "", hasTool, &problems)
+    if len(problems) > 0 { return &PlanError{Problems: problems} }
+    return nil
+}
+
+// validateSteps checks one level of steps. outer holds the IDs they may reference and
+// enclosing the IDs used on the levels above.
+func validateSteps(steps []*models.Step, outer, enclosing map[string]bool, prefix string, hasTool func(string) bool, problems *[]string) {
+    add := func(format string, args ...any) { *problems = append(*problems, prefix+fmt.Sprintf(format, args...)) }
+    earlier := map[string]bool{}
+    for k := range outer { earlier[k] = true }
+    // sub-steps of maps on this level may not reuse any ID of this level or above
+    taken := map[string]bool{}
+    for k := range enclosing { taken[k] = true }
+    for _, s := range steps {
+        if s != nil && s.ID != "" { taken[s.ID] = true }
+    }
+    seen := map[string]bool{}
+    for i, s := range steps {
+        if s == nil { add("step %d is empty", i+1); continue }
+        if s.ID == "" { add("step %d has no id", i+1); continue }
+        if seen[s.ID] { add("duplicate step id %q", s.ID) }
+        if enclosing[s.ID] { add("step id %q is already used outside this map", s.ID) }
+        seen[s.ID] = true
+        switch {
+        case s.Map != nil:
+            if s.Tool != "" && s.Tool != "map" { add("step %s: a step with \"map\" must use tool \"map\", not %q", s.ID, s.Tool) }
+            if s.Map.Over == "" { add("step %s: map needs \"over\"", s.ID) }
+            if len(s.Map.Steps) == 0 { add("step %s: map needs at least one sub-step", s.ID) }
+            for _, ref := range templating.References(s.Map.Over) {
+                if !earlier[ref] { add("step %s: map over references %q, which is not an earlier step", s.ID, ref) }
+            }
+            validateSteps(s.Map.Steps, earlier, taken, prefix+s.ID+".", hasTool, problems)
+        case s.Tool == "":
+            add("step %s has no tool", s.ID)
+        case s.Tool == "map":
+            add("step %s: tool \"map\" needs a \"map\" block", s.ID)
+        case hasTool != nil && !hasTool(s.Tool):
+            add("step %s: unknown tool %q", s.ID, s.Tool)
+        }
+        if s.Timeout != "" {
+            if d, err := time.ParseDuration(s.Timeout); err != nil || d <= 0 { add("step %s: invalid timeout %q", s.ID, s.Timeout) }
+        }
+        for _, d := range s.Deps {
+            if d == s.ID {
+                add("step %s depends on itself", s.ID)
+            } else if !earlier[d] {
+                add("step %s: dependency %q is not an earlier step", s.ID, d)
+            }
+        }
+        refs := append(templating.References(map[string]any(s.Inputs)), templating.References(s.Condition)...)
+        for _, ref := range refs {
+            if !earlier[ref] { add("step %s references %q, which is not an earlier step", s.ID, ref) }
+        }
+        earlier[s.ID] = true
+    }
+}
+
+// PlanVersions returns a task's plan history, oldest first.
+func (o *Orchestrator) PlanVersions(taskID string) []*models.PlanVersion {
+    o.plansMu.Lock()
+    defer o.plansMu.Unlock()
+    return append([]*models.PlanVersion(nil), o.versions[taskID]...)
+}
+
+// EditPlan applies edit to a copy of the task's plan (an empty plan if there is none),
+// validates the result and stores it as a new version. Edits are refused while the task
+// is queued (ErrAlreadyQueued), running (ErrTaskRunning) or paused (ErrTaskPaused).
+func (o *Orchestrator) EditPlan(taskID, author, action string, edit func(p *models.Plan) error) (*models.PlanVersion, error) {
+    t, ok := o.GetTask(taskID)
+    if !ok { return nil, ErrTaskNotFound }
+    o.ctlMu.Lock()
+    _, active := o.controls[taskID]
+    o.ctlMu.Unlock()
+    if active { return nil, ErrTaskRunning }
+    v, err := o.editPlan(t, author, action, edit)
+    if err == nil { o.persist(t) }
+    return v, err
+}
+
+func (o *Orchestrator) editPlan(t *models.Task, author, action string, edit func(p *models.Plan) error) (*models.PlanVersion, error) {
+    taskID := t.ID
+    o.plansMu.Lock()
+    defer o.plansMu.Unlock()
+    switch {
+    case t.Status == models.StatusQueued:
+        return nil, ErrAlreadyQueued
+    case t.Status == models.StatusPaused:
+        return nil, ErrTaskPaused
+    case isActive(t.Status):
+        return nil, ErrTaskRunning
+    }
+    next := t.Plan.Clone()
+    if next == nil { next = &models.Plan{} }
+    if err := edit(next); err != nil { return nil, err }
+    for _, s := range next.Steps {
+        if s != nil && s.Status == "" { s.Status = models.StatusPending }
+    }
+    var hasTool func(string) bool
+    if o.Tools != nil { hasTool = func(name string) bool { _, ok := o.Tools.Get(name); return ok } }
+    if err := ValidatePlan(next, hasTool); err != nil { return nil, err }
+    v := o.recordPlanLocked(t, next, author, action)
+    t.Status = models.StatusPlanned
+    o.hub.Publish(taskID, Event{Event: "task_status", TaskID: taskID, Payload: map[string]any{"status": t.Status}})
+    return v, nil
+}
+
+// recordPlan makes plan the task's current plan and appends a version for it.
+func (o *Orchestrator) recordPlan(t *models.Task, plan *models.Plan, author, action string) *models.PlanVersion {
+    o.plansMu.Lock()
+    v := o.recordPlanLocked(t, plan, author, action)
+    o.plansMu.Unlock()
+    o.persist(t)
+    return v
+}
+
+func (o *Orchestrator) recordPlanLocked(t *models.Task, plan *models.Plan, author, action string) *models.PlanVersion {
+    versions := o.versions[t.ID]
+    v := &models.PlanVersion{Version: len(versions) + 1, Author: author, Action: action, Diff: DiffPlans(t.Plan, plan), Plan: plan.Clone(), CreatedAt: time.Now()}
+    o.versions[t.ID] = append(versions, v)
+    t.Plan = plan
+    t.PlanVersion = v.Version
+    t.UpdatedAt = v.CreatedAt
+    o.hub.Publish(t.ID, Event{Event: "plan", TaskID: t.ID, Payload: plan})
+    o.hub.Publish(t.ID, Event{Event: "plan_version", TaskID: t.ID, Payload: map[string]any{"version": v.Version, "author": author, "action": action, "diff": v.Diff}})
+    return v
+}
+
+// DiffPlans lists the step-level differences between two plans. Runtime status is
+// ignored.
+func DiffPlans(before, after *models.Plan) []models.PlanChange {
+    changes := []models.PlanChange{}
+    index := func(p *models.Plan) (map[string]*models.Step, map[string]int) {
+        byID, pos := map[string]*models.Step{}, map[string]int{}
+        if p == nil { return byID, pos }
+        for i, s := range p.Steps {
+            if s == nil { continue }
+            byID[s.ID], pos[s.ID] = s, i
+        }
+        return byID, pos
+    }
+    oldSteps, oldPos := index(before)
+    newSteps, newPos := index(after)
+    if before != nil {
+        for _, s := range before.Steps {
+            if s != nil && newSteps[s.ID] == nil { changes = append(changes, models.PlanChange{Op: "remove", StepID: s.ID, Before: s}) }
+        }
+    }
+    if after == nil { return changes }
+    // steps both plans share that keep their relative order (the longest common
+    // subsequence of the two orders) stay put; only the others are reported as moved
+    var common []string
+    for _, s := range after.Steps {
+        if s != nil && oldSteps[s.ID] != nil { common = append(common, s.ID) }
+    }
+    oldOrder := append([]string(nil), common...)
+    sort.Slice(oldOrder, func(i, j int) bool { return oldPos[oldOrder[i]] < oldPos[oldOrder[j]] })
+    stays := lcs(oldOrder, common)
+    for _, s := range after.Steps {
+        if s == nil { continue }
+        old := oldSteps[s.ID]
+        if old == nil {
+            changes = append(changes, models.PlanChange{Op: "add", StepID: s.ID, After: s})
+            continue
+        }
+        if !stays[s.ID] { changes = append(changes, models.PlanChange{Op: "move", StepID: s.ID, Before: oldPos[s.ID], After: newPos[s.ID]}) }
+        for _, f := range stepFields(old, s) { changes = append(changes, f) }
+    }
+    return changes
+}
+
+// lcs returns the elements of a longest common subsequence of a and b.
+func lcs(a, b []string) map[string]bool {
+    // n[i][j] is the LCS length of a[i:] and b[j:]
+    n := make([][]int, len(a)+1)
+    for i := range n { n[i] = make([]int, len(b)+1) }
+    for i := len(a) - 1; i >= 0; i-- {
+        for j := len(b) - 1; j >= 0; j-- {
+            if a[i] == b[j] {
+                n[i][j] = n[i+1][j+1] + 1
+            } else {
+                n[i][j] = max(n[i+1][j], n[i][j+1])
+            }
+        }
+    }
+    out := map[string]bool{}
+    for i, j := 0, 0; i < len(a) && j < len(b); {
+        switch {
+        case a[i] == b[j]:
+            out[a[i]] = true
+            i, j = i+1, j+1
+        case n[i+1][j] >= n[i][j+1]:
+            i++
+        default:
+            j++
+        }
+    }
+    return out
+}
+
+// stepFields compares the editable fields of two versions of a step.
+func stepFields(a, b *models.Step) []models.PlanChange {
+    var out []models.PlanChange
+    cmp := func(field string, x, y any) {
+        if !sameJSON(x, y) { out = append(out, models.PlanChange{Op: "change", StepID: b.ID, Field: field, Before: x, After: y}) }
+    }
+    cmp("description", a.Description, b.Description)
+    cmp("tool", a.Tool, b.Tool)
+    cmp("deps", a.Deps, b.Deps)
+    // inputs are compared per key so a fixed URL shows up as exactly that
+    var keys []string
+    for k := range a.Inputs { keys = append(keys, k) }
+    for k := range b.Inputs {
+        if _, ok := a.Inputs[k]; !ok { keys = append(keys, k) }
+    }
+    sort.Strings(keys)
+    for _, k := range keys { cmp("inputs."+k, a.Inputs[k], b.Inputs[k]) }
+    cmp("if", a.Condition, b.Condition)
+    cmp("map", a.Map, b.Map)
+    cmp("timeout", a.Timeout, b.Timeout)
+    return out
+}
+
+func sameJSON(x, y any) bool {
+    bx, _ := json.Marshal(x)
+    by, _ := json.Marshal(y)
+    if string(bx) == string(by) { return true }
+    // nil and empty values are the same to a reader
+    empty := func(b []byte) bool { s := string(b); return s == "null" || s == `""` || s == "[]" || s == "{}" }
+    if empty(bx) && empty(by) { return true }
+    return reflect.DeepEqual(x, y)
+}
+
+// PatchStep applies a JSON merge patch (RFC 7396) to one step: fields present in patch
+// replace the step's, objects such as inputs merge key by key, and null removes a key.
+func PatchStep(step *models.Step, patch map[string]any) (*models.Step, error) {
+    if _, ok := patch["id"]; ok { return nil, fmt.Errorf("step id cannot be changed") }
+    b, err := json.Marshal(step)
+    if err != nil { return nil, err }
+    var doc map[string]any
+    if err := json.Unmarshal(b, &doc); err != nil { return nil, err }
+    merged := mergePatch(doc, patch)
+    b, _ = json.Marshal(merged)
+    var out models.Step
+    if err := json.Unmarshal(b, &out); err != nil { return nil, fmt.Errorf("invalid step: %v", err) }
+    return &out, nil
+}
+
+func mergePatch(target any, patch any) any {
+    p, ok := patch.(map[string]any)
+    if !ok { return patch }
+    t, ok := target.(map[string]any)
+    if !ok { t = map[string]any{} }
+    for k, v := range p {
+        if v == nil {
+            delete(t, k)
+            continue
+        }
+        t[k] = mergePatch(t[k], v)
+    }
+    return t
+}
+
+// StepIndex returns the position of a top-level step, or -1.
+func StepIndex(p *models.Plan, id string) int {
+    if p == nil { return -1 }
+    for i, s := range p.Steps {
+        if s != nil && s.ID == id { return i }
+    }
+    return -1
+}
+
+// hasStep reports whether stepID names a step of t's plan; map sub-steps
+// ("STEP[i].SUB") count as their map step.
+func hasStep(t *models.Task, stepID string) bool {
+    id, _, _ := strings.Cut(stepID, "[")
+    return StepIndex(t.Plan, id) != -1
+}
@@ -0,0 +1,155 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func planOf(ids ...string) *models.Plan {
+    p := &models.Plan{}
+    for _, id := range ids { p.Steps = append(p.Steps, &models.Step{ID: id, Tool: "echo"}) }
+    return p
+}
+
+// describe renders changes as "op:step" (plus the field of a change).
+func describe(changes []models.PlanChange) string {
+    var out []string
+    for _, c := range changes {
+        s := c.Op + ":" + c.StepID
+        if c.Field != "" { s += "." + c.Field }
+        out = append(out, s)
+    }
+    return strings.Join(out, " ")
+}
+
+func TestDiffPlansMoves(t *testing.T) {
+    for _, c := range []struct {
+        before, after []string
+        want          string
+    }{
+        {[]string{"a", "b", "c"}, []string{"b", "c", "a"}, "move:a"},
+        {[]string{"a", "b", "c"}, []string{"c", "a", "b"}, "move:c"},
+        // a swap could be either step moving; the result is deterministic
+        {[]string{"a", "b", "c", "d"}, []string{"a", "c", "b", "d"}, "move:b"},
+        {[]string{"a", "b", "c"}, []string{"c", "b", "a"}, "move:b move:a"},
+        // inserting or removing steps shifts indexes without moving anything
+        {[]string{"a", "b", "c"}, []string{"x", "a", "c"}, "remove:b add:x"},
+        {[]string{"a", "b"}, []string{"a", "b"}, ""},
+    } {
+        if got := describe(DiffPlans(planOf(c.before...), planOf(c.after...))); got != c.want { t.Errorf("%v -> %v: %q, want %q", c.before, c.after, got, c.want) }
+    }
+    if got := describe(DiffPlans(nil, planOf("a"))); got != "add:a" { t.Errorf("from nil: %q", got) }
+}
+
+func TestDiffPlansFields(t *testing.T) {
+    before := planOf("a", "b")
+    before.Steps[1].Inputs = map[string]any{"url": "http://old", "keep": 1}
+    after := before.Clone()
+    after.Steps[1].Inputs["url"] = "http://new"
+    after.Steps[1].Timeout = "5s"
+    after.Steps[0].Status = models.StatusSuccess // runtime state is not a change
+    got := DiffPlans(before, after)
+    if describe(got) != "change:b.inputs.url change:b.timeout" { t.Fatalf("diff %q", describe(got)) }
+    if got[0].Before != "http://old" || got[0].After != "http://new" { t.Fatalf("change %+v", got[0]) }
+}
+
+func TestValidatePlan(t *testing.T) {
+    hasTool := func(name string) bool { return name == "echo" || name == "map" }
+    mapStep := func(id string, sub ...*models.Step) *models.Step {
+        return &models.Step{ID: id, Tool: "map", Map: &models.MapSpec{Over: "{{step:list.output}}", Steps: sub}}
+    }
+    for _, c := range []struct {
+        name  string
+        steps []*models.Step
+        want  string
+    }{
+        {"ok", []*models.Step{{ID: "list", Tool: "echo"}, mapStep("each", &models.Step{ID: "get", Tool: "echo", Inputs: map[string]any{"u": "{{step:list.output}}"}})}, ""},
+        {"duplicate", []*models.Step{{ID: "a", Tool: "echo"}, {ID: "a", Tool: "echo"}}, `duplicate step id "a"`},
+        {"unknown tool", []*models.Step{{ID: "a", Tool: "nope"}}, `step a: unknown tool "nope"`},
+        {"forward dep", []*models.Step{{ID: "a", Tool: "echo", Deps: []string{"b"}}, {ID: "b", Tool: "echo"}}, `step a: dependency "b" is not an earlier step`},
+        {"forward ref", []*models.Step{{ID: "a", Tool: "echo", Inputs: map[string]any{"x": "{{step:b.output}}"}}, {ID: "b", Tool: "echo"}}, `step a references "b", which is not an earlier step`},
+        {"bad timeout", []*models.Step{{ID: "a", Tool: "echo", Timeout: "soon"}}, `step a: invalid timeout "soon"`},
+        // sub-step IDs may not repeat top-level ones, earlier or later
+        {"sub-step shadows earlier", []*models.Step{{ID: "list", Tool: "echo"}, mapStep("each", &models.Step{ID: "list", Tool: "echo"})}, `each.step id "list" is already used outside this map`},
+        {"sub-step shadows later", []*models.Step{{ID: "list", Tool: "echo"}, mapStep("each", &models.Step{ID: "after", Tool: "echo"}), {ID: "after", Tool: "echo"}}, `each.step id "after" is already used outside this map`},
+        {"sub-step shadows map", []*models.Step{{ID: "list", Tool: "echo"}, mapStep("each", &models.Step{ID: "each", Tool: "echo"})}, `each.step id "each" is already used outside this map`},
+        {"nested map", []*models.Step{{ID: "list", Tool: "echo"}, mapStep("outer", &models.Step{ID: "inner", Tool: "map", Map: &models.MapSpec{Over: "{{step:list.output}}", Steps: []*models.Step{{ID: "list", Tool: "echo"}}}})}, `outer.inner.step id "list" is already used outside this map`},
+    } {
+        err := ValidatePlan(&models.Plan{Steps: c.steps}, hasTool)
+        var pe *PlanError
+        switch {
+        case c.want == "" && err != nil:
+            t.Errorf("%s: %v", c.name, err)
+        case c.want != "" && (!errors.As(err, &pe) || !contains(pe.Problems, c.want)):
+            t.Errorf("%s: %v, want %q", c.name, err, c.want)
+        }
+    }
+    if err := ValidatePlan(&models.Plan{}, nil); err == nil { t.Error("empty plan accepted") }
+}
+
+func contains(list []string, s string) bool {
+    for _, x := range list {
+        if x == s { return true }
+    }
+    return false
+}
+
+func TestPatchStep(t *testing.T) {
+    step := &models.Step{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "hi", "drop": 1}}
+    got, err := PatchStep(step, map[string]any{"inputs": map[string]any{"text": "bye", "drop": nil}, "timeout": "1s"})
+    if err != nil { t.Fatal(err) }
+    if fmt.Sprint(got.Inputs) != "map[text:bye]" || got.Timeout != "1s" || got.Tool != "echo" { t.Fatalf("patched %+v", got) }
+    if _, err := PatchStep(step, map[string]any{"id": "b"}); err == nil { t.Fatal("id change accepted") }
+}
+
+func TestEditPlanVersions(t *testing.T) {
+    o, _ := newTestOrchestrator(echoExec)
+    o.CreateTask("t", "q", nil)
+    v1, err := o.EditPlan("t", "alice", "replace_plan", func(p *models.Plan) error { p.Steps = planOf("a", "b").Steps; return nil })
+    if err != nil { t.Fatal(err) }
+    v2, err := o.EditPlan("t", "bob", "move", func(p *models.Plan) error { p.Steps[0], p.Steps[1] = p.Steps[1], p.Steps[0]; return nil })
+    if err != nil { t.Fatal(err) }
+    if v1.Version != 1 || v2.Version != 2 || v2.Author != "bob" || describe(v2.Diff) != "move:a" { t.Fatalf("versions %+v %+v", v1, v2) }
+    task, _ := o.GetTask("t")
+    if task.Status != models.StatusPlanned || task.PlanVersion != 2 || task.Plan.Steps[0].ID != "b" { t.Fatalf("task %s v%d", task.Status, task.PlanVersion) }
+
+    // an invalid edit changes nothing
+    if _, err := o.EditPlan("t", "eve", "replace_plan", func(p *models.Plan) error { p.Steps = planOf("a", "a").Steps; return nil }); err == nil { t.Fatal("invalid plan accepted") }
+    if len(o.PlanVersions("t")) != 2 || task.Plan.Steps[0].ID != "b" { t.Fatal("rejected edit was recorded") }
+
+    task.Status = models.StatusRunning
+    if _, err := o.EditPlan("t", "eve", "x", func(*models.Plan) error { return nil }); !errors.Is(err, ErrTaskRunning) { t.Fatalf("edit while running: %v", err) }
+    if _, err := o.EditPlan("nope", "eve", "x", func(*models.Plan) error { return nil }); !errors.Is(err, ErrTaskNotFound) { t.Fatalf("unknown task: %v", err) }
+}
+
+func TestEditPlanKeepsQueuedAndPausedTasks(t *testing.T) {
+    keep := func(p *models.Plan) error { return nil }
+    // no workers run, so the job stays queued
+    o, _ := newTestOrchestrator(echoExec)
+    task := o.CreateTask("t", "q", nil)
+    if _, err := o.EditPlan("t", "alice", "replace_plan", func(p *models.Plan) error { p.Steps = planOf("a").Steps; return nil }); err != nil { t.Fatal(err) }
+    if _, err := o.Enqueue("t", JobExecute); err != nil { t.Fatal(err) }
+    if _, err := o.EditPlan("t", "bob", "x", keep); !errors.Is(err, ErrAlreadyQueued) { t.Fatalf("edit while queued: %v", err) }
+    if task.Status != models.StatusQueued { t.Fatalf("status %s", task.Status) }
+    if err := o.DeleteTask("t"); err == nil { t.Fatal("queued task deleted") }
+
+    // a paused run resumes with the plan it has
+    started, release := make(chan struct{}), make(chan struct{})
+    o, _ = newTestOrchestrator(gatedExec(started, release), twoSteps()...)
+    task = o.CreateTask("t", "q", nil)
+    o.PlanOnly(context.Background(), "t")
+    done := make(chan struct{})
+    go func() { defer close(done); o.ExecutePlan(context.Background(), "t") }()
+    <-started
+    if err := o.Pause("t"); err != nil { t.Fatal(err) }
+    close(release)
+    <-done
+    if _, err := o.EditPlan("t", "bob", "x", keep); !errors.Is(err, ErrTaskPaused) { t.Fatalf("edit while paused: %v", err) }
+    if task.Status != models.StatusPaused || len(o.PlanVersions("t")) != 1 { t.Fatalf("status %s, %d versions", task.Status, len(o.PlanVersions("t"))) }
+    if err := o.Resume(context.Background(), "t"); err != nil || task.Status != models.StatusSuccess { t.Fatalf("resume: %v, %s", err, task.Status) }
+}
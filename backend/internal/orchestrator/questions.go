@@ -0,0 +1,107 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// ErrInvalidAnswer is returned for an empty answer or one that is not among the
+// question's choices.
+var ErrInvalidAnswer = errors.New("invalid answer")
+
+type answer struct {
+    text string
+    by   string
+}
+
+// askUser is the tools.AskFunc behind ask_user steps: it records the question on the
+// current run, pauses the task in AWAITING_INPUT and waits for AnswerQuestion.
+// stepID is the stream ID, so sub-steps of a map are asked as "STEP[i].SUB".
+func (o *Orchestrator) askUser(ctx context.Context, t *models.Task, stepID, text string, choices []string) (string, error) {
+    run := t.LastRun
+    if run == nil { return "", fmt.Errorf("no active run") }
+    q := &models.Question{StepID: stepID, Text: text, Choices: choices, AskedAt: time.Now()}
+    key := waitKey(t.ID, stepID)
+    ch := o.park(key)
+    o.waitMu.Lock()
+    run.Questions = append(run.Questions, q)
+    o.waitMu.Unlock()
+    o.setInputStatus(t, run, stepID, models.StatusAwaitingInput)
+    payload := map[string]any{"run_id": run.ID, "step_id": stepID, "question": text}
+    if len(choices) > 0 { payload["choices"] = choices }
+    o.hub.Publish(t.ID, Event{Event: "question", TaskID: t.ID, Payload: payload})
+    o.persist(t)
+
+    v, err := o.await(ctx, key, ch, 0)
+    if err != nil {
+        o.setInputStatus(t, run, stepID, models.StatusRunning)
+        return "", fmt.Errorf("no answer: %v", err)
+    }
+    a := v.(answer)
+    o.hub.Publish(t.ID, Event{Event: "question_answered", TaskID: t.ID, Payload: map[string]any{"run_id": run.ID, "step_id": stepID, "answer": a.text, "by": a.by}})
+    o.setInputStatus(t, run, stepID, models.StatusRunning)
+    return a.text, nil
+}
+
+// setInputStatus moves the step (the enclosing map step for sub-steps) and the task
+// in or out of AWAITING_INPUT. They stay there while any question is unanswered.
+func (o *Orchestrator) setInputStatus(t *models.Task, run *models.Run, stepID string, status models.Status) {
+    o.waitMu.Lock()
+    if status == models.StatusRunning {
+        for _, q := range run.Questions {
+            if q.AnsweredAt == nil && q.StepID != stepID { status = models.StatusAwaitingInput }
+        }
+    }
+    o.waitMu.Unlock()
+    base, _, _ := strings.Cut(stepID, "[")
+    if sr := run.Step(base); sr != nil && sr.Status != status {
+        sr.Status = status
+        o.hub.Publish(t.ID, Event{Event: "step_status", TaskID: t.ID, Payload: sr})
+    }
+    if t.Status != status {
+        t.Status = status
+        t.UpdatedAt = time.Now()
+        o.hub.Publish(t.ID, Event{Event: "task_status", TaskID: t.ID, Payload: map[string]any{"status": t.Status, "run_id": run.ID}})
+    }
+}
+
+// AnswerQuestion answers the pending question of an ask_user step and resumes it.
+// When the question has choices the answer must be one of them.
+func (o *Orchestrator) AnswerQuestion(taskID, stepID, text, by string) error {
+    t, ok := o.GetTask(taskID)
+    if !ok { return ErrTaskNotFound }
+    if !hasStep(t, stepID) { return fmt.Errorf("%q: %w", stepID, ErrStepNotFound) }
+    if t.LastRun == nil { return ErrNotAwaiting }
+    o.waitMu.Lock()
+    var q *models.Question
+    for _, c := range t.LastRun.Questions {
+        if c.StepID == stepID && c.AnsweredAt == nil { q = c }
+    }
+    o.waitMu.Unlock()
+    if q == nil { return ErrNotAwaiting }
+    if strings.TrimSpace(text) == "" { return fmt.Errorf("%w: empty answer", ErrInvalidAnswer) }
+    if len(q.Choices) > 0 {
+        found := false
+        for _, c := range q.Choices {
+            if c == text { found = true }
+        }
+        if !found { return fmt.Errorf("%w: want one of %q", ErrInvalidAnswer, q.Choices) }
+    }
+    // record the answer and wake the step in one go, so nobody sees a resumed step
+    // without its answer or an answer for a step that gave up waiting
+    key := waitKey(taskID, stepID)
+    o.waitMu.Lock()
+    defer o.waitMu.Unlock()
+    ch, pending := o.waiting[key]
+    if !pending { return ErrNotAwaiting }
+    delete(o.waiting, key)
+    now := time.Now()
+    q.Answer, q.AnsweredBy, q.AnsweredAt = text, by, &now
+    ch <- answer{text: text, by: by}
+    return nil
+}
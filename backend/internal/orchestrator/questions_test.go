@@ -0,0 +1,119 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/tools"
+)
+
+// askExec runs ask_user steps with the real tool and echoes everything else.
+var askExec = execFunc(func(ctx context.Context, step *models.Step) (any, error) {
+    if step.Tool != "ask_user" { return step.Inputs["text"], nil }
+    out, _, err := (&tools.AskUserTool{}).Execute(ctx, step.Inputs)
+    return out, err
+})
+
+// startAsking runs a task in the background until n questions have been asked. The
+// returned channel is closed when the run ends.
+func startAsking(t *testing.T, o *Orchestrator, id string, n int) (*models.Task, <-chan struct{}) {
+    t.Helper()
+    task := o.CreateTask(id, "q", nil)
+    if _, err := o.PlanOnly(context.Background(), id); err != nil { t.Fatal(err) }
+    events := collect(o, id)
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        o.ExecutePlan(context.Background(), id)
+    }()
+    waitFor(t, "questions", func() bool {
+        asked := 0
+        for _, ev := range events() {
+            if ev.Event == "question" { asked++ }
+        }
+        return asked == n
+    })
+    return task, done
+}
+
+func TestAskUser(t *testing.T) {
+    o, _ := newTestOrchestrator(askExec,
+        &models.Step{ID: "which", Tool: "ask_user", Inputs: map[string]any{"question": "Which file?", "choices": []any{"a.pdf", "b.pdf"}}},
+        &models.Step{ID: "read", Tool: "echo", Inputs: map[string]any{"text": "reading {{step:which.output}}"}},
+    )
+    store := newMemStore()
+    o.Store = store
+    events := collect(o, "t")
+    task, done := startAsking(t, o, "t", 1)
+    if task.Status != models.StatusAwaitingInput || task.LastRun.Step("which").Status != models.StatusAwaitingInput { t.Fatalf("status %s", task.Status) }
+    // the pending question is persisted, so it survives a restart
+    store.mu.Lock()
+    saved := string(store.saved["t"])
+    store.mu.Unlock()
+    if !strings.Contains(saved, `"question":"Which file?"`) { t.Fatalf("saved %s", saved) }
+
+    for _, c := range []struct {
+        step, answer string
+        want         error
+    }{
+        {"which", "c.pdf", ErrInvalidAnswer},
+        {"which", "  ", ErrInvalidAnswer},
+        {"nope", "a.pdf", ErrStepNotFound},
+        {"read", "a.pdf", ErrNotAwaiting},
+    } {
+        if err := o.AnswerQuestion("t", c.step, c.answer, "alice"); !errors.Is(err, c.want) { t.Errorf("%s %q: %v, want %v", c.step, c.answer, err, c.want) }
+    }
+    if err := o.AnswerQuestion("nope", "which", "a.pdf", "alice"); !errors.Is(err, ErrTaskNotFound) { t.Fatalf("unknown task: %v", err) }
+    if err := o.AnswerQuestion("t", "which", "b.pdf", "alice"); err != nil { t.Fatal(err) }
+    <-done
+    if task.Status != models.StatusSuccess { t.Fatalf("status %s: %q", task.Status, task.LastRun.Error) }
+    if out := task.Results[1].Output; out != "reading b.pdf" { t.Fatalf("output %v", out) }
+    q := task.LastRun.Questions[0]
+    if q.Answer != "b.pdf" || q.AnsweredBy != "alice" || q.AnsweredAt == nil { t.Fatalf("question %+v", q) }
+    if err := o.AnswerQuestion("t", "which", "a.pdf", "alice"); !errors.Is(err, ErrNotAwaiting) { t.Fatalf("second answer: %v", err) }
+
+    var asked, answered map[string]any
+    for _, ev := range events() {
+        switch ev.Event {
+        case "question":
+            asked = ev.Payload.(map[string]any)
+        case "question_answered":
+            answered = ev.Payload.(map[string]any)
+        }
+    }
+    if asked["question"] != "Which file?" || len(asked["choices"].([]any)) != 2 { t.Fatalf("question event %v", asked) }
+    if answered["answer"] != "b.pdf" || answered["by"] != "alice" { t.Fatalf("answer event %v", answered) }
+}
+
+// TestAskUserInMap asks one question per item; the task waits until all are answered.
+func TestAskUserInMap(t *testing.T) {
+    o, _ := newTestOrchestrator(askExec,
+        &models.Step{ID: "list", Tool: "echo", Inputs: map[string]any{"text": []any{"x", "y"}}},
+        &models.Step{ID: "each", Tool: "map", Deps: []string{"list"}, Map: &models.MapSpec{
+            Over: "{{step:list.output}}", As: "item", Parallelism: 2,
+            Steps: []*models.Step{{ID: "ok", Tool: "ask_user", Inputs: map[string]any{"question": "keep {{item}}?"}}},
+        }},
+    )
+    task, done := startAsking(t, o, "m", 2)
+    if err := o.AnswerQuestion("m", "each[0].ok", "yes", "bob"); err != nil { t.Fatal(err) }
+    waitFor(t, "first answer", func() bool {
+        o.waitMu.Lock()
+        defer o.waitMu.Unlock()
+        return task.LastRun.Questions[0].AnsweredAt != nil || task.LastRun.Questions[1].AnsweredAt != nil
+    })
+    if err := o.AnswerQuestion("m", "each[1].ok", "no", "bob"); err != nil { t.Fatal(err) }
+    <-done
+    if task.Status != models.StatusSuccess { t.Fatalf("status %s: %q", task.Status, task.LastRun.Error) }
+}
+
+func TestAskUserCancelled(t *testing.T) {
+    o, _ := newTestOrchestrator(askExec, &models.Step{ID: "which", Tool: "ask_user", Inputs: map[string]any{"question": "Which?"}})
+    task, done := startAsking(t, o, "t", 1)
+    if err := o.Cancel("t"); err != nil { t.Fatal(err) }
+    <-done
+    if task.Status != models.StatusCancelled { t.Fatalf("status %s", task.Status) }
+    if err := o.AnswerQuestion("t", "which", "late", "x"); !errors.Is(err, ErrNotAwaiting) { t.Fatalf("answer after cancel: %v", err) }
+}
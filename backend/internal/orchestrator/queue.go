@@ -0,0 +1,293 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "log"
+    "sync"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+var ErrAlreadyQueued = errors.New("task is already queued")
+
+// Job kinds run by queue workers.
+const (
+    JobStart   = "start"
+    JobExecute = "execute"
+    JobResume  = "resume"
+)
+
+// QueuedJob is a task waiting for a worker.
+type QueuedJob struct {
+    TaskID     string    `json:"task_id"`
+    Kind       string    `json:"kind"`
+    Priority   int       `json:"priority"`
+    ClientID   string    `json:"client_id"`
+    Position   int       `json:"position"`
+    EnqueuedAt time.Time `json:"enqueued_at"`
+
+    seq  uint64
+    prev models.Status // status to restore before the job runs or when it is dropped
+}
+
+// QueueStats describes the queue for GET /queue.
+type QueueStats struct {
+    Workers      int          `json:"workers"`
+    Running      int          `json:"running"`
+    Depth        int          `json:"depth"`
+    MaxPerClient int          `json:"max_per_client,omitempty"`
+    Jobs         []*QueuedJob `json:"jobs"`
+}
+
+// taskQueue holds jobs until a worker is free. Higher priority goes first; within a
+// priority, the client served least recently goes first, so one client's burst does
+// not starve the others; a client's own jobs keep their order.
+type taskQueue struct {
+    mu           sync.Mutex
+    cond         *sync.Cond
+    jobs         []*QueuedJob
+    seq          uint64
+    tick         uint64
+    served       map[string]uint64 // client -> tick of its last dequeued job
+    runningBy    map[string]int
+    running      int
+    workers      int
+    maxPerClient int
+    // gen counts StopWorkers calls; a worker exits once it no longer matches
+    gen          uint64
+    alive        int
+}
+
+func newTaskQueue() *taskQueue {
+    q := &taskQueue{served: map[string]uint64{}, runningBy: map[string]int{}}
+    q.cond = sync.NewCond(&q.mu)
+    return q
+}
+
+// before reports whether a should be dequeued before b.
+func (q *taskQueue) before(a, b *QueuedJob, served map[string]uint64) bool {
+    if a.Priority != b.Priority { return a.Priority > b.Priority }
+    if a.ClientID != b.ClientID && served[a.ClientID] != served[b.ClientID] { return served[a.ClientID] < served[b.ClientID] }
+    return a.seq < b.seq
+}
+
+// pickLocked returns the index of the next job a worker may take, or -1. Clients
+// at their concurrency limit are passed over.
+func (q *taskQueue) pickLocked() int {
+    best := -1
+    for i, j := range q.jobs {
+        if q.maxPerClient > 0 && q.runningBy[j.ClientID] >= q.maxPerClient { continue }
+        if best == -1 || q.before(j, q.jobs[best], q.served) { best = i }
+    }
+    return best
+}
+
+// orderLocked is the order in which the queued jobs would be dequeued if nothing else
+// arrived, ignoring concurrency limits; positions are an estimate.
+func (q *taskQueue) orderLocked() []*QueuedJob {
+    rest := append([]*QueuedJob(nil), q.jobs...)
+    served := map[string]uint64{}
+    for k, v := range q.served { served[k] = v }
+    tick := q.tick
+    out := make([]*QueuedJob, 0, len(rest))
+    for len(rest) > 0 {
+        best := 0
+        for i := 1; i < len(rest); i++ {
+            if q.before(rest[i], rest[best], served) { best = i }
+        }
+        j := rest[best]
+        rest = append(rest[:best], rest[best+1:]...)
+        tick++
+        served[j.ClientID] = tick
+        out = append(out, j)
+    }
+    return out
+}
+
+// SetWorkers configures the queue; call it before StartWorkers. maxPerClient limits
+// how many of one client's tasks run at once (0 = no limit).
+func (o *Orchestrator) SetWorkers(n, maxPerClient int) {
+    o.queue.mu.Lock()
+    defer o.queue.mu.Unlock()
+    o.queue.workers = n
+    o.queue.maxPerClient = maxPerClient
+}
+
+// StartWorkers starts the configured number of workers (at least one). Queued tasks
+// only run while workers are started.
+func (o *Orchestrator) StartWorkers() {
+    o.queue.mu.Lock()
+    if o.queue.workers < 1 { o.queue.workers = 1 }
+    n, gen := o.queue.workers, o.queue.gen
+    o.queue.alive += n
+    o.queue.mu.Unlock()
+    for i := 0; i < n; i++ { go o.worker(gen) }
+}
+
+// StopWorkers stops the workers: idle ones right away, busy ones once their task's
+// run returns. Queued tasks stay queued until workers are started again.
+func (o *Orchestrator) StopWorkers() {
+    o.queue.mu.Lock()
+    defer o.queue.mu.Unlock()
+    o.queue.gen++
+    o.queue.cond.Broadcast()
+}
+
+// Enqueue queues a start, execute or resume of a task and returns its position (1 is
+// next). The task becomes QUEUED until a worker picks it up.
+func (o *Orchestrator) Enqueue(taskID, kind string) (*QueuedJob, error) {
+    t, ok := o.GetTask(taskID)
+    if !ok { return nil, ErrTaskNotFound }
+    switch kind {
+    case JobStart:
+    case JobExecute:
+        if t.Plan == nil || len(t.Plan.Steps) == 0 { return nil, ErrNoPlan }
+    case JobResume:
+        if t.Status != models.StatusPaused || t.LastRun == nil { return nil, ErrNotPaused }
+    default:
+        return nil, errors.New("unknown job kind " + kind)
+    }
+    o.ctlMu.Lock()
+    _, active := o.controls[taskID]
+    o.ctlMu.Unlock()
+    if active || isActive(t.Status) { return nil, ErrTaskRunning }
+    q := o.queue
+    q.mu.Lock()
+    for _, j := range q.jobs {
+        if j.TaskID == taskID { q.mu.Unlock(); return nil, ErrAlreadyQueued }
+    }
+    q.seq++
+    j := &QueuedJob{TaskID: taskID, Kind: kind, Priority: t.Priority, ClientID: t.ClientID, EnqueuedAt: time.Now(), seq: q.seq, prev: t.Status}
+    q.jobs = append(q.jobs, j)
+    t.Status = models.StatusQueued
+    t.UpdatedAt = j.EnqueuedAt
+    o.publishPositionsLocked()
+    o.hub.Publish(taskID, Event{Event: "task_status", TaskID: taskID, Payload: map[string]any{"status": t.Status, "position": j.Position, "depth": len(q.jobs)}})
+    out := *j
+    q.cond.Signal()
+    q.mu.Unlock()
+    o.persist(t)
+    return &out, nil
+}
+
+// dequeue removes a queued task, restoring the status it had before. It reports
+// whether the task was queued.
+func (o *Orchestrator) dequeue(taskID string) (*QueuedJob, bool) {
+    q := o.queue
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    for i, j := range q.jobs {
+        if j.TaskID != taskID { continue }
+        q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+        o.publishPositionsLocked()
+        return j, true
+    }
+    return nil, false
+}
+
+// publishPositionsLocked sends a queue_position event to every queued task whose
+// position changed.
+func (o *Orchestrator) publishPositionsLocked() {
+    depth := len(o.queue.jobs)
+    for i, j := range o.queue.orderLocked() {
+        if j.Position == i+1 { continue }
+        j.Position = i + 1
+        o.hub.Publish(j.TaskID, Event{Event: "queue_position", TaskID: j.TaskID, Payload: map[string]any{"position": j.Position, "depth": depth}})
+    }
+}
+
+// QueueStats returns the queued jobs in their expected order.
+func (o *Orchestrator) QueueStats() QueueStats {
+    q := o.queue
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    st := QueueStats{Workers: q.workers, Running: q.running, Depth: len(q.jobs), MaxPerClient: q.maxPerClient, Jobs: []*QueuedJob{}}
+    for _, j := range q.orderLocked() {
+        c := *j
+        st.Jobs = append(st.Jobs, &c)
+    }
+    return st
+}
+
+func (o *Orchestrator) worker(gen uint64) {
+    q := o.queue
+    for {
+        q.mu.Lock()
+        i := q.pickLocked()
+        for i == -1 && q.gen == gen {
+            q.cond.Wait()
+            i = q.pickLocked()
+        }
+        if q.gen != gen {
+            q.alive--
+            q.mu.Unlock()
+            return
+        }
+        j := q.jobs[i]
+        q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+        q.tick++
+        q.served[j.ClientID] = q.tick
+        o.publishPositionsLocked()
+        // the task is marked active before it leaves the queue lock, so Enqueue and
+        // DeleteTask never see it neither queued nor active
+        ctx, release, ok := o.claimLocked(j.TaskID)
+        t, found := o.GetTask(j.TaskID)
+        if !ok || !found {
+            if ok { release() }
+            q.mu.Unlock()
+            if found { log.Printf("%s %s: %v", j.Kind, j.TaskID, ErrTaskRunning) }
+            continue
+        }
+        t.Status = j.prev
+        q.running++
+        q.runningBy[j.ClientID]++
+        q.pruneServedLocked()
+        q.mu.Unlock()
+
+        o.runJob(ctx, t, j)
+        release()
+
+        q.mu.Lock()
+        q.running--
+        if q.runningBy[j.ClientID]--; q.runningBy[j.ClientID] == 0 { delete(q.runningBy, j.ClientID) }
+        // a freed client slot may unblock a job another worker passed over
+        q.cond.Broadcast()
+        q.mu.Unlock()
+    }
+}
+
+// pruneServedLocked forgets clients with nothing queued or running that were served
+// before every queued client: like a new client, they would go first anyway.
+func (q *taskQueue) pruneServedLocked() {
+    waiting := map[string]bool{}
+    oldest := q.tick + 1
+    for _, j := range q.jobs {
+        waiting[j.ClientID] = true
+        if t := q.served[j.ClientID]; t < oldest { oldest = t }
+    }
+    for c, t := range q.served {
+        if !waiting[c] && q.runningBy[c] == 0 && t < oldest { delete(q.served, c) }
+    }
+}
+
+// runJob runs a dequeued job; ctx carries the worker's claim on the task.
+func (o *Orchestrator) runJob(ctx context.Context, t *models.Task, j *QueuedJob) {
+    var err error
+    switch j.Kind {
+    case JobStart:
+        err = o.Start(ctx, j.TaskID)
+    case JobExecute:
+        err = o.ExecutePlan(ctx, j.TaskID)
+    case JobResume:
+        err = o.Resume(ctx, j.TaskID)
+    }
+    if err != nil {
+        log.Printf("%s %s: %v", j.Kind, j.TaskID, err)
+        // the run never started; do not leave the task looking queued
+        if t.Status == j.prev {
+            o.hub.Publish(t.ID, Event{Event: "task_status", TaskID: t.ID, Payload: map[string]any{"status": t.Status, "error": err.Error()}})
+        }
+    }
+}
@@ -0,0 +1,171 @@
+package orchestrator
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "sync"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// plannedTask creates a planned task for a client.
+func plannedTask(t *testing.T, o *Orchestrator, id, client string, priority int) *models.Task {
+    t.Helper()
+    task := o.AddTask(&models.Task{ID: id, Query: "q", ClientID: client, Priority: priority})
+    if _, err := o.PlanOnly(context.Background(), id); err != nil { t.Fatal(err) }
+    return task
+}
+
+func queueOrder(o *Orchestrator) string {
+    var ids []string
+    for _, j := range o.QueueStats().Jobs { ids = append(ids, j.TaskID) }
+    return strings.Join(ids, " ")
+}
+
+func TestQueueOrder(t *testing.T) {
+    o, _ := newTestOrchestrator(echoExec, &models.Step{ID: "a", Tool: "echo"})
+    for _, c := range []struct {
+        id, client string
+        priority   int
+    }{{"a1", "a", 0}, {"a2", "a", 0}, {"a3", "a", 0}, {"b1", "b", 0}, {"c1", "c", 0}, {"urgent", "a", 5}} {
+        plannedTask(t, o, c.id, c.client, c.priority)
+        if _, err := o.Enqueue(c.id, JobExecute); err != nil { t.Fatal(err) }
+    }
+    // priority first, then clients take turns, each in its own order
+    if got := queueOrder(o); got != "urgent b1 c1 a1 a2 a3" { t.Fatalf("order %q", got) }
+    if _, err := o.Enqueue("a1", JobExecute); !errors.Is(err, ErrAlreadyQueued) { t.Fatalf("second enqueue: %v", err) }
+    if _, err := o.Enqueue("a1", JobResume); !errors.Is(err, ErrNotPaused) { t.Fatalf("resume: %v", err) }
+    if _, err := o.Enqueue("nope", JobStart); !errors.Is(err, ErrTaskNotFound) { t.Fatalf("unknown task: %v", err) }
+
+    // cancelling a queued task puts it back as it was
+    if err := o.Cancel("b1"); err != nil { t.Fatal(err) }
+    if got := queueOrder(o); got != "urgent c1 a1 a2 a3" { t.Fatalf("order after cancel %q", got) }
+}
+
+func TestQueueLimitsClients(t *testing.T) {
+    var mu sync.Mutex
+    running, peak := map[string]int{}, 0
+    release := make(chan struct{})
+    exec := execFunc(func(ctx context.Context, step *models.Step) (any, error) {
+        client := step.Inputs["text"].(string)
+        mu.Lock()
+        running[client]++
+        if running[client] > peak { peak = running[client] }
+        mu.Unlock()
+        <-release
+        mu.Lock()
+        running[client]--
+        mu.Unlock()
+        return client, nil
+    })
+    o, _ := newTestOrchestrator(exec)
+    o.Planner = planFunc(func(task *models.Task) *models.Plan {
+        return &models.Plan{Steps: []*models.Step{{ID: "a", Tool: "echo", Inputs: map[string]any{"text": task.ClientID}}}}
+    })
+    o.SetWorkers(3, 1)
+    events := collect(o, AllTasks)
+    for i := 0; i < 4; i++ {
+        plannedTask(t, o, fmt.Sprintf("t%d", i), []string{"a", "b"}[i%2], 0)
+        o.Enqueue(fmt.Sprintf("t%d", i), JobExecute)
+    }
+    o.StartWorkers()
+    waitFor(t, "two running", func() bool { return o.QueueStats().Running == 2 })
+    close(release)
+    waitFor(t, "all done", func() bool {
+        n := 0
+        for _, ev := range events() {
+            if ev.Event == "task_status" && ev.Payload.(map[string]any)["status"] == string(models.StatusSuccess) { n++ }
+        }
+        return n == 4
+    })
+    mu.Lock()
+    defer mu.Unlock()
+    if peak != 1 { t.Fatalf("a client ran %d tasks at once", peak) }
+}
+
+// TestQueueClaim covers the hand-off from the queue to a run: a dequeued task is
+// active before its run starts, so nobody can queue, start or delete it meanwhile.
+func TestQueueClaim(t *testing.T) {
+    o, _ := newTestOrchestrator(echoExec, &models.Step{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "x"}})
+    task := plannedTask(t, o, "t", "", 0)
+    o.queue.mu.Lock()
+    ctx, release, ok := o.claimLocked("t")
+    o.queue.mu.Unlock()
+    if !ok { t.Fatal("claim failed") }
+    defer release()
+    if _, err := o.Enqueue("t", JobExecute); !errors.Is(err, ErrTaskRunning) { t.Fatalf("enqueue while claimed: %v", err) }
+    if err := o.DeleteTask("t"); !errors.Is(err, ErrTaskRunning) { t.Fatalf("delete while claimed: %v", err) }
+    if err := o.ExecutePlan(context.Background(), "t"); !errors.Is(err, ErrTaskRunning) { t.Fatalf("direct run while claimed: %v", err) }
+    o.queue.mu.Lock()
+    _, _, again := o.claimLocked("t")
+    o.queue.mu.Unlock()
+    if again { t.Fatal("claimed twice") }
+    // the worker's own run goes ahead
+    if err := o.ExecutePlan(ctx, "t"); err != nil || task.Status != models.StatusSuccess { t.Fatalf("claimed run: %v, %s", err, task.Status) }
+}
+
+func TestQueueClaimCancelled(t *testing.T) {
+    o, _ := newTestOrchestrator(echoExec, &models.Step{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "x"}})
+    task := plannedTask(t, o, "t", "", 0)
+    o.queue.mu.Lock()
+    ctx, release, _ := o.claimLocked("t")
+    o.queue.mu.Unlock()
+    defer release()
+    // cancelled between leaving the queue and starting: the run ends right away
+    if err := o.Cancel("t"); err != nil { t.Fatal(err) }
+    o.ExecutePlan(ctx, "t")
+    if task.Status != models.StatusCancelled { t.Fatalf("status %s", task.Status) }
+}
+
+func TestQueueForgetsIdleClients(t *testing.T) {
+    o, _ := newTestOrchestrator(echoExec, &models.Step{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "x"}})
+    o.SetWorkers(2, 0)
+    o.StartWorkers()
+    events := collect(o, AllTasks)
+    for i := 0; i < 20; i++ {
+        id := fmt.Sprintf("t%d", i)
+        plannedTask(t, o, id, "client-"+id, 0)
+        if _, err := o.Enqueue(id, JobExecute); err != nil { t.Fatal(err) }
+    }
+    waitFor(t, "all done", func() bool {
+        n := 0
+        for _, ev := range events() {
+            if ev.Event == "task_status" && ev.Payload.(map[string]any)["status"] == string(models.StatusSuccess) { n++ }
+        }
+        return n == 20
+    })
+    waitFor(t, "workers idle", func() bool { return o.QueueStats().Running == 0 })
+    q := o.queue
+    q.mu.Lock()
+    defer q.mu.Unlock()
+    if len(q.served) > 3 || len(q.runningBy) != 0 { t.Fatalf("queue remembers %d clients served, %d running", len(q.served), len(q.runningBy)) }
+}
+
+func TestStopWorkers(t *testing.T) {
+    started, release := make(chan string, 1), make(chan struct{})
+    exec := execFunc(func(ctx context.Context, step *models.Step) (any, error) { started <- step.ID; <-release; return nil, nil })
+    o, _ := newTestOrchestrator(exec, &models.Step{ID: "a", Tool: "echo"})
+    alive := func() int { o.queue.mu.Lock(); defer o.queue.mu.Unlock(); return o.queue.alive }
+    o.SetWorkers(2, 0)
+    o.StartWorkers()
+    plannedTask(t, o, "busy", "", 0)
+    o.Enqueue("busy", JobExecute)
+    <-started
+    // the idle worker leaves right away, the busy one after its run
+    o.StopWorkers()
+    waitFor(t, "idle worker gone", func() bool { return alive() == 1 })
+    plannedTask(t, o, "later", "", 0)
+    o.Enqueue("later", JobExecute)
+    close(release)
+    waitFor(t, "busy worker gone", func() bool { return alive() == 0 })
+    if got := queueOrder(o); got != "later" { t.Fatalf("queue %q", got) }
+
+    // started again, the workers pick up what was left
+    o.StartWorkers()
+    if id := <-started; id != "a" { t.Fatalf("ran %s", id) }
+    waitFor(t, "queue drained", func() bool { return o.QueueStats().Depth == 0 })
+    o.StopWorkers()
+}
@@ -0,0 +1,36 @@
+package orchestrator
+
+import (
+    "context"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func TestRunsKeepTheirOwnState(t *testing.T) {
+    o, v := newTestOrchestrator(echoExec,
+        &models.Step{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "{{task.query}}"}},
+        &models.Step{ID: "b", Tool: "echo", Inputs: map[string]any{"text": "got {{step:a.output}}"}},
+    )
+    v.reject["b"] = true
+    task := runTask(t, o, "t1")
+    // the plan keeps its templates; the run records what the tool was called with
+    if in := task.Plan.Steps[1].Inputs["text"]; in != "got {{step:a.output}}" { t.Fatalf("plan input changed to %v", in) }
+    first := task.LastRun
+    if first.Status != models.StatusFailed || first.Step("b").Inputs["text"] != "got query t1" { t.Fatalf("run 1: %s %+v", first.Status, first.Step("b")) }
+
+    // a second run starts from scratch, on the plan as edited meanwhile
+    v.reject["b"] = false
+    if _, err := o.EditPlan("t1", "tester", "update_step", func(p *models.Plan) error { p.Steps[0].Inputs["text"] = "edited"; return nil }); err != nil { t.Fatal(err) }
+    if err := o.ExecutePlan(context.Background(), "t1"); err != nil { t.Fatal(err) }
+    runs := o.Runs("t1")
+    if len(runs) != 2 || runs[1] != task.LastRun || runs[1].Number != 2 { t.Fatalf("runs %+v", runs) }
+    second := runs[1]
+    if second.Status != models.StatusSuccess || len(second.Results) != 2 || len(first.Results) != 2 { t.Fatalf("results %d / %d", len(first.Results), len(second.Results)) }
+    if second.Results[1].Output != "got edited" || first.Results[1].Output != "got query t1" { t.Fatalf("outputs %v / %v", first.Results[1].Output, second.Results[1].Output) }
+    if first.Plan.Steps[0].Inputs["text"] != "{{task.query}}" || first.PlanVersion == second.PlanVersion { t.Fatalf("run 1 plan snapshot changed: v%d %v", first.PlanVersion, first.Plan.Steps[0].Inputs) }
+    if len(task.Results) != 2 || task.Results[1] != second.Results[1] { t.Fatal("task results are not the last run's") }
+
+    if r, ok := o.GetRun("t1", first.ID); !ok || r != first { t.Fatal("GetRun did not find run 1") }
+    if _, ok := o.GetRun("t1", "t1-run9"); ok { t.Fatal("found a run that does not exist") }
+}
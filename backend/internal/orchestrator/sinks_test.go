@@ -0,0 +1,118 @@
+package orchestrator
+
+import (
+    "errors"
+    "sync"
+    "testing"
+)
+
+// testSink records deliveries and dead letters. Deliver blocks while gate is open
+// (not closed), like a webhook stuck in retries.
+type testSink struct {
+    gate   chan struct{}
+    accept func(Record) bool
+
+    mu        sync.Mutex
+    delivered []Record
+    dead      []string
+}
+
+func (s *testSink) Name() string { return "test" }
+
+func (s *testSink) Accept(rec Record) bool { return s.accept == nil || s.accept(rec) }
+
+func (s *testSink) Deliver(rec Record) error {
+    if s.gate != nil { <-s.gate }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.delivered = append(s.delivered, rec)
+    return nil
+}
+
+func (s *testSink) DeadLetter(rec Record, cause error) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.dead = append(s.dead, rec.Event+": "+cause.Error())
+    return nil
+}
+
+func (s *testSink) counts() (delivered, dead int) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return len(s.delivered), len(s.dead)
+}
+
+// caughtUp reports whether the hub's firehose subscribers (the sink readers) have read
+// every event.
+func caughtUp(h *Hub) bool {
+    h.mu.Lock()
+    defer h.mu.Unlock()
+    for s := range h.subs[AllTasks] {
+        if s.cursor < h.seq { return false }
+    }
+    return true
+}
+
+func TestSinkFiltersBeforeQueueing(t *testing.T) {
+    h := newTestHub(4)
+    s := &testSink{gate: make(chan struct{}), accept: func(r Record) bool { return r.Event == "done" }}
+    stop := h.AddSink(s)
+    defer stop()
+    // the sink is stuck while far more events than the log holds go by; only the
+    // ones it wants are queued, so none of them is lost
+    for i := 0; i < 3; i++ {
+        for j := 0; j < 10; j++ {
+            h.Publish("noise", Event{Event: "step_log"})
+            h.Publish("noise", Event{Event: "step_log"})
+            waitFor(t, "read", func() bool { return caughtUp(h) })
+        }
+        h.Publish("t", Event{Event: "done"})
+    }
+    close(s.gate)
+    waitFor(t, "delivery", func() bool { n, _ := s.counts(); return n == 3 })
+    if _, dead := s.counts(); dead != 0 { t.Fatalf("dead letters: %v", s.dead) }
+}
+
+func TestSinkQueueFullDeadLetters(t *testing.T) {
+    h := newTestHub(2)
+    s := &testSink{gate: make(chan struct{})}
+    stop := h.AddSink(s)
+    defer stop()
+    for i := 0; i < 6; i++ {
+        h.Publish("t", Event{Event: "x"})
+        waitFor(t, "read", func() bool { return caughtUp(h) })
+    }
+    // at most one event is in Deliver and two are queued; the rest did not fit
+    waitFor(t, "dead letters", func() bool { _, dead := s.counts(); return dead >= 3 })
+    close(s.gate)
+    waitFor(t, "delivery", func() bool { n, dead := s.counts(); return n+dead == 6 })
+    if s.dead[0] != "x: "+errSinkQueueFull.Error() { t.Fatalf("dead letter %q", s.dead[0]) }
+}
+
+func TestSinkRecoversGapFromTaskLogs(t *testing.T) {
+    h := newTestHub(3)
+    for i := 0; i < 4; i++ {
+        h.Publish("a", Event{Event: "x"})
+        h.Publish("b", Event{Event: "x"})
+    }
+    // the firehose keeps 6-8; the task logs still hold 3-5, 1 and 2 are gone
+    s := &testSink{}
+    recs := h.recoverGap(s, Gap{LastEventID: 0, FirstAvailableID: 6})
+    if ids(recs) != "[3 4 5]" { t.Fatalf("recovered %s", ids(recs)) }
+    if len(s.dead) != 1 || s.dead[0] != "events_lost: 2 events evicted before delivery" { t.Fatalf("dead letters %q", s.dead) }
+    s.dead = nil
+    if recs := h.recoverGap(s, Gap{LastEventID: 2, FirstAvailableID: 6}); ids(recs) != "[3 4 5]" || len(s.dead) != 0 {
+        t.Fatalf("recovered %s, dead letters %q", ids(recs), s.dead)
+    }
+}
+
+func TestSinkDeadLetterFallsBackToLog(t *testing.T) {
+    // a DeadLetterer that fails is logged; deadLetter must not panic or block
+    deadLetter(failingSink{}, Record{ID: 1, Event: "x"}, errSinkQueueFull)
+}
+
+type failingSink struct{}
+
+func (failingSink) Name() string { return "failing" }
+func (failingSink) Deliver(Record) error { return nil }
+func (failingSink) DeadLetter(Record, error) error { return errors.New("disk full") }
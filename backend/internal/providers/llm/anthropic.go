@@ -63,6 +63,35 @@ func (c *AnthropicClient) GenerateText(ctx context.Context, prompt string) (stri
     return resp.Content[0].Text, nil
 }
 
+// GenerateJSON forces a single tool call whose input schema is the requested schema.
+// Tool inputs must be objects, so other root types are reported as unsupported.
+func (c *AnthropicClient) GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, error) {
+    if t, _ := schema["type"].(string); t != "object" { return "", ErrStructuredUnsupported }
+    body := map[string]any{
+        "model": c.Model,
+        "max_tokens": 2048,
+        "messages": []map[string]any{{
+            "role": "user",
+            "content": []map[string]string{{"type": "text", "text": prompt}},
+        }},
+        "tools": []map[string]any{{
+            "name": "record_extraction",
+            "description": "Record the extracted data.",
+            "input_schema": schema,
+        }},
+        "tool_choice": map[string]any{"type": "tool", "name": "record_extraction"},
+    }
+    var resp struct{ Content []struct{
+        Type  string          `json:"type"`
+        Input json.RawMessage `json:"input"`
+    } `json:"content"` }
+    if err := c.postJSON(ctx, body, &resp); err != nil { return "", err }
+    for _, part := range resp.Content {
+        if part.Type == "tool_use" { return string(part.Input), nil }
+    }
+    return "", errors.New("no tool_use content")
+}
+
 func (c *AnthropicClient) GenerateTextStream(ctx context.Context, prompt string, onDelta func(chunk string) error) error {
     // Fallback to non-streaming for now
     txt, err := c.GenerateText(ctx, prompt)
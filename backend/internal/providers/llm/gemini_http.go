@@ -34,6 +34,10 @@ func (c *GeminiHTTPClient) GenerateText(ctx context.Context, prompt string) (str
     return c.generateText(ctx, prompt)
 }
 
+func (c *GeminiHTTPClient) GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, error) {
+    return c.generate(ctx, prompt, map[string]any{"responseMimeType": "application/json", "responseJsonSchema": schema})
+}
+
 func (c *GeminiHTTPClient) GenerateTextStream(ctx context.Context, prompt string, onDelta func(chunk string) error) error {
     txt, err := c.generateText(ctx, prompt)
     if err != nil { return err }
@@ -42,6 +46,10 @@ func (c *GeminiHTTPClient) GenerateTextStream(ctx context.Context, prompt string
 }
 
 func (c *GeminiHTTPClient) generateText(ctx context.Context, prompt string) (string, error) {
+    return c.generate(ctx, prompt, nil)
+}
+
+func (c *GeminiHTTPClient) generate(ctx context.Context, prompt string, generationConfig map[string]any) (string, error) {
     endpoint := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:generateContent?key=%s", url.PathEscape(c.Model), url.QueryEscape(c.APIKey))
     body := map[string]any{
         "contents": []map[string]any{{
@@ -49,6 +57,7 @@ func (c *GeminiHTTPClient) generateText(ctx context.Context, prompt string) (str
             "parts": []map[string]string{{"text": prompt}},
         }},
     }
+    if generationConfig != nil { body["generationConfig"] = generationConfig }
     b, _ := json.Marshal(body)
     // allow override via GEMINI_API_URL base
     if base := os.Getenv("GEMINI_API_URL"); base != "" {
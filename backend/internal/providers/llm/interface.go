@@ -2,6 +2,7 @@ package llm
 
 import (
     "context"
+    "errors"
 )
 
 // Client is a minimal interface used by planner and verifier.
@@ -13,3 +14,14 @@ type Client interface {
     // GenerateTextStream streams text chunks to onDelta; implementers may fall back to a single final chunk.
     GenerateTextStream(ctx context.Context, prompt string, onDelta func(chunk string) error) error
 }
+
+// StructuredClient is implemented by providers that can constrain a response to a JSON
+// Schema natively (OpenAI json_schema, Anthropic forced tool use, Gemini responseJsonSchema).
+// GenerateJSON returns the raw JSON text; callers still validate it.
+type StructuredClient interface {
+    GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, error)
+}
+
+// ErrStructuredUnsupported is returned by GenerateJSON when a provider cannot express
+// the given schema natively; callers should fall back to prompting for JSON.
+var ErrStructuredUnsupported = errors.New("structured output not supported for this schema")
@@ -2,7 +2,10 @@ package llm
 
 import (
     "context"
+    "encoding/json"
     "strings"
+
+    "github.com/example/agent-orchestrator/internal/jsonschema"
 )
 
 // MockClient is used when no real provider is configured.
@@ -31,6 +34,12 @@ func (m *MockClient) GenerateTextStream(ctx context.Context, prompt string, onDe
     return nil
 }
 
+// GenerateJSON returns a placeholder instance of the schema so structured tools work offline.
+func (m *MockClient) GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, error) {
+    b, err := json.Marshal(jsonschema.Example(schema))
+    return string(b), err
+}
+
 func truncate(s string, n int) string {
     if len(s) <= n { return s }
     return s[:n] + "..."
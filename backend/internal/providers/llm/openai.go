@@ -73,6 +73,30 @@ func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string,
     return resp.Choices[0].Message.Content, nil
 }
 
+// GenerateJSON asks for a json_schema response format. The root of such a schema must
+// be an object, so other root types are reported as unsupported.
+func (c *OpenAIClient) GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, error) {
+    if t, _ := schema["type"].(string); t != "object" { return "", ErrStructuredUnsupported }
+    // strict mode rejects schemas without additionalProperties:false everywhere, so keep it off
+    body := map[string]any{
+        "model": c.Model,
+        "messages": []map[string]string{{"role": "user", "content": prompt}},
+        "temperature": 0,
+        "response_format": map[string]any{
+            "type": "json_schema",
+            "json_schema": map[string]any{"name": "extraction", "schema": schema, "strict": false},
+        },
+    }
+    var resp struct{
+        Choices []struct{ Message struct{ Content string `json:"content"` } `json:"message"` } `json:"choices"`
+    }
+    if err := c.postJSON(ctx, c.endpoint("/v1/chat/completions"), body, &resp); err != nil {
+        return "", err
+    }
+    if len(resp.Choices) == 0 { return "", errors.New("no choices") }
+    return resp.Choices[0].Message.Content, nil
+}
+
 func (c *OpenAIClient) GenerateTextStream(ctx context.Context, prompt string, onDelta func(chunk string) error) error {
     // Stream via Chat Completions SSE
     body := map[string]any{
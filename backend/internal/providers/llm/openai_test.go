@@ -0,0 +1,27 @@
+package llm
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+func TestOpenAIGenerateJSONRootType(t *testing.T) {
+    calls := 0
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        calls++
+        w.Header().Set("Content-Type", "application/json")
+        fmt.Fprint(w, `{"choices": [{"message": {"content": "{\"a\": 1}"}}]}`)
+    }))
+    defer srv.Close()
+    c := &OpenAIClient{Model: "m", BaseURL: srv.URL}
+    ctx := context.Background()
+    // a json_schema response must be an object, other roots are not sent
+    for _, schema := range []map[string]any{{"type": "array", "items": map[string]any{"type": "string"}}, {"type": "string"}, {}} {
+        if _, err := c.GenerateJSON(ctx, "p", schema); err != ErrStructuredUnsupported { t.Errorf("%v: %v", schema, err) }
+    }
+    if calls != 0 { t.Fatalf("%d requests for unsupported schemas", calls) }
+    if out, err := c.GenerateJSON(ctx, "p", map[string]any{"type": "object"}); err != nil || out != `{"a": 1}` { t.Fatalf("object schema: %q, %v", out, err) }
+}
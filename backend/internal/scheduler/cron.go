@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Cron is a parsed five-field cron expression: minute hour day-of-month month
+// day-of-week. Fields accept *, lists (1,15), ranges (1-5), steps (*/15, 8-18/2) and
+// month/day names (jan, mon). Day-of-week 0 and 7 are Sunday. As in classic cron, when
+// both day fields are restricted a day matching either one matches.
+//
+// The descriptors @yearly (@annually), @monthly, @weekly, @daily (@midnight) and
+// @hourly are accepted too.
+type Cron struct {
+    minute, hour, dom, month, dow uint64 // bit sets
+    domAny, dowAny                bool
+}
+
+var descriptors = map[string]string{
+    "@yearly":   "0 0 1 1 *",
+    "@annually": "0 0 1 1 *",
+    "@monthly":  "0 0 1 * *",
+    "@weekly":   "0 0 * * 0",
+    "@daily":    "0 0 * * *",
+    "@midnight": "0 0 * * *",
+    "@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+var dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+
+// ParseCron parses a cron expression.
+func ParseCron(expr string) (*Cron, error) {
+    expr = strings.TrimSpace(expr)
+    if d, ok := descriptors[strings.ToLower(expr)]; ok { expr = d }
+    fields := strings.Fields(expr)
+    if len(fields) != 5 { return nil, fmt.Errorf("cron %q: want 5 fields (minute hour day month weekday), got %d", expr, len(fields)) }
+    c := &Cron{domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+    var err error
+    if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil { return nil, fmt.Errorf("cron minute: %v", err) }
+    if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil { return nil, fmt.Errorf("cron hour: %v", err) }
+    if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil { return nil, fmt.Errorf("cron day of month: %v", err) }
+    if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil { return nil, fmt.Errorf("cron month: %v", err) }
+    if c.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil { return nil, fmt.Errorf("cron day of week: %v", err) }
+    // 7 is another name for Sunday
+    if c.dow&(1<<7) != 0 { c.dow |= 1 }
+    return c, nil
+}
+
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+    var bits uint64
+    for _, part := range strings.Split(field, ",") {
+        rng, step := part, 1
+        if i := strings.IndexByte(part, '/'); i != -1 {
+            n, err := strconv.Atoi(part[i+1:])
+            if err != nil || n < 1 { return 0, fmt.Errorf("invalid step in %q", part) }
+            rng, step = part[:i], n
+        }
+        lo, hi := min, max
+        if rng != "*" {
+            a, b, isRange := strings.Cut(rng, "-")
+            var err error
+            if lo, err = fieldValue(a, names); err != nil { return 0, err }
+            hi = lo
+            if isRange {
+                if hi, err = fieldValue(b, names); err != nil { return 0, err }
+            } else if step > 1 {
+                // "5/15" means from 5 to the end in steps of 15
+                hi = max
+            }
+        }
+        if lo < min || hi > max || lo > hi { return 0, fmt.Errorf("%q out of range %d-%d", part, min, max) }
+        for v := lo; v <= hi; v += step { bits |= 1 << uint(v) }
+    }
+    return bits, nil
+}
+
+func fieldValue(s string, names map[string]int) (int, error) {
+    if v, ok := names[strings.ToLower(s)]; ok { return v, nil }
+    n, err := strconv.Atoi(s)
+    if err != nil { return 0, fmt.Errorf("invalid value %q", s) }
+    return n, nil
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+    dom := c.dom&(1<<uint(t.Day())) != 0
+    dow := c.dow&(1<<uint(t.Weekday())) != 0
+    switch {
+    case c.domAny && c.dowAny:
+        return true
+    case c.domAny:
+        return dow
+    case c.dowAny:
+        return dom
+    }
+    return dom || dow
+}
+
+// Next returns the first matching minute strictly after t, in t's location, or the
+// zero time if there is none within five years (e.g. "0 0 30 2 *").
+func (c *Cron) Next(t time.Time) time.Time {
+    loc := t.Location()
+    t = t.Truncate(time.Minute).Add(time.Minute)
+    limit := t.AddDate(5, 0, 0)
+    for t.Before(limit) {
+        if c.month&(1<<uint(t.Month())) == 0 {
+            t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+            continue
+        }
+        if !c.dayMatches(t) {
+            t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+            continue
+        }
+        if c.hour&(1<<uint(t.Hour())) == 0 {
+            // not Truncate: zones such as +05:30 do not start hours on the UTC hour
+            t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+            continue
+        }
+        if c.minute&(1<<uint(t.Minute())) == 0 {
+            t = t.Add(time.Minute)
+            continue
+        }
+        return t
+    }
+    return time.Time{}
+}
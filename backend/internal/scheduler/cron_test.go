@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+    "testing"
+    "time"
+)
+
+func TestCronNext(t *testing.T) {
+    // Wednesday 2026-01-14 10:07
+    from := time.Date(2026, 1, 14, 10, 7, 0, 0, time.UTC)
+    for _, c := range []struct{ expr, want string }{
+        {"*/15 * * * *", "2026-01-14 10:15"},
+        {"0 8 * * *", "2026-01-15 08:00"},
+        {"30 9-17/4 * * mon-fri", "2026-01-14 13:30"},
+        {"0 0 1 feb *", "2026-02-01 00:00"},
+        {"0 12 * * 7", "2026-01-18 12:00"},
+        // either day field may match: the 20th, or a Friday
+        {"0 0 20 * fri", "2026-01-16 00:00"},
+        {"@hourly", "2026-01-14 11:00"},
+        {"@weekly", "2026-01-18 00:00"},
+    } {
+        cr, err := ParseCron(c.expr)
+        if err != nil { t.Errorf("%s: %v", c.expr, err); continue }
+        if got := cr.Next(from).Format("2006-01-02 15:04"); got != c.want { t.Errorf("%s: next %s, want %s", c.expr, got, c.want) }
+    }
+}
+
+func TestParseCronErrors(t *testing.T) {
+    for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * smarch *"} {
+        if _, err := ParseCron(expr); err == nil { t.Errorf("%q accepted", expr) }
+    }
+}
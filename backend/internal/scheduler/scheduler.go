@@ -0,0 +1,343 @@
+// Package scheduler creates and starts tasks from cron schedules.
+package scheduler
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+var ErrNotFound = errors.New("schedule not found")
+
+// Missed-run policies: what to do about occurrences that passed while the server was
+// down (or too busy to notice for more than a minute).
+const (
+    MissedSkip    = "skip"     // wait for the next occurrence (default)
+    MissedRunOnce = "run_once" // run once now, however many were missed
+)
+
+// missedAfter is how late an occurrence may fire before it counts as missed.
+const missedAfter = time.Minute
+
+// Schedule creates a task from Query and Context at every occurrence of Cron and
+// starts it. With Plan set the plan is used as is instead of planning each time.
+type Schedule struct {
+    ID         string         `json:"id"`
+    Name       string         `json:"name,omitempty"`
+    Cron       string         `json:"cron"`
+    // Timezone is an IANA name such as "Europe/Berlin"; empty means the server's.
+    Timezone   string         `json:"timezone,omitempty"`
+    Query      string         `json:"query"`
+    Context    map[string]any `json:"context,omitempty"`
+    Plan       *models.Plan   `json:"plan,omitempty"`
+    Priority   int            `json:"priority,omitempty"`
+    Missed     string         `json:"missed,omitempty"`
+    // Enabled is true when omitted.
+    Enabled    *bool          `json:"enabled,omitempty"`
+    NextRunAt  *time.Time     `json:"next_run_at,omitempty"`
+    LastRunAt  *time.Time     `json:"last_run_at,omitempty"`
+    LastTaskID string         `json:"last_task_id,omitempty"`
+    // LastError explains why the last occurrence did not start a task.
+    LastError  string         `json:"last_error,omitempty"`
+    CreatedAt  time.Time      `json:"created_at"`
+    UpdatedAt  time.Time      `json:"updated_at"`
+
+    cron *Cron
+    loc  *time.Location
+}
+
+// Scheduler keeps schedules and fires them. With Dir set, every schedule is saved as
+// Dir/<id>.json and loaded again by New, so missed runs are noticed after a restart.
+type Scheduler struct {
+    Orch *orchestrator.Orchestrator
+    Dir  string
+
+    mu        sync.Mutex
+    schedules map[string]*Schedule
+    wake      chan struct{}
+}
+
+// New creates a scheduler, loading saved schedules from dir (which may be empty).
+func New(o *orchestrator.Orchestrator, dir string) (*Scheduler, error) {
+    s := &Scheduler{Orch: o, Dir: dir, schedules: map[string]*Schedule{}, wake: make(chan struct{}, 1)}
+    if dir == "" { return s, nil }
+    if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+    files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+    if err != nil { return nil, err }
+    for _, f := range files {
+        b, err := os.ReadFile(f)
+        if err != nil { return nil, err }
+        var sc Schedule
+        if err := json.Unmarshal(b, &sc); err != nil { return nil, fmt.Errorf("%s: %v", filepath.Base(f), err) }
+        if err := sc.compile(); err != nil { return nil, fmt.Errorf("%s: %v", filepath.Base(f), err) }
+        // a past NextRunAt is kept on purpose: tick applies the missed-run policy to it
+        if sc.IsEnabled() && sc.NextRunAt == nil { sc.NextRunAt = sc.next(time.Now()) }
+        s.schedules[sc.ID] = &sc
+    }
+    return s, nil
+}
+
+// compile parses the cron expression and time zone and checks the other fields.
+func (sc *Schedule) compile() error {
+    c, err := ParseCron(sc.Cron)
+    if err != nil { return err }
+    loc := time.Local
+    if sc.Timezone != "" {
+        if loc, err = time.LoadLocation(sc.Timezone); err != nil { return fmt.Errorf("timezone: %v", err) }
+    }
+    switch sc.Missed {
+    case "", MissedSkip, MissedRunOnce:
+    default:
+        return fmt.Errorf("missed: want %q or %q", MissedSkip, MissedRunOnce)
+    }
+    if strings.TrimSpace(sc.Query) == "" && sc.Plan == nil { return fmt.Errorf("schedule needs a query or a plan") }
+    if sc.Enabled == nil { on := true; sc.Enabled = &on }
+    sc.cron, sc.loc = c, loc
+    return nil
+}
+
+// IsEnabled reports whether the schedule fires; Enabled defaults to true.
+func (sc *Schedule) IsEnabled() bool { return sc.Enabled == nil || *sc.Enabled }
+
+func (sc *Schedule) next(after time.Time) *time.Time {
+    n := sc.cron.Next(after.In(sc.loc))
+    if n.IsZero() { return nil }
+    return &n
+}
+
+func (s *Scheduler) validate(sc *Schedule) error {
+    if err := sc.compile(); err != nil { return err }
+    if sc.next(time.Now()) == nil { return fmt.Errorf("cron %q never matches", sc.Cron) }
+    return s.checkPlan(sc.Plan)
+}
+
+// checkPlan validates a pinned plan against the registered tools; nil is fine.
+func (s *Scheduler) checkPlan(plan *models.Plan) error {
+    if plan == nil { return nil }
+    var hasTool func(string) bool
+    if s.Orch.Tools != nil { hasTool = func(name string) bool { _, ok := s.Orch.Tools.Get(name); return ok } }
+    return orchestrator.ValidatePlan(plan, hasTool)
+}
+
+// List returns all schedules by creation time.
+func (s *Scheduler) List() []*Schedule {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    out := make([]*Schedule, 0, len(s.schedules))
+    for _, sc := range s.schedules {
+        c := *sc
+        out = append(out, &c)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+    return out
+}
+
+// Get returns a copy of one schedule.
+func (s *Scheduler) Get(id string) (*Schedule, bool) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sc, ok := s.schedules[id]
+    if !ok { return nil, false }
+    c := *sc
+    return &c, true
+}
+
+// Create validates and stores a new schedule. ID and bookkeeping fields are set by
+// the scheduler.
+func (s *Scheduler) Create(sc *Schedule) (*Schedule, error) {
+    if err := s.validate(sc); err != nil { return nil, err }
+    now := time.Now()
+    sc.ID = newID()
+    sc.CreatedAt, sc.UpdatedAt = now, now
+    sc.LastRunAt, sc.LastTaskID, sc.LastError = nil, "", ""
+    sc.NextRunAt = nil
+    if sc.IsEnabled() { sc.NextRunAt = sc.next(now) }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.schedules[sc.ID] = sc
+    s.saveLocked(sc)
+    s.poke()
+    c := *sc
+    return &c, nil
+}
+
+// Update replaces a schedule's definition, keeping its ID and run history.
+func (s *Scheduler) Update(id string, sc *Schedule) (*Schedule, error) {
+    if err := s.validate(sc); err != nil { return nil, err }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    old, ok := s.schedules[id]
+    if !ok { return nil, ErrNotFound }
+    now := time.Now()
+    sc.ID, sc.CreatedAt, sc.UpdatedAt = id, old.CreatedAt, now
+    sc.LastRunAt, sc.LastTaskID, sc.LastError = old.LastRunAt, old.LastTaskID, old.LastError
+    sc.NextRunAt = nil
+    if sc.IsEnabled() { sc.NextRunAt = sc.next(now) }
+    s.schedules[id] = sc
+    s.saveLocked(sc)
+    s.poke()
+    c := *sc
+    return &c, nil
+}
+
+// Delete removes a schedule. Tasks it created are kept.
+func (s *Scheduler) Delete(id string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.schedules[id]; !ok { return ErrNotFound }
+    delete(s.schedules, id)
+    if s.Dir != "" {
+        if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) { log.Printf("schedule %s: %v", id, err) }
+    }
+    return nil
+}
+
+// RunNow fires a schedule immediately, outside its cron times. Overlap prevention
+// still applies.
+func (s *Scheduler) RunNow(id string) (*models.Task, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sc, ok := s.schedules[id]
+    if !ok { return nil, ErrNotFound }
+    t, err := s.fireLocked(sc, time.Now())
+    s.saveLocked(sc)
+    return t, err
+}
+
+// Start runs the scheduler until ctx ends.
+func (s *Scheduler) Start(ctx context.Context) { go s.loop(ctx) }
+
+func (s *Scheduler) poke() {
+    select {
+    case s.wake <- struct{}{}:
+    default:
+    }
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+    for {
+        wait := s.tick(time.Now())
+        timer := time.NewTimer(wait)
+        select {
+        case <-ctx.Done():
+            timer.Stop()
+            return
+        case <-s.wake:
+            timer.Stop()
+        case <-timer.C:
+        }
+    }
+}
+
+// tick fires due schedules and returns how long to sleep. Sleeps are capped so clock
+// jumps (suspend, NTP) are noticed.
+func (s *Scheduler) tick(now time.Time) time.Duration {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    wait := time.Minute
+    for _, sc := range s.schedules {
+        if !sc.IsEnabled() || sc.NextRunAt == nil { continue }
+        if due := *sc.NextRunAt; !now.Before(due) {
+            if now.Sub(due) <= missedAfter || sc.Missed == MissedRunOnce {
+                s.fireLocked(sc, due)
+            } else {
+                sc.LastError = fmt.Sprintf("missed run at %s skipped", due.Format(time.RFC3339))
+                log.Printf("schedule %s: %s", sc.ID, sc.LastError)
+            }
+            sc.NextRunAt = sc.next(now)
+            s.saveLocked(sc)
+        }
+        if sc.NextRunAt != nil {
+            if d := sc.NextRunAt.Sub(now); d < wait { wait = d }
+        }
+    }
+    if wait < time.Second { wait = time.Second }
+    return wait
+}
+
+// fireLocked creates and queues the task for one occurrence, unless the schedule's
+// previous task is still queued, running or paused. A task that cannot be queued is
+// removed again, so the schedule never points at a task that will not run. The task
+// is returned as it was queued.
+func (s *Scheduler) fireLocked(sc *Schedule, at time.Time) (*models.Task, error) {
+    o := s.Orch
+    if prev, ok := o.GetTask(sc.LastTaskID); ok && unfinished(prev.Status) {
+        sc.LastError = fmt.Sprintf("run at %s skipped: task %s is still %s", at.Format(time.RFC3339), prev.ID, prev.Status)
+        log.Printf("schedule %s: %s", sc.ID, sc.LastError)
+        return nil, fmt.Errorf("previous task %s is still %s", prev.ID, prev.Status)
+    }
+    // tools may have changed since the schedule was saved
+    if err := s.checkPlan(sc.Plan); err != nil {
+        sc.LastError = "pinned plan: " + err.Error()
+        return nil, err
+    }
+    id := sc.ID + "-" + at.UTC().Format("20060102T150405")
+    for n := 2; ; n++ {
+        if _, taken := o.GetTask(id); !taken { break }
+        id = fmt.Sprintf("%s-%s-%d", sc.ID, at.UTC().Format("20060102T150405"), n)
+    }
+    ctx := make(map[string]any, len(sc.Context))
+    for k, v := range sc.Context { ctx[k] = v }
+    t := o.AddTask(&models.Task{ID: id, Query: sc.Query, Context: ctx, ScheduleID: sc.ID, Priority: sc.Priority, ClientID: "schedule:" + sc.ID})
+    kind := orchestrator.JobStart
+    var err error
+    if sc.Plan != nil {
+        pinned := sc.Plan.Clone()
+        _, err = o.EditPlan(id, "schedule:"+sc.ID, "pinned_plan", func(p *models.Plan) error { p.Steps = pinned.Steps; return nil })
+        if err != nil { err = fmt.Errorf("pinned plan: %w", err) }
+        kind = orchestrator.JobExecute
+    }
+    // a worker may pick the task up right away; callers get it as it was queued
+    snap := *t
+    if snap.Plan != nil { snap.Plan = snap.Plan.Clone() }
+    var job *orchestrator.QueuedJob
+    if err == nil { job, err = o.Enqueue(id, kind) }
+    if err != nil {
+        sc.LastError = err.Error()
+        if derr := o.DeleteTask(id); derr != nil { log.Printf("schedule %s: remove task %s: %v", sc.ID, id, derr) }
+        return nil, err
+    }
+    now := time.Now()
+    sc.LastRunAt, sc.LastTaskID, sc.LastError = &now, id, ""
+    snap.Status, snap.UpdatedAt = models.StatusQueued, job.EnqueuedAt
+    return &snap, nil
+}
+
+func unfinished(st models.Status) bool {
+    switch st {
+    case models.StatusQueued, models.StatusRunning, models.StatusAwaitingApproval, models.StatusAwaitingInput, models.StatusPaused:
+        return true
+    }
+    return false
+}
+
+func (s *Scheduler) path(id string) string { return filepath.Join(s.Dir, id+".json") }
+
+func (s *Scheduler) saveLocked(sc *Schedule) {
+    if s.Dir == "" { return }
+    b, err := json.MarshalIndent(sc, "", "  ")
+    if err == nil {
+        tmp := s.path(sc.ID) + ".tmp"
+        if err = os.WriteFile(tmp, b, 0o644); err == nil { err = os.Rename(tmp, s.path(sc.ID)) }
+    }
+    if err != nil { log.Printf("save schedule %s: %v", sc.ID, err) }
+}
+
+func newID() string {
+    b := make([]byte, 4)
+    rand.Read(b)
+    return "sch_" + hex.EncodeToString(b)
+}
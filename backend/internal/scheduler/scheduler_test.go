@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+    "encoding/json"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/tools"
+)
+
+// newTestScheduler returns a scheduler whose orchestrator knows the echo tool and has
+// no workers, so fired tasks stay queued.
+func newTestScheduler(t *testing.T, dir string) *Scheduler {
+    t.Helper()
+    o := orchestrator.New(nil, nil, nil)
+    o.Tools = tools.NewRegistry()
+    o.Tools.Register(&tools.EchoTool{})
+    s, err := New(o, dir)
+    if err != nil { t.Fatal(err) }
+    return s
+}
+
+func TestScheduleEnabledByDefault(t *testing.T) {
+    s := newTestScheduler(t, "")
+    var sc Schedule
+    json.Unmarshal([]byte(`{"cron": "@daily", "query": "q"}`), &sc)
+    created, err := s.Create(&sc)
+    if err != nil { t.Fatal(err) }
+    if !created.IsEnabled() || created.Enabled == nil || created.NextRunAt == nil { t.Fatalf("created %+v", created) }
+    off := false
+    created, _ = s.Create(&Schedule{Cron: "@daily", Query: "q", Enabled: &off})
+    if created.IsEnabled() || created.NextRunAt != nil { t.Fatalf("disabled schedule %+v", created) }
+    if _, err := s.Create(&Schedule{Cron: "@daily"}); err == nil { t.Fatal("schedule without query or plan accepted") }
+    if _, err := s.Create(&Schedule{Cron: "0 0 31 2 *", Query: "q"}); err == nil { t.Fatal("cron that never matches accepted") }
+}
+
+func TestRunNow(t *testing.T) {
+    s := newTestScheduler(t, "")
+    sc, _ := s.Create(&Schedule{Cron: "@daily", Query: "q", Priority: 3})
+    task, err := s.RunNow(sc.ID)
+    if err != nil { t.Fatal(err) }
+    if task.ScheduleID != sc.ID || task.ClientID != "schedule:"+sc.ID || task.Priority != 3 || task.Status != models.StatusQueued { t.Fatalf("task %+v", task) }
+    got, _ := s.Get(sc.ID)
+    if got.LastTaskID != task.ID || got.LastRunAt == nil { t.Fatalf("schedule %+v", got) }
+    // the previous task is still queued
+    if _, err := s.RunNow(sc.ID); err == nil || !strings.Contains(err.Error(), "still QUEUED") { t.Fatalf("overlap: %v", err) }
+    if _, err := s.RunNow("nope"); err != ErrNotFound { t.Fatalf("unknown schedule: %v", err) }
+}
+
+// TestRunNowPinnedPlanFails fires a schedule whose pinned plan no longer validates: no
+// task is left behind and the schedule keeps pointing at its last real task.
+func TestRunNowPinnedPlanFails(t *testing.T) {
+    s := newTestScheduler(t, "")
+    plan := &models.Plan{Steps: []*models.Step{{ID: "a", Tool: "echo", Inputs: map[string]any{"text": "x"}}}}
+    sc, err := s.Create(&Schedule{Cron: "@daily", Plan: plan})
+    if err != nil { t.Fatal(err) }
+    first, err := s.RunNow(sc.ID)
+    if err != nil { t.Fatal(err) }
+    if first.Plan == nil || first.Plan.Steps[0].Tool != "echo" { t.Fatalf("pinned plan not used: %+v", first.Plan) }
+    s.Orch.Cancel(first.ID)
+
+    s.Orch.Tools = tools.NewRegistry()
+    if _, err := s.RunNow(sc.ID); err == nil { t.Fatal("invalid pinned plan fired") }
+    got, _ := s.Get(sc.ID)
+    if got.LastTaskID != first.ID || !strings.HasPrefix(got.LastError, "pinned plan: ") { t.Fatalf("schedule %+v", got) }
+    if n := len(s.Orch.ListTasks()); n != 1 { t.Fatalf("%d tasks, want only the first", n) }
+}
+
+func TestMissedRuns(t *testing.T) {
+    s := newTestScheduler(t, "")
+    skip, _ := s.Create(&Schedule{Cron: "@hourly", Query: "q"})
+    once, _ := s.Create(&Schedule{Cron: "@hourly", Query: "q", Missed: MissedRunOnce})
+    now := time.Now()
+    s.mu.Lock()
+    for _, sc := range s.schedules {
+        due := now.Add(-3 * time.Hour)
+        sc.NextRunAt = &due
+    }
+    s.mu.Unlock()
+    s.tick(now)
+    got, _ := s.Get(skip.ID)
+    if got.LastTaskID != "" || !strings.Contains(got.LastError, "skipped") || !got.NextRunAt.After(now) { t.Fatalf("skip: %+v", got) }
+    if got, _ = s.Get(once.ID); got.LastTaskID == "" || got.LastError != "" { t.Fatalf("run_once: %+v", got) }
+}
+
+func TestSchedulesPersist(t *testing.T) {
+    dir := t.TempDir()
+    s := newTestScheduler(t, dir)
+    off := false
+    on, _ := s.Create(&Schedule{Cron: "@daily", Query: "q", Timezone: "Europe/Berlin"})
+    disabled, _ := s.Create(&Schedule{Cron: "@daily", Query: "q", Enabled: &off})
+    loaded := newTestScheduler(t, dir)
+    if got, ok := loaded.Get(on.ID); !ok || !got.IsEnabled() || got.Timezone != "Europe/Berlin" || got.NextRunAt == nil { t.Fatalf("loaded %+v", got) }
+    if got, _ := loaded.Get(disabled.ID); got.IsEnabled() { t.Fatalf("disabled schedule loaded enabled") }
+    if err := loaded.Delete(on.ID); err != nil { t.Fatal(err) }
+    if _, ok := newTestScheduler(t, dir).Get(on.ID); ok { t.Fatal("deleted schedule loaded") }
+}
@@ -0,0 +1,96 @@
+package sinks
+
+import (
+    "fmt"
+    "log"
+    "os"
+    "sync"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+// AuditLog appends every event as one JSON line to a file. When the file would grow
+// past MaxBytes it is rotated: path -> path.1 -> path.2 ..., keeping MaxFiles old files
+// (all of them when MaxFiles <= 0).
+type AuditLog struct {
+    Path     string
+    MaxBytes int64
+    MaxFiles int
+
+    mu   sync.Mutex
+    f    *os.File
+    size int64
+}
+
+// NewAuditLog opens (or creates) the audit file for appending. maxBytes <= 0 disables
+// rotation; maxFiles <= 0 keeps every rotated file.
+func NewAuditLog(path string, maxBytes int64, maxFiles int) (*AuditLog, error) {
+    a := &AuditLog{Path: path, MaxBytes: maxBytes, MaxFiles: maxFiles}
+    if err := a.open(); err != nil { return nil, err }
+    return a, nil
+}
+
+func (a *AuditLog) Name() string { return "audit" }
+
+func (a *AuditLog) Deliver(rec orchestrator.Record) error {
+    line := append(append([]byte(nil), rec.Data...), '\n')
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if a.MaxBytes > 0 && a.size > 0 && a.size+int64(len(line)) > a.MaxBytes {
+        // the event still goes to the current file if it cannot be rotated
+        if err := a.rotate(); err != nil { log.Printf("%v", err) }
+    }
+    if a.f == nil {
+        if err := a.open(); err != nil { return err }
+    }
+    n, err := a.f.Write(line)
+    a.size += int64(n)
+    return err
+}
+
+// Close flushes and closes the current file.
+func (a *AuditLog) Close() error {
+    a.mu.Lock()
+    defer a.mu.Unlock()
+    if a.f == nil { return nil }
+    err := a.f.Close()
+    a.f = nil
+    return err
+}
+
+func (a *AuditLog) open() error {
+    f, err := os.OpenFile(a.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+    if err != nil { return fmt.Errorf("audit log: %w", err) }
+    st, err := f.Stat()
+    if err != nil { f.Close(); return fmt.Errorf("audit log: %w", err) }
+    a.f, a.size = f, st.Size()
+    return nil
+}
+
+// rotate moves the current file to path.1 and opens a new one. Whatever happens, a
+// file is open again afterwards unless opening fails.
+func (a *AuditLog) rotate() error {
+    a.f.Close()
+    a.f = nil
+    keep := a.MaxFiles
+    if keep <= 0 {
+        // shift every old file up by one
+        keep = 1
+        for exists(fmt.Sprintf("%s.%d", a.Path, keep)) { keep++ }
+    } else {
+        os.Remove(fmt.Sprintf("%s.%d", a.Path, keep))
+    }
+    for i := keep - 1; i >= 1; i-- {
+        old := fmt.Sprintf("%s.%d", a.Path, i)
+        if exists(old) { os.Rename(old, fmt.Sprintf("%s.%d", a.Path, i+1)) }
+    }
+    err := os.Rename(a.Path, a.Path+".1")
+    if oerr := a.open(); oerr != nil { return oerr }
+    if err != nil { return fmt.Errorf("audit log: rotate: %w", err) }
+    return nil
+}
+
+func exists(path string) bool {
+    _, err := os.Stat(path)
+    return err == nil
+}
@@ -0,0 +1,65 @@
+package sinks
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestAuditLogRotates(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.jsonl")
+    a, err := NewAuditLog(path, 60, 2)
+    if err != nil { t.Fatal(err) }
+    defer a.Close()
+    for i := uint64(1); i <= 6; i++ {
+        if err := a.Deliver(record(i, "result", "")); err != nil { t.Fatal(err) }
+    }
+    // each record is about 60 bytes, so every file holds one; two old files are kept
+    for _, p := range []string{path, path + ".1", path + ".2"} {
+        if lines := readLines(t, p); len(lines) != 1 { t.Fatalf("%s: %d lines", p, len(lines)) }
+    }
+    if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) { t.Fatalf("%s.3 kept: %v", path, err) }
+    if lines := readLines(t, path); lines[0]["id"] != float64(6) { t.Fatalf("current file holds %v", lines[0]) }
+    if lines := readLines(t, path+".2"); lines[0]["id"] != float64(4) { t.Fatalf("oldest file holds %v", lines[0]) }
+}
+
+func TestAuditLogAppends(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.jsonl")
+    for i := uint64(1); i <= 2; i++ {
+        a, err := NewAuditLog(path, 0, 0)
+        if err != nil { t.Fatal(err) }
+        a.Deliver(record(i, "result", ""))
+        a.Close()
+    }
+    b, _ := os.ReadFile(path)
+    if n := strings.Count(string(b), "\n"); n != 2 { t.Fatalf("reopening lost lines: %q", b) }
+}
+
+func TestAuditLogKeepsAllFiles(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.jsonl")
+    a, err := NewAuditLog(path, 60, 0)
+    if err != nil { t.Fatal(err) }
+    defer a.Close()
+    for i := uint64(1); i <= 4; i++ {
+        if err := a.Deliver(record(i, "result", "")); err != nil { t.Fatal(err) }
+    }
+    // without a limit every rotated file is kept, the oldest with the highest number
+    for i, p := range []string{path, path + ".1", path + ".2", path + ".3"} {
+        if lines := readLines(t, p); len(lines) != 1 || lines[0]["id"] != float64(4-i) { t.Fatalf("%s holds %v", p, lines) }
+    }
+}
+
+func TestAuditLogRotateFails(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "audit.jsonl")
+    // a directory in the way of path.1 makes every rotation fail
+    if err := os.MkdirAll(filepath.Join(path+".1", "x"), 0o755); err != nil { t.Fatal(err) }
+    a, err := NewAuditLog(path, 60, 1)
+    if err != nil { t.Fatal(err) }
+    defer a.Close()
+    for i := uint64(1); i <= 3; i++ {
+        if err := a.Deliver(record(i, "result", "")); err != nil { t.Fatalf("event %d: %v", i, err) }
+    }
+    // the events stay in the current file
+    if lines := readLines(t, path); len(lines) != 3 { t.Fatalf("%d lines", len(lines)) }
+}
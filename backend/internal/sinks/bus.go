@@ -0,0 +1,165 @@
+package sinks
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "log"
+    "net"
+    "net/url"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+// Publisher is the one method a bus client needs; *nats.Conn from nats.go satisfies
+// it, as does the minimal NATSConn below.
+type Publisher interface {
+    Publish(subject string, data []byte) error
+}
+
+// Bus publishes each event on "<Prefix>.<task id>.<event>", e.g.
+// ensemble.20251018-a.task_status, so consumers can subscribe to ensemble.*.task_status.
+type Bus struct {
+    Publisher Publisher
+    Prefix    string
+}
+
+func (b *Bus) Name() string { return "bus" }
+
+func (b *Bus) Deliver(rec orchestrator.Record) error {
+    subject := subjectToken(rec.TaskID) + "." + subjectToken(rec.Event)
+    if b.Prefix != "" { subject = b.Prefix + "." + subject }
+    return b.Publisher.Publish(subject, rec.Data)
+}
+
+// subjectToken keeps a value from splitting or wildcarding a subject.
+func subjectToken(s string) string {
+    if s == "" { return "_" }
+    return strings.Map(func(r rune) rune {
+        switch r {
+        case '.', '*', '>', ' ', '\t', '\r', '\n':
+            return '_'
+        }
+        return r
+    }, s)
+}
+
+// NATSConn is a publish-only client for the NATS text protocol (INFO / CONNECT / PUB /
+// PING / PONG), enough to feed events to a nats-server without another dependency.
+// It reconnects once per Publish when the connection has dropped. TLS is not
+// supported.
+type NATSConn struct {
+    addr        string
+    user, pass  string
+    token       string
+
+    mu   sync.Mutex
+    conn net.Conn
+    w    *bufio.Writer
+}
+
+// DialNATS connects to a nats://[user:pass@|token@]host[:port] URL.
+func DialNATS(rawURL string) (*NATSConn, error) {
+    u, err := url.Parse(rawURL)
+    if err != nil { return nil, fmt.Errorf("nats: %w", err) }
+    if u.Scheme != "nats" && u.Scheme != "" { return nil, fmt.Errorf("nats: unsupported scheme %q", u.Scheme) }
+    c := &NATSConn{addr: u.Host}
+    if u.Port() == "" { c.addr = net.JoinHostPort(u.Hostname(), "4222") }
+    if u.User != nil {
+        if p, ok := u.User.Password(); ok {
+            c.user, c.pass = u.User.Username(), p
+        } else {
+            c.token = u.User.Username()
+        }
+    }
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if err := c.connect(); err != nil { return nil, err }
+    return c, nil
+}
+
+func (c *NATSConn) Publish(subject string, data []byte) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    err := c.publish(subject, data)
+    if err == nil { return nil }
+    // the connection may have gone away since the last publish; try once more
+    if cerr := c.connect(); cerr != nil { return fmt.Errorf("nats: %v (reconnect: %v)", err, cerr) }
+    return c.publish(subject, data)
+}
+
+func (c *NATSConn) Close() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if c.conn == nil { return nil }
+    err := c.conn.Close()
+    c.conn = nil
+    return err
+}
+
+func (c *NATSConn) publish(subject string, data []byte) error {
+    if c.conn == nil { return fmt.Errorf("not connected") }
+    c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+    fmt.Fprintf(c.w, "PUB %s %d\r\n", subject, len(data))
+    c.w.Write(data)
+    c.w.WriteString("\r\n")
+    return c.w.Flush()
+}
+
+// connect dials and performs the handshake; c.mu must be held.
+func (c *NATSConn) connect() error {
+    if c.conn != nil { c.conn.Close(); c.conn = nil }
+    conn, err := net.DialTimeout("tcp", c.addr, 5*time.Second)
+    if err != nil { return fmt.Errorf("nats: %w", err) }
+    conn.SetDeadline(time.Now().Add(5 * time.Second))
+    r := bufio.NewReader(conn)
+    line, err := r.ReadString('\n')
+    if err != nil || !strings.HasPrefix(line, "INFO ") {
+        conn.Close()
+        return fmt.Errorf("nats: expected INFO from %s, got %q (%v)", c.addr, strings.TrimSpace(line), err)
+    }
+    opts := map[string]any{"verbose": false, "pedantic": false, "name": "ensemble", "lang": "go", "version": "0.1.0", "protocol": 0}
+    if c.user != "" { opts["user"], opts["pass"] = c.user, c.pass }
+    if c.token != "" { opts["auth_token"] = c.token }
+    b, _ := json.Marshal(opts)
+    w := bufio.NewWriter(conn)
+    w.WriteString("CONNECT " + string(b) + "\r\nPING\r\n")
+    if err := w.Flush(); err != nil { conn.Close(); return fmt.Errorf("nats: %w", err) }
+    // the PONG confirms CONNECT was accepted; errors such as bad credentials come first
+    for {
+        line, err = r.ReadString('\n')
+        if err != nil { conn.Close(); return fmt.Errorf("nats: handshake: %w", err) }
+        line = strings.TrimSpace(line)
+        if line == "PONG" { break }
+        if strings.HasPrefix(line, "-ERR") { conn.Close(); return fmt.Errorf("nats: %s", line) }
+    }
+    conn.SetDeadline(time.Time{})
+    c.conn, c.w = conn, w
+    go c.readLoop(conn, r)
+    return nil
+}
+
+// readLoop answers server PINGs and logs protocol errors until conn closes.
+func (c *NATSConn) readLoop(conn net.Conn, r *bufio.Reader) {
+    for {
+        line, err := r.ReadString('\n')
+        if err != nil {
+            c.mu.Lock()
+            if c.conn == conn { conn.Close(); c.conn = nil }
+            c.mu.Unlock()
+            return
+        }
+        line = strings.TrimSpace(line)
+        switch {
+        case line == "PING":
+            c.mu.Lock()
+            if c.conn == conn { c.w.WriteString("PONG\r\n"); c.w.Flush() }
+            c.mu.Unlock()
+        case strings.HasPrefix(line, "-ERR"):
+            log.Printf("nats %s: %s", c.addr, line)
+        }
+    }
+}
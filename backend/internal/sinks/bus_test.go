@@ -0,0 +1,151 @@
+package sinks
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net"
+    "strconv"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+// natsServer is an embedded server speaking the part of the NATS protocol a publisher
+// uses: INFO, CONNECT (with credential checks), PING/PONG and PUB.
+type natsServer struct {
+    ln    net.Listener
+    user  string
+    pass  string
+    msgs  chan natsMsg
+
+    mu    sync.Mutex
+    conns []net.Conn
+    opts  []map[string]any
+}
+
+type natsMsg struct{ subject, data string }
+
+func startNATS(t *testing.T, user, pass string) *natsServer {
+    t.Helper()
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil { t.Fatal(err) }
+    s := &natsServer{ln: ln, user: user, pass: pass, msgs: make(chan natsMsg, 100)}
+    go func() {
+        for {
+            conn, err := ln.Accept()
+            if err != nil { return }
+            s.mu.Lock()
+            s.conns = append(s.conns, conn)
+            s.mu.Unlock()
+            go s.serve(conn)
+        }
+    }()
+    t.Cleanup(func() { ln.Close(); s.dropClients() })
+    return s
+}
+
+func (s *natsServer) url(cred string) string { return "nats://" + cred + s.ln.Addr().String() }
+
+// dropClients closes every client connection, as a restarting server would.
+func (s *natsServer) dropClients() {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, c := range s.conns { c.Close() }
+    s.conns = nil
+}
+
+func (s *natsServer) serve(conn net.Conn) {
+    defer conn.Close()
+    r := bufio.NewReader(conn)
+    fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576,\"auth_required\":%t}\r\n", s.user != "")
+    for {
+        line, err := r.ReadString('\n')
+        if err != nil { return }
+        verb, rest, _ := strings.Cut(strings.TrimSpace(line), " ")
+        switch verb {
+        case "CONNECT":
+            var opts map[string]any
+            json.Unmarshal([]byte(rest), &opts)
+            s.mu.Lock()
+            s.opts = append(s.opts, opts)
+            s.mu.Unlock()
+            if s.user != "" && (opts["user"] != s.user || opts["pass"] != s.pass) {
+                fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+                return
+            }
+        case "PING":
+            fmt.Fprint(conn, "PONG\r\n")
+        case "PUB":
+            f := strings.Fields(rest)
+            n, _ := strconv.Atoi(f[len(f)-1])
+            data := make([]byte, n+2)
+            if _, err := io.ReadFull(r, data); err != nil { return }
+            s.msgs <- natsMsg{subject: f[0], data: string(data[:n])}
+        }
+    }
+}
+
+func (s *natsServer) next(t *testing.T) natsMsg {
+    t.Helper()
+    select {
+    case m := <-s.msgs:
+        return m
+    case <-time.After(2 * time.Second):
+        t.Fatal("no message published")
+    }
+    return natsMsg{}
+}
+
+func TestBusPublishesToNATS(t *testing.T) {
+    srv := startNATS(t, "ens", "pw")
+    conn, err := DialNATS(srv.url("ens:pw@"))
+    if err != nil { t.Fatal(err) }
+    defer conn.Close()
+    bus := &Bus{Publisher: conn, Prefix: "ensemble"}
+    rec := record(3, "task_status", "SUCCESS")
+    rec.TaskID = "2025.a*b"
+    if err := bus.Deliver(rec); err != nil { t.Fatal(err) }
+    m := srv.next(t)
+    if m.subject != "ensemble.2025_a_b.task_status" || m.data != string(rec.Data) { t.Fatalf("got %+v", m) }
+    srv.mu.Lock()
+    opts := srv.opts[0]
+    srv.mu.Unlock()
+    if opts["name"] != "ensemble" || opts["verbose"] != false { t.Fatalf("connect options %v", opts) }
+
+    // a dropped connection is re-established on the next publish
+    srv.dropClients()
+    time.Sleep(20 * time.Millisecond)
+    if err := bus.Deliver(rec); err != nil { t.Fatal(err) }
+    if m := srv.next(t); m.subject != "ensemble.2025_a_b.task_status" { t.Fatalf("after reconnect got %+v", m) }
+}
+
+func TestDialNATSRejected(t *testing.T) {
+    srv := startNATS(t, "ens", "pw")
+    if _, err := DialNATS(srv.url("ens:wrong@")); err == nil || !strings.Contains(err.Error(), "Authorization Violation") {
+        t.Fatalf("err = %v", err)
+    }
+    if _, err := DialNATS("http://localhost:4222"); err == nil { t.Fatal("http scheme accepted") }
+}
+
+func TestBusSinkFilter(t *testing.T) {
+    srv := startNATS(t, "", "")
+    t.Setenv("NATS_URL", srv.url(""))
+    t.Setenv("NATS_EVENTS", "result")
+    list, err := FromEnv()
+    if err != nil || len(list) != 1 { t.Fatalf("sinks %v, %v", list, err) }
+    h := orchestrator.NewHub()
+    defer h.AddSink(list[0])()
+    h.Publish("t", orchestrator.Event{Event: "step_log"})
+    h.Publish("t", orchestrator.Event{Event: "result"})
+    if m := srv.next(t); m.subject != "ensemble.t.result" { t.Fatalf("got %+v", m) }
+    select {
+    case m := <-srv.msgs:
+        t.Fatalf("unexpected %+v", m)
+    case <-time.After(20 * time.Millisecond):
+    }
+}
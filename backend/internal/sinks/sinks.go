@@ -0,0 +1,126 @@
+// Package sinks forwards orchestrator events to systems outside the process: signed
+// webhooks, a JSONL audit file and a message bus. Each sink implements
+// orchestrator.EventSink and is attached with Orchestrator.AddSink.
+package sinks
+
+import (
+    "encoding/json"
+    "errors"
+    "os"
+    "strconv"
+    "strings"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+// Filter selects events by name, optionally narrowed to a task status:
+// "result" matches every result event, "task_status:SUCCESS" only task_status events
+// whose payload status is SUCCESS. An empty filter matches everything.
+type Filter []string
+
+// ParseFilter reads a comma separated filter such as "task_status:SUCCESS,result".
+func ParseFilter(s string) Filter {
+    var f Filter
+    for _, part := range strings.Split(s, ",") {
+        if part = strings.TrimSpace(part); part != "" { f = append(f, part) }
+    }
+    return f
+}
+
+// TerminalStatuses matches tasks reaching SUCCESS or FAILED.
+var TerminalStatuses = Filter{"task_status:SUCCESS", "task_status:FAILED"}
+
+func (f Filter) Match(rec orchestrator.Record) bool {
+    if len(f) == 0 { return true }
+    status := ""
+    for _, entry := range f {
+        name, want, hasStatus := strings.Cut(entry, ":")
+        if name != rec.Event { continue }
+        if !hasStatus { return true }
+        if status == "" { status = payloadStatus(rec.Data) }
+        if strings.EqualFold(status, want) { return true }
+    }
+    return false
+}
+
+func payloadStatus(data []byte) string {
+    var ev struct {
+        Payload struct {
+            Status string `json:"status"`
+        } `json:"payload"`
+    }
+    json.Unmarshal(data, &ev)
+    return ev.Payload.Status
+}
+
+// filtered wraps a sink so it only sees matching events. It implements
+// orchestrator.SinkFilter, so AddSink drops the others before queueing them.
+type filtered struct {
+    orchestrator.EventSink
+    filter Filter
+}
+
+// Filtered returns s restricted to events matching f.
+func Filtered(s orchestrator.EventSink, f Filter) orchestrator.EventSink {
+    if len(f) == 0 { return s }
+    return &filtered{EventSink: s, filter: f}
+}
+
+func (f *filtered) Accept(rec orchestrator.Record) bool { return f.filter.Match(rec) }
+
+func (f *filtered) Deliver(rec orchestrator.Record) error {
+    if !f.filter.Match(rec) { return nil }
+    return f.EventSink.Deliver(rec)
+}
+
+// DeadLetter forwards to the wrapped sink; without one the cause is returned, and
+// AddSink logs it.
+func (f *filtered) DeadLetter(rec orchestrator.Record, cause error) error {
+    if d, ok := f.EventSink.(orchestrator.DeadLetterer); ok { return d.DeadLetter(rec, cause) }
+    return cause
+}
+
+// FromEnv builds the sinks configured in the environment:
+//
+//   - EVENT_WEBHOOK_URL, EVENT_WEBHOOK_SECRET, EVENT_WEBHOOK_EVENTS (default: terminal
+//     task statuses), EVENT_WEBHOOK_RETRIES (3), EVENT_WEBHOOK_DLQ (dead-letter file)
+//   - EVENT_AUDIT_FILE, EVENT_AUDIT_EVENTS (default: all), EVENT_AUDIT_MAX_BYTES
+//     (10 MiB), EVENT_AUDIT_MAX_FILES (5)
+//   - NATS_URL, NATS_SUBJECT_PREFIX ("ensemble"), NATS_EVENTS (default: all)
+//
+// A sink that cannot be set up is left out; the others are returned together with
+// the errors.
+func FromEnv() ([]orchestrator.EventSink, error) {
+    var out []orchestrator.EventSink
+    var errs []error
+    if url := strings.TrimSpace(os.Getenv("EVENT_WEBHOOK_URL")); url != "" {
+        f := TerminalStatuses
+        if v := os.Getenv("EVENT_WEBHOOK_EVENTS"); v != "" { f = ParseFilter(v) }
+        w := &Webhook{URL: url, Secret: os.Getenv("EVENT_WEBHOOK_SECRET"), Retries: envInt("EVENT_WEBHOOK_RETRIES", 3), DeadLetterFile: os.Getenv("EVENT_WEBHOOK_DLQ")}
+        out = append(out, Filtered(w, f))
+    }
+    if path := strings.TrimSpace(os.Getenv("EVENT_AUDIT_FILE")); path != "" {
+        a, err := NewAuditLog(path, int64(envInt("EVENT_AUDIT_MAX_BYTES", 10<<20)), envInt("EVENT_AUDIT_MAX_FILES", 5))
+        if err != nil {
+            errs = append(errs, err)
+        } else {
+            out = append(out, Filtered(a, ParseFilter(os.Getenv("EVENT_AUDIT_EVENTS"))))
+        }
+    }
+    if url := strings.TrimSpace(os.Getenv("NATS_URL")); url != "" {
+        conn, err := DialNATS(url)
+        if err != nil {
+            errs = append(errs, err)
+        } else {
+            prefix := os.Getenv("NATS_SUBJECT_PREFIX")
+            if prefix == "" { prefix = "ensemble" }
+            out = append(out, Filtered(&Bus{Publisher: conn, Prefix: prefix}, ParseFilter(os.Getenv("NATS_EVENTS"))))
+        }
+    }
+    return out, errors.Join(errs...)
+}
+
+func envInt(key string, def int) int {
+    if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key))); err == nil { return v }
+    return def
+}
@@ -0,0 +1,30 @@
+package sinks
+
+import (
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestFilter(t *testing.T) {
+    f := ParseFilter(" task_status:failed , result,")
+    for _, c := range []struct {
+        rec  string
+        st   string
+        want bool
+    }{{"task_status", "FAILED", true}, {"task_status", "SUCCESS", false}, {"result", "", true}, {"step_log", "", false}} {
+        if got := f.Match(record(1, c.rec, c.st)); got != c.want { t.Errorf("%s/%s: %v", c.rec, c.st, got) }
+    }
+    if !Filter(nil).Match(record(1, "anything", "")) { t.Error("empty filter must match") }
+    if _, wrapped := Filtered(&Bus{}, nil).(*filtered); wrapped { t.Error("empty filter must not wrap") }
+}
+
+func TestFromEnvKeepsWorkingSinks(t *testing.T) {
+    t.Setenv("EVENT_WEBHOOK_URL", "http://localhost/hook")
+    t.Setenv("EVENT_AUDIT_FILE", filepath.Join(t.TempDir(), "missing", "audit.jsonl"))
+    t.Setenv("NATS_URL", "http://localhost")
+    // the sinks that fail are reported, the webhook is still returned
+    out, err := FromEnv()
+    if len(out) != 1 || out[0].Name() != "webhook" { t.Fatalf("sinks %v", out) }
+    if err == nil || !strings.Contains(err.Error(), "audit log: ") || !strings.Contains(err.Error(), "nats: ") { t.Fatalf("error %v", err) }
+}
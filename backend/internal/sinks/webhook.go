@@ -0,0 +1,119 @@
+package sinks
+
+import (
+    "bytes"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "os"
+    "strconv"
+    "sync"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+// Webhook POSTs each event as JSON to URL. When Secret is set the request carries
+//
+//    X-Ensemble-Timestamp: <unix seconds>
+//    X-Ensemble-Signature: sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+//
+// so receivers can verify origin and reject replays. Network errors, 408, 429 and 5xx
+// responses are retried with exponential backoff; events that still fail are appended
+// to DeadLetterFile (JSONL) when set, as are events AddSink could not queue.
+type Webhook struct {
+    URL            string
+    Secret         string
+    Retries        int
+    DeadLetterFile string
+    // Backoff is the first retry delay (default 500ms), doubled per attempt.
+    Backoff        time.Duration
+    Client         *http.Client
+
+    dlqMu sync.Mutex
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Deliver(rec orchestrator.Record) error {
+    backoff := w.Backoff
+    if backoff <= 0 { backoff = 500 * time.Millisecond }
+    var err error
+    attempts := 0
+    for attempts <= w.Retries {
+        if attempts > 0 {
+            time.Sleep(backoff)
+            backoff *= 2
+        }
+        attempts++
+        var retry bool
+        retry, err = w.post(rec)
+        if err == nil || !retry { break }
+    }
+    if err != nil {
+        if dlqErr := w.deadLetter(rec, err, attempts); dlqErr != nil { return fmt.Errorf("%v (dead letter: %v)", err, dlqErr) }
+    }
+    return err
+}
+
+// post sends one attempt and reports whether a failure is worth retrying.
+func (w *Webhook) post(rec orchestrator.Record) (bool, error) {
+    req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(rec.Data))
+    if err != nil { return false, err }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("X-Ensemble-Event", rec.Event)
+    req.Header.Set("X-Ensemble-Event-ID", strconv.FormatUint(rec.ID, 10))
+    if w.Secret != "" {
+        ts := strconv.FormatInt(time.Now().Unix(), 10)
+        req.Header.Set("X-Ensemble-Timestamp", ts)
+        req.Header.Set("X-Ensemble-Signature", "sha256="+Sign(w.Secret, ts, rec.Data))
+    }
+    client := w.Client
+    if client == nil { client = &http.Client{Timeout: 10 * time.Second} }
+    resp, err := client.Do(req)
+    if err != nil { return true, err }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+    if resp.StatusCode/100 == 2 { return false, nil }
+    retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+    return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// Sign computes the hex HMAC-SHA256 of "<timestamp>.<body>"; receivers recompute it to
+// verify X-Ensemble-Signature.
+func Sign(secret, timestamp string, body []byte) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(timestamp + "."))
+    mac.Write(body)
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeadLetter records an event that was never posted; without DeadLetterFile the cause
+// is returned.
+func (w *Webhook) DeadLetter(rec orchestrator.Record, cause error) error {
+    if w.DeadLetterFile == "" { return cause }
+    return w.deadLetter(rec, cause, 0)
+}
+
+func (w *Webhook) deadLetter(rec orchestrator.Record, cause error, attempts int) error {
+    if w.DeadLetterFile == "" { return nil }
+    line, _ := json.Marshal(map[string]any{
+        "event_id":  rec.ID,
+        "url":       w.URL,
+        "error":     cause.Error(),
+        "attempts":  attempts,
+        "failed_at": time.Now().UTC(),
+        "event":     json.RawMessage(rec.Data),
+    })
+    w.dlqMu.Lock()
+    defer w.dlqMu.Unlock()
+    f, err := os.OpenFile(w.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+    if err != nil { return err }
+    defer f.Close()
+    _, err = f.Write(append(line, '\n'))
+    return err
+}
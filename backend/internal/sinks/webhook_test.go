@@ -0,0 +1,153 @@
+package sinks
+
+import (
+    "bufio"
+    "encoding/json"
+    "fmt"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+)
+
+// receiver is a webhook endpoint answering with the scripted statuses in turn (200
+// once they run out) and keeping every request.
+type receiver struct {
+    mu       sync.Mutex
+    statuses []int
+    bodies   []string
+    headers  []http.Header
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+    b, _ := io.ReadAll(req.Body)
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    r.bodies = append(r.bodies, string(b))
+    r.headers = append(r.headers, req.Header.Clone())
+    status := http.StatusOK
+    if len(r.statuses) > 0 { status, r.statuses = r.statuses[0], r.statuses[1:] }
+    w.WriteHeader(status)
+}
+
+func (r *receiver) count() int {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return len(r.bodies)
+}
+
+func record(id uint64, event, status string) orchestrator.Record {
+    b, _ := json.Marshal(orchestrator.Event{ID: id, Event: event, TaskID: "t", Payload: map[string]any{"status": status}})
+    return orchestrator.Record{ID: id, TaskID: "t", Event: event, Data: b}
+}
+
+func readLines(t *testing.T, path string) []map[string]any {
+    t.Helper()
+    f, err := os.Open(path)
+    if err != nil { t.Fatal(err) }
+    defer f.Close()
+    var out []map[string]any
+    sc := bufio.NewScanner(f)
+    for sc.Scan() {
+        var m map[string]any
+        if err := json.Unmarshal(sc.Bytes(), &m); err != nil { t.Fatalf("line %q: %v", sc.Text(), err) }
+        out = append(out, m)
+    }
+    return out
+}
+
+func TestWebhookSigns(t *testing.T) {
+    rcv := &receiver{}
+    srv := httptest.NewServer(rcv)
+    defer srv.Close()
+    w := &Webhook{URL: srv.URL, Secret: "s3cret"}
+    rec := record(7, "task_status", "SUCCESS")
+    if err := w.Deliver(rec); err != nil { t.Fatal(err) }
+    h := rcv.headers[0]
+    if h.Get("X-Ensemble-Event") != "task_status" || h.Get("X-Ensemble-Event-ID") != "7" { t.Fatalf("headers %v", h) }
+    want := "sha256=" + Sign("s3cret", h.Get("X-Ensemble-Timestamp"), rec.Data)
+    if h.Get("X-Ensemble-Signature") != want { t.Fatalf("signature %q, want %q", h.Get("X-Ensemble-Signature"), want) }
+    if rcv.bodies[0] != string(rec.Data) { t.Fatalf("body %s", rcv.bodies[0]) }
+}
+
+func TestWebhookRetries(t *testing.T) {
+    rcv := &receiver{statuses: []int{503, 429}}
+    srv := httptest.NewServer(rcv)
+    defer srv.Close()
+    w := &Webhook{URL: srv.URL, Retries: 3, Backoff: time.Millisecond}
+    if err := w.Deliver(record(1, "task_status", "SUCCESS")); err != nil { t.Fatal(err) }
+    if rcv.count() != 3 { t.Fatalf("%d attempts, want 3", rcv.count()) }
+}
+
+func TestWebhookDeadLetters(t *testing.T) {
+    rcv := &receiver{statuses: []int{400, 500, 500}}
+    srv := httptest.NewServer(rcv)
+    defer srv.Close()
+    dlq := filepath.Join(t.TempDir(), "dlq.jsonl")
+    w := &Webhook{URL: srv.URL, Retries: 1, Backoff: time.Millisecond, DeadLetterFile: dlq}
+    // 4xx is not retried
+    if err := w.Deliver(record(1, "task_status", "FAILED")); err == nil { t.Fatal("400 accepted") }
+    if rcv.count() != 1 { t.Fatalf("%d attempts after 400", rcv.count()) }
+    // 5xx is, until the retries run out
+    if err := w.Deliver(record(2, "task_status", "FAILED")); err == nil { t.Fatal("500 accepted") }
+    if rcv.count() != 3 { t.Fatalf("%d attempts after 500", rcv.count()) }
+    // events AddSink could not queue land in the same file
+    if err := w.DeadLetter(record(3, "task_status", "FAILED"), io.ErrShortWrite); err != nil { t.Fatal(err) }
+
+    lines := readLines(t, dlq)
+    if len(lines) != 3 { t.Fatalf("%d dead letters", len(lines)) }
+    for i, attempts := range []float64{1, 2, 0} {
+        if lines[i]["event_id"] != float64(i+1) || lines[i]["attempts"] != attempts { t.Fatalf("dead letter %d: %v", i, lines[i]) }
+    }
+    if ev, _ := lines[0]["event"].(map[string]any); ev["event"] != "task_status" { t.Fatalf("event not kept: %v", lines[0]) }
+    if (&Webhook{}).DeadLetter(record(4, "x", ""), io.ErrShortWrite) != io.ErrShortWrite { t.Fatal("no dead letter file should return the cause") }
+}
+
+// TestWebhookSinkKeepsTerminalEvents runs the configured webhook behind a hub: a stuck
+// receiver and a flood of other events must not cost it the terminal events it wants.
+func TestWebhookSinkKeepsTerminalEvents(t *testing.T) {
+    release := make(chan struct{})
+    var mu sync.Mutex
+    var got []string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        <-release
+        b, _ := io.ReadAll(r.Body)
+        mu.Lock()
+        got = append(got, payloadStatus(b))
+        mu.Unlock()
+    }))
+    defer srv.Close()
+    t.Setenv("EVENT_WEBHOOK_URL", srv.URL)
+    t.Setenv("EVENT_REPLAY_SIZE", "5")
+    list, err := FromEnv()
+    if err != nil || len(list) != 1 { t.Fatalf("sinks %v, %v", list, err) }
+    h := orchestrator.NewHub()
+    stop := h.AddSink(list[0])
+    defer stop()
+    // the firehose log holds 5 events, so the sink's reader may miss some; the task
+    // logs still hold the terminal ones
+    for i := 0; i < 4; i++ {
+        for j := 0; j < 8; j++ { h.Publish("noise", orchestrator.Event{Event: "step_log"}) }
+        task := fmt.Sprintf("t%d", i)
+        h.Publish(task, orchestrator.Event{Event: "task_status", Payload: map[string]any{"status": "RUNNING"}})
+        h.Publish(task, orchestrator.Event{Event: "task_status", Payload: map[string]any{"status": []string{"SUCCESS", "FAILED"}[i%2]}})
+    }
+    close(release)
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        mu.Lock()
+        n := len(got)
+        mu.Unlock()
+        if n == 4 { break }
+        if time.Now().After(deadline) { t.Fatalf("delivered %v", got) }
+        time.Sleep(2 * time.Millisecond)
+    }
+    if strings.Join(got, ",") != "SUCCESS,FAILED,SUCCESS,FAILED" { t.Fatalf("delivered %v", got) }
+}
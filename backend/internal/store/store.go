@@ -0,0 +1,74 @@
+// Package store persists task state so tasks survive a server restart.
+package store
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// FileStore keeps one JSON file per task in Dir.
+type FileStore struct {
+    Dir string
+}
+
+// NewFileStore creates dir if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+    if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+    return &FileStore{Dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) string {
+    // task IDs come from clients of the orchestrator; escaping keeps them inside Dir
+    // and apart from each other ("a/b" and "a_b" get different files)
+    return filepath.Join(s.Dir, url.PathEscape(id)+".json")
+}
+
+// SaveTask writes the state atomically, so a crash leaves the previous version intact.
+func (s *FileStore) SaveTask(st *models.TaskState) error {
+    b, err := json.Marshal(st)
+    if err != nil { return err }
+    path := s.path(st.Task.ID)
+    tmp, err := os.CreateTemp(s.Dir, ".task-*")
+    if err != nil { return err }
+    if _, err := tmp.Write(b); err != nil {
+        tmp.Close()
+        os.Remove(tmp.Name())
+        return err
+    }
+    if err := tmp.Close(); err != nil { os.Remove(tmp.Name()); return err }
+    return os.Rename(tmp.Name(), path)
+}
+
+// DeleteTask removes a task's file; deleting a task that was never saved is not an
+// error.
+func (s *FileStore) DeleteTask(id string) error {
+    if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) { return err }
+    return nil
+}
+
+// LoadTasks reads every stored task, oldest first.
+func (s *FileStore) LoadTasks() ([]*models.TaskState, error) {
+    files, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+    if err != nil { return nil, err }
+    var out []*models.TaskState
+    for _, f := range files {
+        b, err := os.ReadFile(f)
+        if err != nil { return nil, err }
+        var st models.TaskState
+        if err := json.Unmarshal(b, &st); err != nil { return nil, fmt.Errorf("%s: %v", filepath.Base(f), err) }
+        if st.Task == nil { continue }
+        // files written before IDs were escaped move to their escaped name
+        if want := s.path(st.Task.ID); want != f {
+            if err := os.Rename(f, want); err != nil { return nil, err }
+        }
+        out = append(out, &st)
+    }
+    sort.Slice(out, func(i, j int) bool { return out[i].Task.CreatedAt.Before(out[j].Task.CreatedAt) })
+    return out, nil
+}
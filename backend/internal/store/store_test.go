@@ -0,0 +1,54 @@
+package store
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func state(id string, created time.Time) *models.TaskState {
+    return &models.TaskState{Task: &models.Task{ID: id, Query: "q " + id, CreatedAt: created}}
+}
+
+func TestFileStore(t *testing.T) {
+    dir := t.TempDir()
+    s, err := NewFileStore(filepath.Join(dir, "tasks"))
+    if err != nil { t.Fatal(err) }
+    now := time.Now()
+    // IDs that a replacing scheme would map to one file, or out of Dir
+    ids := []string{"a_b", "a/b", `a\b`, "../up", "..", "50%"}
+    for i, id := range ids {
+        if err := s.SaveTask(state(id, now.Add(time.Duration(i)*time.Second))); err != nil { t.Fatalf("%s: %v", id, err) }
+    }
+    if _, err := os.Stat(filepath.Join(dir, "up.json")); err == nil { t.Fatal("a task was written outside the store") }
+    got, err := s.LoadTasks()
+    if err != nil { t.Fatal(err) }
+    if len(got) != len(ids) { t.Fatalf("loaded %d tasks", len(got)) }
+    for i, st := range got {
+        if st.Task.ID != ids[i] || st.Task.Query != "q "+ids[i] { t.Fatalf("task %d: %+v", i, st.Task) }
+    }
+    if err := s.DeleteTask("a/b"); err != nil { t.Fatal(err) }
+    if err := s.DeleteTask("never-saved"); err != nil { t.Fatal(err) }
+    got, _ = s.LoadTasks()
+    for _, st := range got {
+        if st.Task.ID == "a/b" { t.Fatal("deleted task loaded") }
+    }
+    if len(got) != len(ids)-1 { t.Fatalf("deleted more than one task: %d left", len(got)) }
+}
+
+// TestFileStoreMigratesOldNames loads a file named by the old scheme ("/" replaced by
+// "_"), which must move to its escaped name so saving the task again does not leave
+// two files.
+func TestFileStoreMigratesOldNames(t *testing.T) {
+    s, _ := NewFileStore(t.TempDir())
+    b, _ := json.Marshal(state("a/b", time.Now()))
+    os.WriteFile(filepath.Join(s.Dir, "a_b.json"), b, 0o644)
+    if got, err := s.LoadTasks(); err != nil || len(got) != 1 { t.Fatalf("loaded %v: %v", got, err) }
+    s.SaveTask(state("a/b", time.Now()))
+    files, _ := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+    if len(files) != 1 || filepath.Base(files[0]) != "a%2Fb.json" { t.Fatalf("files %v", files) }
+}
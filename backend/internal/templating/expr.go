@@ -0,0 +1,347 @@
+package templating
+
+import (
+    "fmt"
+    "reflect"
+    "regexp"
+    "strconv"
+    "strings"
+    "sync"
+)
+
+// EvalCondition evaluates a step condition such as
+//
+//    contains({{step:step1.logs}}, "application/pdf")
+//    len({{step:step2.output}}) >= 500 && !{{context.skip_summary}}
+//
+// Operands are {{...}} references, string/number/true/false/null literals and calls to
+// len, contains, startsWith, endsWith, lower, upper and matches. Operators are
+// == != < <= > >= && || ! and parentheses. References that cannot be resolved (a
+// skipped step, a missing context key) evaluate to null rather than failing, so
+// conditions can test for presence. The result is converted with Truthy.
+func EvalCondition(expr string, s *Scope) (bool, error) {
+    v, err := Eval(expr, s)
+    if err != nil { return false, err }
+    return Truthy(v), nil
+}
+
+// Eval evaluates an expression and returns its value.
+func Eval(expr string, s *Scope) (any, error) {
+    toks, err := lex(expr)
+    if err != nil { return nil, err }
+    p := &parser{toks: toks, scope: s}
+    v, err := p.or()
+    if err != nil { return nil, err }
+    if p.pos < len(p.toks) { return nil, fmt.Errorf("unexpected %q in condition", p.toks[p.pos].text) }
+    return v, nil
+}
+
+// Truthy treats null, false, "", 0 and empty arrays/objects as false.
+func Truthy(v any) bool {
+    switch t := plain(v).(type) {
+    case nil:
+        return false
+    case bool:
+        return t
+    case string:
+        return t != ""
+    case float64:
+        return t != 0
+    case []any:
+        return len(t) > 0
+    case map[string]any:
+        return len(t) > 0
+    }
+    return true
+}
+
+type tokKind int
+
+const (
+    tokRef tokKind = iota
+    tokString
+    tokNumber
+    tokIdent
+    tokOp
+)
+
+type token struct {
+    kind tokKind
+    text string
+}
+
+func lex(src string) ([]token, error) {
+    var toks []token
+    for i := 0; i < len(src); {
+        c := src[i]
+        switch {
+        case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+            i++
+        case strings.HasPrefix(src[i:], "{{"):
+            end := strings.Index(src[i:], "}}")
+            if end == -1 { return nil, fmt.Errorf("unclosed {{ in condition") }
+            toks = append(toks, token{tokRef, strings.TrimSpace(src[i+2 : i+end])})
+            i += end + 2
+        case c == '"' || c == '\'':
+            j := i + 1
+            for j < len(src) && src[j] != c {
+                if src[j] == '\\' { j++ }
+                j++
+            }
+            if j >= len(src) { return nil, fmt.Errorf("unterminated string in condition") }
+            raw := src[i+1 : j]
+            if c == '\'' { raw = strings.ReplaceAll(strings.ReplaceAll(raw, `\'`, `'`), `"`, `\"`) }
+            str, err := strconv.Unquote(`"` + raw + `"`)
+            if err != nil { return nil, fmt.Errorf("invalid string literal %s", src[i:j+1]) }
+            toks = append(toks, token{tokString, str})
+            i = j + 1
+        case c >= '0' && c <= '9':
+            j := i
+            for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') { j++ }
+            toks = append(toks, token{tokNumber, src[i:j]})
+            i = j
+        case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+            j := i
+            for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') { j++ }
+            toks = append(toks, token{tokIdent, src[i:j]})
+            i = j
+        default:
+            op := ""
+            for _, cand := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", ",", "-"} {
+                if strings.HasPrefix(src[i:], cand) { op = cand; break }
+            }
+            if op == "" { return nil, fmt.Errorf("unexpected character %q in condition", c) }
+            toks = append(toks, token{tokOp, op})
+            i += len(op)
+        }
+    }
+    return toks, nil
+}
+
+type parser struct {
+    toks  []token
+    pos   int
+    scope *Scope
+}
+
+func (p *parser) peekOp(ops ...string) string {
+    if p.pos >= len(p.toks) || p.toks[p.pos].kind != tokOp { return "" }
+    for _, op := range ops {
+        if p.toks[p.pos].text == op { return op }
+    }
+    return ""
+}
+
+func (p *parser) or() (any, error) {
+    left, err := p.and()
+    if err != nil { return nil, err }
+    for p.peekOp("||") != "" {
+        p.pos++
+        right, err := p.and()
+        if err != nil { return nil, err }
+        left = Truthy(left) || Truthy(right)
+    }
+    return left, nil
+}
+
+func (p *parser) and() (any, error) {
+    left, err := p.not()
+    if err != nil { return nil, err }
+    for p.peekOp("&&") != "" {
+        p.pos++
+        right, err := p.not()
+        if err != nil { return nil, err }
+        left = Truthy(left) && Truthy(right)
+    }
+    return left, nil
+}
+
+func (p *parser) not() (any, error) {
+    if p.peekOp("!") != "" {
+        p.pos++
+        v, err := p.not()
+        if err != nil { return nil, err }
+        return !Truthy(v), nil
+    }
+    return p.compare()
+}
+
+func (p *parser) compare() (any, error) {
+    left, err := p.primary()
+    if err != nil { return nil, err }
+    op := p.peekOp("==", "!=", "<=", ">=", "<", ">")
+    if op == "" { return left, nil }
+    p.pos++
+    right, err := p.primary()
+    if err != nil { return nil, err }
+    switch op {
+    case "==":
+        return valuesEqual(left, right), nil
+    case "!=":
+        return !valuesEqual(left, right), nil
+    }
+    c, err := order(left, right)
+    if err != nil { return nil, err }
+    switch op {
+    case "<":
+        return c < 0, nil
+    case "<=":
+        return c <= 0, nil
+    case ">":
+        return c > 0, nil
+    default:
+        return c >= 0, nil
+    }
+}
+
+func (p *parser) primary() (any, error) {
+    if p.pos >= len(p.toks) { return nil, fmt.Errorf("unexpected end of condition") }
+    tok := p.toks[p.pos]
+    p.pos++
+    switch tok.kind {
+    case tokRef:
+        v, err := Lookup(tok.text, p.scope)
+        if err != nil { return nil, nil }
+        return v, nil
+    case tokString:
+        return tok.text, nil
+    case tokNumber:
+        f, err := strconv.ParseFloat(tok.text, 64)
+        if err != nil { return nil, fmt.Errorf("invalid number %q", tok.text) }
+        return f, nil
+    case tokIdent:
+        switch tok.text {
+        case "true":
+            return true, nil
+        case "false":
+            return false, nil
+        case "null":
+            return nil, nil
+        }
+        if p.peekOp("(") == "" { return nil, fmt.Errorf("unknown identifier %q (wrap references in {{ }})", tok.text) }
+        p.pos++
+        var args []any
+        for p.peekOp(")") == "" {
+            if len(args) > 0 {
+                if p.peekOp(",") == "" { return nil, fmt.Errorf("expected , in call to %s", tok.text) }
+                p.pos++
+            }
+            v, err := p.or()
+            if err != nil { return nil, err }
+            args = append(args, v)
+        }
+        p.pos++
+        return call(tok.text, args)
+    case tokOp:
+        switch tok.text {
+        case "(":
+            v, err := p.or()
+            if err != nil { return nil, err }
+            if p.peekOp(")") == "" { return nil, fmt.Errorf("missing )") }
+            p.pos++
+            return v, nil
+        case "-":
+            v, err := p.primary()
+            if err != nil { return nil, err }
+            f, ok := toNumber(v)
+            if !ok { return nil, fmt.Errorf("cannot negate %v", v) }
+            return -f, nil
+        }
+    }
+    return nil, fmt.Errorf("unexpected %q in condition", tok.text)
+}
+
+func call(name string, args []any) (any, error) {
+    want := map[string]int{"len": 1, "lower": 1, "upper": 1, "contains": 2, "startsWith": 2, "endsWith": 2, "matches": 2}
+    n, ok := want[name]
+    if !ok { return nil, fmt.Errorf("unknown function %s", name) }
+    if len(args) != n { return nil, fmt.Errorf("%s expects %d argument(s), got %d", name, n, len(args)) }
+    switch name {
+    case "len":
+        switch t := plain(args[0]).(type) {
+        case nil:
+            return 0.0, nil
+        case string:
+            return float64(len([]rune(t))), nil
+        case []any:
+            return float64(len(t)), nil
+        case map[string]any:
+            return float64(len(t)), nil
+        }
+        return float64(len(Stringify(args[0]))), nil
+    case "lower":
+        return strings.ToLower(Stringify(args[0])), nil
+    case "upper":
+        return strings.ToUpper(Stringify(args[0])), nil
+    case "contains":
+        if arr, ok := plain(args[0]).([]any); ok {
+            for _, x := range arr {
+                if valuesEqual(x, args[1]) { return true, nil }
+            }
+            return false, nil
+        }
+        if args[0] == nil { return false, nil }
+        return strings.Contains(Stringify(args[0]), Stringify(args[1])), nil
+    case "startsWith":
+        return args[0] != nil && strings.HasPrefix(Stringify(args[0]), Stringify(args[1])), nil
+    case "endsWith":
+        return args[0] != nil && strings.HasSuffix(Stringify(args[0]), Stringify(args[1])), nil
+    default: // matches
+        re, err := compileCached(Stringify(args[1]))
+        if err != nil { return nil, err }
+        return args[0] != nil && re.MatchString(Stringify(args[0])), nil
+    }
+}
+
+func valuesEqual(a, b any) bool {
+    if fa, ok := toNumber(a); ok {
+        if fb, ok := toNumber(b); ok { return fa == fb }
+    }
+    return reflect.DeepEqual(plain(a), plain(b))
+}
+
+// order compares numbers numerically and anything else as strings.
+func order(a, b any) (int, error) {
+    fa, okA := toNumber(a)
+    fb, okB := toNumber(b)
+    if okA && okB {
+        switch {
+        case fa < fb:
+            return -1, nil
+        case fa > fb:
+            return 1, nil
+        }
+        return 0, nil
+    }
+    if a == nil || b == nil { return 0, fmt.Errorf("cannot order null") }
+    return strings.Compare(Stringify(a), Stringify(b)), nil
+}
+
+func toNumber(v any) (float64, bool) {
+    switch t := v.(type) {
+    case float64:
+        return t, true
+    case int:
+        return float64(t), true
+    case int64:
+        return float64(t), true
+    }
+    return 0, false
+}
+
+// plain is generic without parsing JSON held in strings: in conditions a string output
+// is text.
+func plain(v any) any {
+    if s, ok := v.(string); ok { return s }
+    return generic(v)
+}
+
+var regexCache sync.Map
+
+func compileCached(pattern string) (*regexp.Regexp, error) {
+    if re, ok := regexCache.Load(pattern); ok { return re.(*regexp.Regexp), nil }
+    re, err := regexp.Compile(pattern)
+    if err != nil { return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err) }
+    regexCache.Store(pattern, re)
+    return re, nil
+}
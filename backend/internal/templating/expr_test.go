@@ -0,0 +1,47 @@
+package templating
+
+import (
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func TestEvalCondition(t *testing.T) {
+    s := &Scope{
+        Task: &models.Task{Query: "Summarize https://example.com", Context: map[string]any{"n": 3.0, "flag": false}},
+        Steps: map[string]*models.Result{
+            "s1": {Output: "hello world", Logs: "content-type: application/pdf"},
+            "s2": {Output: []any{1.0, 2.0}},
+        },
+    }
+    for expr, want := range map[string]bool{
+        `contains({{step:s1.logs}}, "application/pdf")`:          true,
+        `len({{step:s1.output}}) >= 5 && !{{context.flag}}`:      true,
+        `len({{step:s2.output}}) == 2`:                           true,
+        `{{context.n}} > 3 || {{context.n}} == 3`:                true,
+        `!({{context.n}} < 4)`:                                   false,
+        `startsWith(lower({{task.query}}), "summarize")`:         true,
+        `matches({{task.query}}, "https?://")`:                   true,
+        `endsWith(upper({{step:s1.output}}), "WORLD")`:           true,
+        `{{context.missing}}`:                                    false,
+        `{{step:skipped.output}} == null`:                        true,
+        `"a" != "b" && 1 <= 2`:                                   true,
+        `{{step:s2.output}}`:                                     true,
+    } {
+        got, err := EvalCondition(expr, s)
+        if err != nil { t.Errorf("%s: %v", expr, err); continue }
+        if got != want { t.Errorf("%s = %v, want %v", expr, got, want) }
+    }
+    for _, bad := range []string{`len(`, `1 +`, `nope(1)`, `"unterminated`, `1 2`} {
+        if _, err := EvalCondition(bad, s); err == nil { t.Errorf("%s: no error", bad) }
+    }
+}
+
+func TestTruthy(t *testing.T) {
+    for _, v := range []any{nil, false, "", 0.0, []any{}, map[string]any{}} {
+        if Truthy(v) { t.Errorf("Truthy(%#v) = true", v) }
+    }
+    for _, v := range []any{true, "x", 1.0, []any{nil}, map[string]any{"a": 1}} {
+        if !Truthy(v) { t.Errorf("Truthy(%#v) = false", v) }
+    }
+}
@@ -0,0 +1,349 @@
+// Package templating resolves {{...}} references in step inputs.
+//
+// Supported references:
+//
+//    {{step:ID.output}}                 output of a prior step
+//    {{step:ID.output.items[0].url}}    field access into that output
+//    {{step:ID.logs}} / {{step:ID.error}}
+//    {{task.query}} / {{task.id}}
+//    {{context.key.sub}}                task context
+//    {{params.name}}                    workflow parameters of the task
+//    {{NAME.path}}                      variables bound by the caller (see Scope.Vars)
+//
+// A string that is exactly one reference resolves to the referenced value with its
+// type preserved; references embedded in longer strings are stringified. Maps and
+// arrays are resolved recursively. Unresolvable references are errors.
+package templating
+
+import (
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+// Scope is everything a reference can see.
+type Scope struct {
+    Task  *models.Task
+    Steps map[string]*models.Result
+    // Vars are extra roots such as a loop item. They take precedence over the built-in
+    // roots (task, context, params) of the same name.
+    Vars map[string]any
+}
+
+var refPattern = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// Resolve returns a copy of v with every reference substituted.
+func Resolve(v any, s *Scope) (any, error) {
+    switch t := v.(type) {
+    case string:
+        return ResolveString(t, s)
+    case map[string]any:
+        out := make(map[string]any, len(t))
+        for k, val := range t {
+            r, err := Resolve(val, s)
+            if err != nil { return nil, fmt.Errorf("%s: %w", k, err) }
+            out[k] = r
+        }
+        return out, nil
+    case []any:
+        out := make([]any, len(t))
+        for i, val := range t {
+            r, err := Resolve(val, s)
+            if err != nil { return nil, fmt.Errorf("[%d]: %w", i, err) }
+            out[i] = r
+        }
+        return out, nil
+    default:
+        return v, nil
+    }
+}
+
+// ResolveInputs resolves a step's input map.
+func ResolveInputs(inputs map[string]any, s *Scope) (map[string]any, error) {
+    if inputs == nil { return nil, nil }
+    out, err := Resolve(inputs, s)
+    if err != nil { return nil, err }
+    return out.(map[string]any), nil
+}
+
+// ResolveString substitutes references in one string. Braces around text that cannot
+// be a reference, such as {{ two words }} or {{#if}}, are left untouched.
+func ResolveString(str string, s *Scope) (any, error) {
+    matches := refPattern.FindAllStringSubmatchIndex(str, -1)
+    if len(matches) == 0 { return str, nil }
+    if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(str) {
+        expr := str[matches[0][2]:matches[0][3]]
+        if !isReference(expr) { return str, nil }
+        return Lookup(expr, s)
+    }
+    var b strings.Builder
+    last := 0
+    for _, m := range matches {
+        expr := str[m[2]:m[3]]
+        b.WriteString(str[last:m[0]])
+        last = m[1]
+        if !isReference(expr) {
+            b.WriteString(str[m[0]:m[1]])
+            continue
+        }
+        v, err := Lookup(expr, s)
+        if err != nil { return nil, err }
+        b.WriteString(Stringify(v))
+    }
+    b.WriteString(str[last:])
+    return b.String(), nil
+}
+
+// References lists the step IDs referenced anywhere in v, in order of appearance.
+func References(v any) []string {
+    var out []string
+    var walk func(v any)
+    walk = func(v any) {
+        switch t := v.(type) {
+        case string:
+            for _, m := range refPattern.FindAllStringSubmatch(t, -1) {
+                if id, _, ok := splitStepRef(m[1]); ok { out = append(out, id) }
+            }
+        case map[string]any:
+            for _, val := range t { walk(val) }
+        case []any:
+            for _, val := range t { walk(val) }
+        }
+    }
+    walk(v)
+    return out
+}
+
+// Lookup evaluates a single reference expression (the text between the braces).
+func Lookup(expr string, s *Scope) (any, error) {
+    expr = strings.TrimSpace(expr)
+    if id, rest, ok := splitStepRef(expr); ok {
+        res, found := s.Steps[id]
+        if !found || res == nil {
+            return nil, fmt.Errorf("{{%s}}: step %q has no result", expr, id)
+        }
+        root := map[string]any{"output": res.Output, "logs": res.Logs, "error": res.Error, "verified": res.Verified}
+        v, err := walkPath(root, rest, "")
+        if err != nil { return nil, fmt.Errorf("{{%s}}: %w", expr, err) }
+        return v, nil
+    }
+    name, rest := splitRoot(expr)
+    var root any
+    switch {
+    case s.Vars != nil && hasKey(s.Vars, name):
+        root = s.Vars[name]
+    case name == "task":
+        root = s.taskMap()
+    case name == "context":
+        root = s.contextMap()
+    case name == "params":
+        root = s.paramsMap()
+    default:
+        return nil, fmt.Errorf("{{%s}}: unknown reference %q", expr, name)
+    }
+    v, err := walkPath(root, rest, name)
+    if err != nil { return nil, fmt.Errorf("{{%s}}: %w", expr, err) }
+    return v, nil
+}
+
+// List returns v as a generic array, accepting typed slices and JSON array strings.
+func List(v any) ([]any, bool) {
+    arr, ok := generic(v).([]any)
+    return arr, ok
+}
+
+// Stringify renders a value for embedding in text.
+func Stringify(v any) string {
+    switch t := v.(type) {
+    case nil:
+        return ""
+    case string:
+        return t
+    case fmt.Stringer:
+        return t.String()
+    default:
+        b, _ := json.Marshal(t)
+        return string(b)
+    }
+}
+
+var rootName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// isReference reports whether expr has the form of a reference: a step reference or a
+// name, known or not, optionally followed by a path.
+func isReference(expr string) bool {
+    if _, _, ok := splitStepRef(expr); ok { return true }
+    name, _ := splitRoot(strings.TrimSpace(expr))
+    return rootName.MatchString(name)
+}
+
+func (s *Scope) taskMap() map[string]any {
+    if s.Task == nil { return map[string]any{} }
+    return map[string]any{"id": s.Task.ID, "query": s.Task.Query, "context": s.contextMap()}
+}
+
+func (s *Scope) contextMap() map[string]any {
+    if s.Task == nil || s.Task.Context == nil { return map[string]any{} }
+    return s.Task.Context
+}
+
+func (s *Scope) paramsMap() map[string]any {
+    if s.Task == nil || s.Task.Params == nil { return map[string]any{} }
+    return s.Task.Params
+}
+
+// RootFields lists the first field under root for every {{root.field...}} reference
+// in v, e.g. the parameter names a plan uses.
+func RootFields(v any, root string) []string {
+    var out []string
+    var walk func(v any)
+    walk = func(v any) {
+        switch t := v.(type) {
+        case string:
+            for _, m := range refPattern.FindAllStringSubmatch(t, -1) {
+                name, rest := splitRoot(strings.TrimSpace(m[1]))
+                if name != root { continue }
+                segs, err := parsePath(rest)
+                if err != nil || len(segs) == 0 || segs[0].isIdx { continue }
+                out = append(out, segs[0].key)
+            }
+        case map[string]any:
+            for _, val := range t { walk(val) }
+        case []any:
+            for _, val := range t { walk(val) }
+        }
+    }
+    walk(v)
+    return out
+}
+
+var stepRef = regexp.MustCompile(`^step:([a-zA-Z0-9_\-]+)(.*)$`)
+
+// splitStepRef splits "step:ID.rest" into ID and ".rest".
+func splitStepRef(expr string) (string, string, bool) {
+    m := stepRef.FindStringSubmatch(strings.TrimSpace(expr))
+    if m == nil { return "", "", false }
+    return m[1], m[2], true
+}
+
+func splitRoot(expr string) (string, string) {
+    if i := strings.IndexAny(expr, ".["); i != -1 { return expr[:i], expr[i:] }
+    return expr, ""
+}
+
+func hasKey(m map[string]any, k string) bool {
+    _, ok := m[k]
+    return ok
+}
+
+// segment is one step of a path: a field name or an array index.
+type segment struct {
+    key   string
+    index int
+    isIdx bool
+}
+
+func parsePath(p string) ([]segment, error) {
+    var segs []segment
+    for i := 0; i < len(p); {
+        switch p[i] {
+        case '.':
+            j := i + 1
+            for j < len(p) && p[j] != '.' && p[j] != '[' { j++ }
+            if j == i+1 { return nil, fmt.Errorf("empty field name in %q", p) }
+            segs = append(segs, segment{key: p[i+1 : j]})
+            i = j
+        case '[':
+            j := strings.IndexByte(p[i:], ']')
+            if j == -1 { return nil, fmt.Errorf("unclosed [ in %q", p) }
+            inner := strings.TrimSpace(p[i+1 : i+j])
+            if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+                segs = append(segs, segment{key: inner[1 : len(inner)-1]})
+            } else {
+                n, err := strconv.Atoi(inner)
+                if err != nil { return nil, fmt.Errorf("invalid index [%s]", inner) }
+                segs = append(segs, segment{index: n, isIdx: true})
+            }
+            i += j + 1
+        default:
+            return nil, fmt.Errorf("unexpected %q in path %q", p[i], p)
+        }
+    }
+    return segs, nil
+}
+
+// walkPath follows p from root; label names the root in error messages.
+func walkPath(root any, p string, label string) (any, error) {
+    segs, err := parsePath(p)
+    if err != nil { return nil, err }
+    cur := root
+    walked := label
+    for _, seg := range segs {
+        cur = generic(cur)
+        if seg.isIdx {
+            arr, ok := cur.([]any)
+            if !ok { return nil, fmt.Errorf("%s is %s, not an array", describe(walked), kindOf(cur)) }
+            i := seg.index
+            if i < 0 { i += len(arr) }
+            if i < 0 || i >= len(arr) { return nil, fmt.Errorf("index %d out of range for %s (len %d)", seg.index, describe(walked), len(arr)) }
+            cur = arr[i]
+            walked += fmt.Sprintf("[%d]", seg.index)
+            continue
+        }
+        obj, ok := cur.(map[string]any)
+        if !ok { return nil, fmt.Errorf("%s is %s, not an object", describe(walked), kindOf(cur)) }
+        v, found := obj[seg.key]
+        if !found { return nil, fmt.Errorf("no field %q in %s", seg.key, describe(walked)) }
+        cur = v
+        walked += "." + seg.key
+    }
+    return cur, nil
+}
+
+// generic converts typed values (structs, typed slices) to map/slice form and parses
+// strings holding JSON so paths can reach into them.
+func generic(v any) any {
+    switch t := v.(type) {
+    case nil, map[string]any, []any, bool, float64:
+        return v
+    case string:
+        s := strings.TrimSpace(t)
+        if strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") {
+            var out any
+            if json.Unmarshal([]byte(s), &out) == nil { return out }
+        }
+        return v
+    }
+    b, err := json.Marshal(v)
+    if err != nil { return v }
+    var out any
+    if json.Unmarshal(b, &out) != nil { return v }
+    return out
+}
+
+func describe(walked string) string {
+    if walked == "" { return "value" }
+    return strings.TrimPrefix(walked, ".")
+}
+
+func kindOf(v any) string {
+    switch v.(type) {
+    case nil:
+        return "null"
+    case string:
+        return "a string"
+    case bool:
+        return "a boolean"
+    case float64, int:
+        return "a number"
+    case []any:
+        return "an array"
+    case map[string]any:
+        return "an object"
+    }
+    return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,84 @@
+package templating
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+func testScope() *Scope {
+    return &Scope{
+        Task: &models.Task{ID: "t1", Query: "find pages", Context: map[string]any{"lang": "en", "limits": map[string]any{"pages": 3.0}}},
+        Steps: map[string]*models.Result{
+            "s1": {Output: map[string]any{"items": []any{map[string]any{"url": "https://a.test"}, map[string]any{"url": "https://b.test"}}}, Logs: "2 items"},
+            "raw": {Output: `{"n": 5}`},
+        },
+    }
+}
+
+func TestResolve(t *testing.T) {
+    s := testScope()
+    in := map[string]any{
+        "first":  "{{step:s1.output.items[0].url}}",
+        "last":   "{{ step:s1.output.items[-1].url }}",
+        "items":  "{{step:s1.output.items}}",
+        "text":   "{{task.query}} in {{context.lang}} ({{step:s1.logs}})",
+        "nested": []any{map[string]any{"n": "{{context.limits.pages}}"}, "{{step:raw.output.n}}"},
+        "other":  "{{ not a reference }} and {{#if x}} stay",
+    }
+    out, err := ResolveInputs(in, s)
+    if err != nil { t.Fatal(err) }
+    want := map[string]any{
+        "first":  "https://a.test",
+        "last":   "https://b.test",
+        "items":  []any{map[string]any{"url": "https://a.test"}, map[string]any{"url": "https://b.test"}},
+        "text":   "find pages in en (2 items)",
+        "nested": []any{map[string]any{"n": 3.0}, 5.0},
+        "other":  "{{ not a reference }} and {{#if x}} stay",
+    }
+    if !reflect.DeepEqual(out, want) { t.Fatalf("got %#v\nwant %#v", out, want) }
+}
+
+func TestResolveErrors(t *testing.T) {
+    s := testScope()
+    for expr, msg := range map[string]string{
+        "{{step:missing.output}}":      `step "missing" has no result`,
+        "{{step:s1.output.items[5]}}":  "index 5 out of range",
+        "{{step:s1.output.nope}}":      `no field "nope"`,
+        "{{step:s1.output.items.url}}": "not an object",
+        "see {{context.lang.x}}":       "not an object",
+        "{{not_a_root}}":               `unknown reference "not_a_root"`,
+        "see {{nope.field}}":           `unknown reference "nope"`,
+    } {
+        _, err := ResolveString(expr, s)
+        if err == nil || !strings.Contains(err.Error(), msg) { t.Errorf("%s: err = %v, want %q", expr, err, msg) }
+    }
+}
+
+func TestVarsTakePrecedence(t *testing.T) {
+    s := testScope()
+    s.Vars = map[string]any{"item": "x", "context": map[string]any{"lang": "de"}}
+    v, err := ResolveString("{{item}}-{{context.lang}}", s)
+    if err != nil { t.Fatal(err) }
+    if v != "x-de" { t.Fatalf("got %v", v) }
+}
+
+func TestReferences(t *testing.T) {
+    refs := References(map[string]any{"a": "{{step:s1.output}} {{task.query}}", "b": []any{"{{step:s2.logs}}"}})
+    if len(refs) != 2 { t.Fatalf("refs = %v", refs) }
+}
+
+type named struct{}
+
+func (named) String() string { return "named" }
+
+func TestStringify(t *testing.T) {
+    for _, c := range []struct {
+        in   any
+        want string
+    }{{nil, ""}, {"s", "s"}, {named{}, "named"}, {map[string]any{"a": 1}, `{"a":1}`}, {[]int{1, 2}, "[1,2]"}} {
+        if got := Stringify(c.in); got != c.want { t.Errorf("Stringify(%v) = %q, want %q", c.in, got, c.want) }
+    }
+}
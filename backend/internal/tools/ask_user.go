@@ -0,0 +1,61 @@
+package tools
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "strings"
+)
+
+// AskFunc puts a question to the person behind a task and blocks until they answer.
+type AskFunc func(ctx context.Context, question string, choices []string) (string, error)
+
+// ctx key for passing AskFunc through context; the orchestrator sets it per step.
+var CtxAskKey ctxKey = "ask"
+
+// AskUserTool pauses the run until the user answers a question. The answer is the
+// step output.
+type AskUserTool struct{}
+
+func (a *AskUserTool) Name() string { return "ask_user" }
+
+func (a *AskUserTool) Execute(ctx context.Context, inputs map[string]any) (any, string, error) {
+    question := strings.TrimSpace(stringInput(inputs, "question"))
+    if question == "" { return nil, "", fmt.Errorf("missing question") }
+    choices, err := choicesInput(inputs["choices"])
+    if err != nil { return nil, "", err }
+    ask, ok := ctx.Value(CtxAskKey).(AskFunc)
+    if !ok || ask == nil { return nil, "", fmt.Errorf("ask_user needs an interactive run") }
+    answer, err := ask(ctx, question, choices)
+    if err != nil { return nil, "", err }
+    return answer, fmt.Sprintf("question: %s", question), nil
+}
+
+// choicesInput accepts a list of strings, or a JSON array of them (e.g. from a template).
+func choicesInput(v any) ([]string, error) {
+    switch c := v.(type) {
+    case nil:
+        return nil, nil
+    case []string:
+        return c, nil
+    case string:
+        if strings.TrimSpace(c) == "" { return nil, nil }
+        var out []string
+        if err := json.Unmarshal([]byte(c), &out); err != nil { return nil, fmt.Errorf("choices: want a list of strings") }
+        return out, nil
+    case []any:
+        out := make([]string, 0, len(c))
+        for _, x := range c {
+            switch x := x.(type) {
+            case string:
+                out = append(out, x)
+            case nil:
+                return nil, fmt.Errorf("choices: empty entry")
+            default:
+                out = append(out, fmt.Sprint(x))
+            }
+        }
+        return out, nil
+    }
+    return nil, fmt.Errorf("choices: want a list of strings")
+}
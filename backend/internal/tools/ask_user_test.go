@@ -0,0 +1,40 @@
+package tools
+
+import (
+    "context"
+    "reflect"
+    "testing"
+)
+
+func TestAskUserTool(t *testing.T) {
+    tool := &AskUserTool{}
+    if _, _, err := tool.Execute(context.Background(), map[string]any{"question": "Which?"}); err == nil { t.Fatal("ran without an interactive run") }
+    var gotChoices []string
+    ctx := context.WithValue(context.Background(), CtxAskKey, AskFunc(func(ctx context.Context, q string, choices []string) (string, error) {
+        gotChoices = choices
+        return "b", nil
+    }))
+    if _, _, err := tool.Execute(ctx, map[string]any{"question": " "}); err == nil { t.Fatal("empty question accepted") }
+    out, _, err := tool.Execute(ctx, map[string]any{"question": "Which?", "choices": `["a","b"]`})
+    if err != nil || out != "b" || !reflect.DeepEqual(gotChoices, []string{"a", "b"}) { t.Fatalf("out %v, choices %v, err %v", out, gotChoices, err) }
+}
+
+func TestChoicesInput(t *testing.T) {
+    for _, c := range []struct {
+        in   any
+        want []string
+        ok   bool
+    }{
+        {nil, nil, true},
+        {"", nil, true},
+        {[]string{"a"}, []string{"a"}, true},
+        {`["a","b"]`, []string{"a", "b"}, true},
+        {[]any{"a", 2}, []string{"a", "2"}, true},
+        {"a,b", nil, false},
+        {[]any{"a", nil}, nil, false},
+        {3, nil, false},
+    } {
+        got, err := choicesInput(c.in)
+        if (err == nil) != c.ok || !reflect.DeepEqual(got, c.want) { t.Errorf("choicesInput(%#v) = %v, %v", c.in, got, err) }
+    }
+}
@@ -0,0 +1,359 @@
+package tools
+
+import (
+    "bufio"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "path"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "golang.org/x/net/html"
+)
+
+// CrawlTool walks a site breadth-first from a start URL, following same-origin links
+// up to a depth and page limit. It honors robots.txt, waits between requests to the
+// same host and returns the text of each page in visit order. A robots.txt
+// Crawl-delay is honored up to CRAWL_MAX_DELAY_MS (default 10s).
+type CrawlTool struct{}
+
+// CrawlPage is one fetched page in the crawl output.
+type CrawlPage struct {
+    URL   string `json:"url"`
+    Title string `json:"title,omitempty"`
+    Depth int    `json:"depth"`
+    Text  string `json:"text"`
+}
+
+const crawlUserAgent = "ensemble-crawler/1.0"
+
+func (c *CrawlTool) Name() string { return "crawl" }
+
+func (c *CrawlTool) Execute(ctx context.Context, inputs map[string]any) (any, string, error) {
+    rawURL, _ := inputs["url"].(string)
+    if rawURL == "" { return nil, "", fmt.Errorf("missing url") }
+    start, err := url.Parse(strings.TrimSpace(rawURL))
+    if err != nil { return nil, "", fmt.Errorf("invalid url: %w", err) }
+    if start.Scheme != "http" && start.Scheme != "https" {
+        return nil, "", fmt.Errorf("unsupported scheme: %s", start.Scheme)
+    }
+    maxDepth := getInt(inputs, "max_depth", 2)
+    maxPages := getInt(inputs, "max_pages", 10)
+    if limit := envInt("CRAWL_MAX_PAGES", 100); maxPages > limit { maxPages = limit }
+    if maxPages < 1 { maxPages = 1 }
+    delay := time.Duration(getInt(inputs, "delay_ms", envInt("CRAWL_DELAY_MS", 500))) * time.Millisecond
+    client := &http.Client{Timeout: time.Duration(getInt(inputs, "timeout_ms", 10000)) * time.Millisecond}
+
+    var cb TokenCallback
+    if fn, ok := ctx.Value(CtxTokenCallbackKey).(TokenCallback); ok { cb = fn }
+
+    cr := &crawler{client: client, limiter: newHostLimiter(delay), origin: originOf(start)}
+    robots := cr.fetchRobots(ctx, start)
+    clamped := ""
+    if robots.delay > delay {
+        // a site must not stall the step for minutes between pages
+        limit := time.Duration(envInt("CRAWL_MAX_DELAY_MS", 10000)) * time.Millisecond
+        if limit < delay { limit = delay }
+        cr.limiter.delay = robots.delay
+        if robots.delay > limit {
+            cr.limiter.delay = limit
+            clamped = fmt.Sprintf(" crawl_delay=%s (robots.txt asks %s)", limit, robots.delay)
+        }
+    }
+
+    type item struct {
+        u     *url.URL
+        depth int
+    }
+    // seen holds the URLs queued so far, fetched the pages kept so far (by their own and
+    // their canonical URL)
+    seen := map[string]bool{canonicalURL(start): true}
+    fetched := map[string]bool{}
+    queue := []item{{u: start, depth: 0}}
+    var pages []CrawlPage
+    skippedRobots, failed := 0, 0
+    for len(queue) > 0 && len(pages) < maxPages {
+        if err := ctx.Err(); err != nil { return nil, "", err }
+        it := queue[0]
+        queue = queue[1:]
+        if !robots.allowed(it.u) { skippedRobots++; continue }
+        page, links, err := cr.fetchPage(ctx, it.u)
+        if err != nil {
+            if ctx.Err() != nil { return nil, "", ctx.Err() }
+            failed++
+            if cb != nil { cb(fmt.Sprintf("[crawl] skip %s: %v\n", it.u, err)) }
+            continue
+        }
+        // a page may declare itself a duplicate of one we already have via rel=canonical
+        key := canonicalURL(it.u)
+        if fetched[key] || (page.canonical != "" && fetched[page.canonical]) { continue }
+        fetched[key] = true
+        if page.canonical != "" {
+            fetched[page.canonical] = true
+            seen[page.canonical] = true
+        }
+        pages = append(pages, CrawlPage{URL: it.u.String(), Title: page.title, Depth: it.depth, Text: page.text})
+        if cb != nil { cb(fmt.Sprintf("[crawl %d/%d] %s\n", len(pages), maxPages, it.u)) }
+        if it.depth >= maxDepth { continue }
+        for _, link := range links {
+            key := canonicalURL(link)
+            if seen[key] { continue }
+            seen[key] = true
+            queue = append(queue, item{u: link, depth: it.depth + 1})
+        }
+    }
+    if len(pages) == 0 {
+        return nil, "", fmt.Errorf("no pages crawled from %s (robots_skipped=%d errors=%d)", start, skippedRobots, failed)
+    }
+    logs := fmt.Sprintf("pages=%d max_depth=%d robots_skipped=%d errors=%d", len(pages), maxDepth, skippedRobots, failed) + clamped
+    return pages, logs, nil
+}
+
+type crawler struct {
+    client  *http.Client
+    limiter *hostLimiter
+    origin  string
+}
+
+type crawledPage struct {
+    title     string
+    text      string
+    canonical string
+}
+
+func (c *crawler) get(ctx context.Context, u *url.URL) (*http.Response, error) {
+    if err := c.limiter.wait(ctx, u.Host); err != nil { return nil, err }
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+    if err != nil { return nil, err }
+    req.Header.Set("User-Agent", crawlUserAgent)
+    return c.client.Do(req)
+}
+
+// fetchPage downloads an HTML page and returns its text and the same-origin links on it.
+func (c *crawler) fetchPage(ctx context.Context, u *url.URL) (*crawledPage, []*url.URL, error) {
+    resp, err := c.get(ctx, u)
+    if err != nil { return nil, nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return nil, nil, fmt.Errorf("status %d", resp.StatusCode)
+    }
+    // redirects may leave the site
+    if originOf(resp.Request.URL) != c.origin { return nil, nil, fmt.Errorf("redirected off-origin to %s", resp.Request.URL) }
+    if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+        return nil, nil, fmt.Errorf("not html: %s", ct)
+    }
+    const max = 2 << 20
+    node, err := html.Parse(io.LimitReader(resp.Body, max))
+    if err != nil { return nil, nil, err }
+
+    base := resp.Request.URL
+    page := &crawledPage{text: nodeText(node)}
+    var links []*url.URL
+    var walk func(n *html.Node)
+    walk = func(n *html.Node) {
+        if n.Type == html.ElementNode {
+            switch n.Data {
+            case "title":
+                if page.title == "" && n.FirstChild != nil { page.title = strings.TrimSpace(n.FirstChild.Data) }
+            case "base":
+                if href := attr(n, "href"); href != "" {
+                    if b, err := base.Parse(href); err == nil { base = b }
+                }
+            case "link":
+                if strings.EqualFold(attr(n, "rel"), "canonical") {
+                    if cu, err := base.Parse(attr(n, "href")); err == nil && originOf(cu) == c.origin {
+                        page.canonical = canonicalURL(cu)
+                    }
+                }
+            case "a":
+                href := attr(n, "href")
+                if href != "" && !strings.Contains(attr(n, "rel"), "nofollow") {
+                    if lu, err := base.Parse(href); err == nil && originOf(lu) == c.origin {
+                        lu.Fragment = ""
+                        links = append(links, lu)
+                    }
+                }
+            }
+        }
+        for ch := n.FirstChild; ch != nil; ch = ch.NextSibling { walk(ch) }
+    }
+    walk(node)
+    return page, links, nil
+}
+
+func attr(n *html.Node, key string) string {
+    for _, a := range n.Attr {
+        if strings.EqualFold(a.Key, key) { return strings.TrimSpace(a.Val) }
+    }
+    return ""
+}
+
+// originOf returns scheme://host[:port] with default ports removed.
+func originOf(u *url.URL) string {
+    scheme := strings.ToLower(u.Scheme)
+    host := strings.ToLower(u.Hostname())
+    if p := u.Port(); p != "" && !(scheme == "http" && p == "80") && !(scheme == "https" && p == "443") {
+        host += ":" + p
+    }
+    return scheme + "://" + host
+}
+
+// canonicalURL normalizes a URL for deduplication: lowercase origin, no fragment,
+// cleaned path without trailing slash, sorted query without tracking parameters.
+func canonicalURL(u *url.URL) string {
+    p := u.EscapedPath()
+    if p == "" { p = "/" }
+    p = path.Clean(p)
+    q := u.Query()
+    for k := range q {
+        if strings.HasPrefix(strings.ToLower(k), "utm_") { q.Del(k) }
+    }
+    keys := make([]string, 0, len(q))
+    for k := range q { keys = append(keys, k) }
+    sort.Strings(keys)
+    var qs []string
+    for _, k := range keys {
+        vs := q[k]
+        sort.Strings(vs)
+        for _, v := range vs { qs = append(qs, url.QueryEscape(k)+"="+url.QueryEscape(v)) }
+    }
+    out := originOf(u) + p
+    if len(qs) > 0 { out += "?" + strings.Join(qs, "&") }
+    return out
+}
+
+// hostLimiter spaces out requests to the same host by at least delay.
+type hostLimiter struct {
+    mu    sync.Mutex
+    delay time.Duration
+    next  map[string]time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+    return &hostLimiter{delay: delay, next: map[string]time.Time{}}
+}
+
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+    l.mu.Lock()
+    now := time.Now()
+    at := l.next[host]
+    if at.Before(now) { at = now }
+    l.next[host] = at.Add(l.delay)
+    l.mu.Unlock()
+    if d := time.Until(at); d > 0 {
+        timer := time.NewTimer(d)
+        defer timer.Stop()
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-timer.C:
+        }
+    }
+    return nil
+}
+
+// robotsRules holds the Allow/Disallow rules that apply to our user agent.
+type robotsRules struct {
+    allow    []robotsRule
+    disallow []robotsRule
+    delay    time.Duration
+}
+
+// robotsRule is one Allow or Disallow path pattern, compiled when parsed.
+type robotsRule struct {
+    pattern string
+    re      *regexp.Regexp
+}
+
+// fetchRobots loads /robots.txt for the start URL's origin. A missing or unreadable
+// file allows everything.
+func (c *crawler) fetchRobots(ctx context.Context, start *url.URL) *robotsRules {
+    ru, _ := url.Parse(originOf(start) + "/robots.txt")
+    resp, err := c.get(ctx, ru)
+    if err != nil { return &robotsRules{} }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 { return &robotsRules{} }
+    return parseRobots(io.LimitReader(resp.Body, 512<<10), "ensemble-crawler")
+}
+
+// parseRobots extracts the group for agent: the one with the longest user-agent token
+// contained in agent (the earliest on a tie), falling back to the "*" group.
+func parseRobots(r io.Reader, agent string) *robotsRules {
+    groups := map[string]*robotsRules{}
+    var order []string
+    var current []string
+    inRules := false
+    sc := bufio.NewScanner(r)
+    for sc.Scan() {
+        line := sc.Text()
+        if i := strings.Index(line, "#"); i != -1 { line = line[:i] }
+        key, val, ok := strings.Cut(line, ":")
+        if !ok { continue }
+        key = strings.ToLower(strings.TrimSpace(key))
+        val = strings.TrimSpace(val)
+        switch key {
+        case "user-agent":
+            // consecutive user-agent lines share one group
+            if inRules { current = nil; inRules = false }
+            ua := strings.ToLower(val)
+            current = append(current, ua)
+            if groups[ua] == nil { groups[ua] = &robotsRules{}; order = append(order, ua) }
+        case "allow", "disallow", "crawl-delay":
+            inRules = true
+            var rule robotsRule
+            if key != "crawl-delay" && val != "" {
+                re, err := compileRobots(val)
+                if err != nil { continue }
+                rule = robotsRule{pattern: val, re: re}
+            }
+            for _, ua := range current {
+                g := groups[ua]
+                switch key {
+                case "allow":
+                    if rule.re != nil { g.allow = append(g.allow, rule) }
+                case "disallow":
+                    if rule.re != nil { g.disallow = append(g.disallow, rule) }
+                case "crawl-delay":
+                    if secs, err := strconv.ParseFloat(val, 64); err == nil { g.delay = time.Duration(secs * float64(time.Second)) }
+                }
+            }
+        }
+    }
+    agent = strings.ToLower(agent)
+    best := ""
+    for _, ua := range order {
+        if ua != "*" && strings.Contains(agent, ua) && len(ua) > len(best) { best = ua }
+    }
+    if best != "" { return groups[best] }
+    if g := groups["*"]; g != nil { return g }
+    return &robotsRules{}
+}
+
+// allowed applies longest-match semantics; Allow wins a tie.
+func (r *robotsRules) allowed(u *url.URL) bool {
+    p := u.EscapedPath()
+    if p == "" { p = "/" }
+    if u.RawQuery != "" { p += "?" + u.RawQuery }
+    best, allow := -1, true
+    for _, rule := range r.disallow {
+        if rule.re.MatchString(p) && len(rule.pattern) > best { best, allow = len(rule.pattern), false }
+    }
+    for _, rule := range r.allow {
+        if rule.re.MatchString(p) && len(rule.pattern) >= best { best, allow = len(rule.pattern), true }
+    }
+    return allow
+}
+
+// compileRobots compiles a robots.txt path pattern supporting '*' and a trailing '$'.
+func compileRobots(pattern string) (*regexp.Regexp, error) {
+    expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSuffix(pattern, "$")), `\*`, ".*")
+    if strings.HasSuffix(pattern, "$") { expr += "$" }
+    return regexp.Compile(expr)
+}
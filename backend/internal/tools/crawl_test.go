@@ -0,0 +1,105 @@
+package tools
+
+import (
+    "context"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "testing"
+)
+
+func TestParseRobotsPicksMostSpecificGroup(t *testing.T) {
+    txt := `
+User-agent: *
+Disallow: /all
+
+User-agent: ensemble
+Disallow: /short
+
+User-agent: ensemble-crawler
+Disallow: /long
+Crawl-delay: 2
+`
+    // the longest matching user-agent wins on every run, whatever the map order
+    for i := 0; i < 50; i++ {
+        r := parseRobots(strings.NewReader(txt), "ensemble-crawler")
+        if len(r.disallow) != 1 || r.disallow[0].pattern != "/long" { t.Fatalf("run %d: got %+v", i, r.disallow) }
+        if r.delay.Seconds() != 2 { t.Fatalf("delay = %v", r.delay) }
+    }
+    r := parseRobots(strings.NewReader(txt), "other-bot")
+    if len(r.disallow) != 1 || r.disallow[0].pattern != "/all" { t.Fatalf("fallback: got %+v", r.disallow) }
+}
+
+func TestRobotsAllowed(t *testing.T) {
+    r := parseRobots(strings.NewReader("User-agent: *\nDisallow: /private\nAllow: /private/ok\nDisallow: /*.pdf$\n"), crawlUserAgent)
+    for path, want := range map[string]bool{
+        "/":               true,
+        "/private/x":      false,
+        "/private/ok/doc": true,
+        "/files/a.pdf":    false,
+        "/files/a.pdf?x":  true,
+    } {
+        u, _ := url.Parse("http://example.test" + path)
+        if got := r.allowed(u); got != want { t.Errorf("allowed(%s) = %v, want %v", path, got, want) }
+    }
+}
+
+func TestCrawl(t *testing.T) {
+    var srv *httptest.Server
+    srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/robots.txt":
+            fmt.Fprint(w, "User-agent: *\nDisallow: /secret\n")
+        case "/":
+            fmt.Fprint(w, `<html><title>Home</title><a href="/a">a</a><a href="/a#top">a again</a><a href="/secret">s</a><a href="https://other.test/">off</a></html>`)
+        case "/a":
+            fmt.Fprint(w, `<html><title>A</title><p>page a</p><a href="/b?utm_source=x">b</a></html>`)
+        case "/b":
+            fmt.Fprint(w, `<html><title>B</title><a href="/c">c</a></html>`)
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+    defer srv.Close()
+
+    var progress []string
+    ctx := context.WithValue(context.Background(), CtxTokenCallbackKey, TokenCallback(func(s string) { progress = append(progress, s) }))
+    out, logs, err := (&CrawlTool{}).Execute(ctx, map[string]any{"url": srv.URL + "/", "max_depth": 2, "delay_ms": 0})
+    if err != nil { t.Fatal(err) }
+    pages := out.([]CrawlPage)
+    var got []string
+    for _, p := range pages { got = append(got, fmt.Sprintf("%s@%d", p.Title, p.Depth)) }
+    if strings.Join(got, ",") != "Home@0,A@1,B@2" { t.Fatalf("pages = %v (%s)", got, logs) }
+    if !strings.Contains(logs, "robots_skipped=1") { t.Errorf("logs = %s", logs) }
+    if len(progress) != len(pages) { t.Errorf("progress = %q", progress) }
+}
+
+func TestCrawlCanonicalAndDelay(t *testing.T) {
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/robots.txt":
+            fmt.Fprint(w, "User-agent: *\nCrawl-delay: 3600\n")
+        case "/":
+            fmt.Fprint(w, `<html><title>Home</title><a href="/a">a</a><a href="/gone">gone</a><a href="/copy">copy</a></html>`)
+        case "/a":
+            // declares a page that cannot be fetched as its canonical one
+            fmt.Fprint(w, `<html><title>A</title><link rel="canonical" href="/gone"></html>`)
+        case "/copy":
+            fmt.Fprint(w, `<html><title>Copy</title><link rel="canonical" href="/a"></html>`)
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+    defer srv.Close()
+    t.Setenv("CRAWL_MAX_DELAY_MS", "1")
+    // the hour robots.txt asks for is cut to the limit; a page is only dropped as a
+    // duplicate of a page that was fetched
+    out, logs, err := (&CrawlTool{}).Execute(context.Background(), map[string]any{"url": srv.URL + "/", "delay_ms": 0})
+    if err != nil { t.Fatal(err) }
+    var got []string
+    for _, p := range out.([]CrawlPage) { got = append(got, p.Title) }
+    if strings.Join(got, ",") != "Home,A" { t.Fatalf("pages = %v (%s)", got, logs) }
+    if !strings.Contains(logs, "crawl_delay=1ms (robots.txt asks 1h0m0s)") { t.Errorf("logs = %s", logs) }
+}
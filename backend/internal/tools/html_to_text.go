@@ -12,14 +12,18 @@ type HTMLToTextTool struct{}
 func (t *HTMLToTextTool) Name() string { return "html_to_text" }
 
 func (t *HTMLToTextTool) Execute(ctx context.Context, inputs map[string]any) (any, string, error) {
-    htmlStr, _ := inputs["html"].(string)
+    htmlStr := stringInput(inputs, "html")
     if htmlStr == "" { return "", "", nil }
     node, err := html.Parse(strings.NewReader(htmlStr))
     if err != nil { return "", "", err }
+    return nodeText(node), "", nil
+}
+
+// nodeText returns the readable text of a parsed HTML tree.
+func nodeText(node *html.Node) string {
     var b strings.Builder
     extractText(node, &b, false)
-    out := strings.TrimSpace(compactWhitespace(b.String()))
-    return out, "", nil
+    return strings.TrimSpace(compactWhitespace(b.String()))
 }
 
 func extractText(n *html.Node, b *strings.Builder, inHidden bool) {
@@ -57,4 +61,3 @@ func compactWhitespace(s string) string {
     }
     return strings.Join(out, "\n")
 }
-
@@ -15,6 +15,9 @@ type HTTPPostJSONTool struct{}
 
 func (h *HTTPPostJSONTool) Name() string { return "http_post_json" }
 
+// Risk is high: a POST can change state in the systems it reaches.
+func (h *HTTPPostJSONTool) Risk() Risk { return RiskHigh }
+
 func (h *HTTPPostJSONTool) Execute(ctx context.Context, inputs map[string]any) (any, string, error) {
     rawURL, _ := inputs["url"].(string)
     if rawURL == "" { return nil, "", fmt.Errorf("missing url") }
@@ -28,6 +28,6 @@ func (h *HTTPGetTool) Execute(ctx context.Context, inputs map[string]any) (any,
     }
     defer resp.Body.Close()
     b, _ := io.ReadAll(resp.Body)
-    return string(b), fmt.Sprintf("status=%d", resp.StatusCode), nil
+    return string(b), fmt.Sprintf("status=%d content_type=%s", resp.StatusCode, resp.Header.Get("Content-Type")), nil
 }
 
@@ -0,0 +1,9 @@
+package tools
+
+import "github.com/example/agent-orchestrator/internal/templating"
+
+// stringInput reads a text input. References that resolve to structured values (a crawl
+// result, an extracted object) are rendered as JSON so text tools still accept them.
+func stringInput(inputs map[string]any, key string) string {
+    return templating.Stringify(inputs[key])
+}
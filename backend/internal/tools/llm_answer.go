@@ -13,11 +13,11 @@ func (t *LLMAnswerTool) Name() string { return "llm_answer" }
 
 func (t *LLMAnswerTool) Execute(ctx context.Context, inputs map[string]any) (any, string, error) {
     // accept either "text" or "question"
-    q, _ := inputs["text"].(string)
-    if q == "" { q, _ = inputs["question"].(string) }
+    q := stringInput(inputs, "text")
+    if q == "" { q = stringInput(inputs, "question") }
     if q == "" { return nil, "", fmt.Errorf("missing text/question") }
     // optional instructions
-    inst, _ := inputs["instructions"].(string)
+    inst := stringInput(inputs, "instructions")
     prompt := q
     if inst != "" { prompt = inst + "\n\nQuestion:\n" + q }
     if cb, ok := ctx.Value(CtxTokenCallbackKey).(TokenCallback); ok && cb != nil {
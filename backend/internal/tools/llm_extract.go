@@ -0,0 +1,91 @@
+package tools
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+
+    "github.com/example/agent-orchestrator/internal/jsonschema"
+    "github.com/example/agent-orchestrator/internal/providers/llm"
+)
+
+// LLMExtractTool asks the LLM for a JSON value matching a JSON Schema. Invalid answers
+// are retried with the validation errors fed back to the model. Providers with native
+// structured output (llm.StructuredClient) are used in that mode.
+type LLMExtractTool struct{ Client llm.Client }
+
+func (t *LLMExtractTool) Name() string { return "llm_extract" }
+
+func (t *LLMExtractTool) Execute(ctx context.Context, inputs map[string]any) (any, string, error) {
+    text := stringInput(inputs, "text")
+    if text == "" { return nil, "", fmt.Errorf("missing text") }
+    schema, err := jsonschema.Parse(inputs["schema"])
+    if err != nil { return nil, "", err }
+    schemaJSON, _ := json.MarshalIndent(schema, "", "  ")
+    inst := stringInput(inputs, "instructions")
+    maxRetries := getInt(inputs, "max_retries", 2)
+    if maxRetries < 0 { return nil, "", fmt.Errorf("max_retries must not be negative, got %d", maxRetries) }
+
+    structured, native := t.Client.(llm.StructuredClient)
+    var lastRaw string
+    var lastErr error
+    for attempt := 0; attempt <= maxRetries; attempt++ {
+        prompt := buildExtractPrompt(inst, string(schemaJSON), text, lastRaw, lastErr)
+        var raw string
+        if native {
+            raw, err = structured.GenerateJSON(ctx, prompt, schema)
+            if errors.Is(err, llm.ErrStructuredUnsupported) {
+                native = false
+                raw, err = t.Client.GenerateText(ctx, prompt)
+            }
+        } else {
+            raw, err = t.Client.GenerateText(ctx, prompt)
+        }
+        if err != nil { return nil, "", err }
+        var out any
+        if err := json.Unmarshal([]byte(extractJSONText(raw)), &out); err != nil {
+            lastRaw, lastErr = raw, fmt.Errorf("response is not valid JSON: %v", err)
+            continue
+        }
+        if err := jsonschema.Validate(schema, out); err != nil {
+            lastRaw, lastErr = raw, err
+            continue
+        }
+        return out, fmt.Sprintf("attempts=%d native=%t", attempt+1, native), nil
+    }
+    return nil, fmt.Sprintf("attempts=%d native=%t", maxRetries+1, native), fmt.Errorf("extraction failed validation: %v", lastErr)
+}
+
+func buildExtractPrompt(instructions, schema, text, prevRaw string, prevErr error) string {
+    var b strings.Builder
+    if instructions != "" { b.WriteString(instructions + "\n\n") }
+    b.WriteString("Extract data from the text below. Respond with JSON only (no prose, no code fences) that validates against this JSON Schema:\n")
+    b.WriteString(schema)
+    b.WriteString("\n\nUse null for values that are absent from the text only if the schema allows it; never invent data.\n")
+    if prevErr != nil {
+        fmt.Fprintf(&b, "\nYour previous answer was rejected.\nPrevious answer:\n%s\nProblems:\n%s\nReturn a corrected answer.\n", prevRaw, prevErr)
+    }
+    b.WriteString("\nText:\n")
+    b.WriteString(text)
+    return b.String()
+}
+
+// extractJSONText strips code fences and surrounding prose from a model answer.
+func extractJSONText(s string) string {
+    t := strings.TrimSpace(s)
+    if strings.HasPrefix(t, "```") {
+        t = strings.TrimPrefix(t, "```")
+        if i := strings.IndexByte(t, '\n'); i != -1 { t = t[i+1:] }
+        if j := strings.LastIndex(t, "```"); j != -1 { t = t[:j] }
+        t = strings.TrimSpace(t)
+    }
+    if json.Valid([]byte(t)) { return t }
+    // fall back to the outermost object or array in the text
+    start := strings.IndexAny(t, "{[")
+    if start == -1 { return t }
+    end := strings.LastIndexAny(t, "}]")
+    if end <= start { return t }
+    return t[start : end+1]
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/providers/llm"
+)
+
+// scriptedClient answers GenerateText with the next scripted reply and keeps the prompts.
+// It has no native structured output, so llm_extract falls back to prompting.
+type scriptedClient struct {
+    llm.MockClient
+    replies []string
+    prompts []string
+}
+
+func (c *scriptedClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+    c.prompts = append(c.prompts, prompt)
+    r := c.replies[0]
+    if len(c.replies) > 1 { c.replies = c.replies[1:] }
+    return r, nil
+}
+
+func (c *scriptedClient) GenerateJSON(ctx context.Context, prompt string, schema map[string]any) (string, error) {
+    return "", llm.ErrStructuredUnsupported
+}
+
+var personSchema = map[string]any{
+    "type":     "object",
+    "required": []any{"name", "age"},
+    "properties": map[string]any{
+        "name": map[string]any{"type": "string"},
+        "age":  map[string]any{"type": "integer", "minimum": 0},
+    },
+}
+
+func TestLLMExtractRetriesWithProblems(t *testing.T) {
+    c := &scriptedClient{replies: []string{"not json", `{"name": "Ada"}`, "```json\n{\"name\": \"Ada\", \"age\": 36}\n```"}}
+    out, logs, err := (&LLMExtractTool{Client: c}).Execute(context.Background(), map[string]any{"text": "Ada is 36.", "schema": personSchema})
+    if err != nil { t.Fatal(err) }
+    if m := out.(map[string]any); m["name"] != "Ada" || m["age"] != float64(36) { t.Fatalf("out = %v", out) }
+    if logs != "attempts=3 native=false" { t.Errorf("logs = %q", logs) }
+    if !strings.Contains(c.prompts[1], "not valid JSON") || !strings.Contains(c.prompts[2], "age") { t.Errorf("retries lack feedback: %q", c.prompts[1:]) }
+}
+
+func TestLLMExtractGivesUp(t *testing.T) {
+    c := &scriptedClient{replies: []string{`{"name": 1}`}}
+    _, _, err := (&LLMExtractTool{Client: c}).Execute(context.Background(), map[string]any{"text": "x", "schema": personSchema, "max_retries": 1})
+    if err == nil || !strings.Contains(err.Error(), "$.name") { t.Fatalf("err = %v", err) }
+    if len(c.prompts) != 2 { t.Errorf("%d attempts, want 2", len(c.prompts)) }
+}
+
+func TestLLMExtractRejectsNegativeRetries(t *testing.T) {
+    c := &scriptedClient{replies: []string{`{}`}}
+    _, _, err := (&LLMExtractTool{Client: c}).Execute(context.Background(), map[string]any{"text": "x", "schema": personSchema, "max_retries": -1})
+    if err == nil || !strings.Contains(err.Error(), "max_retries") { t.Fatalf("err = %v", err) }
+    if len(c.prompts) != 0 { t.Errorf("called the model %d times", len(c.prompts)) }
+}
+
+func TestExtractJSONText(t *testing.T) {
+    for in, want := range map[string]string{
+        `{"a":1}`:                       `{"a":1}`,
+        "```json\n[1,2]\n```":           `[1,2]`,
+        `Here you go: {"a": {"b": 2}}.`: `{"a": {"b": 2}}`,
+    } {
+        if got := extractJSONText(in); got != want { t.Errorf("extractJSONText(%q) = %q, want %q", in, got, want) }
+    }
+}
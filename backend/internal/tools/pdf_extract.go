@@ -59,8 +59,9 @@ func (t *PDFExtractTool) Execute(ctx context.Context, inputs map[string]any) (an
         t := strings.TrimSpace(txt)
         if t != "" {
             if cb != nil { cb(fmt.Sprintf("\n\n--- Page %d ---\n%s", page, t)) }
+            // pages are separated by a form feed so summarize can chunk by page
+            if out.Len() > 0 { out.WriteString("\n\f\n") }
             out.WriteString(t)
-            out.WriteString("\n\n")
         }
     }
     text := strings.TrimSpace(out.String())
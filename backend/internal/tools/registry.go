@@ -1,6 +1,9 @@
 package tools
 
-import "context"
+import (
+    "context"
+    "sort"
+)
 
 type Tool interface {
     Name() string
@@ -9,10 +12,11 @@ type Tool interface {
 
 type Registry struct {
     tools map[string]Tool
+    risks map[string]Risk
 }
 
 func NewRegistry() *Registry {
-    return &Registry{tools: map[string]Tool{}}
+    return &Registry{tools: map[string]Tool{}, risks: map[string]Risk{}}
 }
 
 func (r *Registry) Register(t Tool) {
@@ -24,3 +28,21 @@ func (r *Registry) Get(name string) (Tool, bool) {
     return t, ok
 }
 
+
+// Names lists the registered tools, sorted.
+func (r *Registry) Names() []string {
+    out := make([]string, 0, len(r.tools))
+    for name := range r.tools { out = append(out, name) }
+    sort.Strings(out)
+    return out
+}
+
+// SetRisk overrides the risk a tool reports for itself.
+func (r *Registry) SetRisk(name string, risk Risk) { r.risks[name] = risk }
+
+// Risk returns a tool's risk: an override, else what the tool reports, else low.
+func (r *Registry) Risk(name string) Risk {
+    if risk, ok := r.risks[name]; ok { return risk }
+    if c, ok := r.tools[name].(RiskClassifier); ok { return c.Risk() }
+    return RiskLow
+}
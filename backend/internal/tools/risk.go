@@ -0,0 +1,43 @@
+package tools
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Risk classifies how much damage a tool call can do.
+type Risk string
+
+const (
+    RiskLow    Risk = "low"
+    RiskMedium Risk = "medium"
+    RiskHigh   Risk = "high"
+)
+
+// RiskClassifier is implemented by tools with side effects; tools that do not
+// implement it are low risk.
+type RiskClassifier interface {
+    Risk() Risk
+}
+
+func (r Risk) level() int {
+    switch r {
+    case RiskHigh:
+        return 3
+    case RiskMedium:
+        return 2
+    }
+    return 1
+}
+
+// AtLeast reports whether r is as risky as min.
+func (r Risk) AtLeast(min Risk) bool { return r.level() >= min.level() }
+
+// ParseRisk accepts low, medium or high.
+func ParseRisk(s string) (Risk, error) {
+    switch r := Risk(strings.ToLower(strings.TrimSpace(s))); r {
+    case RiskLow, RiskMedium, RiskHigh:
+        return r, nil
+    }
+    return "", fmt.Errorf("unknown risk %q (want low, medium or high)", s)
+}
@@ -0,0 +1,17 @@
+package tools
+
+import "testing"
+
+func TestRisk(t *testing.T) {
+    if !RiskHigh.AtLeast(RiskMedium) || RiskLow.AtLeast(RiskMedium) || !RiskMedium.AtLeast(RiskMedium) { t.Fatal("AtLeast ordering") }
+    if r, err := ParseRisk(" High "); err != nil || r != RiskHigh { t.Fatalf("ParseRisk: %v %v", r, err) }
+    if _, err := ParseRisk("severe"); err == nil { t.Fatal("unknown risk accepted") }
+
+    r := NewRegistry()
+    r.Register(&EchoTool{})
+    r.Register(&HTTPPostJSONTool{})
+    if r.Risk("echo") != RiskLow || r.Risk("http_post_json") != RiskHigh || r.Risk("unknown") != RiskLow { t.Fatal("default risks") }
+    r.SetRisk("echo", RiskMedium)
+    r.SetRisk("http_post_json", RiskLow)
+    if r.Risk("echo") != RiskMedium || r.Risk("http_post_json") != RiskLow { t.Fatal("overrides ignored") }
+}
@@ -3,27 +3,230 @@ package tools
 import (
     "context"
     "fmt"
+    "regexp"
+    "strings"
+    "sync"
+    "unicode/utf8"
 
     "github.com/example/agent-orchestrator/internal/providers/llm"
 )
 
 type SummarizeTool struct{ Client llm.Client }
 
+// SummaryChunk is the summary of one slice of a long input.
+type SummaryChunk struct {
+    Index   int    `json:"index"`
+    Chars   int    `json:"chars"`
+    Summary string `json:"summary"`
+}
+
+// MapReduceSummary is the output for inputs too large for a single prompt. The chunk
+// summaries are kept so callers can drill down; String returns the final summary so
+// {{step:ID.output}} keeps passing plain text to later steps.
+type MapReduceSummary struct {
+    Summary  string         `json:"summary"`
+    Strategy string         `json:"strategy"`
+    Levels   int            `json:"levels"`
+    Chunks   []SummaryChunk `json:"chunks"`
+}
+
+func (m *MapReduceSummary) String() string { return m.Summary }
+
+const summarizePrompt = "Summarize the following text in a concise way (3-5 bullet points or a short paragraph). Focus on key facts.\n\nText:\n%s"
+
 func (s *SummarizeTool) Name() string { return "summarize" }
 
 func (s *SummarizeTool) Execute(ctx context.Context, inputs map[string]any) (any, string, error) {
-    text, _ := inputs["text"].(string)
+    text := stringInput(inputs, "text")
     if text == "" {
         return nil, "", fmt.Errorf("missing text")
     }
-    prompt := fmt.Sprintf("Summarize the following text in a concise way (3-5 bullet points or a short paragraph). Focus on key facts.\n\nText:\n%s", text)
-    if cb, ok := ctx.Value(CtxTokenCallbackKey).(TokenCallback); ok && cb != nil {
-        var acc string
-        err := s.Client.GenerateTextStream(ctx, prompt, func(chunk string) error { acc += chunk; cb(chunk); return nil })
+    var cb TokenCallback
+    if fn, ok := ctx.Value(CtxTokenCallbackKey).(TokenCallback); ok { cb = fn }
+
+    // roughly 4 characters (runes, not bytes) per token
+    chunkTokens := getInt(inputs, "chunk_tokens", envInt("SUMMARIZE_CHUNK_TOKENS", 3000))
+    if chunkTokens < 1 { return nil, "", fmt.Errorf("chunk_tokens must be at least 1, got %d", chunkTokens) }
+    maxChars := chunkTokens * 4
+    if utf8.RuneCountInString(text) <= maxChars {
+        out, err := s.generate(ctx, fmt.Sprintf(summarizePrompt, text), cb)
         if err != nil { return nil, "", err }
-        return acc, "", nil
+        return out, "", nil
+    }
+
+    strategy, _ := inputs["chunk_by"].(string)
+    if strategy == "" { strategy = "paragraphs" }
+    chunks, err := splitChunks(text, strategy, maxChars)
+    if err != nil { return nil, "", err }
+    concurrency := getInt(inputs, "concurrency", envInt("SUMMARIZE_CONCURRENCY", 4))
+    if concurrency < 1 { concurrency = 1 }
+    progress := func(format string, args ...any) {
+        if cb != nil { cb(fmt.Sprintf(format, args...)) }
     }
-    out, err := s.Client.GenerateText(ctx, prompt)
+
+    // map: summarize every chunk independently
+    mapped, err := s.summarizeAll(ctx, chunks, concurrency, func(i int, chunk string) string {
+        return fmt.Sprintf("This is part %d of %d of a longer document. Summarize the key facts of this part concisely; do not add an introduction.\n\nText:\n%s", i+1, len(chunks), chunk)
+    }, func(done int) { progress("[summarize] chunk %d/%d done\n", done, len(chunks)) })
     if err != nil { return nil, "", err }
-    return out, "", nil
+    result := &MapReduceSummary{Strategy: strategy, Chunks: make([]SummaryChunk, len(chunks))}
+    for i := range chunks {
+        result.Chunks[i] = SummaryChunk{Index: i, Chars: utf8.RuneCountInString(chunks[i]), Summary: mapped[i]}
+    }
+
+    // reduce: combine summaries level by level until one prompt can hold them all
+    combinePrompt := func(_ int, joined string) string {
+        return fmt.Sprintf("Combine the following partial summaries of one document into a single concise summary (3-5 bullet points or a short paragraph). Remove repetition and keep the key facts.\n\nPartial summaries:\n%s", joined)
+    }
+    level := mapped
+    for {
+        result.Levels++
+        groups := groupSummaries(level, maxChars)
+        if len(groups) == 1 {
+            progress("[summarize] combining %d summaries\n", len(level))
+            out, err := s.generate(ctx, combinePrompt(0, groups[0]), cb)
+            if err != nil { return nil, "", err }
+            result.Summary = out
+            break
+        }
+        progress("[summarize] reduce level %d: %d groups\n", result.Levels, len(groups))
+        level, err = s.summarizeAll(ctx, groups, concurrency, combinePrompt, nil)
+        if err != nil { return nil, "", err }
+    }
+    logs := fmt.Sprintf("chunks=%d levels=%d strategy=%s", len(chunks), result.Levels, strategy)
+    return result, logs, nil
+}
+
+// generate runs a single prompt, streaming through cb when one is set.
+func (s *SummarizeTool) generate(ctx context.Context, prompt string, cb TokenCallback) (string, error) {
+    if cb != nil {
+        var acc string
+        err := s.Client.GenerateTextStream(ctx, prompt, func(chunk string) error { acc += chunk; cb(chunk); return nil })
+        return acc, err
+    }
+    return s.Client.GenerateText(ctx, prompt)
+}
+
+// summarizeAll runs one prompt per part with at most concurrency requests in flight.
+// The first error cancels the remaining work.
+func (s *SummarizeTool) summarizeAll(ctx context.Context, parts []string, concurrency int, prompt func(i int, part string) string, onDone func(done int)) ([]string, error) {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    out := make([]string, len(parts))
+    sem := make(chan struct{}, concurrency)
+    var (
+        wg       sync.WaitGroup
+        mu       sync.Mutex
+        done     int
+        firstErr error
+    )
+    for i, part := range parts {
+        wg.Add(1)
+        go func(i int, part string) {
+            defer wg.Done()
+            select {
+            case sem <- struct{}{}:
+            case <-ctx.Done():
+                return
+            }
+            defer func() { <-sem }()
+            txt, err := s.Client.GenerateText(ctx, prompt(i, part))
+            mu.Lock()
+            defer mu.Unlock()
+            if err != nil {
+                if firstErr == nil { firstErr = fmt.Errorf("part %d: %w", i+1, err); cancel() }
+                return
+            }
+            out[i] = strings.TrimSpace(txt)
+            done++
+            if onDone != nil { onDone(done) }
+        }(i, part)
+    }
+    wg.Wait()
+    if firstErr != nil { return nil, firstErr }
+    if err := ctx.Err(); err != nil { return nil, err }
+    return out, nil
+}
+
+var paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+
+// splitChunks cuts text into pieces of at most maxChars runes. "paragraphs" and
+// "pages" (form feed separated, as produced by pdf_extract) pack whole units greedily;
+// "tokens" packs words.
+func splitChunks(text, strategy string, maxChars int) ([]string, error) {
+    switch strategy {
+    case "tokens":
+        return packUnits(strings.Fields(text), " ", maxChars), nil
+    case "paragraphs":
+        return packUnits(paragraphBreak.Split(text, -1), "\n\n", maxChars), nil
+    case "pages":
+        if !strings.Contains(text, "\f") {
+            return packUnits(paragraphBreak.Split(text, -1), "\n\n", maxChars), nil
+        }
+        return packUnits(strings.Split(text, "\f"), "\n\n", maxChars), nil
+    default:
+        return nil, fmt.Errorf("unknown chunk_by %q (want tokens, paragraphs or pages)", strategy)
+    }
+}
+
+func packUnits(units []string, sep string, maxChars int) []string {
+    var chunks []string
+    var cur strings.Builder
+    size, sepSize := 0, utf8.RuneCountInString(sep)
+    flush := func() {
+        if cur.Len() > 0 { chunks = append(chunks, cur.String()); cur.Reset(); size = 0 }
+    }
+    for _, u := range units {
+        u = strings.TrimSpace(u)
+        if u == "" { continue }
+        n := utf8.RuneCountInString(u)
+        if n > maxChars {
+            // an oversized unit falls back to word packing, a single oversized word
+            // (a URL, a base64 blob) to cutting it
+            flush()
+            if words := strings.Fields(u); len(words) > 1 {
+                chunks = append(chunks, packUnits(words, " ", maxChars)...)
+            } else {
+                chunks = append(chunks, splitRunes(u, maxChars)...)
+            }
+            continue
+        }
+        if size > 0 && size+sepSize+n > maxChars { flush() }
+        if size > 0 { cur.WriteString(sep); size += sepSize }
+        cur.WriteString(u)
+        size += n
+    }
+    flush()
+    return chunks
+}
+
+// splitRunes cuts s into pieces of at most maxChars runes.
+func splitRunes(s string, maxChars int) []string {
+    var pieces []string
+    start, n := 0, 0
+    for i := range s {
+        if n == maxChars { pieces = append(pieces, s[start:i]); start, n = i, 0 }
+        n++
+    }
+    if start < len(s) { pieces = append(pieces, s[start:]) }
+    return pieces
+}
+
+// groupSummaries joins summaries into groups that fit maxChars, keeping at least two
+// per group so every reduce level shrinks the list.
+func groupSummaries(summaries []string, maxChars int) []string {
+    var groups []string
+    var cur []string
+    size := 0
+    for _, s := range summaries {
+        n := utf8.RuneCountInString(s)
+        if len(cur) >= 2 && size+n > maxChars {
+            groups = append(groups, strings.Join(cur, "\n\n---\n\n"))
+            cur, size = nil, 0
+        }
+        cur = append(cur, s)
+        size += n
+    }
+    if len(cur) > 0 { groups = append(groups, strings.Join(cur, "\n\n---\n\n")) }
+    return groups
 }
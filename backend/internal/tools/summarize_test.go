@@ -0,0 +1,72 @@
+package tools
+
+import (
+    "context"
+    "strings"
+    "sync/atomic"
+    "testing"
+    "unicode/utf8"
+
+    "github.com/example/agent-orchestrator/internal/providers/llm"
+)
+
+// countingClient answers every prompt with a short summary and counts the calls.
+type countingClient struct {
+    llm.MockClient
+    calls atomic.Int32
+}
+
+func (c *countingClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+    c.calls.Add(1)
+    return "summary", nil
+}
+
+func TestSplitChunksLongWord(t *testing.T) {
+    // a word longer than the limit is cut into pieces that fit
+    word := strings.Repeat("a", 100)
+    for _, strategy := range []string{"tokens", "paragraphs", "pages"} {
+        chunks, err := splitChunks("intro\n\n"+word, strategy, 16)
+        if err != nil { t.Fatal(err) }
+        if strings.Join(chunks, "") != "intro"+word { t.Fatalf("%s: chunks = %q", strategy, chunks) }
+        for _, c := range chunks {
+            if n := utf8.RuneCountInString(c); n > 16 { t.Fatalf("%s: chunk of %d chars", strategy, n) }
+        }
+    }
+}
+
+func TestSplitRunesKeepsRunes(t *testing.T) {
+    s := strings.Repeat("é", 12) // 2 bytes each
+    pieces := splitRunes(s, 5)
+    if strings.Join(pieces, "") != s || len(pieces) != 3 { t.Fatalf("pieces = %q", pieces) }
+    for _, p := range pieces {
+        if !utf8.ValidString(p) || utf8.RuneCountInString(p) > 5 { t.Fatalf("bad piece %q", p) }
+    }
+}
+
+func TestSplitChunksCountsRunes(t *testing.T) {
+    // the limit counts characters: five words of two é and their spaces are 14
+    // characters, though 24 bytes
+    text := "éé éé éé éé éé"
+    chunks, err := splitChunks(text, "tokens", 14)
+    if err != nil { t.Fatal(err) }
+    if len(chunks) != 1 || chunks[0] != text { t.Fatalf("chunks = %q", chunks) }
+    if chunks, _ := splitChunks(text, "tokens", 13); len(chunks) != 2 { t.Fatalf("chunks = %q", chunks) }
+}
+
+func TestSummarizeMapReduce(t *testing.T) {
+    c := &countingClient{}
+    text := strings.Repeat("word ", 200) + strings.Repeat("x", 300)
+    out, logs, err := (&SummarizeTool{Client: c}).Execute(context.Background(), map[string]any{"text": text, "chunk_tokens": 10, "chunk_by": "tokens"})
+    if err != nil { t.Fatal(err) }
+    res, ok := out.(*MapReduceSummary)
+    if !ok { t.Fatalf("output %T", out) }
+    if res.Summary != "summary" || len(res.Chunks) < 2 || res.Levels < 1 { t.Fatalf("result = %+v (%s)", res, logs) }
+    if int(c.calls.Load()) < len(res.Chunks)+1 { t.Fatalf("%d calls for %d chunks", c.calls.Load(), len(res.Chunks)) }
+}
+
+func TestSummarizeRejectsChunkTokens(t *testing.T) {
+    for _, n := range []int{0, -5} {
+        _, _, err := (&SummarizeTool{Client: &countingClient{}}).Execute(context.Background(), map[string]any{"text": "hello", "chunk_tokens": n})
+        if err == nil || !strings.Contains(err.Error(), "chunk_tokens") { t.Fatalf("chunk_tokens=%d: err = %v", n, err) }
+    }
+}
@@ -0,0 +1,148 @@
+// Package wiring builds an orchestrator from its parts. The public ensemble package
+// fills a Config from its builder; the HTTP server and the offline runner in
+// cmd/ensemble fill one from the environment and keep the full orchestrator.
+package wiring
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/agents"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/providers/llm"
+    "github.com/example/agent-orchestrator/internal/store"
+    "github.com/example/agent-orchestrator/internal/tools"
+)
+
+// Config lists the parts of an engine. Nil parts get Build's defaults: a mock LLM,
+// the mock planner, a ToolExecutor over the tools and the simple verifier.
+type Config struct {
+    LLM      llm.Client
+    Builtins bool
+    // Tools replace built-ins of the same name.
+    Tools    []tools.Tool
+    Risks    map[string]tools.Risk
+    Planner  agents.Planner
+    Executor agents.Executor
+    Verifier agents.Verifier
+    Store    orchestrator.Store
+    // StoreDir is Store with a file store for the directory.
+    StoreDir string
+    Sinks    []orchestrator.EventSink
+    // Workers run queued tasks (at least one), at most MaxPerClient (0 = no limit)
+    // per client.
+    Workers         int
+    MaxPerClient    int
+    ApprovalRisk    tools.Risk
+    ApprovalTimeout time.Duration
+}
+
+// FromEnv is the server's wiring: built-in tools, the LLM client from LLM_PROVIDER and
+// API key variables, USE_LLM_PLANNER / USE_LLM_VERIFIER, TOOL_RISK=name=level,..., and
+// approval gates from APPROVAL_RISK (default high, "off" disables) and
+// APPROVAL_TIMEOUT. Invalid values are logged and ignored. Storage and sinks are
+// left unset, with 4 workers.
+func FromEnv() *Config {
+    c := &Config{LLM: llm.NewFromEnv(), Builtins: true, Risks: map[string]tools.Risk{}, Workers: 4, ApprovalRisk: tools.RiskHigh}
+    if os.Getenv("USE_LLM_PLANNER") == "1" { c.Planner = &agents.LLMPlanner{Client: c.LLM} }
+    if os.Getenv("USE_LLM_VERIFIER") == "1" { c.Verifier = &agents.LLMVerifier{Client: c.LLM} }
+    for _, kv := range strings.Split(os.Getenv("TOOL_RISK"), ",") {
+        name, level, ok := strings.Cut(strings.TrimSpace(kv), "=")
+        if !ok { continue }
+        risk, err := tools.ParseRisk(level)
+        if err != nil { log.Printf("TOOL_RISK %s: %v", name, err); continue }
+        c.Risks[name] = risk
+    }
+    if v := strings.TrimSpace(os.Getenv("APPROVAL_RISK")); v == "off" {
+        c.ApprovalRisk = ""
+    } else if v != "" {
+        risk, err := tools.ParseRisk(v)
+        if err != nil { log.Printf("APPROVAL_RISK: %v", err) } else { c.ApprovalRisk = risk }
+    }
+    if v := os.Getenv("APPROVAL_TIMEOUT"); v != "" {
+        d, err := time.ParseDuration(v)
+        if err != nil { log.Printf("APPROVAL_TIMEOUT: %v", err) } else { c.ApprovalTimeout = d }
+    }
+    return c
+}
+
+// BuiltinTools returns the server's tools, with LLM-backed ones using c.
+func BuiltinTools(c llm.Client) []tools.Tool {
+    return []tools.Tool{
+        &tools.EchoTool{},
+        &tools.HTTPGetTool{},
+        &tools.SummarizeTool{Client: c},
+        &tools.LLMAnswerTool{Client: c},
+        &tools.LLMExtractTool{Client: c},
+        &tools.HTMLToTextTool{},
+        &tools.HTTPPostJSONTool{},
+        &tools.PDFExtractTool{},
+        &tools.CrawlTool{},
+        &tools.AskUserTool{},
+    }
+}
+
+// Engine is a built orchestrator with its queue workers and sinks running.
+type Engine struct {
+    Orch *orchestrator.Orchestrator
+    // Restored is the number of tasks loaded from the store.
+    Restored int
+
+    stopOnce sync.Once
+    stops    []func()
+}
+
+// Build wires the parts, restores stored tasks (interrupted runs come back PAUSED)
+// and starts the queue workers and event sinks.
+func Build(c *Config) (*Engine, error) {
+    client := c.LLM
+    if client == nil { client = &llm.MockClient{} }
+    reg := tools.NewRegistry()
+    if c.Builtins {
+        for _, t := range BuiltinTools(client) { reg.Register(t) }
+    }
+    for _, t := range c.Tools {
+        if t == nil || t.Name() == "" { return nil, errors.New("tool without a name") }
+        reg.Register(t)
+    }
+    for name, risk := range c.Risks { reg.SetRisk(name, risk) }
+    planner, executor, verifier := c.Planner, c.Executor, c.Verifier
+    if planner == nil { planner = &agents.MockPlanner{} }
+    if executor == nil { executor = &agents.ToolExecutor{Registry: reg} }
+    if verifier == nil { verifier = &agents.SimpleVerifier{} }
+    o := orchestrator.New(planner, executor, verifier)
+    o.Tools = reg
+    o.ApprovalRisk, o.ApprovalTimeout = c.ApprovalRisk, c.ApprovalTimeout
+    e := &Engine{Orch: o}
+    st := c.Store
+    if c.StoreDir != "" {
+        fs, err := store.NewFileStore(c.StoreDir)
+        if err != nil { return nil, fmt.Errorf("store: %v", err) }
+        st = fs
+    }
+    if st != nil {
+        o.Store = st
+        // tasks that cannot be read are logged and skipped, like at server start
+        n, err := o.Restore()
+        if err != nil { log.Printf("restore tasks: %v", err) }
+        e.Restored = n
+    }
+    o.SetWorkers(c.Workers, c.MaxPerClient)
+    o.StartWorkers()
+    for _, s := range c.Sinks { e.stops = append(e.stops, o.AddSink(s)) }
+    return e, nil
+}
+
+// Close stops the queue workers (a running task finishes its run first) and event
+// delivery to sinks.
+func (e *Engine) Close() {
+    e.stopOnce.Do(func() {
+        e.Orch.StopWorkers()
+        for _, stop := range e.stops { stop() }
+    })
+}
@@ -0,0 +1,308 @@
+package workflows
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "math"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/example/agent-orchestrator/internal/models"
+    "gopkg.in/yaml.v3"
+)
+
+// File is the on-disk form of a workflow or a plain plan, in YAML or JSON:
+//
+//    name: fetch-page                 # defaults to the file name
+//    description: Fetch and summarize a page
+//    metadata: {owner: search-team}
+//    query: "summarize {{params.url}}"
+//    params:
+//      - {name: url, type: string, required: true}
+//    steps:
+//      - id: fetch
+//        tool: http_get
+//        inputs: {url: "{{params.url}}"}
+//        timeout: 30s
+//      - id: sum
+//        tool: summarize
+//        deps: [fetch]
+//        inputs: {text: "{{step:fetch.output}}"}
+//
+// Unknown fields are errors. A file without params is simply a plan.
+type File struct {
+    Name        string            `yaml:"name,omitempty" json:"name,omitempty"`
+    Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+    Metadata    map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
+    Query       string            `yaml:"query,omitempty" json:"query,omitempty"`
+    Params      []FileParam       `yaml:"params,omitempty" json:"params,omitempty"`
+    Steps       []*FileStep       `yaml:"steps" json:"steps"`
+}
+
+type FileParam struct {
+    Name        string `yaml:"name" json:"name"`
+    Type        string `yaml:"type" json:"type"`
+    Description string `yaml:"description,omitempty" json:"description,omitempty"`
+    Required    bool   `yaml:"required,omitempty" json:"required,omitempty"`
+    Default     any    `yaml:"default,omitempty" json:"default,omitempty"`
+    Enum        []any  `yaml:"enum,omitempty" json:"enum,omitempty"`
+}
+
+type FileStep struct {
+    ID          string         `yaml:"id" json:"id"`
+    Description string         `yaml:"description,omitempty" json:"description,omitempty"`
+    Tool        string         `yaml:"tool,omitempty" json:"tool,omitempty"`
+    Deps        []string       `yaml:"deps,omitempty" json:"deps,omitempty"`
+    Inputs      map[string]any `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+    If          string         `yaml:"if,omitempty" json:"if,omitempty"`
+    Timeout     string         `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+    Map         *FileMap       `yaml:"map,omitempty" json:"map,omitempty"`
+}
+
+type FileMap struct {
+    Over        string      `yaml:"over" json:"over"`
+    As          string      `yaml:"as,omitempty" json:"as,omitempty"`
+    Parallelism int         `yaml:"parallelism,omitempty" json:"parallelism,omitempty"`
+    Steps       []*FileStep `yaml:"steps" json:"steps"`
+}
+
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// ParseFile decodes and validates a workflow file. JSON is read as YAML, so both
+// report problems as "path:line: message" in an *Error.
+func (l *Library) ParseFile(path string, data []byte) (*Workflow, error) {
+    fail := func(line int, msg string) string { return fmt.Sprintf("%s:%d: %s", path, line, msg) }
+    dec := yaml.NewDecoder(bytes.NewReader(data))
+    dec.KnownFields(true)
+    var f File
+    if err := dec.Decode(&f); err != nil {
+        if errors.Is(err, io.EOF) { return nil, &Error{Problems: []string{path + ": empty file"}} }
+        var te *yaml.TypeError
+        msgs := []string{err.Error()}
+        if errors.As(err, &te) { msgs = te.Errors }
+        var problems []string
+        for _, m := range msgs {
+            if g := yamlLine.FindStringSubmatch(m); g != nil {
+                n, _ := strconv.Atoi(g[1])
+                problems = append(problems, fail(n, g[2]))
+            } else {
+                problems = append(problems, path+": "+strings.TrimPrefix(m, "yaml: "))
+            }
+        }
+        return nil, &Error{Problems: problems}
+    }
+    var root yaml.Node
+    yaml.Unmarshal(data, &root)
+    lines := indexLines(&root)
+    if f.Name == "" { f.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) }
+    w, err := f.workflow()
+    if err == nil { err = l.Check(w) }
+    var we *Error
+    if errors.As(err, &we) {
+        problems := make([]string, len(we.Problems))
+        for i, p := range we.Problems { problems[i] = fail(lines.locate(p, data), p) }
+        return nil, &Error{Problems: problems}
+    }
+    if err != nil { return nil, fmt.Errorf("%s: %v", path, err) }
+    w.Source = path
+    return w, nil
+}
+
+// workflow converts the file form. Values decoded from YAML are normalized to what
+// JSON would produce (float64 numbers, map[string]any objects).
+func (f *File) workflow() (*Workflow, error) {
+    w := &Workflow{Name: f.Name, Description: f.Description, Metadata: f.Metadata, Query: f.Query, Plan: &models.Plan{}}
+    var problems []string
+    norm := func(what string, v any) any {
+        out, err := normalize(v)
+        if err != nil { problems = append(problems, fmt.Sprintf("%s: %v", what, err)) }
+        return out
+    }
+    for _, p := range f.Params {
+        param := Param{Name: p.Name, Type: p.Type, Description: p.Description, Required: p.Required, Default: norm("parameter "+p.Name+": default", p.Default)}
+        if p.Enum != nil { param.Enum, _ = norm("parameter "+p.Name+": enum", p.Enum).([]any) }
+        w.Params = append(w.Params, param)
+    }
+    var convert func(in []*FileStep) []*models.Step
+    convert = func(in []*FileStep) []*models.Step {
+        var out []*models.Step
+        for _, fs := range in {
+            if fs == nil { out = append(out, nil); continue }
+            s := &models.Step{ID: fs.ID, Description: fs.Description, Tool: fs.Tool, Deps: fs.Deps, Condition: fs.If, Timeout: fs.Timeout}
+            if fs.Inputs != nil { s.Inputs, _ = norm("step "+fs.ID+": inputs", fs.Inputs).(map[string]any) }
+            if fs.Map != nil {
+                s.Map = &models.MapSpec{Over: fs.Map.Over, As: fs.Map.As, Parallelism: fs.Map.Parallelism, Steps: convert(fs.Map.Steps)}
+                if s.Tool == "" { s.Tool = "map" }
+            }
+            out = append(out, s)
+        }
+        return out
+    }
+    w.Plan.Steps = convert(f.Steps)
+    if len(problems) > 0 { return nil, &Error{Problems: problems} }
+    return w, nil
+}
+
+func normalize(v any) (any, error) {
+    if v == nil { return nil, nil }
+    b, err := json.Marshal(v)
+    if err != nil { return nil, fmt.Errorf("not representable as JSON: %v", err) }
+    var out any
+    err = json.Unmarshal(b, &out)
+    return out, err
+}
+
+// fileLines remembers where things are defined in a file, for error messages.
+type fileLines struct {
+    steps  map[string][]int // "ID" or "MAP.ID" -> lines of each definition
+    params map[string]int
+    name   int
+    first  int
+}
+
+func indexLines(root *yaml.Node) *fileLines {
+    fl := &fileLines{steps: map[string][]int{}, params: map[string]int{}, first: 1}
+    doc := root
+    if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 { doc = doc.Content[0] }
+    if doc.Kind != yaml.MappingNode { return fl }
+    fl.first = doc.Line
+    var walkSteps func(seq *yaml.Node, prefix string)
+    walkSteps = func(seq *yaml.Node, prefix string) {
+        if seq == nil || seq.Kind != yaml.SequenceNode { return }
+        for _, item := range seq.Content {
+            id := scalar(item, "id")
+            fl.steps[prefix+id] = append(fl.steps[prefix+id], item.Line)
+            if m := child(item, "map"); m != nil { walkSteps(child(m, "steps"), prefix+id+".") }
+        }
+    }
+    walkSteps(child(doc, "steps"), "")
+    if ps := child(doc, "params"); ps != nil && ps.Kind == yaml.SequenceNode {
+        for _, item := range ps.Content { fl.params[scalar(item, "name")] = item.Line }
+    }
+    if n := child(doc, "name"); n != nil { fl.name = n.Line }
+    return fl
+}
+
+// child returns the value of key in a mapping node.
+func child(n *yaml.Node, key string) *yaml.Node {
+    if n == nil || n.Kind != yaml.MappingNode { return nil }
+    for i := 0; i+1 < len(n.Content); i += 2 {
+        if n.Content[i].Value == key { return n.Content[i+1] }
+    }
+    return nil
+}
+
+func scalar(n *yaml.Node, key string) string {
+    if v := child(n, key); v != nil && v.Kind == yaml.ScalarNode { return v.Value }
+    return ""
+}
+
+var (
+    stepProblem  = regexp.MustCompile(`^((?:[\w-]+\.)*)(?:step ([\w-]+)|duplicate step id "([^"]*)")`)
+    paramProblem = regexp.MustCompile(`^parameter ([\w-]*)`)
+    paramRef     = regexp.MustCompile(`^\{\{params\.([\w-]+)\}\}`)
+)
+
+// locate finds the line a validation problem refers to, falling back to the start
+// of the document.
+func (fl *fileLines) locate(problem string, data []byte) int {
+    if g := stepProblem.FindStringSubmatch(problem); g != nil {
+        if g[3] != "" {
+            // a duplicate is reported at its second definition
+            if ls := fl.steps[g[1]+g[3]]; len(ls) > 1 { return ls[1] }
+        } else if ls := fl.steps[g[1]+g[2]]; len(ls) > 0 {
+            return ls[0]
+        }
+    }
+    if g := paramProblem.FindStringSubmatch(problem); g != nil {
+        if n, ok := fl.params[g[1]]; ok { return n }
+    }
+    if g := paramRef.FindStringSubmatch(problem); g != nil {
+        // the first use of the undeclared parameter
+        for i, line := range strings.Split(string(data), "\n") {
+            if strings.Contains(line, "params."+g[1]) { return i + 1 }
+        }
+    }
+    if strings.HasPrefix(problem, "invalid name") && fl.name > 0 { return fl.name }
+    return fl.first
+}
+
+// FileFor converts a workflow to its file form.
+func FileFor(w *Workflow) *File {
+    f := &File{Name: w.Name, Description: w.Description, Metadata: w.Metadata, Query: w.Query}
+    for _, p := range w.Params {
+        f.Params = append(f.Params, FileParam{Name: p.Name, Type: p.Type, Description: p.Description, Required: p.Required, Default: p.Default, Enum: p.Enum})
+    }
+    if w.Plan != nil { f.Steps = fileSteps(w.Plan.Steps) }
+    return f
+}
+
+// FileForTask exports a task's current plan. Parameters of a task created from a
+// workflow are declared with the values it was run with as defaults, so the file
+// loads back as-is.
+func FileForTask(t *models.Task) *File {
+    f := &File{Name: t.ID, Description: t.Query}
+    if t.Workflow != "" { f.Metadata = map[string]string{"workflow": t.Workflow} }
+    names := make([]string, 0, len(t.Params))
+    for k := range t.Params { names = append(names, k) }
+    sort.Strings(names)
+    for _, k := range names {
+        f.Params = append(f.Params, FileParam{Name: k, Type: jsonType(t.Params[k]), Default: t.Params[k]})
+    }
+    if t.Plan != nil { f.Steps = fileSteps(t.Plan.Steps) }
+    return f
+}
+
+func fileSteps(steps []*models.Step) []*FileStep {
+    out := []*FileStep{}
+    for _, s := range steps {
+        if s == nil { continue }
+        fs := &FileStep{ID: s.ID, Description: s.Description, Tool: s.Tool, Deps: s.Deps, Inputs: s.Inputs, If: s.Condition, Timeout: s.Timeout}
+        if s.Map != nil {
+            fs.Tool = ""
+            fs.Map = &FileMap{Over: s.Map.Over, As: s.Map.As, Parallelism: s.Map.Parallelism, Steps: fileSteps(s.Map.Steps)}
+        }
+        out = append(out, fs)
+    }
+    return out
+}
+
+func jsonType(v any) string {
+    switch x := v.(type) {
+    case bool:
+        return "boolean"
+    case float64:
+        if x == math.Trunc(x) { return "integer" }
+        return "number"
+    case int, int64:
+        return "integer"
+    case []any:
+        return "array"
+    case map[string]any:
+        return "object"
+    }
+    return "string"
+}
+
+// Marshal writes a file as "yaml" (the default) or "json".
+func (f *File) Marshal(format string) ([]byte, error) {
+    switch format {
+    case "", "yaml", "yml":
+        var buf bytes.Buffer
+        enc := yaml.NewEncoder(&buf)
+        enc.SetIndent(2)
+        if err := enc.Encode(f); err != nil { return nil, err }
+        enc.Close()
+        return buf.Bytes(), nil
+    case "json":
+        b, err := json.MarshalIndent(f, "", "  ")
+        return append(b, '\n'), err
+    }
+    return nil, fmt.Errorf("unknown format %q (yaml or json)", format)
+}
@@ -0,0 +1,89 @@
+package workflows
+
+import (
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/models"
+)
+
+const fetchFile = `description: Fetch a page
+metadata: {owner: search}
+query: "fetch {{params.url}}"
+params:
+  - {name: url, type: string, required: true}
+  - {name: depth, type: integer, default: 2}
+steps:
+  - id: fetch
+    tool: echo
+    inputs: {text: "{{params.url}}", n: 3}
+    timeout: 30s
+  - id: each
+    deps: [fetch]
+    map:
+      over: "{{step:fetch.output}}"
+      as: item
+      steps:
+        - id: say
+          tool: echo
+          inputs: {text: "{{item}}"}
+`
+
+func TestParseFile(t *testing.T) {
+    l := newTestLibrary(t, "")
+    w, err := l.ParseFile("dir/fetch-page.yaml", []byte(fetchFile))
+    if err != nil { t.Fatal(err) }
+    if w.Name != "fetch-page" || w.Source != "dir/fetch-page.yaml" || w.Metadata["owner"] != "search" || len(w.Params) != 2 { t.Fatalf("workflow %+v", w) }
+    // YAML values come out as JSON would decode them
+    if w.Params[1].Default != float64(2) || w.Plan.Steps[0].Inputs["n"] != float64(3) { t.Fatalf("not normalized: %#v %#v", w.Params[1].Default, w.Plan.Steps[0].Inputs["n"]) }
+    each := w.Plan.Steps[1]
+    if each.Tool != "map" || each.Map.As != "item" || each.Map.Steps[0].ID != "say" || w.Plan.Steps[0].Timeout != "30s" { t.Fatalf("steps %+v %+v", w.Plan.Steps[0], each) }
+}
+
+func TestParseFileErrors(t *testing.T) {
+    l := newTestLibrary(t, "")
+    for _, c := range []struct{ name, data, want string }{
+        {"empty", "", "f.yaml: empty file"},
+        {"unknown field", "steps:\n  - id: a\n    tool: echo\n    colour: red\n", "f.yaml:4: field colour not found"},
+        {"bad yaml", "steps: [\n", "f.yaml:"},
+        {"unknown tool", "steps:\n  - id: a\n    tool: echo\n  - id: b\n    tool: nope\n", "f.yaml:4: step b"},
+        {"duplicate id", "steps:\n  - id: a\n    tool: echo\n  - id: a\n    tool: echo\n", `f.yaml:4: duplicate step id "a"`},
+        {"undeclared param", "steps:\n  - id: a\n    tool: echo\n    inputs:\n      text: \"{{params.who}}\"\n", "f.yaml:5: {{params.who}} is not a declared parameter"},
+        {"bad param", "params:\n  - {name: n, type: float}\nsteps:\n  - id: a\n    tool: echo\n", `f.yaml:2: parameter n: unknown type "float"`},
+        {"bad name", "steps: []\nname: \"a b\"\n", `f.yaml:2: invalid name "a b"`},
+        {"sub-step", "steps:\n  - id: m\n    map:\n      over: \"[]\"\n      steps:\n        - id: x\n          tool: nope\n", "f.yaml:6: m.step x"},
+    } {
+        _, err := l.ParseFile("f.yaml", []byte(c.data))
+        if err == nil { t.Errorf("%s: accepted", c.name); continue }
+        if got := problems(err); !strings.Contains(got, c.want) { t.Errorf("%s: problems %q, want %q", c.name, got, c.want) }
+    }
+}
+
+func TestFileRoundTrip(t *testing.T) {
+    l := newTestLibrary(t, "")
+    w, err := l.ParseFile("fetch.yaml", []byte(fetchFile))
+    if err != nil { t.Fatal(err) }
+    for _, format := range []string{"yaml", "json"} {
+        b, err := FileFor(w).Marshal(format)
+        if err != nil { t.Fatal(err) }
+        back, err := l.ParseFile("fetch."+format, b)
+        if err != nil { t.Fatalf("%s: %v\n%s", format, err, b) }
+        if !sameFile(FileFor(back), FileFor(w)) { t.Fatalf("%s round trip changed the workflow:\n%s", format, b) }
+    }
+    if _, err := FileFor(w).Marshal("toml"); err == nil { t.Fatal("unknown format accepted") }
+}
+
+func TestFileForTask(t *testing.T) {
+    task := &models.Task{ID: "t1", Query: "q", Workflow: "greet@2", Params: map[string]any{"who": "ann", "n": float64(2), "ok": true},
+        Plan: &models.Plan{Steps: []*models.Step{{ID: "say", Tool: "echo", Inputs: map[string]any{"text": "{{params.who}} {{params.n}} {{params.ok}}"}}}}}
+    f := FileForTask(task)
+    if f.Name != "t1" || f.Metadata["workflow"] != "greet@2" { t.Fatalf("file %+v", f) }
+    types := map[string]string{}
+    for _, p := range f.Params { types[p.Name] = p.Type }
+    if types["who"] != "string" || types["n"] != "integer" || types["ok"] != "boolean" { t.Fatalf("param types %v", types) }
+    // the export loads back as a workflow whose defaults reproduce the run
+    b, _ := f.Marshal("yaml")
+    w, err := newTestLibrary(t, "").ParseFile("t1.yaml", b)
+    if err != nil { t.Fatalf("%v\n%s", err, b) }
+    if bound, err := w.BindParams(nil); err != nil || bound["who"] != "ann" { t.Fatalf("bound %v, %v", bound, err) }
+}
@@ -0,0 +1,130 @@
+package workflows
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+)
+
+// fileState is what LoadDir last saw of a file.
+type fileState struct {
+    mod  time.Time
+    size int64
+    // name is the workflow the file defines, once it loaded successfully
+    name string
+}
+
+// LoadDir loads every *.yaml, *.yml and *.json file in dir as a workflow (see File).
+// Valid files are loaded even when others fail; the failures are returned as one
+// *Error. Files that did not change since the last call are skipped, and a file
+// whose content matches the workflow's latest version does not add a version.
+func (l *Library) LoadDir(dir string) error {
+    entries, err := os.ReadDir(dir)
+    if err != nil { return err }
+    seen := map[string]bool{}
+    var problems []string
+    for _, e := range entries {
+        ext := strings.ToLower(filepath.Ext(e.Name()))
+        if e.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") { continue }
+        info, err := e.Info()
+        if err != nil { continue }
+        path := filepath.Join(dir, e.Name())
+        seen[path] = true
+        l.mu.Lock()
+        prev, known := l.files[path]
+        l.mu.Unlock()
+        if known && prev.mod.Equal(info.ModTime()) && prev.size == info.Size() { continue }
+        st := fileState{mod: info.ModTime(), size: info.Size(), name: prev.name}
+        if err := l.loadFile(path, &st); err != nil {
+            var we *Error
+            if errors.As(err, &we) { problems = append(problems, we.Problems...) } else { problems = append(problems, err.Error()) }
+        }
+        l.mu.Lock()
+        l.files[path] = st
+        l.mu.Unlock()
+    }
+    // a removed file takes its workflow with it, unless it was redefined since
+    l.mu.Lock()
+    var gone []fileState
+    var gonePaths []string
+    for path, st := range l.files {
+        if strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) && !seen[path] {
+            delete(l.files, path)
+            gone, gonePaths = append(gone, st), append(gonePaths, path)
+        }
+    }
+    l.mu.Unlock()
+    for i, st := range gone {
+        if w, ok := l.Get(st.name, 0); ok && w.Source == gonePaths[i] {
+            l.Delete(st.name)
+            log.Printf("workflow %s removed with %s", st.name, gonePaths[i])
+        }
+    }
+    if len(problems) > 0 {
+        sort.Strings(problems)
+        return &Error{Problems: problems}
+    }
+    return nil
+}
+
+func (l *Library) loadFile(path string, st *fileState) error {
+    data, err := os.ReadFile(path)
+    if err != nil { return err }
+    w, err := l.ParseFile(path, data)
+    if err != nil { return err }
+    l.mu.Lock()
+    for other, ost := range l.files {
+        if other != path && ost.name == w.Name {
+            l.mu.Unlock()
+            return fmt.Errorf("%s: workflow %q is already defined in %s", path, w.Name, other)
+        }
+    }
+    l.mu.Unlock()
+    if st.name != "" && st.name != w.Name {
+        // renamed: the old name goes away like a removed file
+        if old, ok := l.Get(st.name, 0); ok && old.Source == path { l.Delete(st.name) }
+    }
+    st.name = w.Name
+    if latest, ok := l.Get(w.Name, 0); ok && sameFile(FileFor(latest), FileFor(w)) { return nil }
+    w.Author = "file:" + filepath.Base(path)
+    saved, err := l.Save(w)
+    if err != nil { return err }
+    log.Printf("workflow %s@%d loaded from %s", saved.Name, saved.Version, path)
+    return nil
+}
+
+func sameFile(a, b *File) bool {
+    ba, _ := json.Marshal(a)
+    bb, _ := json.Marshal(b)
+    return string(ba) == string(bb)
+}
+
+// WatchDir reloads dir every interval until ctx ends (hot reload). Invalid files are
+// logged and leave the workflow's previous version in place.
+func (l *Library) WatchDir(ctx context.Context, dir string, interval time.Duration) {
+    go func() {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case <-ticker.C:
+            }
+            if err := l.LoadDir(dir); err != nil { logLoadError(dir, err) }
+        }
+    }()
+}
+
+func logLoadError(dir string, err error) {
+    var we *Error
+    if !errors.As(err, &we) { log.Printf("workflows %s: %v", dir, err); return }
+    for _, p := range we.Problems { log.Printf("workflow file %s", p) }
+}
@@ -0,0 +1,95 @@
+package workflows
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+)
+
+func echoFile(text string) string {
+    return "steps:\n  - id: say\n    tool: echo\n    inputs: {text: " + text + "}\n"
+}
+
+// writeFile writes a workflow file with a modification time that differs from the
+// previous write, so LoadDir sees the change.
+func writeFile(t *testing.T, path, data string) {
+    t.Helper()
+    if err := os.WriteFile(path, []byte(data), 0o644); err != nil { t.Fatal(err) }
+    next := time.Now().Add(time.Duration(len(data)) * time.Second)
+    if info, err := os.Stat(path); err == nil && !next.After(info.ModTime()) { next = info.ModTime().Add(time.Second) }
+    os.Chtimes(path, next, next)
+}
+
+func TestLoadDir(t *testing.T) {
+    dir := t.TempDir()
+    l := newTestLibrary(t, "")
+    writeFile(t, filepath.Join(dir, "a.yaml"), echoFile("one"))
+    writeFile(t, filepath.Join(dir, "b.json"), `{"steps": [{"id": "say", "tool": "echo"}]}`)
+    writeFile(t, filepath.Join(dir, "broken.yml"), "steps:\n  - id: x\n    tool: nope\n")
+    writeFile(t, filepath.Join(dir, "notes.txt"), "ignored")
+    err := l.LoadDir(dir)
+    // the valid files load despite the broken one, which is reported with its line
+    if err == nil || !strings.Contains(problems(err), "broken.yml:2: step x") { t.Fatalf("err %v", err) }
+    if names := len(l.List()); names != 2 { t.Fatalf("%d workflows", names) }
+    a, _ := l.Get("a", 0)
+    if a.Author != "file:a.yaml" || a.Source != filepath.Join(dir, "a.yaml") { t.Fatalf("a %+v", a) }
+
+    // unchanged and rewritten-but-identical files add no version
+    writeFile(t, filepath.Join(dir, "a.yaml"), echoFile("one"))
+    l.LoadDir(dir)
+    if n := len(l.Versions("a")); n != 1 { t.Fatalf("%d versions after identical rewrite", n) }
+    writeFile(t, filepath.Join(dir, "a.yaml"), echoFile("two"))
+    l.LoadDir(dir)
+    if latest, _ := l.Get("a", 0); latest.Version != 2 || latest.Plan.Steps[0].Inputs["text"] != "two" { t.Fatalf("latest %+v", latest) }
+
+    // an invalid edit keeps the previous version
+    writeFile(t, filepath.Join(dir, "a.yaml"), "steps: [")
+    if err := l.LoadDir(dir); err == nil { t.Fatal("invalid edit accepted") }
+    if latest, _ := l.Get("a", 0); latest.Version != 2 { t.Fatalf("latest %d after invalid edit", latest.Version) }
+
+    // a second file defining the same name is rejected
+    writeFile(t, filepath.Join(dir, "c.yaml"), "name: b\n"+echoFile("x"))
+    if err := l.LoadDir(dir); err == nil || !strings.Contains(problems(err), `workflow "b" is already defined`) { t.Fatalf("duplicate name: %v", err) }
+    os.Remove(filepath.Join(dir, "c.yaml"))
+
+    // renaming a workflow inside its file drops the old name; removing the file drops it
+    writeFile(t, filepath.Join(dir, "b.json"), `{"name": "bee", "steps": [{"id": "say", "tool": "echo"}]}`)
+    l.LoadDir(dir)
+    if _, ok := l.Get("b", 0); ok { t.Fatal("old name kept after rename") }
+    os.Remove(filepath.Join(dir, "b.json"))
+    l.LoadDir(dir)
+    if _, ok := l.Get("bee", 0); ok { t.Fatal("workflow kept after its file was removed") }
+}
+
+// TestLoadDirKeepsRedefined removes a file whose workflow was saved again over the
+// API since: the newer version stays.
+func TestLoadDirKeepsRedefined(t *testing.T) {
+    dir := t.TempDir()
+    l := newTestLibrary(t, "")
+    writeFile(t, filepath.Join(dir, "a.yaml"), echoFile("one"))
+    l.LoadDir(dir)
+    w := greet()
+    w.Name = "a"
+    l.Save(w)
+    os.Remove(filepath.Join(dir, "a.yaml"))
+    l.LoadDir(dir)
+    if latest, ok := l.Get("a", 0); !ok || latest.Version != 2 { t.Fatalf("latest %+v", latest) }
+}
+
+func TestWatchDir(t *testing.T) {
+    dir := t.TempDir()
+    l := newTestLibrary(t, "")
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    l.WatchDir(ctx, dir, 5*time.Millisecond)
+    writeFile(t, filepath.Join(dir, "hot.yaml"), echoFile("x"))
+    deadline := time.Now().Add(2 * time.Second)
+    for {
+        if _, ok := l.Get("hot", 0); ok { break }
+        if time.Now().After(deadline) { t.Fatal("file not loaded") }
+        time.Sleep(5 * time.Millisecond)
+    }
+}
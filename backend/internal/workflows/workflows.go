@@ -0,0 +1,291 @@
+// Package workflows stores plans as named, versioned templates with typed parameters
+// and runs them without the planner.
+package workflows
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/example/agent-orchestrator/internal/jsonschema"
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/templating"
+)
+
+var ErrNotFound = errors.New("workflow not found")
+
+// Param declares one workflow parameter. Type is a JSON Schema type: string, number,
+// integer, boolean, array or object.
+type Param struct {
+    Name        string `json:"name"`
+    Type        string `json:"type"`
+    Description string `json:"description,omitempty"`
+    Required    bool   `json:"required,omitempty"`
+    Default     any    `json:"default,omitempty"`
+    Enum        []any  `json:"enum,omitempty"`
+}
+
+// Workflow is one version of a template. Steps use {{params.NAME}} anywhere a
+// template is allowed; Query (optional) may too and becomes the task query.
+type Workflow struct {
+    Name        string            `json:"name"`
+    Version     int               `json:"version"`
+    Description string            `json:"description,omitempty"`
+    Query       string            `json:"query,omitempty"`
+    Metadata    map[string]string `json:"metadata,omitempty"`
+    Params      []Param           `json:"params,omitempty"`
+    Plan        *models.Plan      `json:"plan"`
+    Author      string            `json:"author,omitempty"`
+    // Source is the file the version was loaded from, if any (see LoadDir).
+    Source      string            `json:"source,omitempty"`
+    CreatedAt   time.Time         `json:"created_at"`
+}
+
+// Error lists what is wrong with a workflow definition or with run parameters.
+type Error struct {
+    Problems []string `json:"problems"`
+}
+
+func (e *Error) Error() string { return strings.Join(e.Problems, "; ") }
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+var paramTypes = map[string]bool{"string": true, "number": true, "integer": true, "boolean": true, "array": true, "object": true}
+
+// Library keeps every version of every workflow. With Dir set, each workflow's
+// versions are saved as Dir/<name>.json and loaded by New.
+type Library struct {
+    Orch *orchestrator.Orchestrator
+    Dir  string
+
+    mu        sync.Mutex
+    workflows map[string][]*Workflow // name -> versions, oldest first
+    files     map[string]fileState   // LoadDir: path -> last state seen
+}
+
+// New creates a library, loading saved workflows from dir (which may be empty).
+func New(o *orchestrator.Orchestrator, dir string) (*Library, error) {
+    l := &Library{Orch: o, Dir: dir, workflows: map[string][]*Workflow{}, files: map[string]fileState{}}
+    if dir == "" { return l, nil }
+    if err := os.MkdirAll(dir, 0o755); err != nil { return nil, err }
+    files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+    if err != nil { return nil, err }
+    for _, f := range files {
+        b, err := os.ReadFile(f)
+        if err != nil { return nil, err }
+        var versions []*Workflow
+        if err := json.Unmarshal(b, &versions); err != nil { return nil, fmt.Errorf("%s: %v", filepath.Base(f), err) }
+        if len(versions) > 0 { l.workflows[versions[0].Name] = versions }
+    }
+    return l, nil
+}
+
+// Check validates a workflow definition: name, parameter declarations and defaults,
+// the plan, and that the plan only references declared parameters.
+func (l *Library) Check(w *Workflow) error {
+    var problems []string
+    if !validName.MatchString(w.Name) { problems = append(problems, fmt.Sprintf("invalid name %q (letters, digits, _ . -)", w.Name)) }
+    declared := map[string]bool{}
+    for _, p := range w.Params {
+        switch {
+        case p.Name == "":
+            problems = append(problems, "parameter without a name")
+        case declared[p.Name]:
+            problems = append(problems, fmt.Sprintf("duplicate parameter %q", p.Name))
+        case !paramTypes[p.Type]:
+            problems = append(problems, fmt.Sprintf("parameter %s: unknown type %q", p.Name, p.Type))
+        case p.Default != nil:
+            if err := jsonschema.Validate(p.schema(), p.Default); err != nil { problems = append(problems, fmt.Sprintf("parameter %s: default: %v", p.Name, err)) }
+        }
+        declared[p.Name] = true
+    }
+    var hasTool func(string) bool
+    if l.Orch != nil && l.Orch.Tools != nil { hasTool = func(name string) bool { _, ok := l.Orch.Tools.Get(name); return ok } }
+    var pe *orchestrator.PlanError
+    if err := orchestrator.ValidatePlan(w.Plan, hasTool); errors.As(err, &pe) {
+        problems = append(problems, pe.Problems...)
+    }
+    for _, name := range usedParams(w) {
+        if !declared[name] { problems = append(problems, fmt.Sprintf("{{params.%s}} is not a declared parameter", name)) }
+    }
+    if len(problems) > 0 { return &Error{Problems: problems} }
+    return nil
+}
+
+// usedParams lists the parameters the query and plan reference, without duplicates.
+func usedParams(w *Workflow) []string {
+    var all []string
+    all = append(all, templating.RootFields(w.Query, "params")...)
+    if w.Plan != nil {
+        // marshal to generic form so map blocks and conditions are searched too
+        b, _ := json.Marshal(w.Plan)
+        var doc any
+        json.Unmarshal(b, &doc)
+        all = append(all, templating.RootFields(doc, "params")...)
+    }
+    seen := map[string]bool{}
+    var out []string
+    for _, n := range all {
+        if !seen[n] { seen[n] = true; out = append(out, n) }
+    }
+    return out
+}
+
+func (p Param) schema() map[string]any {
+    s := map[string]any{"type": p.Type}
+    if len(p.Enum) > 0 { s["enum"] = p.Enum }
+    return s
+}
+
+// Save validates w and stores it as the next version of its name.
+func (l *Library) Save(w *Workflow) (*Workflow, error) {
+    if err := l.Check(w); err != nil { return nil, err }
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    versions := l.workflows[w.Name]
+    v := *w
+    v.Plan = w.Plan.Clone()
+    for _, s := range v.Plan.Steps { s.Status = "" }
+    v.Version = len(versions) + 1
+    if n := len(versions); n > 0 { v.Version = versions[n-1].Version + 1 }
+    v.CreatedAt = time.Now()
+    l.workflows[w.Name] = append(versions, &v)
+    l.saveLocked(w.Name)
+    return &v, nil
+}
+
+// Get returns one version of a workflow; version 0 is the latest.
+func (l *Library) Get(name string, version int) (*Workflow, bool) {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    versions := l.workflows[name]
+    if len(versions) == 0 { return nil, false }
+    if version == 0 { return versions[len(versions)-1], true }
+    for _, w := range versions {
+        if w.Version == version { return w, true }
+    }
+    return nil, false
+}
+
+// Versions returns every version of a workflow, oldest first.
+func (l *Library) Versions(name string) []*Workflow {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    return append([]*Workflow(nil), l.workflows[name]...)
+}
+
+// List returns the latest version of each workflow, by name.
+func (l *Library) List() []*Workflow {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    out := make([]*Workflow, 0, len(l.workflows))
+    for _, versions := range l.workflows { out = append(out, versions[len(versions)-1]) }
+    sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+    return out
+}
+
+// Delete removes a workflow with all its versions. Tasks created from it are kept.
+func (l *Library) Delete(name string) error {
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if _, ok := l.workflows[name]; !ok { return ErrNotFound }
+    delete(l.workflows, name)
+    if l.Dir != "" {
+        if err := os.Remove(filepath.Join(l.Dir, name+".json")); err != nil && !os.IsNotExist(err) { log.Printf("workflow %s: %v", name, err) }
+    }
+    return nil
+}
+
+// BindParams checks params against the declarations and fills in defaults. Unknown
+// parameters are rejected.
+func (w *Workflow) BindParams(params map[string]any) (map[string]any, error) {
+    props := map[string]any{}
+    var required []any
+    bound := map[string]any{}
+    for _, p := range w.Params {
+        props[p.Name] = p.schema()
+        if v, ok := params[p.Name]; ok && v != nil {
+            bound[p.Name] = v
+        } else if p.Default != nil {
+            bound[p.Name] = p.Default
+        } else if p.Required {
+            required = append(required, p.Name)
+        }
+    }
+    for k, v := range params {
+        if _, ok := bound[k]; !ok && v != nil { bound[k] = v }
+    }
+    schema := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+    if len(required) > 0 { schema["required"] = required }
+    if err := jsonschema.Validate(schema, bound); err != nil {
+        var problems []string
+        var errs jsonschema.Errors
+        if errors.As(err, &errs) {
+            for _, e := range errs { problems = append(problems, strings.Replace(e.Error(), "$", "params", 1)) }
+        } else {
+            problems = []string{err.Error()}
+        }
+        return nil, &Error{Problems: problems}
+    }
+    return bound, nil
+}
+
+// RunOptions are the per-run inputs of POST /workflows/{name}/run.
+type RunOptions struct {
+    Version  int            `json:"version,omitempty"`
+    Params   map[string]any `json:"params,omitempty"`
+    Context  map[string]any `json:"context,omitempty"`
+    Priority int            `json:"priority,omitempty"`
+    ClientID string         `json:"-"`
+}
+
+// Run creates a task from a workflow with bound parameters, sets the template's plan
+// (author "workflow:NAME@VERSION") and queues its execution. The planner is not used.
+// The task is returned as it was queued; one that cannot be queued is removed again.
+func (l *Library) Run(taskID, name string, opts RunOptions) (*models.Task, *orchestrator.QueuedJob, error) {
+    w, ok := l.Get(name, opts.Version)
+    if !ok { return nil, nil, ErrNotFound }
+    params, err := w.BindParams(opts.Params)
+    if err != nil { return nil, nil, err }
+    ref := fmt.Sprintf("%s@%d", w.Name, w.Version)
+    query := "workflow " + ref
+    if w.Query != "" {
+        q, err := templating.ResolveString(w.Query, &templating.Scope{Vars: map[string]any{"params": params}})
+        if err != nil { return nil, nil, &Error{Problems: []string{"query: " + err.Error()}} }
+        query = templating.Stringify(q)
+    }
+    o := l.Orch
+    t := o.AddTask(&models.Task{ID: taskID, Query: query, Context: opts.Context, Workflow: ref, Params: params, Priority: opts.Priority, ClientID: opts.ClientID})
+    plan := w.Plan.Clone()
+    _, err = o.EditPlan(taskID, "workflow:"+ref, "workflow", func(p *models.Plan) error { p.Steps = plan.Steps; return nil })
+    // a worker may pick the task up right away
+    snap := *t
+    if snap.Plan != nil { snap.Plan = snap.Plan.Clone() }
+    var job *orchestrator.QueuedJob
+    if err == nil { job, err = o.Enqueue(taskID, orchestrator.JobExecute) }
+    if err != nil {
+        if derr := o.DeleteTask(taskID); derr != nil { log.Printf("workflow %s: remove task %s: %v", ref, taskID, derr) }
+        return nil, nil, err
+    }
+    snap.Status, snap.UpdatedAt = models.StatusQueued, job.EnqueuedAt
+    return &snap, job, nil
+}
+
+func (l *Library) saveLocked(name string) {
+    if l.Dir == "" { return }
+    b, err := json.MarshalIndent(l.workflows[name], "", "  ")
+    if err == nil {
+        path := filepath.Join(l.Dir, name+".json")
+        if err = os.WriteFile(path+".tmp", b, 0o644); err == nil { err = os.Rename(path+".tmp", path) }
+    }
+    if err != nil { log.Printf("save workflow %s: %v", name, err) }
+}
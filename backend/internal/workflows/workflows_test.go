@@ -0,0 +1,113 @@
+package workflows
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "testing"
+
+    "github.com/example/agent-orchestrator/internal/agents"
+    "github.com/example/agent-orchestrator/internal/models"
+    "github.com/example/agent-orchestrator/internal/orchestrator"
+    "github.com/example/agent-orchestrator/internal/tools"
+)
+
+// newTestLibrary returns a library whose orchestrator runs echo steps and has no
+// workers, so runs stay queued until the test executes them.
+func newTestLibrary(t *testing.T, dir string) *Library {
+    t.Helper()
+    reg := tools.NewRegistry()
+    reg.Register(&tools.EchoTool{})
+    o := orchestrator.New(nil, &agents.ToolExecutor{Registry: reg}, &agents.SimpleVerifier{})
+    o.Tools = reg
+    l, err := New(o, dir)
+    if err != nil { t.Fatal(err) }
+    return l
+}
+
+func greet() *Workflow {
+    return &Workflow{
+        Name:   "greet",
+        Query:  "greet {{params.who}}",
+        Params: []Param{{Name: "who", Type: "string", Required: true}, {Name: "lang", Type: "string", Default: "en", Enum: []any{"en", "de"}}},
+        Plan:   &models.Plan{Steps: []*models.Step{{ID: "say", Tool: "echo", Inputs: map[string]any{"text": "hello {{params.who}} ({{params.lang}})"}}}},
+    }
+}
+
+func problems(err error) string {
+    var we *Error
+    if !errors.As(err, &we) { return "not a workflow error: " + err.Error() }
+    return strings.Join(we.Problems, "\n")
+}
+
+func TestCheck(t *testing.T) {
+    l := newTestLibrary(t, "")
+    if err := l.Check(greet()); err != nil { t.Fatal(err) }
+    w := greet()
+    w.Name = "../x"
+    w.Params = append(w.Params, Param{Name: "who", Type: "string"}, Param{Name: "n", Type: "float"}, Param{Name: "k", Type: "integer", Default: "ten"}, Param{Type: "string"})
+    w.Plan.Steps = append(w.Plan.Steps, &models.Step{ID: "x", Tool: "nope", Inputs: map[string]any{"text": "{{params.missing}}"}})
+    got := problems(l.Check(w))
+    for _, want := range []string{`invalid name "../x"`, `duplicate parameter "who"`, `parameter n: unknown type "float"`, "parameter k: default:", "parameter without a name", "nope", "{{params.missing}} is not a declared parameter"} {
+        if !strings.Contains(got, want) { t.Errorf("problems lack %q:\n%s", want, got) }
+    }
+}
+
+func TestVersions(t *testing.T) {
+    dir := t.TempDir()
+    l := newTestLibrary(t, dir)
+    v1, err := l.Save(greet())
+    if err != nil { t.Fatal(err) }
+    w := greet()
+    w.Description = "second"
+    v2, _ := l.Save(w)
+    if v1.Version != 1 || v2.Version != 2 { t.Fatalf("versions %d %d", v1.Version, v2.Version) }
+    if got, _ := l.Get("greet", 0); got.Version != 2 { t.Fatalf("latest %d", got.Version) }
+    if got, ok := l.Get("greet", 1); !ok || got.Description != "" { t.Fatalf("v1 %+v", got) }
+    if _, ok := l.Get("greet", 3); ok { t.Fatal("missing version found") }
+    // saved versions come back after a restart
+    loaded := newTestLibrary(t, dir)
+    if n := len(loaded.Versions("greet")); n != 2 { t.Fatalf("loaded %d versions", n) }
+    if err := loaded.Delete("greet"); err != nil { t.Fatal(err) }
+    if err := loaded.Delete("greet"); err != ErrNotFound { t.Fatalf("second delete: %v", err) }
+    if len(newTestLibrary(t, dir).List()) != 0 { t.Fatal("deleted workflow loaded") }
+}
+
+func TestBindParams(t *testing.T) {
+    w := greet()
+    bound, err := w.BindParams(map[string]any{"who": "ann"})
+    if err != nil || bound["who"] != "ann" || bound["lang"] != "en" { t.Fatalf("bound %v, %v", bound, err) }
+    for _, c := range []struct {
+        params map[string]any
+        want   string
+    }{
+        {map[string]any{}, "who"},
+        {map[string]any{"who": 3}, "params.who"},
+        {map[string]any{"who": "ann", "lang": "fr"}, "params.lang"},
+        {map[string]any{"who": "ann", "extra": true}, "extra"},
+    } {
+        if _, err := w.BindParams(c.params); err == nil || !strings.Contains(problems(err), c.want) { t.Errorf("%v: %v", c.params, err) }
+    }
+}
+
+func TestRun(t *testing.T) {
+    l := newTestLibrary(t, "")
+    l.Save(greet())
+    if _, _, err := l.Run("t0", "nope", RunOptions{}); err != ErrNotFound { t.Fatalf("unknown workflow: %v", err) }
+    if _, _, err := l.Run("t0", "greet", RunOptions{}); err == nil { t.Fatal("missing required parameter accepted") }
+    if _, ok := l.Orch.GetTask("t0"); ok { t.Fatal("rejected run created a task") }
+
+    task, job, err := l.Run("t1", "greet", RunOptions{Params: map[string]any{"who": "ann", "lang": "de"}, Priority: 2, ClientID: "c"})
+    if err != nil { t.Fatal(err) }
+    if task.Query != "greet ann" || task.Workflow != "greet@1" || task.ClientID != "c" || job.Kind != orchestrator.JobExecute || job.Priority != 2 { t.Fatalf("task %+v, job %+v", task, job) }
+    // the plan is used as is, recorded with the workflow as author
+    if vs := l.Orch.PlanVersions("t1"); len(vs) != 1 || vs[0].Author != "workflow:greet@1" { t.Fatalf("plan versions %+v", vs) }
+    if err := l.Orch.ExecutePlan(context.Background(), "t1"); err != nil { t.Fatal(err) }
+    ran, _ := l.Orch.GetTask("t1")
+    if out := ran.Results[0].Output; out != "echo: hello ann (de)" { t.Fatalf("output %v", out) }
+
+    // a plan that no longer validates leaves no task behind
+    l.Orch.Tools = tools.NewRegistry()
+    if _, _, err := l.Run("t2", "greet", RunOptions{Params: map[string]any{"who": "ann"}}); err == nil { t.Fatal("invalid plan queued") }
+    if _, ok := l.Orch.GetTask("t2"); ok { t.Fatal("failed run left its task") }
+}
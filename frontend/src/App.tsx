@@ -6,15 +6,31 @@ type Task = {
   status: string
   plan?: { steps: Step[] }
   results?: Result[]
+  last_run?: { id: string; status: string; steps: { id: string; status: string }[]; questions?: Question[] }
   created_at?: string
   updated_at?: string
 }
 type Step = { id: string; description: string; tool: string; status: string }
+type Question = { step_id: string; question: string; choices?: string[]; answer?: string }
 type Result = { step_id: string; output?: any; logs?: string; verified: boolean; error?: string }
 
+// The task plan is immutable; step statuses live on the latest run.
+const withRunStatuses = (t: Task): Task => {
+  if (!t.plan || !t.last_run) return t
+  const byId: Record<string, string> = {}
+  t.last_run.steps?.forEach(s => { byId[s.id] = s.status })
+  return { ...t, plan: { ...t.plan, steps: t.plan.steps.map(s => byId[s.id] ? { ...s, status: byId[s.id] } : s) } }
+}
+
 const API_BASE = 'http://localhost:8080'
 const API = (path: string) => `${API_BASE}${path}`
 
+// errors are {error, code, problems?}
+async function errorText(res: Response): Promise<string> {
+  const text = await res.text()
+  try { return JSON.parse(text).error ?? text } catch { return text }
+}
+
 export default function App() {
   const [tasks, setTasks] = useState<Task[]>([])
   const [query, setQuery] = useState('')
@@ -25,6 +41,7 @@ export default function App() {
   const [llmInfo, setLlmInfo] = useState<any | null>(null)
   const [streaming, setStreaming] = useState<Record<string,string>>({})
   const [copiedKey, setCopiedKey] = useState<string | null>(null)
+  const [answers, setAnswers] = useState<Record<string,string>>({})
   const [uploading, setUploading] = useState(false)
   const [drag, setDrag] = useState(false)
   const [pdfDataUrl, setPdfDataUrl] = useState<string | null>(null)
@@ -70,12 +87,12 @@ export default function App() {
   }
 
   async function refresh() {
-    const res = await fetch(API('/tasks'))
+    const res = await fetch(API('/v1/tasks'))
     const data = await res.json()
     setTasks(data)
     if (selected) {
-      const sres = await fetch(API(`/tasks/${selected.id}`))
-      setSelected(await sres.json())
+      const sres = await fetch(API(`/v1/tasks/${selected.id}`))
+      setSelected(withRunStatuses(await sres.json()))
     }
   }
 
@@ -91,7 +108,7 @@ export default function App() {
     es?.close()
     setEs(null)
     if (!selected) return
-    const src = new EventSource(API(`/tasks/${selected.id}/events`))
+    const src = new EventSource(API(`/v1/tasks/${selected.id}/events`))
     src.onmessage = (e) => {
       try {
         const data = JSON.parse(e.data)
@@ -101,8 +118,10 @@ export default function App() {
       } catch {}
     }
     src.addEventListener('snapshot', (e:any) => {
-      try { setSelected(JSON.parse(e.data)) } catch {}
+      try { setSelected(withRunStatuses(JSON.parse(e.data))) } catch {}
     })
+    // events were lost while disconnected; a fresh snapshot follows, partial token streams are stale
+    src.addEventListener('gap', () => { setStreaming({}) })
     src.addEventListener('update', (e:any) => {
       try {
         const ev = JSON.parse(e.data)
@@ -110,6 +129,19 @@ export default function App() {
         if (ev.event === 'task_status') {
           setSelected(prev => prev && prev.id===ev.task_id ? { ...prev, status: ev.payload?.status || prev.status } : prev)
           setTasks(prev => prev.map(t => t.id===ev.task_id ? { ...t, status: ev.payload?.status || t.status } : t))
+        } else if (ev.event === 'run_status' && ev.payload?.status === 'RUNNING') {
+          // a new run starts from a clean slate
+          setSelected(prev => {
+            if (!prev || prev.id!==ev.task_id) return prev
+            const steps = prev.plan?.steps?.map((s:any) => ({ ...s, status: 'PENDING' }))
+            return { ...prev, results: [], plan: prev.plan ? { ...prev.plan, steps } : prev.plan }
+          })
+        } else if (ev.event === 'question') {
+          setSelected(prev => {
+            if (!prev || prev.id!==ev.task_id || !prev.last_run) return prev
+            const questions = [...(prev.last_run.questions||[]), ev.payload]
+            return { ...prev, last_run: { ...prev.last_run, questions } }
+          })
         } else if (ev.event === 'plan') {
           setSelected(prev => prev && prev.id===ev.task_id ? { ...prev, plan: ev.payload } : prev)
         } else if (ev.event === 'step_status') {
@@ -145,33 +177,59 @@ export default function App() {
       if (pdfDataUrl) {
         body.context = { pdf_data_base64: pdfDataUrl, filename: pdfName }
       }
-      const res = await fetch(API('/tasks'), {
+      const res = await fetch(API('/v1/tasks'), {
         method: 'POST', headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify(body)
       })
       const data: Task = await res.json()
       setQuery('')
       setTasks(prev => [data, ...prev])
-      setSelected(data)
+      setSelected(withRunStatuses(data))
     } finally { setBusy(false) }
   }
 
   async function startTask(id: string) {
     setBusy(true)
-    try { await fetch(API(`/tasks/start/${id}`), { method: 'POST' }); await refresh() } finally { setBusy(false) }
+    try { await fetch(API(`/v1/tasks/${id}:start`), { method: 'POST' }); await refresh() } finally { setBusy(false) }
   }
 
   async function planTask(id: string) {
     setBusy(true)
     try {
-      const res = await fetch(API(`/tasks/plan/${id}`), { method: 'POST' })
+      const res = await fetch(API(`/v1/tasks/${id}:plan`), { method: 'POST' })
       if (res.ok) await refresh()
     } finally { setBusy(false) }
   }
 
   async function executeTask(id: string) {
     setBusy(true)
-    try { await fetch(API(`/tasks/execute/${id}`), { method: 'POST' }); await refresh() } finally { setBusy(false) }
+    try { await fetch(API(`/v1/tasks/${id}:execute`), { method: 'POST' }); await refresh() } finally { setBusy(false) }
+  }
+
+  async function controlTask(id: string, action: 'pause' | 'resume' | 'cancel') {
+    const res = await fetch(API(`/v1/tasks/${id}:${action}`), { method: 'POST' })
+    if (!res.ok) alert(await errorText(res))
+  }
+
+  async function decideStep(id: string, stepId: string, action: 'approve' | 'reject') {
+    // the server records who decided and refuses decisions without a name
+    const by = window.prompt(`Your name (recorded with the ${action === 'approve' ? 'approval' : 'rejection'})`, localStorage.getItem('approver') || '')?.trim()
+    if (!by) return
+    localStorage.setItem('approver', by)
+    const res = await fetch(API(`/v1/tasks/${id}/steps/${encodeURIComponent(stepId)}:${action}`), {
+      method: 'POST', headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ by })
+    })
+    if (!res.ok) alert(await errorText(res))
+  }
+
+  async function answerStep(id: string, stepId: string, answer: string) {
+    const res = await fetch(API(`/v1/tasks/${id}/steps/${encodeURIComponent(stepId)}:answer`), {
+      method: 'POST', headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ answer })
+    })
+    if (!res.ok) { alert(await errorText(res)); return }
+    setAnswers(prev => { const n={...prev}; delete n[stepId]; return n })
   }
 
   async function fetchLLM() {
@@ -268,6 +326,9 @@ export default function App() {
                 <button className="btn ghost sm" onClick={()=> planTask(selectedId!)} disabled={busy}>Plan</button>
                 <button className="btn secondary md" onClick={()=> executeTask(selectedId!)} disabled={busy}>Execute</button>
                 <button className="btn primary lg" onClick={()=> startTask(selectedId!)} disabled={busy}>Start</button>
+                {['RUNNING','AWAITING_APPROVAL','AWAITING_INPUT'].includes(selected.status) ? <button className="btn ghost sm" onClick={()=> controlTask(selectedId!, 'pause')}>Pause</button> : null}
+                {selected.status==='PAUSED' ? <button className="btn secondary md" onClick={()=> controlTask(selectedId!, 'resume')}>Resume</button> : null}
+                {['RUNNING','AWAITING_APPROVAL','AWAITING_INPUT','PAUSED'].includes(selected.status) ? <button className="btn ghost sm" onClick={()=> controlTask(selectedId!, 'cancel')}>Cancel</button> : null}
               </div>
             </div>
             <div>
@@ -280,6 +341,27 @@ export default function App() {
                         {(s.status==='RUNNING' || streaming[s.id]) ? <span className="spinner" aria-label="loading" /> : null}
                       </div>
                       <div className="muted small">{s.id} — {s.description}</div>
+                      {s.status==='AWAITING_APPROVAL' ? (
+                        <div className="toolbar" style={{gap:8}}>
+                          <button className="btn primary sm" onClick={()=> decideStep(selected.id, s.id, 'approve')}>Approve</button>
+                          <button className="btn ghost sm" onClick={()=> decideStep(selected.id, s.id, 'reject')}>Reject</button>
+                        </div>
+                      ) : null}
+                      {s.status==='AWAITING_INPUT' ? selected.last_run?.questions?.filter(q => !q.answer && (q.step_id===s.id || q.step_id.startsWith(s.id+'['))).map(q => (
+                        <div key={q.step_id}>
+                          <div className="small"><strong>{q.question}</strong>{q.step_id!==s.id ? <span className="muted"> ({q.step_id})</span> : null}</div>
+                          {q.choices?.length ? (
+                            <div className="toolbar" style={{gap:8}}>
+                              {q.choices.map(c => <button key={c} className="btn secondary sm" onClick={()=> answerStep(selected.id, q.step_id, c)}>{c}</button>)}
+                            </div>
+                          ) : (
+                            <div className="toolbar" style={{gap:8}}>
+                              <input value={answers[q.step_id]||''} onChange={e => setAnswers(prev => ({ ...prev, [q.step_id]: e.target.value }))} placeholder="Your answer" />
+                              <button className="btn primary sm" onClick={()=> answerStep(selected.id, q.step_id, answers[q.step_id]||'')} disabled={!answers[q.step_id]}>Answer</button>
+                            </div>
+                          )}
+                        </div>
+                      )) : null}
                       {s.inputs ? <pre>{JSON.stringify(s.inputs,null,2)}</pre> : null}
                       {streaming[s.id] ? (
                         <div>